@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/internal/anomaly"
+	"github.com/Alias1177/Predictor/internal/cli"
+	"github.com/Alias1177/Predictor/internal/instrument"
+)
+
+func init() {
+	// Load .env file
+	cli.LoadEnv()
+}
+
+// Обучает изолирующие леса аномалий для всех символов и таймфреймов и сохраняет их
+// вместе со статистикой заполнения гэпов выходного дня
+func main() {
+	apiKey := cli.APIKey(true)
+
+	outputDir := cli.String("ANOMALY_MODEL_DIR", "data/anomaly")
+	symbols := cli.List("ANOMALY_SYMBOLS", cli.AllSymbols)
+	intervals := cli.List("ANOMALY_INTERVALS", cli.AllIntervals)
+	days := cli.Int("ANOMALY_DAYS", 30)
+	trees := cli.Int("ANOMALY_TREES", 100)
+	sampleSize := cli.Int("ANOMALY_SAMPLE_SIZE", 256)
+	// Гэпы бывают раз в неделю, поэтому их статистика собирается на более длинной истории
+	gapDays := cli.Int("ANOMALY_GAP_DAYS", 365)
+
+	ctx := context.Background()
+
+	for _, symbol := range symbols {
+		for _, interval := range intervals {
+			cfg := cli.Config(apiKey, symbol, interval)
+			client := config.NewClient(cfg)
+
+			candles, err := client.GetHistoricalCandles(ctx, days)
+			if err != nil {
+				log.Printf("Failed to fetch %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			forest, err := anomaly.TrainIsolationForest(candles, trees, sampleSize, time.Now().UnixNano())
+			if err != nil {
+				log.Printf("Failed to train isolation forest for %s %s: %v", symbol, interval, err)
+				continue
+			}
+			forest.Symbol = symbol
+			forest.Interval = interval
+
+			if err := anomaly.SaveIsolationForest(outputDir, forest); err != nil {
+				log.Printf("Failed to save isolation forest for %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			printForest(forest)
+
+			// Криптовалюты торгуются без выходных
+			if instrument.Class(symbol) == instrument.ClassCrypto {
+				continue
+			}
+			if gapDays > days {
+				if candles, err = client.GetHistoricalCandles(ctx, gapDays); err != nil {
+					log.Printf("Failed to fetch gap history for %s %s: %v", symbol, interval, err)
+					continue
+				}
+			}
+			gaps := anomaly.BuildGapStats(candles, symbol, interval)
+			if err := anomaly.SaveGapStats(outputDir, gaps); err != nil {
+				log.Printf("Failed to save gap stats for %s %s: %v", symbol, interval, err)
+				continue
+			}
+			fmt.Printf("Гэпов выходного дня: %d, заполнено за %d свечей: %d (%d свечей истории)\n",
+				gaps.Occurrences, gaps.Horizon, gaps.Filled, gaps.Candles)
+		}
+	}
+}
+
+// printForest выводит пороги оценок, соответствующие основным перцентилям
+func printForest(forest *anomaly.IsolationForest) {
+	fmt.Printf("\n===== %s %s (%d деревьев, %d свечей) =====\n",
+		forest.Symbol, forest.Interval, len(forest.Trees), len(forest.Calibration))
+
+	for _, q := range []float64{0.5, 0.9, 0.99, 0.999} {
+		index := int(q * float64(len(forest.Calibration)-1))
+		fmt.Printf("p%-6.1f оценка=%.3f\n", q*100, forest.Calibration[index])
+	}
+}
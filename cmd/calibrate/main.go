@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/internal/baktest"
+	"github.com/Alias1177/Predictor/internal/calibration"
+	"github.com/Alias1177/Predictor/internal/cli"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/internal/strategy"
+	"github.com/Alias1177/Predictor/models"
+)
+
+func init() {
+	// Load .env file
+	cli.LoadEnv()
+}
+
+// Обучает калибраторы вероятностей по результатам бэктеста и сохраняет их
+func main() {
+	// Каталог свечей "EUR-USD_1h.csv": калибровка на сохраненной истории вместо загрузки из сети
+	candlesDir := cli.String("CALIBRATION_CANDLES_DIR", "")
+
+	apiKey := cli.APIKey(candlesDir == "")
+
+	outputDir := cli.String("CALIBRATION_DIR", "data/calibration")
+	symbols := cli.List("CALIBRATION_SYMBOLS", cli.CoreSymbols)
+	intervals := cli.List("CALIBRATION_INTERVALS", cli.CoreIntervals)
+	strategies := cli.List("CALIBRATION_STRATEGIES", []string{strategy.DefaultStrategy})
+	days := cli.Int("CALIBRATION_DAYS", 30)
+
+	// Счет зависит от весов, поэтому калибровка обучается на тех же параметрах, что и прогнозы
+	paramsFile := cli.String("STRATEGY_PARAMS_FILE", "config/strategy_params.json")
+	if loaded, err := params.LoadFile(paramsFile); err != nil {
+		log.Printf("Failed to load strategy params from %s, using built-in weights: %v", paramsFile, err)
+	} else {
+		log.Printf("Strategy params %s loaded", loaded.Version)
+	}
+
+	ctx := context.Background()
+
+	for _, name := range strategies {
+		for _, symbol := range symbols {
+			for _, interval := range intervals {
+				cfg := cli.Config(apiKey, symbol, interval)
+				cfg.BacktestDays = days
+				cfg.Strategy = name
+				var source baktest.CandleSource = baktest.ClientSource{Client: config.NewClient(cfg), Days: days}
+				if candlesDir != "" {
+					source = baktest.StoreSource{Store: baktest.DirStore{Dir: candlesDir}, Symbol: symbol, Interval: interval}
+				}
+
+				// Калибровке денежные итоги не нужны, поэтому счет ведется в котируемой валюте
+				// и кроссы не требуют курсов пересчета
+				simulation := baktest.DefaultSimulation()
+				_, simulation.AccountCurrency, _ = strings.Cut(symbol, "/")
+				results, err := baktest.Run(ctx, source, cfg, baktest.Options{Simulation: &simulation})
+				if err != nil {
+					log.Printf("Backtest failed for %s %s %s: %v", name, symbol, interval, err)
+					continue
+				}
+				if results.Calibration == nil {
+					log.Printf("Not enough predictions to calibrate %s %s %s", name, symbol, interval)
+				} else if err := calibration.Save(outputDir, results.Calibration.Calibrator); err != nil {
+					log.Printf("Failed to save calibrator for %s %s %s: %v", name, symbol, interval, err)
+				} else {
+					printReport(name, symbol, interval, results.Calibration)
+				}
+
+				// Калибраторы прогнозов на горизонтах дальше одной свечи
+				horizons := make([]string, 0, len(results.HorizonCalibration))
+				for h := range results.HorizonCalibration {
+					horizons = append(horizons, h)
+				}
+				sort.Strings(horizons)
+				for _, h := range horizons {
+					report := results.HorizonCalibration[h]
+					if err := calibration.Save(outputDir, report.Calibrator); err != nil {
+						log.Printf("Failed to save %s calibrator for %s %s %s: %v", h, name, symbol, interval, err)
+						continue
+					}
+					printReport(name, symbol, interval+" "+h, report)
+				}
+			}
+		}
+	}
+}
+
+// printReport выводит диаграмму надежности на отложенной части истории
+func printReport(name, symbol, interval string, report *models.CalibrationReport) {
+	fmt.Printf("\n===== %s %s %s: %s (обучение %d, проверка %d) =====\n",
+		name, symbol, interval, report.Method, report.TrainSamples, report.TestSamples)
+	fmt.Printf("Брайер: %.4f (базовый %.4f), ECE: %.4f\n", report.BrierScore, report.BaselineBrier, report.ECE)
+
+	for _, bin := range report.Bins {
+		if bin.Count == 0 {
+			continue
+		}
+		fmt.Printf("%3.0f-%3.0f%%  прогноз=%5.1f%%  факт=%5.1f%%  n=%d\n",
+			bin.Lower*100, bin.Upper*100, bin.MeanPredicted*100, bin.ObservedRate*100, bin.Count)
+	}
+}
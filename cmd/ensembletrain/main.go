@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/internal/cli"
+	"github.com/Alias1177/Predictor/internal/ensemble"
+	"github.com/Alias1177/Predictor/internal/ml"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/internal/patterns"
+	"github.com/Alias1177/Predictor/internal/strategy"
+	"github.com/Alias1177/Predictor/models"
+)
+
+func init() {
+	// Load .env file
+	cli.LoadEnv()
+}
+
+// Собирает голоса участников ансамбля на истории после конца их обучения, обучает веса
+// стекинга на отложенной проверке и сохраняет их для стратегии ensemble. Участники
+// обучаются с ENSEMBLE_HOLDOUT_DAYS, чтобы последние дни истории остались вне их выборки
+func main() {
+	apiKey := cli.APIKey(true)
+
+	outputDir := cli.String("ENSEMBLE_MODEL_DIR", "data/ensemble")
+	symbols := cli.List("ENSEMBLE_SYMBOLS", cli.CoreSymbols)
+	intervals := cli.List("ENSEMBLE_INTERVALS", cli.CoreIntervals)
+	days := cli.Int("ENSEMBLE_DAYS", 30)
+	horizon := cli.Int("ENSEMBLE_HORIZON", 1)
+
+	// Голоса участников зависят от весов скоринга, статистики паттернов и ML-моделей
+	paramsFile := cli.String("STRATEGY_PARAMS_FILE", "config/strategy_params.json")
+	if loaded, err := params.LoadFile(paramsFile); err != nil {
+		log.Printf("Failed to load strategy params from %s, using built-in weights: %v", paramsFile, err)
+	} else {
+		log.Printf("Strategy params %s loaded", loaded.Version)
+	}
+	statsDir := cli.String("PATTERN_STATS_DIR", "data/pattern_stats")
+	if loaded, err := patterns.LoadPatternStatsDir(statsDir); err != nil {
+		log.Printf("Failed to load pattern stats from %s: %v", statsDir, err)
+	} else {
+		log.Printf("Pattern stats loaded: %d tables", loaded)
+	}
+	mlDir := cli.String("ML_MODEL_DIR", "data/ml")
+	if loaded, err := ml.LoadDir(mlDir); err != nil {
+		log.Printf("Failed to load ML models from %s: %v", mlDir, err)
+	} else {
+		log.Printf("ML models loaded: %d", loaded)
+	}
+
+	ctx := context.Background()
+
+	for _, symbol := range symbols {
+		for _, interval := range intervals {
+			cfg := cli.Config(apiKey, symbol, interval)
+			client := config.NewClient(cfg)
+
+			candles, err := client.GetHistoricalCandles(ctx, days)
+			if err != nil {
+				log.Printf("Failed to fetch %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			// Голоса участников собираются только вне их обучающей истории
+			trainingEnd := strategy.MembersTrainingEnd(symbol, interval)
+			dataset, err := strategy.CollectEnsembleVotes(ctx, candles, cfg, cfg.CandleCount, horizon, trainingEnd)
+			if err != nil {
+				log.Printf("Failed to collect votes for %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			weights, err := ensemble.Fit(dataset)
+			if err != nil {
+				log.Printf("Failed to fit ensemble for %s %s: %v", symbol, interval, err)
+				continue
+			}
+			weights.Symbol = symbol
+			weights.Interval = interval
+
+			if err := ensemble.Save(outputDir, weights); err != nil {
+				log.Printf("Failed to save ensemble for %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			printWeights(weights)
+		}
+	}
+}
+
+// printWeights выводит веса участников и их точность на отложенной проверке
+func printWeights(weights *models.EnsembleWeights) {
+	fmt.Printf("\n===== %s %s: %s (наблюдений %d) =====\n",
+		weights.Symbol, weights.Interval, weights.Method, weights.Samples)
+	fmt.Printf("Точность ансамбля на проверке: %.2f%%, свободный член %+.4f\n",
+		weights.ValidationAccuracy, weights.Bias)
+	for i, name := range weights.Members {
+		fmt.Printf("%-16s вес %+.4f, точность %.2f%%\n", name, weights.Weights[i], weights.MemberAccuracy[name])
+	}
+}
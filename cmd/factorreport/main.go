@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/Alias1177/Predictor/internal/database"
+	"github.com/Alias1177/Predictor/models"
+	"github.com/joho/godotenv"
+)
+
+func init() {
+	// Load .env file
+	if err := godotenv.Load(); err != nil {
+		log.Println(".env file not found, relying on actual environment variables")
+	}
+}
+
+// Выводит выученные веса факторов и их точность по периодам
+func main() {
+	dbParams := database.ConnectionParams{
+		Host:     os.Getenv("DB_HOST"),
+		Port:     os.Getenv("DB_PORT"),
+		User:     os.Getenv("DB_USER"),
+		Password: os.Getenv("DB_PASSWORD"),
+		DBName:   os.Getenv("DB_NAME"),
+		SSLMode:  os.Getenv("DB_SSLMODE"),
+	}
+
+	db, err := database.New(dbParams)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	days := getEnvInt("FACTOR_REPORT_DAYS", 90)
+	period := os.Getenv("FACTOR_REPORT_PERIOD")
+	if period == "" {
+		period = "week"
+	}
+
+	weights, err := db.LoadFactorWeights()
+	if err != nil {
+		log.Fatalf("Failed to load factor weights: %v", err)
+	}
+
+	fmt.Printf("===== ВЕСА ФАКТОРОВ =====\n")
+	fmt.Printf("%-16s %-9s %-6s %-32s %8s %10s %10s %10s  %s\n", "Стратегия", "Символ", "ТФ", "Фактор", "Вес", "Сигналов", "Точность", "Вклад", "Обновлен")
+	for _, w := range weights {
+		accuracy := 0.0
+		if w.Occurrences > 0 {
+			accuracy = w.Hits / w.Occurrences * 100
+		}
+		fmt.Printf("%-16s %-9s %-6s %-32s %8.2f %10.1f %9.1f%% %10.2f  %s\n",
+			w.Strategy, w.Symbol, w.Interval, w.FactorID, w.Weight, w.Occurrences, accuracy, w.Contribution, w.LastUpdate.Format("2006-01-02 15:04"))
+	}
+
+	report, err := db.FactorReport(time.Now().AddDate(0, 0, -days), period)
+	if err != nil {
+		log.Fatalf("Failed to build factor report: %v", err)
+	}
+
+	printReport(report, period, days)
+}
+
+// printReport выводит точность и вклад каждого фактора стратегии по периодам
+func printReport(report []models.FactorReportRow, period string, days int) {
+	fmt.Printf("\n===== ТОЧНОСТЬ ФАКТОРОВ ЗА %d ДНЕЙ (период: %s) =====\n", days, period)
+
+	current := ""
+	for _, row := range report {
+		if key := row.Strategy + "/" + row.FactorID; key != current {
+			current = key
+			fmt.Printf("\n%s (средний вес %.2f)\n", key, row.Weight)
+		}
+		fmt.Printf("  %s  сигналов=%-5d верных=%-5d точность=%5.1f%%  вклад=%.2f\n",
+			row.Period, row.Occurrences, row.Hits, row.Accuracy, row.AvgContribution)
+	}
+}
+
+// Helper function to get integer environment variables
+func getEnvInt(key string, defaultVal int) int {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultVal
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		return defaultVal
+	}
+	return value
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/internal/anomaly"
+	"github.com/Alias1177/Predictor/internal/cli"
+)
+
+func init() {
+	// Load .env file
+	cli.LoadEnv()
+}
+
+// Обучает HMM режимов рынка для всех символов и таймфреймов и сохраняет параметры моделей
+func main() {
+	apiKey := cli.APIKey(true)
+
+	outputDir := cli.String("HMM_MODEL_DIR", "data/hmm")
+	symbols := cli.List("HMM_SYMBOLS", cli.AllSymbols)
+	intervals := cli.List("HMM_INTERVALS", cli.AllIntervals)
+	days := cli.Int("HMM_DAYS", 30)
+	states := cli.Int("HMM_STATES", 3)
+	window := cli.Int("HMM_WINDOW", 10)
+	iterations := cli.Int("HMM_ITERATIONS", 100)
+
+	ctx := context.Background()
+
+	for _, symbol := range symbols {
+		for _, interval := range intervals {
+			cfg := cli.Config(apiKey, symbol, interval)
+			client := config.NewClient(cfg)
+
+			candles, err := client.GetHistoricalCandles(ctx, days)
+			if err != nil {
+				log.Printf("Failed to fetch %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			model, err := anomaly.TrainHMM(candles, states, window, iterations)
+			if err != nil {
+				log.Printf("Failed to train HMM for %s %s: %v", symbol, interval, err)
+				continue
+			}
+			model.Symbol = symbol
+			model.Interval = interval
+
+			if err := anomaly.SaveHMM(outputDir, model); err != nil {
+				log.Printf("Failed to save HMM for %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			printModel(model)
+		}
+	}
+}
+
+// printModel выводит параметры состояний и матрицу переходов
+func printModel(model *anomaly.GaussianHMM) {
+	fmt.Printf("\n===== %s %s (итераций %d, logL %.2f) =====\n",
+		model.Symbol, model.Interval, model.Iterations, model.LogLikelihood)
+
+	for k := 0; k < model.States; k++ {
+		fmt.Printf("%-9s доходность=%+.2f bp волатильность=%.2f bp переходы=",
+			model.Labels[k], model.Means[k][0], model.Means[k][1])
+		for j := 0; j < model.States; j++ {
+			fmt.Printf(" %.3f", model.Transition[k][j])
+		}
+		fmt.Println()
+	}
+}
@@ -6,14 +6,32 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Alias1177/Predictor/config"
-	"github.com/Alias1177/Predictor/internal/analyze"
 	"github.com/Alias1177/Predictor/internal/anomaly"
 	"github.com/Alias1177/Predictor/internal/baktest"
+	"github.com/Alias1177/Predictor/internal/benchmark"
 	"github.com/Alias1177/Predictor/internal/calculate"
+	"github.com/Alias1177/Predictor/internal/calendar"
+	"github.com/Alias1177/Predictor/internal/calibration"
+	"github.com/Alias1177/Predictor/internal/costs"
+	"github.com/Alias1177/Predictor/internal/database"
+	"github.com/Alias1177/Predictor/internal/ensemble"
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/internal/horizon"
+	"github.com/Alias1177/Predictor/internal/ml"
+	"github.com/Alias1177/Predictor/internal/news"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/internal/patterns"
+	"github.com/Alias1177/Predictor/internal/strategy"
+	"github.com/Alias1177/Predictor/internal/strength"
+	"github.com/Alias1177/Predictor/internal/utils"
+	"github.com/Alias1177/Predictor/internal/volatility"
 	"github.com/Alias1177/Predictor/models"
 	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -168,6 +186,23 @@ func main() {
 		cfg.BacktestDays = 5
 	}
 
+	cfg.Strategy = os.Getenv("STRATEGY")
+	if cfg.Strategy == "" {
+		cfg.Strategy = strategy.DefaultStrategy
+	}
+
+	if marketEnv := os.Getenv("ENABLE_MARKET_ANALYSIS"); marketEnv != "" {
+		cfg.EnableMarketAnalysis = marketEnv == "true" || marketEnv == "1" || marketEnv == "yes"
+	}
+	if strengthEnv := os.Getenv("ENABLE_CURRENCY_STRENGTH"); strengthEnv != "" {
+		cfg.EnableCurrencyStrength = strengthEnv == "true" || strengthEnv == "1" || strengthEnv == "yes"
+	}
+
+	cfg.Language = os.Getenv("FACTOR_LANGUAGE")
+	if cfg.Language == "" {
+		cfg.Language = explain.DefaultLanguage
+	}
+
 	// Для отладки выводим текущие значения конфигурации
 	fmt.Printf("Используемая конфигурация:\n")
 	fmt.Printf("Symbol: %s\n", cfg.Symbol)
@@ -181,42 +216,273 @@ func main() {
 	fmt.Printf("ATR Period: %d\n", cfg.ATRPeriod)
 	fmt.Printf("Adaptive Indicator: %t\n", cfg.AdaptiveIndicator)
 	fmt.Printf("Backtest: %t, Days: %d\n", cfg.EnableBacktest, cfg.BacktestDays)
+	fmt.Printf("Strategy: %s\n", cfg.Strategy)
+	fmt.Printf("Factor Language: %s\n", cfg.Language)
 
 	lvl, _ := zerolog.ParseLevel("info")
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr}).Level(lvl)
 
 	fmt.Println("Backtest enabled:", cfg.EnableBacktest)
 
+	// Загружаем статистику исходов паттернов для скоринга
+	statsDir := os.Getenv("PATTERN_STATS_DIR")
+	if statsDir == "" {
+		statsDir = "data/pattern_stats"
+	}
+	if loaded, err := patterns.LoadPatternStatsDir(statsDir); err != nil {
+		log.Warn().Err(err).Str("dir", statsDir).Msg("Failed to load pattern statistics")
+	} else {
+		log.Info().Int("tables", loaded).Str("dir", statsDir).Msg("Pattern statistics loaded")
+	}
+
+	hmmDir := os.Getenv("HMM_MODEL_DIR")
+	if hmmDir == "" {
+		hmmDir = "data/hmm"
+	}
+	if loaded, err := anomaly.LoadHMMDir(hmmDir); err != nil {
+		log.Warn().Err(err).Str("dir", hmmDir).Msg("Failed to load regime HMMs")
+	} else {
+		log.Info().Int("models", loaded).Str("dir", hmmDir).Msg("Regime HMMs loaded")
+	}
+
+	anomalyDir := os.Getenv("ANOMALY_MODEL_DIR")
+	if anomalyDir == "" {
+		anomalyDir = "data/anomaly"
+	}
+	if loaded, err := anomaly.LoadIsolationForestDir(anomalyDir); err != nil {
+		log.Warn().Err(err).Str("dir", anomalyDir).Msg("Failed to load isolation forests")
+	} else {
+		log.Info().Int("models", loaded).Str("dir", anomalyDir).Msg("Isolation forests loaded")
+	}
+	if loaded, err := anomaly.LoadGapStatsDir(anomalyDir); err != nil {
+		log.Warn().Err(err).Str("dir", anomalyDir).Msg("Failed to load gap statistics")
+	} else {
+		log.Info().Int("tables", loaded).Str("dir", anomalyDir).Msg("Gap statistics loaded")
+	}
+
+	calibrationDir := os.Getenv("CALIBRATION_DIR")
+	if calibrationDir == "" {
+		calibrationDir = "data/calibration"
+	}
+	if loaded, err := calibration.LoadDir(calibrationDir); err != nil {
+		log.Warn().Err(err).Str("dir", calibrationDir).Msg("Failed to load probability calibrators")
+	} else {
+		log.Info().Int("models", loaded).Str("dir", calibrationDir).Msg("Probability calibrators loaded")
+	}
+
+	// Выученные веса факторов берутся из базы бота и сохраняются туда после бэктеста,
+	// если база настроена
+	if os.Getenv("DB_HOST") != "" {
+		db, err := database.New(database.ConnectionParams{
+			Host:     os.Getenv("DB_HOST"),
+			Port:     os.Getenv("DB_PORT"),
+			User:     os.Getenv("DB_USER"),
+			Password: os.Getenv("DB_PASSWORD"),
+			DBName:   os.Getenv("DB_NAME"),
+			SSLMode:  os.Getenv("DB_SSLMODE"),
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to connect to database, factor weights will not be persisted")
+		} else {
+			defer db.Close()
+			if weights, err := db.LoadFactorWeights(); err != nil {
+				log.Warn().Err(err).Msg("Failed to load factor weights")
+			} else {
+				utils.LoadFactorWeights(weights)
+				log.Info().Int("factors", len(weights)).Msg("Factor weights loaded")
+			}
+			utils.SetFactorStore(db)
+		}
+	}
+
+	volatilityDir := os.Getenv("VOLATILITY_MODEL_DIR")
+	if volatilityDir == "" {
+		volatilityDir = "data/volatility"
+	}
+	if loaded, err := volatility.LoadDir(volatilityDir); err != nil {
+		log.Warn().Err(err).Str("dir", volatilityDir).Msg("Failed to load volatility models")
+	} else {
+		log.Info().Int("models", loaded).Str("dir", volatilityDir).Msg("Volatility models loaded")
+	}
+
+	mlDir := os.Getenv("ML_MODEL_DIR")
+	if mlDir == "" {
+		mlDir = "data/ml"
+	}
+	if loaded, err := ml.LoadDir(mlDir); err != nil {
+		log.Warn().Err(err).Str("dir", mlDir).Msg("Failed to load ML models")
+	} else {
+		log.Info().Int("models", loaded).Str("dir", mlDir).Msg("ML models loaded")
+	}
+
+	ensembleDir := os.Getenv("ENSEMBLE_MODEL_DIR")
+	if ensembleDir == "" {
+		ensembleDir = "data/ensemble"
+	}
+	if loaded, err := ensemble.LoadDir(ensembleDir); err != nil {
+		log.Warn().Err(err).Str("dir", ensembleDir).Msg("Failed to load ensemble weights")
+	} else {
+		log.Info().Int("models", loaded).Str("dir", ensembleDir).Msg("Ensemble weights loaded")
+	}
+
+	// News feed for the market analysis stage; without a provider news are skipped
+	newsCacheMinutes, err := strconv.Atoi(os.Getenv("NEWS_CACHE_MINUTES"))
+	if err != nil {
+		newsCacheMinutes = 15
+	}
+	newsProvider, err := news.Configure(os.Getenv("NEWS_PROVIDER"), os.Getenv("FINNHUB_API_KEY"),
+		os.Getenv("NEWS_FIXTURE_FILE"), 10*time.Second, time.Duration(newsCacheMinutes)*time.Minute)
+	if err != nil {
+		log.Warn().Err(err).Msg("News provider disabled")
+	} else if newsProvider != nil {
+		news.SetProvider(newsProvider)
+		log.Info().Str("provider", newsProvider.Name()).Int("cache_minutes", newsCacheMinutes).Msg("News provider configured")
+	}
+
+	// Economic calendar: predictions near high-impact releases are flagged or suppressed
+	window := calendar.DefaultWindow()
+	if val, err := strconv.Atoi(os.Getenv("EVENT_WINDOW_BEFORE")); err == nil && val >= 0 {
+		window.Before = time.Duration(val) * time.Minute
+	}
+	if val, err := strconv.Atoi(os.Getenv("EVENT_WINDOW_AFTER")); err == nil && val >= 0 {
+		window.After = time.Duration(val) * time.Minute
+	}
+	if impact := calendar.ParseImpact(os.Getenv("EVENT_MIN_IMPACT")); impact != "" {
+		window.MinImpact = impact
+	}
+	window.Suppress = os.Getenv("EVENT_BLACKOUT_MODE") != "flag"
+	calendar.SetWindow(window)
+	if calendarFile := os.Getenv("ECONOMIC_CALENDAR"); calendarFile != "" {
+		if loaded, err := calendar.LoadFile(context.Background(), calendarFile); err != nil {
+			log.Warn().Err(err).Str("calendar", calendarFile).Msg("Failed to load economic calendar")
+		} else {
+			log.Info().Int("events", loaded).Str("calendar", calendarFile).Msg("Economic calendar loaded")
+		}
+	}
+
+	if overridesEnv := os.Getenv("STRATEGY_OVERRIDES"); overridesEnv != "" {
+		overrides, err := strategy.ParseSymbolOverrides(overridesEnv)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid STRATEGY_OVERRIDES")
+		}
+		strategy.SetSymbolOverrides(overrides)
+	}
+
+	paramsFile := os.Getenv("STRATEGY_PARAMS_FILE")
+	if paramsFile == "" {
+		paramsFile = "config/strategy_params.json"
+	}
+	if loaded, err := params.LoadFile(paramsFile); err != nil {
+		log.Warn().Err(err).Str("file", paramsFile).Msg("Failed to load strategy params, using built-in weights")
+	} else {
+		log.Info().Str("version", loaded.Version).Str("file", paramsFile).Msg("Strategy params loaded")
+	}
+
 	// Остальной код остается без изменений
 	client := config.NewClient(&cfg)
 	ctx := context.Background()
 	if cfg.EnableBacktest {
 		log.Info().Msg("Running backtesting...")
-		results, err := baktest.RunBacktest(ctx, client, &cfg)
+		// BACKTEST_CANDLES задает файл свечей (CSV или JSON) для воспроизводимого бэктеста без сети;
+		// BACKTEST_FROM и BACKTEST_TO (RFC3339 или 2006-01-02) ограничивают диапазон прогнозов
+		var source baktest.CandleSource = baktest.ClientSource{Client: client, Days: cfg.BacktestDays}
+		if candlesFile := os.Getenv("BACKTEST_CANDLES"); candlesFile != "" {
+			source = baktest.FileSource{Path: candlesFile, Symbol: cfg.Symbol, Interval: cfg.Interval}
+		}
+		// Правила симулятора сделок: BACKTEST_AMBIGUITY (stop_first, target_first, nearest),
+		// тайм-стоп BACKTEST_MAX_BARS свечей, разворот по противоположному сигналу BACKTEST_EXIT_ON_OPPOSITE
+		simulation := baktest.DefaultSimulation()
+		if ambiguity := os.Getenv("BACKTEST_AMBIGUITY"); ambiguity != "" {
+			simulation.Ambiguity = ambiguity
+		}
+		if val, err := strconv.Atoi(os.Getenv("BACKTEST_MAX_BARS")); err == nil && val >= 0 {
+			simulation.MaxBars = val
+		}
+		if oppositeEnv := os.Getenv("BACKTEST_EXIT_ON_OPPOSITE"); oppositeEnv != "" {
+			simulation.ExitOnOpposite = oppositeEnv == "true" || oppositeEnv == "1" || oppositeEnv == "yes"
+		}
+		// Издержки: профили инструментов из COST_PROFILES_FILE поверх встроенных;
+		// BACKTEST_COSTS=false считает сделки без спреда, комиссий, проскальзывания и свопов
+		costsFile := os.Getenv("COST_PROFILES_FILE")
+		if costsFile == "" {
+			costsFile = "config/cost_profiles.json"
+		}
+		if count, err := costs.LoadFile(costsFile); err != nil {
+			log.Warn().Err(err).Str("file", costsFile).Msg("Failed to load cost profiles, using built-in costs")
+		} else {
+			log.Info().Int("profiles", count).Str("file", costsFile).Msg("Cost profiles loaded")
+		}
+		if costsEnv := os.Getenv("BACKTEST_COSTS"); costsEnv == "false" || costsEnv == "0" || costsEnv == "no" {
+			simulation.Costs = &models.CostProfile{}
+		}
+		// Валюта счета BACKTEST_ACCOUNT_CURRENCY (по умолчанию USD) и курсы для кроссов без нее
+		// BACKTEST_CONVERSION_RATES: стоимость единицы котируемой валюты в валюте счета, "JPY=0.0067,CHF=1.13"
+		simulation.AccountCurrency = strings.ToUpper(os.Getenv("BACKTEST_ACCOUNT_CURRENCY"))
+		if ratesEnv := os.Getenv("BACKTEST_CONVERSION_RATES"); ratesEnv != "" {
+			simulation.ConversionRates = make(map[string]float64)
+			for _, pair := range strings.Split(ratesEnv, ",") {
+				currency, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || rate <= 0 {
+					log.Fatal().Str("value", pair).Msg("Invalid BACKTEST_CONVERSION_RATES entry")
+				}
+				simulation.ConversionRates[strings.ToUpper(strings.TrimSpace(currency))] = rate
+			}
+		}
+		opts := baktest.Options{Simulation: &simulation}
+		for _, bound := range []struct {
+			env    string
+			target *time.Time
+		}{{"BACKTEST_FROM", &opts.From}, {"BACKTEST_TO", &opts.To}} {
+			value := os.Getenv(bound.env)
+			if value == "" {
+				continue
+			}
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				at, err = time.Parse(time.DateOnly, value)
+			}
+			if err != nil {
+				log.Fatal().Err(err).Str("value", value).Msgf("Invalid %s", bound.env)
+			}
+			*bound.target = at
+		}
+		results, err := baktest.Run(ctx, source, &cfg, opts)
 		if err != nil {
 			log.Error().Err(err).Msg("Backtest failed")
 		} else if results != nil {
-			// Вычисляем общую прибыль/убыток в процентах
-			initialBalance := 10000.0 // Начальный баланс
-			finalBalance := initialBalance
-			if len(results.DetailedResults) > 0 {
-				// Рассчитываем финальный баланс на основе сделок
-				for _, result := range results.DetailedResults {
-					if result.WasCorrect {
-						// Примерная прибыль (можно настроить под ваши формулы)
-						finalBalance += results.AverageGain
-					} else {
-						finalBalance -= results.AverageLoss
-					}
-				}
-			}
-			totalProfitPercent := ((finalBalance - initialBalance) / initialBalance) * 100
-
 			// Выводим результаты бэктестинга с процентами
 			fmt.Printf("\n===== РЕЗУЛЬТАТЫ БЭКТЕСТИНГА =====\n")
-			fmt.Printf("Всего сделок: %d\n", results.TotalTrades)
-			fmt.Printf("Успешных сделок: %d (%.2f%%)\n", results.WinningTrades, results.WinPercentage)
-			fmt.Printf("Общая доходность: %.2f%%\n", totalProfitPercent)
+			fmt.Printf("Стратегия: %s\n", results.Strategy)
+			fmt.Printf("Версия параметров: %s\n", results.ParamsVersion)
+			fmt.Printf("Период: %s - %s\n", results.From.Format(time.DateTime), results.To.Format(time.DateTime))
+			fmt.Printf("Проверено прогнозов: %d, верное направление: %d (%.2f%%)\n",
+				results.TotalTrades, results.WinningTrades, results.WinPercentage)
+			if stats := results.TradeStats; stats != nil {
+				fmt.Printf("Сделок: %d (прибыльных %d, %.2f%%), пропущено сигналов: %d, среднее удержание %.1f свечей\n",
+					stats.Trades, stats.Wins, stats.WinRate, stats.SkippedSignals, stats.AverageBars)
+				if stats.Ruined {
+					fmt.Printf("Баланс исчерпан, последующие сигналы не исполнялись\n")
+				}
+				gross := stats.Gross
+				fmt.Printf("%-22s %14s %14s\n", "", "Без издержек", "С издержками")
+				fmt.Printf("%-22s %14.1f %14.1f\n", "Итог, пунктов", gross.TotalPips, stats.TotalPips)
+				fmt.Printf("%-22s %14.2f %14.2f\n", "Итог, "+stats.Currency, gross.NetPnL, stats.NetPnL)
+				fmt.Printf("%-22s %14.2f %14.2f\n", "Итоговый баланс", gross.FinalBalance, stats.FinalBalance)
+				fmt.Printf("%-22s %13.2f%% %13.2f%%\n", "Прибыльных сделок", gross.WinRate, stats.WinRate)
+				fmt.Printf("%-22s %14.2f %14.2f\n", "Коэффициент прибыли", gross.ProfitFactor, stats.ProfitFactor)
+				fmt.Printf("%-22s %13.2f%% %13.2f%%\n", "Макс. просадка", gross.MaxDrawdown, stats.MaxDrawdown)
+				fmt.Printf("Издержки: спред %.2f, проскальзывание %.2f, комиссия %.2f, своп %.2f, всего %.2f %s\n",
+					stats.Costs.Spread, stats.Costs.Slippage, stats.Costs.Commission, stats.Costs.Swap,
+					stats.Costs.Total, stats.Currency)
+				reasons := make([]string, 0, len(stats.ExitReasons))
+				for reason, count := range stats.ExitReasons {
+					reasons = append(reasons, fmt.Sprintf("%s %d", reason, count))
+				}
+				sort.Strings(reasons)
+				fmt.Printf("Причины выхода: %s\n", strings.Join(reasons, ", "))
+			}
+			fmt.Printf("Общая доходность с издержками: %.2f%%\n", results.TotalReturnPercent)
 			fmt.Printf("Средняя прибыль на сделку: %.2f пунктов (%.2f%%)\n",
 				results.AverageGain, results.AverageGainPercent)
 			fmt.Printf("Средний убыток на сделку: %.2f пунктов (%.2f%%)\n",
@@ -228,8 +494,8 @@ func main() {
 			// Максимальная просадка
 			fmt.Printf("Максимальная просадка: %.2f%%\n", results.MaxDrawdown)
 
-			fmt.Printf("Макс. последовательных выигрышей: %d\n", results.MaxConsecutive.Wins)
-			fmt.Printf("Макс. последовательных проигрышей: %d\n", results.MaxConsecutive.Loses)
+			fmt.Printf("Макс. верных прогнозов подряд: %d\n", results.MaxConsecutive.Wins)
+			fmt.Printf("Макс. неверных прогнозов подряд: %d\n", results.MaxConsecutive.Loses)
 
 			// Выводим производительность по режимам рынка в процентах
 			fmt.Println("\nПроизводительность по режимам рынка:")
@@ -239,6 +505,30 @@ func main() {
 				}
 			}
 
+			// Статистика временной шкалы режимов
+			if results.RegimeTimeline != nil && len(results.RegimeTimeline.Stats) > 0 {
+				fmt.Println("\nСтатистика режимов рынка:")
+				for regime, stats := range results.RegimeTimeline.Stats {
+					fmt.Printf("- %s: %.1f%% истории, %d сегм., ср. длительность %.1f свечей, доходность %+.4f%%, волатильность %.4f%%\n",
+						regime, stats.Share, stats.Segments, stats.AvgDuration, stats.AvgReturn, stats.Volatility)
+				}
+			}
+
+			if len(results.RegimeMonthlyPerformance) > 0 {
+				fmt.Println("\nТочность по режимам и месяцам:")
+				months := make([]string, 0, len(results.RegimeMonthlyPerformance))
+				for month := range results.RegimeMonthlyPerformance {
+					months = append(months, month)
+				}
+				sort.Strings(months)
+
+				for _, month := range months {
+					for regime, winRate := range results.RegimeMonthlyPerformance[month] {
+						fmt.Printf("- %s %s: %.2f%%\n", month, regime, winRate)
+					}
+				}
+			}
+
 			// Отображение месячной доходности
 			if len(results.MonthlyReturns) > 0 {
 				fmt.Println("\nДоходность по месяцам:")
@@ -271,6 +561,46 @@ func main() {
 			// Общий рост капитала
 			fmt.Printf("\nОбщий рост капитала: %.2f%%\n", results.EquityGrowthPercent)
 
+			if report := results.Calibration; report != nil {
+				fmt.Printf("\nКалибровка вероятностей (%s, обучение %d, проверка %d):\n",
+					report.Method, report.TrainSamples, report.TestSamples)
+				fmt.Printf("Брайер: %.4f (базовый %.4f), ECE: %.4f\n", report.BrierScore, report.BaselineBrier, report.ECE)
+				for _, bin := range report.Bins {
+					if bin.Count > 0 {
+						fmt.Printf("- %3.0f-%3.0f%%: прогноз %5.1f%%, факт %5.1f%% (n=%d)\n",
+							bin.Lower*100, bin.Upper*100, bin.MeanPredicted*100, bin.ObservedRate*100, bin.Count)
+					}
+				}
+			}
+
+			if len(results.HorizonPerformance) > 0 {
+				fmt.Println("\nТочность по горизонтам:")
+				for _, h := range []string{horizon.NextBar, horizon.ThreeBars, horizon.TwelveBars, horizon.SessionEnd} {
+					if accuracy, ok := results.HorizonPerformance[h]; ok {
+						fmt.Printf("- %s: %.2f%%\n", h, accuracy)
+					}
+				}
+			}
+
+			// Обучаем веса факторов на проверенных прогнозах бэктеста
+			if err := utils.UpdateFactorWeights(results.Strategy, cfg.Symbol, cfg.Interval, results.DetailedResults); err != nil {
+				log.Warn().Err(err).Msg("Failed to update factor weights")
+			}
+			if weights := utils.FactorWeights(); len(weights) > 0 {
+				fmt.Printf("\nВеса факторов (%s, %s %s):\n", results.Strategy, cfg.Symbol, cfg.Interval)
+				for _, w := range weights {
+					if w.Strategy != results.Strategy || w.Symbol != cfg.Symbol || w.Interval != cfg.Interval {
+						continue
+					}
+					accuracy := 0.0
+					if w.Occurrences > 0 {
+						accuracy = w.Hits / w.Occurrences * 100
+					}
+					fmt.Printf("- %s: вес %.2f, точность %.1f%% (%.0f сигналов), вклад %.2f\n",
+						w.FactorID, w.Weight, accuracy, w.Occurrences, w.Contribution)
+				}
+			}
+
 			// Removed duplicate line:
 			// fmt.Printf("Успешных сделок: %d (%.2f%%)\n", results.WinningTrades, results.WinPercentage)
 		} else {
@@ -305,17 +635,94 @@ func main() {
 		}
 	}
 
-	// 7) Генерируем прогноз
-	prediction, err := analyze.EnhancedPrediction(
-		context.Background(), candles, indicators, mtfData, regime, anomaly2, &cfg)
+	// 7) Комплексный анализ рынка с индексом доллара (необязательный этап)
+	var marketAnalysis *models.MarketAnalysis
+	if cfg.EnableMarketAnalysis {
+		marketAnalysis, err = benchmark.Analyze(ctx, &cfg, candles)
+		if err != nil {
+			log.Warn().Err(err).Msg("Market analysis skipped")
+		} else {
+			fmt.Printf("Market analysis: %s (confidence %.0f%%), regime %s\n",
+				marketAnalysis.Direction, marketAnalysis.Confidence*100, marketAnalysis.MarketRegime)
+			if c := marketAnalysis.Correlations; c != nil && c.Benchmark != "" {
+				fmt.Printf("Benchmark %s: %+.2f%%, correlation %.2f\n", c.Benchmark, c.MarketTrend, c.MarketCorrelation)
+			}
+		}
+	}
+
+	// Рейтинг силы валют по основным парам (необязательный этап)
+	if cfg.EnableCurrencyStrength {
+		snapshot, err := strength.Update(ctx, &cfg, strength.DefaultPairs, strength.DefaultIntervals, strength.DefaultLookback)
+		if err != nil {
+			log.Warn().Err(err).Msg("Currency strength skipped")
+		} else {
+			fmt.Println("Currency strength:")
+			for i, entry := range snapshot.Currencies {
+				fmt.Printf("  %d. %s %+.2f\n", i+1, entry.Currency, entry.Score)
+			}
+		}
+	}
+
+	// 8) Генерируем прогноз выбранной стратегией
+	selected, err := strategy.Select(&cfg, "")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to select strategy")
+	}
+	prediction, err := selected.Predict(context.Background(), &models.StrategyInput{
+		Candles:        candles,
+		Indicators:     indicators,
+		MTFData:        mtfData,
+		Regime:         regime,
+		Anomaly:        anomaly2,
+		Config:         &cfg,
+		MarketAnalysis: marketAnalysis,
+	})
 	if err != nil {
 		log.Error().Err(err).Msg("Prediction failed")
 	} else {
-		fmt.Printf("Prediction: %s (conf=%s score=%.2f)\nFactors: %v\n",
-			prediction.Direction, prediction.Confidence, prediction.Score, prediction.Factors)
+		fmt.Printf("Prediction [%s, params %s]: %s (conf=%s score=%.2f)\nFactors: %v\n",
+			prediction.Strategy, prediction.ParamsVersion, prediction.Direction, prediction.Confidence, prediction.Score, prediction.Factors)
+		if prediction.Probability > 0 {
+			fmt.Printf("Probability: %.1f%% (%s)\n", prediction.Probability*100, prediction.CalibrationMethod)
+		}
+		if suggestion := prediction.TradingSuggestion; suggestion != nil && suggestion.ExpectedRange != nil {
+			vol := suggestion.ExpectedRange
+			fmt.Printf("Expected range (%s, %d bars, σ=%.2f%%): 68%% %.5f-%.5f, 95%% %.5f-%.5f\n",
+				vol.Method, vol.Bars, vol.HorizonSigma*100, vol.Range68.Low, vol.Range68.High, vol.Range95.Low, vol.Range95.High)
+			fmt.Printf("Stop loss: %.5f, take profit: %.5f (R:R %.2f)\n",
+				suggestion.StopLoss, suggestion.TakeProfit, suggestion.RiskRewardRatio)
+		}
+		if breakdown := prediction.Breakdown; breakdown != nil {
+			fmt.Println("Score breakdown:")
+			for _, factor := range append(breakdown.Factors, breakdown.Adjustments...) {
+				fmt.Printf("  %+7.3f %-10s %-24s %s\n", factor.Contribution, factor.Category, factor.ID,
+					explain.Describe(factor, cfg.Language))
+			}
+			fmt.Printf("  raw %.3f -> score %.3f\n", breakdown.RawScore, breakdown.Score)
+		}
+		if risk := prediction.EventRisk; risk != nil {
+			for _, event := range risk.Events {
+				fmt.Printf("Event window: %s %s %s at %s UTC\n",
+					event.Impact, event.Currency, event.Title, event.Time.UTC().Format("2006-01-02 15:04"))
+			}
+			if risk.Suppressed {
+				fmt.Println("Trade suppressed by the economic calendar blackout")
+			} else if risk.Next != nil {
+				fmt.Printf("Next event: %s %s %s at %s UTC\n",
+					risk.Next.Impact, risk.Next.Currency, risk.Next.Title, risk.Next.Time.UTC().Format("2006-01-02 15:04"))
+			}
+		}
+		for _, h := range prediction.Horizons {
+			fmt.Printf("Horizon %-11s (%4d bars, until %s): %-7s score=%6.2f move=±%.5f (%.2f%%)",
+				h.Horizon, h.Bars, h.PredictionTarget.UTC().Format("2006-01-02 15:04"), h.Direction, h.Score, h.ExpectedMove, h.ExpectedMovePct)
+			if h.Probability > 0 {
+				fmt.Printf(" p=%.1f%%", h.Probability*100)
+			}
+			fmt.Println()
+		}
 	}
 
-	// 8) Формируем prompt и шлём в OpenAI
+	// 9) Формируем prompt и шлём в OpenAI
 	//prompt := gpt.FormatPrompt(candles, cfg.Symbol)
 	//gpt.AskGPT(cfg.OpenAIAPIKey, prompt) // использует cfg.OpenAIAPIKey внутри
 }
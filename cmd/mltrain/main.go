@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/internal/cli"
+	"github.com/Alias1177/Predictor/internal/ml"
+	"github.com/Alias1177/Predictor/models"
+)
+
+func init() {
+	// Load .env file
+	cli.LoadEnv()
+}
+
+// Обучает логистическую регрессию и бустинг деревьев для всех символов и таймфреймов,
+// оценивает их на проверке по времени и сохраняет обе модели; стратегия ml выбирает лучшую
+func main() {
+	apiKey := cli.APIKey(true)
+
+	outputDir := cli.String("ML_MODEL_DIR", "data/ml")
+	symbols := cli.List("ML_SYMBOLS", cli.CoreSymbols)
+	intervals := cli.List("ML_INTERVALS", cli.CoreIntervals)
+	days := cli.Int("ML_DAYS", 60)
+	horizon := cli.Int("ML_HORIZON", 1)
+	folds := cli.Int("ML_FOLDS", 5)
+	holdoutDays := cli.Int("ENSEMBLE_HOLDOUT_DAYS", 0)
+
+	opts := ml.DefaultGBTOptions()
+	opts.Trees = cli.Int("ML_TREES", opts.Trees)
+	opts.Depth = cli.Int("ML_DEPTH", opts.Depth)
+
+	ctx := context.Background()
+
+	for _, symbol := range symbols {
+		for _, interval := range intervals {
+			// Индикаторы считаются с теми же периодами, что и в боте
+			cfg := cli.Config(apiKey, symbol, interval)
+			client := config.NewClient(cfg)
+
+			candles, err := client.GetHistoricalCandles(ctx, days)
+			if err != nil {
+				log.Printf("Failed to fetch %s %s: %v", symbol, interval, err)
+				continue
+			}
+			candles = cli.Holdout(candles, holdoutDays)
+
+			dataset, err := ml.BuildDataset(candles, cfg, cfg.CandleCount, horizon)
+			if err != nil {
+				log.Printf("Failed to build dataset for %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			for _, modelType := range []string{ml.TypeLogistic, ml.TypeGBT} {
+				report, err := ml.CrossValidate(dataset, modelType, opts, folds, horizon)
+				if err != nil {
+					log.Printf("Cross-validation of %s failed for %s %s: %v", modelType, symbol, interval, err)
+					continue
+				}
+
+				model, err := ml.Train(dataset, modelType, opts)
+				if err != nil {
+					log.Printf("Failed to train %s for %s %s: %v", modelType, symbol, interval, err)
+					continue
+				}
+				model.Symbol = symbol
+				model.Interval = interval
+				model.Horizon = horizon
+				model.Window = cfg.CandleCount
+				model.CV = report
+				model.DataEnd = candles[len(candles)-1].Timestamp
+
+				if err := ml.Save(outputDir, model); err != nil {
+					log.Printf("Failed to save %s for %s %s: %v", modelType, symbol, interval, err)
+					continue
+				}
+
+				printModel(model)
+			}
+		}
+	}
+}
+
+// printModel выводит качество модели на проверке по времени и веса признаков
+func printModel(model *models.MLModel) {
+	fmt.Printf("\n===== %s %s: %s (наблюдений %d, горизонт %d) =====\n",
+		model.Symbol, model.Interval, model.Type, model.Samples, model.Horizon)
+	fmt.Printf("CV: фолдов %d, проверка %d, лог-лосс %.4f (базовый %.4f), точность %.2f%%\n",
+		model.CV.Folds, model.CV.TestSamples, model.CV.LogLoss, model.CV.BaseLogLoss, model.CV.Accuracy)
+
+	if model.Type == ml.TypeLogistic {
+		for i, name := range model.Features {
+			fmt.Printf("%-18s %+.4f\n", name, model.Weights[i])
+		}
+	} else {
+		fmt.Printf("Деревьев: %d, скорость обучения %.2f\n", len(model.Trees), model.LearningRate)
+	}
+}
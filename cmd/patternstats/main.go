@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/internal/cli"
+	"github.com/Alias1177/Predictor/internal/patterns"
+	"github.com/Alias1177/Predictor/models"
+)
+
+func init() {
+	// Load .env file
+	cli.LoadEnv()
+}
+
+// Сканирует историю всех символов и таймфреймов и сохраняет статистику исходов паттернов
+func main() {
+	apiKey := cli.APIKey(true)
+
+	outputDir := cli.String("PATTERN_STATS_DIR", "data/pattern_stats")
+	symbols := cli.List("PATTERN_STATS_SYMBOLS", cli.AllSymbols)
+	intervals := cli.List("PATTERN_STATS_INTERVALS", cli.AllIntervals)
+	days := cli.Int("PATTERN_STATS_DAYS", 30)
+	horizon := cli.Int("PATTERN_STATS_HORIZON", 3)
+	holdoutDays := cli.Int("ENSEMBLE_HOLDOUT_DAYS", 0)
+
+	ctx := context.Background()
+
+	for _, symbol := range symbols {
+		for _, interval := range intervals {
+			cfg := cli.Config(apiKey, symbol, interval)
+			client := config.NewClient(cfg)
+
+			candles, err := client.GetHistoricalCandles(ctx, days)
+			if err != nil {
+				log.Printf("Failed to fetch %s %s: %v", symbol, interval, err)
+				continue
+			}
+			candles = cli.Holdout(candles, holdoutDays)
+
+			table := patterns.BuildPatternStats(candles, symbol, interval, horizon)
+			if err := patterns.SavePatternStats(outputDir, table); err != nil {
+				log.Printf("Failed to save stats for %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			printTable(table)
+		}
+	}
+}
+
+// printTable выводит таблицу hit-rate и матожидания по паттернам
+func printTable(table *models.PatternStatsTable) {
+	fmt.Printf("\n===== %s %s (%d свечей, горизонт %d) =====\n",
+		table.Symbol, table.Interval, table.Candles, table.Horizon)
+
+	names := make([]string, 0, len(table.Stats))
+	for name := range table.Stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		stats := table.Stats[name]
+		fmt.Printf("%-24s n=%-5d hit=%5.1f%% exp=%+.4f%%\n",
+			name, stats.Occurrences, stats.HitRate*100, stats.Expectancy)
+	}
+}
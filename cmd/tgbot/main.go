@@ -4,19 +4,36 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq"
 
 	"github.com/Alias1177/Predictor/config"
-	"github.com/Alias1177/Predictor/internal/analyze"
 	"github.com/Alias1177/Predictor/internal/anomaly"
+	"github.com/Alias1177/Predictor/internal/benchmark"
 	"github.com/Alias1177/Predictor/internal/calculate"
+	"github.com/Alias1177/Predictor/internal/calendar"
+	"github.com/Alias1177/Predictor/internal/calibration"
+	"github.com/Alias1177/Predictor/internal/correlation"
 	"github.com/Alias1177/Predictor/internal/database"
+	"github.com/Alias1177/Predictor/internal/ensemble"
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/internal/horizon"
+	"github.com/Alias1177/Predictor/internal/ml"
+	"github.com/Alias1177/Predictor/internal/news"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/internal/patterns"
 	"github.com/Alias1177/Predictor/internal/payment"
+	"github.com/Alias1177/Predictor/internal/strategy"
+	"github.com/Alias1177/Predictor/internal/strength"
+	"github.com/Alias1177/Predictor/internal/utils"
+	"github.com/Alias1177/Predictor/internal/volatility"
 	"github.com/Alias1177/Predictor/models"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -39,6 +56,24 @@ var (
 		"1min", "5min", "15min", "30min", "1h", "4h", "1day",
 	}
 
+	// correlationWindows are the rolling windows of the correlations view, in candles
+	correlationWindows = correlation.DefaultWindows
+	// correlationMaxAge is how old a correlation snapshot may get before the view refreshes it in the background
+	correlationMaxAge = time.Hour
+
+	// strengthIntervals and strengthLookback define the currency strength table
+	strengthIntervals = strength.DefaultIntervals
+	strengthLookback  = strength.DefaultLookback
+	// strengthMaxAge is how old the currency strength table may get before the view refreshes it in the background
+	strengthMaxAge = time.Hour
+
+	// backgroundRefreshes tracks on-demand refreshes in progress so that repeated
+	// requests do not start duplicate fetches over all supported pairs
+	backgroundRefreshes = struct {
+		mu      sync.Mutex
+		running map[string]bool
+	}{running: make(map[string]bool)}
+
 	// Промокод для бесплатного доступа - МЕНЯЙ ЗДЕСЬ НА СВОЙ
 	FREE_PROMO_CODE   = "FREEACCESS2025"
 	FREE24_PROMO_CODE = "FREE24"
@@ -74,6 +109,11 @@ type UserState struct {
 	PaymentURL   string    // Stripe payment URL
 	SessionID    string    // Stripe session ID
 	PromoCode    string    // Current promo code being used
+	Strategy     string    // Selected prediction strategy (empty means symbol/default strategy)
+	// LastPrediction is the latest prediction shown to the user, explained by the "Why?" button
+	LastPrediction *models.Prediction
+	// LastAnalysis is the market analysis behind the latest prediction, shown by the "Deep Analysis" button
+	LastAnalysis *models.MarketAnalysis
 }
 
 // Global variables for database and payment service
@@ -128,6 +168,226 @@ func main() {
 
 	logger.Info().Str("username", bot.Self.UserName).Msg("Authorized on Telegram")
 
+	// Load pattern outcome statistics used by the prediction scorer
+	statsDir := os.Getenv("PATTERN_STATS_DIR")
+	if statsDir == "" {
+		statsDir = "data/pattern_stats"
+	}
+	if loaded, err := patterns.LoadPatternStatsDir(statsDir); err != nil {
+		logger.Warn().Err(err).Str("dir", statsDir).Msg("Failed to load pattern statistics")
+	} else {
+		logger.Info().Int("tables", loaded).Str("dir", statsDir).Msg("Pattern statistics loaded")
+	}
+
+	hmmDir := os.Getenv("HMM_MODEL_DIR")
+	if hmmDir == "" {
+		hmmDir = "data/hmm"
+	}
+	if loaded, err := anomaly.LoadHMMDir(hmmDir); err != nil {
+		logger.Warn().Err(err).Str("dir", hmmDir).Msg("Failed to load regime HMMs")
+	} else {
+		logger.Info().Int("models", loaded).Str("dir", hmmDir).Msg("Regime HMMs loaded")
+	}
+
+	anomalyDir := os.Getenv("ANOMALY_MODEL_DIR")
+	if anomalyDir == "" {
+		anomalyDir = "data/anomaly"
+	}
+	if loaded, err := anomaly.LoadIsolationForestDir(anomalyDir); err != nil {
+		logger.Warn().Err(err).Str("dir", anomalyDir).Msg("Failed to load isolation forests")
+	} else {
+		logger.Info().Int("models", loaded).Str("dir", anomalyDir).Msg("Isolation forests loaded")
+	}
+	if loaded, err := anomaly.LoadGapStatsDir(anomalyDir); err != nil {
+		logger.Warn().Err(err).Str("dir", anomalyDir).Msg("Failed to load gap statistics")
+	} else {
+		logger.Info().Int("tables", loaded).Str("dir", anomalyDir).Msg("Gap statistics loaded")
+	}
+
+	calibrationDir := os.Getenv("CALIBRATION_DIR")
+	if calibrationDir == "" {
+		calibrationDir = "data/calibration"
+	}
+	if loaded, err := calibration.LoadDir(calibrationDir); err != nil {
+		logger.Warn().Err(err).Str("dir", calibrationDir).Msg("Failed to load probability calibrators")
+	} else {
+		logger.Info().Int("models", loaded).Str("dir", calibrationDir).Msg("Probability calibrators loaded")
+	}
+
+	volatilityDir := os.Getenv("VOLATILITY_MODEL_DIR")
+	if volatilityDir == "" {
+		volatilityDir = "data/volatility"
+	}
+	if loaded, err := volatility.LoadDir(volatilityDir); err != nil {
+		logger.Warn().Err(err).Str("dir", volatilityDir).Msg("Failed to load volatility models")
+	} else {
+		logger.Info().Int("models", loaded).Str("dir", volatilityDir).Msg("Volatility models loaded")
+	}
+
+	mlDir := os.Getenv("ML_MODEL_DIR")
+	if mlDir == "" {
+		mlDir = "data/ml"
+	}
+	if loaded, err := ml.LoadDir(mlDir); err != nil {
+		logger.Warn().Err(err).Str("dir", mlDir).Msg("Failed to load ML models")
+	} else {
+		logger.Info().Int("models", loaded).Str("dir", mlDir).Msg("ML models loaded")
+	}
+
+	ensembleDir := os.Getenv("ENSEMBLE_MODEL_DIR")
+	if ensembleDir == "" {
+		ensembleDir = "data/ensemble"
+	}
+	if loaded, err := ensemble.LoadDir(ensembleDir); err != nil {
+		logger.Warn().Err(err).Str("dir", ensembleDir).Msg("Failed to load ensemble weights")
+	} else {
+		logger.Info().Int("models", loaded).Str("dir", ensembleDir).Msg("Ensemble weights loaded")
+	}
+
+	// News feed for the market analysis stage; without a provider news are skipped
+	newsCacheMinutes, err := strconv.Atoi(os.Getenv("NEWS_CACHE_MINUTES"))
+	if err != nil {
+		newsCacheMinutes = 15
+	}
+	newsProvider, err := news.Configure(os.Getenv("NEWS_PROVIDER"), os.Getenv("FINNHUB_API_KEY"),
+		os.Getenv("NEWS_FIXTURE_FILE"), 10*time.Second, time.Duration(newsCacheMinutes)*time.Minute)
+	if err != nil {
+		logger.Warn().Err(err).Msg("News provider disabled")
+	} else if newsProvider != nil {
+		news.SetProvider(newsProvider)
+		logger.Info().Str("provider", newsProvider.Name()).Int("cache_minutes", newsCacheMinutes).Msg("News provider configured")
+	}
+
+	// Economic calendar: predictions near high-impact releases are flagged or suppressed
+	window := calendar.DefaultWindow()
+	if val, err := strconv.Atoi(os.Getenv("EVENT_WINDOW_BEFORE")); err == nil && val >= 0 {
+		window.Before = time.Duration(val) * time.Minute
+	}
+	if val, err := strconv.Atoi(os.Getenv("EVENT_WINDOW_AFTER")); err == nil && val >= 0 {
+		window.After = time.Duration(val) * time.Minute
+	}
+	if impact := calendar.ParseImpact(os.Getenv("EVENT_MIN_IMPACT")); impact != "" {
+		window.MinImpact = impact
+	}
+	window.Suppress = os.Getenv("EVENT_BLACKOUT_MODE") != "flag"
+	calendar.SetWindow(window)
+	if calendarFile := os.Getenv("ECONOMIC_CALENDAR"); calendarFile != "" {
+		if loaded, err := calendar.LoadFile(context.Background(), calendarFile); err != nil {
+			logger.Warn().Err(err).Str("calendar", calendarFile).Msg("Failed to load economic calendar")
+		} else {
+			logger.Info().Int("events", loaded).Str("calendar", calendarFile).Msg("Economic calendar loaded")
+		}
+		refreshInterval := 6 * time.Hour
+		if val, err := strconv.Atoi(os.Getenv("ECONOMIC_CALENDAR_REFRESH")); err == nil && val > 0 {
+			refreshInterval = time.Duration(val) * time.Minute
+		}
+		go calendar.Refresh(context.Background(), calendarFile, refreshInterval, func(loaded int, err error) {
+			if err != nil {
+				logger.Error().Err(err).Str("calendar", calendarFile).Msg("Economic calendar refresh failed, keeping previous events")
+				return
+			}
+			logger.Info().Int("events", loaded).Msg("Economic calendar refreshed")
+		})
+	}
+
+	// Rolling correlation matrix over all supported pairs, refreshed in the background
+	// for the timeframes in CORRELATION_INTERVALS and on the first request for the rest
+	if windowsEnv := os.Getenv("CORRELATION_WINDOWS"); windowsEnv != "" {
+		windows, err := correlation.ParseWindows(windowsEnv)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Invalid CORRELATION_WINDOWS")
+		}
+		correlationWindows = windows
+	}
+	if val, err := strconv.Atoi(os.Getenv("CORRELATION_REFRESH")); err == nil && val > 0 {
+		correlationMaxAge = time.Duration(val) * time.Minute
+	}
+	if intervalsEnv := os.Getenv("CORRELATION_INTERVALS"); intervalsEnv != "" {
+		var intervals []string
+		for _, interval := range strings.Split(intervalsEnv, ",") {
+			if interval = strings.TrimSpace(interval); contains(supportedIntervals, interval) {
+				intervals = append(intervals, interval)
+			}
+		}
+		go correlation.Refresh(context.Background(), marketDataConfig(), supportedPairs, intervals, correlationWindows,
+			correlationMaxAge, func(interval string, snapshot *models.CorrelationSnapshot, err error) {
+				if err != nil {
+					logger.Error().Err(err).Str("interval", interval).Msg("Correlation refresh failed, keeping previous matrix")
+					return
+				}
+				logger.Info().Str("interval", interval).Int("symbols", len(snapshot.Matrices[0].Symbols)).
+					Int("breakdowns", len(snapshot.Breakdowns)).Msg("Correlation matrix refreshed")
+			})
+	}
+
+	// Currency strength across all supported pairs; with the background refresh enabled
+	// it also feeds the CURRENCY_STRENGTH factor of the enhanced strategy
+	if intervalsEnv := os.Getenv("STRENGTH_INTERVALS"); intervalsEnv != "" {
+		var intervals []string
+		for _, interval := range strings.Split(intervalsEnv, ",") {
+			if interval = strings.TrimSpace(interval); contains(supportedIntervals, interval) {
+				intervals = append(intervals, interval)
+			}
+		}
+		if len(intervals) > 0 {
+			strengthIntervals = intervals
+		}
+	}
+	strengthLookback = getEnvInt("STRENGTH_LOOKBACK", strength.DefaultLookback)
+	if val, err := strconv.Atoi(os.Getenv("STRENGTH_REFRESH")); err == nil && val > 0 {
+		strengthMaxAge = time.Duration(val) * time.Minute
+	}
+	if getEnvBool("ENABLE_CURRENCY_STRENGTH", false) {
+		go strength.Refresh(context.Background(), marketDataConfig(), supportedPairs, strengthIntervals, strengthLookback,
+			strengthMaxAge, func(snapshot *models.CurrencyStrengthSnapshot, err error) {
+				if err != nil {
+					logger.Error().Err(err).Msg("Currency strength refresh failed, keeping previous table")
+					return
+				}
+				logger.Info().Int("pairs", snapshot.Pairs).Str("strongest", snapshot.Currencies[0].Currency).
+					Str("weakest", snapshot.Currencies[len(snapshot.Currencies)-1].Currency).Msg("Currency strength refreshed")
+			})
+	}
+
+	if overridesEnv := os.Getenv("STRATEGY_OVERRIDES"); overridesEnv != "" {
+		overrides, err := strategy.ParseSymbolOverrides(overridesEnv)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Invalid STRATEGY_OVERRIDES")
+		}
+		strategy.SetSymbolOverrides(overrides)
+	}
+
+	// Scoring weights are hot-reloaded when the params file changes
+	paramsFile := os.Getenv("STRATEGY_PARAMS_FILE")
+	if paramsFile == "" {
+		paramsFile = "config/strategy_params.json"
+	}
+	if loaded, err := params.LoadFile(paramsFile); err != nil {
+		logger.Warn().Err(err).Str("file", paramsFile).Msg("Failed to load strategy params, using built-in weights")
+	} else {
+		logger.Info().Str("version", loaded.Version).Str("file", paramsFile).Msg("Strategy params loaded")
+	}
+	reloadInterval := 30 * time.Second
+	if val, err := strconv.Atoi(os.Getenv("STRATEGY_PARAMS_RELOAD")); err == nil && val > 0 {
+		reloadInterval = time.Duration(val) * time.Second
+	}
+	go params.Watch(context.Background(), paramsFile, reloadInterval, func(p *models.StrategyParams, err error) {
+		if err != nil {
+			logger.Error().Err(err).Str("file", paramsFile).Msg("Strategy params reload failed, keeping previous version")
+			return
+		}
+		logger.Info().Str("version", p.Version).Msg("Strategy params reloaded")
+	})
+
+	// Learned factor weights survive restarts and are persisted after every update
+	if weights, err := db.LoadFactorWeights(); err != nil {
+		logger.Warn().Err(err).Msg("Failed to load factor weights")
+	} else {
+		utils.LoadFactorWeights(weights)
+		logger.Info().Int("factors", len(weights)).Msg("Factor weights loaded")
+	}
+	utils.SetFactorStore(db)
+
 	// Setup update configuration
 	updateConfig := tgbotapi.NewUpdate(0)
 	updateConfig.Timeout = 60
@@ -270,6 +530,62 @@ func handleMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message, logger *zero
 				}
 			}
 		}
+	case "📅 Events", "/events":
+		if state.Symbol == "" {
+			msg := tgbotapi.NewMessage(chatID, "Please select a currency pair first.")
+			bot.Send(msg)
+			sendCurrencyPairMenu(bot, chatID)
+			state.Stage = StageAwaitingPair
+			return
+		}
+		msg := tgbotapi.NewMessage(chatID, formatUpcomingEvents(state.Symbol, time.Now()))
+		msg.ParseMode = "Markdown"
+		bot.Send(msg)
+	case "🔗 Correlations", "/correlations":
+		if state.Symbol == "" || state.Interval == "" {
+			msg := tgbotapi.NewMessage(chatID, "Please select a currency pair and timeframe first.")
+			bot.Send(msg)
+			sendCurrencyPairMenu(bot, chatID)
+			state.Stage = StageAwaitingPair
+			return
+		}
+		// Fetching all supported pairs takes a while, so the view only serves the last
+		// snapshot and refreshes a missing or stale one outside the update loop
+		interval := state.Interval
+		snapshot, ok := correlation.Get(interval)
+		if !ok || time.Since(snapshot.UpdatedAt) > correlationMaxAge {
+			refreshInBackground("correlation:"+interval, func() {
+				cfg := marketDataConfig()
+				cfg.Interval = interval
+				if _, err := correlation.Update(context.Background(), cfg, supportedPairs, correlationWindows); err != nil {
+					logger.Error().Err(err).Str("interval", interval).Msg("Correlation update failed")
+				}
+			})
+		}
+		if !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+				"Correlations on %s are not ready yet, please try again in a minute.", interval)))
+			return
+		}
+		msg := tgbotapi.NewMessage(chatID, formatCorrelations(snapshot, state.Symbol))
+		msg.ParseMode = "Markdown"
+		bot.Send(msg)
+	case "💪 Strength", "/strength":
+		snapshot := strength.Active()
+		if snapshot == nil || time.Since(snapshot.UpdatedAt) > strengthMaxAge {
+			refreshInBackground("strength", func() {
+				if _, err := strength.Update(context.Background(), marketDataConfig(), supportedPairs, strengthIntervals, strengthLookback); err != nil {
+					logger.Error().Err(err).Msg("Currency strength update failed")
+				}
+			})
+		}
+		if snapshot == nil {
+			bot.Send(tgbotapi.NewMessage(chatID, "Currency strength is not ready yet, please try again in a minute."))
+			return
+		}
+		msg := tgbotapi.NewMessage(chatID, formatCurrencyStrength(snapshot, state.Symbol))
+		msg.ParseMode = "Markdown"
+		bot.Send(msg)
 	case "💎 Subscribe", "Subscribe Now":
 		if state.Symbol == "" || state.Interval == "" {
 			msg := tgbotapi.NewMessage(chatID, "Please select both currency pair and timeframe before subscribing.")
@@ -957,6 +1273,63 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery, logg
 		editMsg.ParseMode = "Markdown"
 		editMsg.ReplyMarkup = &keyboard
 		bot.Send(editMsg)
+	} else if data == "strategy_menu" {
+		// Show available strategies with the current selection marked
+		var text strings.Builder
+		text.WriteString("🧠 *Prediction Strategy*\n\n")
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for _, name := range strategy.Names() {
+			s, _ := strategy.Get(name)
+			label := name
+			if name == state.Strategy {
+				label = "✅ " + name
+			}
+			text.WriteString(fmt.Sprintf("• %s - %s\n", strings.ReplaceAll(name, "_", " "), s.Description()))
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(label, "strategy_"+name),
+			))
+		}
+		defaultLabel := "Auto (per symbol)"
+		if state.Strategy == "" {
+			defaultLabel = "✅ " + defaultLabel
+		}
+		rows = append(rows,
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(defaultLabel, "strategy_auto")),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("← Back to Settings", "settings_menu")),
+		)
+
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+		editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, text.String())
+		editMsg.ParseMode = "Markdown"
+		editMsg.ReplyMarkup = &keyboard
+		bot.Send(editMsg)
+	} else if strings.HasPrefix(data, "strategy_") {
+		name := strings.TrimPrefix(data, "strategy_")
+		if name == "auto" {
+			state.Strategy = ""
+			bot.Send(tgbotapi.NewMessage(chatID, "✅ Strategy will be selected automatically for each symbol"))
+		} else if _, err := strategy.Get(name); err != nil {
+			bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "❌ Unknown strategy"))
+		} else {
+			state.Strategy = name
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Strategy set to %s", strings.ReplaceAll(name, "_", " "))))
+		}
+	} else if data == "why_prediction" {
+		if state.LastPrediction == nil || state.LastPrediction.Breakdown == nil {
+			bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "Run a prediction first"))
+			return
+		}
+		msg := tgbotapi.NewMessage(chatID, formatBreakdown(state.LastPrediction, os.Getenv("FACTOR_LANGUAGE")))
+		msg.ParseMode = "Markdown"
+		bot.Send(msg)
+	} else if data == "deep_analysis" {
+		if state.LastAnalysis == nil {
+			bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "Run a prediction with market analysis first"))
+			return
+		}
+		msg := tgbotapi.NewMessage(chatID, formatDeepAnalysis(state.LastAnalysis))
+		msg.ParseMode = "Markdown"
+		bot.Send(msg)
 	} else if data == "separator_crypto" {
 		// Just acknowledge separator button without action
 		bot.Request(tgbotapi.NewCallback(callback.ID, "🚀 Crypto section"))
@@ -1047,6 +1420,11 @@ func getMainMenuKeyboard(isPremium bool) tgbotapi.ReplyKeyboardMarkup {
 			tgbotapi.NewKeyboardButtonRow(
 				tgbotapi.NewKeyboardButton("🔮 Run Prediction"),
 			),
+			tgbotapi.NewKeyboardButtonRow(
+				tgbotapi.NewKeyboardButton("📅 Events"),
+				tgbotapi.NewKeyboardButton("🔗 Correlations"),
+				tgbotapi.NewKeyboardButton("💪 Strength"),
+			),
 			tgbotapi.NewKeyboardButtonRow(
 				tgbotapi.NewKeyboardButton("⚙️ Settings"),
 				tgbotapi.NewKeyboardButton("📊 Select Pair & Timeframe"),
@@ -1095,6 +1473,9 @@ func getSettingsKeyboard(isPremium bool) tgbotapi.InlineKeyboardMarkup {
 
 	// Add common settings
 	buttons = append(buttons,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🧠 Strategy", "strategy_menu"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("ℹ️ About", "about_info"),
 		),
@@ -1201,23 +1582,27 @@ func runPrediction(bot *tgbotapi.BotAPI, chatID int64, state *UserState, logger
 
 	// Create a config object with user selections and environment variables
 	cfg := &models.Config{
-		TwelveAPIKey:      os.Getenv("TWELVE_API_KEY"),
-		OpenAIAPIKey:      os.Getenv("OPENAI_API_KEY"),
-		Symbol:            state.Symbol,
-		Interval:          state.Interval,
-		CandleCount:       getEnvInt("CANDLE_COUNT", 42),
-		RSIPeriod:         getEnvInt("RSI_PERIOD", 11),
-		MACDFastPeriod:    getEnvInt("MACD_FAST_PERIOD", 3),
-		MACDSlowPeriod:    getEnvInt("MACD_SLOW_PERIOD", 11),
-		MACDSignalPeriod:  getEnvInt("MACD_SIGNAL_PERIOD", 3),
-		BBPeriod:          getEnvInt("BB_PERIOD", 19),
-		BBStdDev:          getEnvFloat("BB_STD_DEV", 3.4),
-		EMAPeriod:         getEnvInt("EMA_PERIOD", 7),
-		ADXPeriod:         getEnvInt("ADX_PERIOD", 28),
-		ATRPeriod:         getEnvInt("ATR_PERIOD", 10),
-		RequestTimeout:    getEnvInt("REQUEST_TIMEOUT", 30),
-		AdaptiveIndicator: getEnvBool("ADAPTIVE_INDICATOR", true),
-		EnableBacktest:    false, // Disable backtesting for faster response
+		TwelveAPIKey:           os.Getenv("TWELVE_API_KEY"),
+		OpenAIAPIKey:           os.Getenv("OPENAI_API_KEY"),
+		Symbol:                 state.Symbol,
+		Interval:               state.Interval,
+		CandleCount:            getEnvInt("CANDLE_COUNT", 42),
+		RSIPeriod:              getEnvInt("RSI_PERIOD", 11),
+		MACDFastPeriod:         getEnvInt("MACD_FAST_PERIOD", 3),
+		MACDSlowPeriod:         getEnvInt("MACD_SLOW_PERIOD", 11),
+		MACDSignalPeriod:       getEnvInt("MACD_SIGNAL_PERIOD", 3),
+		BBPeriod:               getEnvInt("BB_PERIOD", 19),
+		BBStdDev:               getEnvFloat("BB_STD_DEV", 3.4),
+		EMAPeriod:              getEnvInt("EMA_PERIOD", 7),
+		ADXPeriod:              getEnvInt("ADX_PERIOD", 28),
+		ATRPeriod:              getEnvInt("ATR_PERIOD", 10),
+		RequestTimeout:         getEnvInt("REQUEST_TIMEOUT", 30),
+		AdaptiveIndicator:      getEnvBool("ADAPTIVE_INDICATOR", true),
+		EnableBacktest:         false, // Disable backtesting for faster response
+		Strategy:               os.Getenv("STRATEGY"),
+		Language:               os.Getenv("FACTOR_LANGUAGE"),
+		EnableMarketAnalysis:   getEnvBool("ENABLE_MARKET_ANALYSIS", false),
+		EnableCurrencyStrength: getEnvBool("ENABLE_CURRENCY_STRENGTH", false),
 	}
 
 	// Create client and context
@@ -1267,10 +1652,30 @@ func runPrediction(bot *tgbotapi.BotAPI, chatID int64, state *UserState, logger
 	}
 	anomalyData := anomaly.DetectMarketAnomalies(candles)
 
-	// Generate prediction
-	prediction, err := analyze.EnhancedPrediction(
-		ctx,
-		candles, indicators, mtfData, regime, anomalyData, cfg)
+	// Optional market analysis against the dollar index; the prediction goes on without it on failure
+	var marketAnalysis *models.MarketAnalysis
+	if cfg.EnableMarketAnalysis {
+		marketAnalysis, err = benchmark.Analyze(ctx, cfg, candles)
+		if err != nil {
+			logger.Warn().Err(err).Str("symbol", cfg.Symbol).Msg("Market analysis skipped")
+		}
+	}
+
+	// Generate prediction with the user's strategy or the one configured for the symbol
+	selected, err := strategy.Select(cfg, state.Strategy)
+	if err != nil {
+		logger.Warn().Err(err).Str("strategy", state.Strategy).Msg("Falling back to default strategy")
+		selected, _ = strategy.Get(strategy.DefaultStrategy)
+	}
+	prediction, err := selected.Predict(ctx, &models.StrategyInput{
+		Candles:        candles,
+		Indicators:     indicators,
+		MTFData:        mtfData,
+		Regime:         regime,
+		Anomaly:        anomalyData,
+		Config:         cfg,
+		MarketAnalysis: marketAnalysis,
+	})
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to generate prediction")
 		errMsg := tgbotapi.NewMessage(chatID, "Error generating prediction. Please try again later.")
@@ -1295,13 +1700,63 @@ func runPrediction(bot *tgbotapi.BotAPI, chatID int64, state *UserState, logger
 	}
 
 	resultText.WriteString(fmt.Sprintf("*Direction:* %s %s\n", directionEmoji, prediction.Direction))
-	resultText.WriteString(fmt.Sprintf("*Confidence:* %s\n", prediction.Confidence))
-	resultText.WriteString(fmt.Sprintf("*Score:* %.2f\n\n", prediction.Score))
+	if prediction.Probability > 0 {
+		resultText.WriteString(fmt.Sprintf("*Probability:* %.0f%% (%s)\n", prediction.Probability*100, prediction.Confidence))
+	} else {
+		resultText.WriteString(fmt.Sprintf("*Confidence:* %s\n", prediction.Confidence))
+	}
+	resultText.WriteString(fmt.Sprintf("*Score:* %.2f\n", prediction.Score))
+	resultText.WriteString(fmt.Sprintf("*Strategy:* %s\n", strings.ReplaceAll(prediction.Strategy, "_", " ")))
+	resultText.WriteString(fmt.Sprintf("*Params:* %s\n\n", strings.ReplaceAll(prediction.ParamsVersion, "_", " ")))
+
+	// Forecasts per horizon
+	if len(prediction.Horizons) > 0 {
+		resultText.WriteString("*Horizons:*\n")
+		for _, h := range prediction.Horizons {
+			resultText.WriteString(formatHorizon(h))
+		}
+		resultText.WriteString("\n")
+	}
 
 	// Market regime
 	resultText.WriteString(fmt.Sprintf("*Market Regime:* %s\n", regime.Type))
 	resultText.WriteString(fmt.Sprintf("*Regime Strength:* %.2f\n", regime.Strength))
-	resultText.WriteString(fmt.Sprintf("*Volatility:* %s\n\n", regime.VolatilityLevel))
+	resultText.WriteString(fmt.Sprintf("*Volatility:* %s\n", regime.VolatilityLevel))
+	if cp := regime.ChangePoint; cp != nil && cp.LastBreak != nil {
+		resultText.WriteString(fmt.Sprintf("*Regime Age:* %d bars (last break %s, p=%.0f%%)\n",
+			cp.RegimeAge, cp.LastBreak.Timestamp.Format("2006-01-02 15:04"), cp.BreakProbability*100))
+		if cp.RecentBreak {
+			resultText.WriteString("⚠️ *Recent structural break - signal damped*\n")
+		}
+	}
+	resultText.WriteString("\n")
+
+	// Session-aware anomalies
+	if gap := anomalyData.Gap; gap != nil {
+		resultText.WriteString(fmt.Sprintf("*Weekend Gap:* %s %.5f (%.1f ATR)\n", gap.Direction, gap.Size, gap.SizeATR))
+		if gap.Occurrences > 0 {
+			resultText.WriteString(fmt.Sprintf("Fill probability: %.0f%% (%d gaps) | ", gap.FillProbability*100, gap.Occurrences))
+		}
+		resultText.WriteString(fmt.Sprintf("Targets: %.5f / %.5f\n\n", gap.FillTargets[0], gap.FillTargets[1]))
+	} else if anomalyData.Session == "ROLLOVER" {
+		resultText.WriteString("⚠️ *Rollover spread widening - avoid entries*\n\n")
+	} else if anomalyData.Session != "" {
+		resultText.WriteString(fmt.Sprintf("⚠️ *%s session open spike*\n\n", strings.ReplaceAll(anomalyData.Session, "_", " ")))
+	}
+
+	// Scheduled releases around the prediction interval
+	if risk := prediction.EventRisk; risk != nil && risk.Blackout {
+		event := risk.Events[0]
+		resultText.WriteString(fmt.Sprintf("⚠️ *Event window:* %s %s at %s UTC\n",
+			event.Currency, markdownPlain(event.Title), event.Time.UTC().Format("Jan 2 15:04")))
+		if risk.Suppressed {
+			resultText.WriteString("Trade recommendation suppressed until the release settles\n")
+		}
+		resultText.WriteString("\n")
+	} else if risk != nil && risk.Next != nil {
+		resultText.WriteString(fmt.Sprintf("*Next Event:* %s %s at %s UTC\n\n",
+			risk.Next.Currency, markdownPlain(risk.Next.Title), risk.Next.Time.UTC().Format("Jan 2 15:04")))
+	}
 
 	// Key Indicators
 	resultText.WriteString("*Key Indicators:*\n")
@@ -1323,6 +1778,14 @@ func runPrediction(bot *tgbotapi.BotAPI, chatID int64, state *UserState, logger
 		resultText.WriteString(fmt.Sprintf("\n*Current Price:* %.5f\n", currentPrice))
 	}
 
+	// Expected price range from the volatility forecast
+	if prediction.TradingSuggestion != nil && prediction.TradingSuggestion.ExpectedRange != nil {
+		vol := prediction.TradingSuggestion.ExpectedRange
+		resultText.WriteString(fmt.Sprintf("\n*Expected Range (%d bars, %s):*\n", vol.Bars, vol.Method))
+		resultText.WriteString(fmt.Sprintf("68%%: %.5f - %.5f\n", vol.Range68.Low, vol.Range68.High))
+		resultText.WriteString(fmt.Sprintf("95%%: %.5f - %.5f\n", vol.Range95.Low, vol.Range95.High))
+	}
+
 	if prediction.TradingSuggestion != nil && prediction.TradingSuggestion.Action != "NO_TRADE" {
 		resultText.WriteString("\n\n*Trading Recommendations:*\n")
 		resultText.WriteString(fmt.Sprintf("Action: %s\n", prediction.TradingSuggestion.Action))
@@ -1334,9 +1797,21 @@ func runPrediction(bot *tgbotapi.BotAPI, chatID int64, state *UserState, logger
 		resultText.WriteString(fmt.Sprintf("Risk per Trade: %.1f%%\n", prediction.TradingSuggestion.AccountRisk))
 	}
 
-	// Send the final result
+	// Send the final result; the breakdown and the deep analysis are shown on demand
+	state.LastPrediction = prediction
+	state.LastAnalysis = marketAnalysis
 	resultMsg := tgbotapi.NewMessage(chatID, resultText.String())
 	resultMsg.ParseMode = "Markdown"
+	var buttons []tgbotapi.InlineKeyboardButton
+	if prediction.Breakdown != nil {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("❓ Why?", "why_prediction"))
+	}
+	if marketAnalysis != nil {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("🔬 Deep Analysis", "deep_analysis"))
+	}
+	if len(buttons) > 0 {
+		resultMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+	}
 	bot.Send(resultMsg)
 
 	//// Try to get prediction from OpenAI if API key is available
@@ -1362,6 +1837,370 @@ func runPrediction(bot *tgbotapi.BotAPI, chatID int64, state *UserState, logger
 	//}
 }
 
+// horizonLabels are the user-facing names of prediction horizons
+var horizonLabels = map[string]string{
+	horizon.NextBar:    "Next bar",
+	horizon.ThreeBars:  "3 bars",
+	horizon.TwelveBars: "12 bars",
+	horizon.SessionEnd: "Session end",
+}
+
+// formatHorizon renders one horizon forecast line for the prediction message
+func formatHorizon(h models.HorizonForecast) string {
+	directionEmoji := "⚖️"
+	if h.Direction == "BUY" {
+		directionEmoji = "🔼"
+	} else if h.Direction == "SELL" {
+		directionEmoji = "🔽"
+	}
+
+	certainty := h.Confidence
+	if h.Probability > 0 {
+		certainty = fmt.Sprintf("%.0f%%", h.Probability*100)
+	}
+
+	return fmt.Sprintf("%s: %s %s (%s) ±%.5f, until %s UTC\n",
+		horizonLabels[h.Horizon], directionEmoji, h.Direction, certainty, h.ExpectedMove,
+		h.PredictionTarget.UTC().Format("Jan 2 15:04"))
+}
+
+// breakdownBarWidth is the number of bar characters for the largest contribution
+const breakdownBarWidth = 8
+
+// formatBreakdown renders the score breakdown as a waterfall: every factor and
+// adjustment moves the running score from zero to the final prediction score
+func formatBreakdown(prediction *models.Prediction, lang string) string {
+	breakdown := prediction.Breakdown
+	steps := append(append([]models.Factor(nil), breakdown.Factors...), breakdown.Adjustments...)
+
+	largest := 0.0
+	for _, step := range steps {
+		largest = math.Max(largest, math.Abs(step.Contribution))
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("*Why %s?* Score %.2f (%s)\n", prediction.Direction, prediction.Score,
+		strings.ReplaceAll(prediction.Strategy, "_", " ")))
+	text.WriteString("```\n")
+	running := 0.0
+	for _, step := range steps {
+		running += step.Contribution
+		bar := ""
+		if largest > 0 {
+			width := int(math.Round(math.Abs(step.Contribution) / largest * breakdownBarWidth))
+			if step.Contribution > 0 {
+				bar = strings.Repeat("█", max(width, 1))
+			} else {
+				bar = strings.Repeat("░", max(width, 1))
+			}
+		}
+		text.WriteString(fmt.Sprintf("%-16.16s %+6.2f %-8s %6.2f\n", step.ID, step.Contribution, bar, running))
+	}
+	text.WriteString(fmt.Sprintf("%-16s %6s %-8s %6.2f\n", "SCORE", "", "", breakdown.Score))
+	text.WriteString("```\n")
+
+	for _, step := range steps {
+		description := explain.Describe(step, lang)
+		if description == step.ID {
+			continue
+		}
+		text.WriteString(fmt.Sprintf("• %s: %s\n", strings.ReplaceAll(step.ID, "_", " "), strings.ReplaceAll(description, "_", " ")))
+	}
+	text.WriteString("\n█ bullish  ░ bearish")
+
+	return text.String()
+}
+
+// formatDeepAnalysis renders every section of the market analysis behind the prediction
+func formatDeepAnalysis(analysis *models.MarketAnalysis) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("*Deep Analysis for %s (%s)*\n", analysis.Symbol, analysis.TimeFrame))
+	text.WriteString(fmt.Sprintf("Direction: %s (confidence %.0f%%)\n", analysis.Direction, analysis.Confidence*100))
+	text.WriteString(fmt.Sprintf("Regime: %s, strength %.2f\n", strings.ReplaceAll(analysis.MarketRegime, "_", " "), analysis.RegimeStrength))
+	text.WriteString(fmt.Sprintf("Updated: %s UTC\n", analysis.LastUpdated.UTC().Format("Jan 2 15:04")))
+
+	if s := analysis.MarketSentiment; s != nil {
+		text.WriteString("\n*Sentiment:*\n")
+		text.WriteString(fmt.Sprintf("Mood: %s | Fear/Greed: %.0f\n", s.MarketMood, s.FearGreedIndex))
+		text.WriteString(fmt.Sprintf("Volume sentiment: %+.2f | Volatility mood: %s\n", s.VolumeSentiment, s.VolatilityMood))
+	}
+
+	if c := analysis.Correlations; c != nil {
+		text.WriteString("\n*Correlations:*\n")
+		if c.Benchmark != "" {
+			text.WriteString(fmt.Sprintf("Benchmark %s: %+.2f%% over the window\n", strings.ReplaceAll(c.Benchmark, "_", " "), c.MarketTrend))
+			text.WriteString(fmt.Sprintf("Correlation with benchmark: %.2f\n", c.MarketCorrelation))
+		} else {
+			text.WriteString("Benchmark: unavailable\n")
+		}
+		text.WriteString(fmt.Sprintf("Volatility: %.2f | Volume: %.2f\n", c.VolatilityCorrelation, c.VolumeCorrelation))
+	}
+
+	if l := analysis.Liquidity; l != nil {
+		text.WriteString("\n*Liquidity:*\n")
+		text.WriteString(fmt.Sprintf("Score: %.2f | Spread: %.5f\n", l.LiquidityScore, l.BidAskSpread))
+		text.WriteString(fmt.Sprintf("Depth: %.2f | Impact: %.5f\n", l.OrderBookDepth, l.MarketImpact))
+	}
+
+	if v := analysis.Volume; v != nil {
+		text.WriteString("\n*Volume:*\n")
+		text.WriteString(fmt.Sprintf("Trend: %s | Strength: %.2f | Imbalance: %+.2f\n", v.VolumeTrend, v.VolumeStrength, v.VolumeImbalance))
+		if len(v.VolumeClusters) > 0 {
+			text.WriteString(fmt.Sprintf("Clusters: %d\n", len(v.VolumeClusters)))
+		}
+	}
+
+	if m := analysis.Microstructure; m != nil {
+		text.WriteString("\n*Microstructure:*\n")
+		text.WriteString(fmt.Sprintf("Buy/Sell pressure: %.2f / %.2f | Net flow: %+.2f\n",
+			m.OrderFlow.BuyPressure, m.OrderFlow.SellPressure, m.OrderFlow.NetFlow))
+		text.WriteString(fmt.Sprintf("Efficiency: %.2f | Resilience: %.2f\n", m.MarketQuality.Efficiency, m.MarketQuality.Resilience))
+	}
+
+	if n := analysis.News; n != nil {
+		text.WriteString("\n*News:*\n")
+		if n.NewsCount == 0 {
+			text.WriteString("No relevant news\n")
+		} else {
+			text.WriteString(fmt.Sprintf("%d items | Sentiment: %+.2f | Impact: %.2f\n", n.NewsCount, n.Sentiment, n.Impact))
+			for i, item := range n.TopNews {
+				if i == 3 {
+					break
+				}
+				text.WriteString(fmt.Sprintf("• %s (%+.2f)\n", markdownPlain(item.Title), item.Sentiment))
+			}
+		}
+	}
+
+	if f := analysis.Fundamentals; f != nil {
+		text.WriteString("\n*Fundamentals:*\n")
+		text.WriteString(fmt.Sprintf("Regime: %s (%.2f)\n", strings.ReplaceAll(f.MarketRegime, "_", " "), f.RegimeStrength))
+		keys := make([]string, 0, len(f.RiskFactors))
+		for key := range f.RiskFactors {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			text.WriteString(fmt.Sprintf("%s: %.2f\n", strings.ReplaceAll(key, "_", " "), f.RiskFactors[key]))
+		}
+	}
+
+	return text.String()
+}
+
+// upcomingEventsDays is how far ahead the events view looks
+const upcomingEventsDays = 7
+
+// formatUpcomingEvents lists scheduled releases for the currencies of the pair
+func formatUpcomingEvents(symbol string, now time.Time) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📅 *Upcoming events for %s*\n\n", symbol))
+
+	if calendar.Len() == 0 {
+		text.WriteString("Economic calendar is not configured.")
+		return text.String()
+	}
+
+	events := calendar.Upcoming(symbol, now, now.AddDate(0, 0, upcomingEventsDays), calendar.ImpactLow)
+	if len(events) == 0 {
+		text.WriteString(fmt.Sprintf("No scheduled releases in the next %d days.", upcomingEventsDays))
+		return text.String()
+	}
+
+	impactMarks := map[string]string{
+		calendar.ImpactHigh:   "🔴",
+		calendar.ImpactMedium: "🟠",
+		calendar.ImpactLow:    "🟡",
+	}
+	day := ""
+	for _, event := range events {
+		at := event.Time.UTC()
+		if date := at.Format("Mon Jan 2"); date != day {
+			day = date
+			text.WriteString(fmt.Sprintf("*%s*\n", day))
+		}
+		text.WriteString(fmt.Sprintf("%s %s %s %s", impactMarks[event.Impact], at.Format("15:04"), event.Currency, markdownPlain(event.Title)))
+		if event.Forecast != "" || event.Previous != "" {
+			text.WriteString(fmt.Sprintf(" (f: %s, p: %s)", markdownPlain(event.Forecast), markdownPlain(event.Previous)))
+		}
+		text.WriteString("\n")
+	}
+
+	window := calendar.ActiveWindow()
+	text.WriteString(fmt.Sprintf("\nTimes in UTC. Predictions within %.0f min before and %.0f min after %s-impact releases are ",
+		window.Before.Minutes(), window.After.Minutes(), strings.ToLower(window.MinImpact)))
+	if window.Suppress {
+		text.WriteString("not traded.")
+	} else {
+		text.WriteString("flagged.")
+	}
+	return text.String()
+}
+
+// marketDataConfig returns the data source settings for the cross-pair views (correlations, currency strength)
+// refreshInBackground runs refresh in a goroutine unless a refresh with the same key is still running
+func refreshInBackground(key string, refresh func()) {
+	backgroundRefreshes.mu.Lock()
+	defer backgroundRefreshes.mu.Unlock()
+	if backgroundRefreshes.running[key] {
+		return
+	}
+	backgroundRefreshes.running[key] = true
+
+	go func() {
+		defer func() {
+			backgroundRefreshes.mu.Lock()
+			delete(backgroundRefreshes.running, key)
+			backgroundRefreshes.mu.Unlock()
+		}()
+		refresh()
+	}()
+}
+
+func marketDataConfig() *models.Config {
+	return &models.Config{
+		TwelveAPIKey:   os.Getenv("TWELVE_API_KEY"),
+		RequestTimeout: getEnvInt("REQUEST_TIMEOUT", 30),
+	}
+}
+
+// correlationsShown is how many pairs each side of the correlations view lists
+const correlationsShown = 5
+
+// formatCorrelations shows which pairs currently move with and against the symbol
+// and which long-standing relationships have broken down
+func formatCorrelations(snapshot *models.CorrelationSnapshot, symbol string) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("🔗 *Correlations for %s (%s)*\n", symbol, snapshot.Interval))
+
+	related := correlation.Related(snapshot, symbol)
+	if len(related) == 0 {
+		text.WriteString("\nNot enough data for this pair yet.")
+		return text.String()
+	}
+
+	windows := make([]string, len(snapshot.Matrices))
+	for i, matrix := range snapshot.Matrices {
+		windows[i] = strconv.Itoa(matrix.Window)
+	}
+	text.WriteString(fmt.Sprintf("Windows: %s candles\n", strings.Join(windows, " / ")))
+
+	writePairs := func(title string, pairs []models.PairCorrelation) {
+		if len(pairs) == 0 {
+			return
+		}
+		text.WriteString(fmt.Sprintf("\n*%s*\n", title))
+		for i, pair := range pairs {
+			if i == correlationsShown {
+				break
+			}
+			values := make([]string, len(pair.Correlations))
+			for j, rho := range pair.Correlations {
+				values[j] = fmt.Sprintf("%+.2f", rho)
+			}
+			line := fmt.Sprintf("%s: %s", pair.Symbol, strings.Join(values, " / "))
+			if pair.Breakdown {
+				line += " ⚠️"
+			}
+			text.WriteString(line + "\n")
+		}
+	}
+
+	// Pairs are sorted by the strength of the shortest window, so this is what moves together now
+	var together, inverse []models.PairCorrelation
+	for _, pair := range related {
+		switch rho := pair.Correlations[0]; {
+		case rho >= 0.5:
+			together = append(together, pair)
+		case rho <= -0.5:
+			inverse = append(inverse, pair)
+		}
+	}
+	writePairs("Moving together", together)
+	writePairs("Moving inversely", inverse)
+	if len(together) == 0 && len(inverse) == 0 {
+		text.WriteString("\nNo pair is strongly correlated right now.\n")
+	}
+
+	var broken []string
+	for _, breakdown := range snapshot.Breakdowns {
+		other := ""
+		switch symbol {
+		case breakdown.SymbolA:
+			other = breakdown.SymbolB
+		case breakdown.SymbolB:
+			other = breakdown.SymbolA
+		default:
+			continue
+		}
+		broken = append(broken, fmt.Sprintf("%s: %+.2f → %+.2f", other, breakdown.Long, breakdown.Short))
+	}
+	if len(broken) > 0 {
+		text.WriteString(fmt.Sprintf("\n⚠️ *Correlation breakdowns* (%d → %d candles)\n%s\n",
+			snapshot.Matrices[len(snapshot.Matrices)-1].Window, snapshot.Matrices[0].Window, strings.Join(broken, "\n")))
+	}
+
+	text.WriteString(fmt.Sprintf("\nUpdated %s UTC", snapshot.UpdatedAt.UTC().Format("Jan 2 15:04")))
+	return text.String()
+}
+
+// formatCurrencyStrength ranks currencies from strongest to weakest and marks the
+// currencies of the selected pair
+func formatCurrencyStrength(snapshot *models.CurrencyStrengthSnapshot, symbol string) string {
+	var text strings.Builder
+	text.WriteString("💪 *Currency Strength*\n")
+	text.WriteString(fmt.Sprintf("Change over %d candles per timeframe, %d pairs\n\n", snapshot.Lookback, snapshot.Pairs))
+
+	base, quote, _ := strings.Cut(symbol, "/")
+	text.WriteString(fmt.Sprintf("`#  CCY  %s  SCORE`\n", strings.Join(padIntervals(snapshot.Intervals), " ")))
+	for i, entry := range snapshot.Currencies {
+		values := make([]string, len(snapshot.Intervals))
+		for j, interval := range snapshot.Intervals {
+			if value, ok := entry.Strength[interval]; ok {
+				values[j] = fmt.Sprintf("%+6.2f", value)
+			} else {
+				values[j] = fmt.Sprintf("%6s", "-")
+			}
+		}
+		line := fmt.Sprintf("`%d  %s  %s  %+5.2f`", i+1, entry.Currency, strings.Join(values, " "), entry.Score)
+		if entry.Currency == base || entry.Currency == quote {
+			line += " ◀️"
+		}
+		text.WriteString(line + "\n")
+	}
+
+	if strongest, weakest := snapshot.Currencies[0], snapshot.Currencies[len(snapshot.Currencies)-1]; strongest.Currency != weakest.Currency {
+		text.WriteString(fmt.Sprintf("\nStrongest vs weakest: %s vs %s\n", strongest.Currency, weakest.Currency))
+	}
+	if baseStrength, quoteStrength, diff, ok := strength.Differential(snapshot, symbol); ok {
+		bias := "balanced"
+		if diff >= strength.Neutral {
+			bias = "favors " + baseStrength.Currency
+		} else if diff <= -strength.Neutral {
+			bias = "favors " + quoteStrength.Currency
+		}
+		text.WriteString(fmt.Sprintf("%s: %+.2f vs %+.2f, %s\n", symbol, baseStrength.Score, quoteStrength.Score, bias))
+	}
+
+	text.WriteString(fmt.Sprintf("\nValues in %%, score in units of cross-currency spread. Updated %s UTC",
+		snapshot.UpdatedAt.UTC().Format("Jan 2 15:04")))
+	return text.String()
+}
+
+// padIntervals right-aligns timeframe names to the width of the strength columns
+func padIntervals(intervals []string) []string {
+	padded := make([]string, len(intervals))
+	for i, interval := range intervals {
+		padded[i] = fmt.Sprintf("%6s", interval)
+	}
+	return padded
+}
+
+// markdownPlain removes characters that legacy Telegram Markdown treats as markup
+func markdownPlain(s string) string {
+	return strings.NewReplacer("*", "", "_", " ", "`", "'", "[", "(", "]", ")").Replace(s)
+}
+
 // Helper function to get integer environment variables
 func getEnvInt(key string, defaultVal int) int {
 	valueStr := os.Getenv(key)
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/internal/cli"
+	"github.com/Alias1177/Predictor/internal/volatility"
+	"github.com/Alias1177/Predictor/models"
+)
+
+func init() {
+	// Load .env file
+	cli.LoadEnv()
+}
+
+// Обучает модели волатильности GARCH(1,1) (или EWMA при нестационарности) для всех символов и таймфреймов
+func main() {
+	apiKey := cli.APIKey(true)
+
+	outputDir := cli.String("VOLATILITY_MODEL_DIR", "data/volatility")
+	symbols := cli.List("VOLATILITY_SYMBOLS", cli.AllSymbols)
+	intervals := cli.List("VOLATILITY_INTERVALS", cli.AllIntervals)
+	days := cli.Int("VOLATILITY_DAYS", 30)
+
+	ctx := context.Background()
+
+	for _, symbol := range symbols {
+		for _, interval := range intervals {
+			cfg := cli.Config(apiKey, symbol, interval)
+			client := config.NewClient(cfg)
+
+			candles, err := client.GetHistoricalCandles(ctx, days)
+			if err != nil {
+				log.Printf("Failed to fetch %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			model, err := volatility.Fit(volatility.LogReturns(candles))
+			if err != nil {
+				log.Printf("Failed to fit volatility model for %s %s: %v", symbol, interval, err)
+				continue
+			}
+			model.Symbol = symbol
+			model.Interval = interval
+
+			if err := volatility.Save(outputDir, model); err != nil {
+				log.Printf("Failed to save volatility model for %s %s: %v", symbol, interval, err)
+				continue
+			}
+
+			printModel(model)
+		}
+	}
+}
+
+// printModel выводит параметры модели и безусловную волатильность за свечу
+func printModel(model *models.VolatilityModel) {
+	fmt.Printf("\n===== %s %s: %s (доходностей %d, logL %.2f) =====\n",
+		model.Symbol, model.Interval, model.Method, model.Samples, model.LogLikelihood)
+
+	if model.Method == volatility.MethodGARCH {
+		persistence := model.Alpha + model.Beta
+		fmt.Printf("omega=%.3e alpha=%.4f beta=%.4f persistence=%.4f полураспад=%.1f свечей\n",
+			model.Omega, model.Alpha, model.Beta, persistence, math.Log(0.5)/math.Log(persistence))
+	} else {
+		fmt.Printf("lambda=%.3f\n", model.Lambda)
+	}
+	fmt.Printf("Волатильность за свечу: %.2f bp\n", math.Sqrt(model.LongRunVar)*10000)
+}
@@ -1,13 +1,16 @@
 package analyze
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"math"
-	"net/http"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/Alias1177/Predictor/internal/calendar"
+	"github.com/Alias1177/Predictor/internal/correlation"
+	"github.com/Alias1177/Predictor/internal/news"
 	"github.com/Alias1177/Predictor/internal/utils"
 	"github.com/Alias1177/Predictor/models"
 	"github.com/rs/zerolog/log"
@@ -343,11 +346,29 @@ func analyzeCorrelations(candles []models.Candle, marketCandles []models.Candle)
 		AssetCorrelations: make(map[string]float64),
 	}
 
-	// Расчет корреляции с рынком
-	analysis.MarketCorrelation = calculateCorrelation(
-		extractPrices(candles),
-		extractPrices(marketCandles),
-	)
+	// Корреляция с рынком считается по доходностям: уровни цен двух трендовых рядов
+	// коррелируют почти всегда. Бенчмарк должен быть выровнен по свечам инструмента
+	if len(marketCandles) == len(candles) && len(candles) > 2 {
+		analysis.MarketCorrelation = calculateCorrelation(
+			extractReturns(candles),
+			extractReturns(marketCandles),
+		)
+		analysis.Benchmark = marketCandles[len(marketCandles)-1].Symbol
+		analysis.AssetCorrelations[analysis.Benchmark] = analysis.MarketCorrelation
+		if first := marketCandles[0].Close; first > 0 {
+			analysis.MarketTrend = (marketCandles[len(marketCandles)-1].Close - first) / first * 100
+		}
+	}
+
+	// Корреляции с остальными парами по последней матрице на самом длинном окне
+	if len(candles) > 0 {
+		last := candles[len(candles)-1]
+		if snapshot, ok := correlation.Get(last.TimeFrame); ok {
+			for _, pair := range correlation.Related(snapshot, last.Symbol) {
+				analysis.AssetCorrelations[pair.Symbol] = pair.Correlations[len(pair.Correlations)-1]
+			}
+		}
+	}
 
 	// Расчет корреляции с волатильностью
 	analysis.VolatilityCorrelation = calculateVolatilityCorrelation(candles)
@@ -431,6 +452,19 @@ func extractPrices(candles []models.Candle) []float64 {
 	return prices
 }
 
+// extractReturns извлекает относительные изменения цен закрытия
+func extractReturns(candles []models.Candle) []float64 {
+	returns := make([]float64, 0, len(candles)-1)
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].Close == 0 {
+			returns = append(returns, 0)
+			continue
+		}
+		returns = append(returns, candles[i].Close/candles[i-1].Close-1)
+	}
+	return returns
+}
+
 // analyzeLiquidity анализирует ликвидность
 func analyzeLiquidity(candles []models.Candle) *models.LiquidityAnalysis {
 	analysis := &models.LiquidityAnalysis{}
@@ -617,6 +651,10 @@ func calculateVolumeStrength(candles []models.Candle) float64 {
 	}
 
 	avgVolume := sumVolume / float64(len(candles))
+	if avgVolume == 0 {
+		// У валютных пар Twelve Data не отдает объем
+		return 0.0
+	}
 	lastVolume := float64(candles[len(candles)-1].Volume)
 
 	return math.Min(lastVolume/avgVolume, 1.0)
@@ -874,26 +912,6 @@ func analyzeTradingActivity(candles []models.Candle) models.TradingActivityMetri
 	return metrics
 }
 
-// NewsAnalysis представляет анализ новостей
-type NewsAnalysis struct {
-	Sentiment    float64            `json:"sentiment"`     // Общий сентимент (-1 до 1)
-	Impact       float64            `json:"impact"`        // Влияние на рынок (0-1)
-	Relevance    float64            `json:"relevance"`     // Релевантность (0-1)
-	NewsCount    int                `json:"news_count"`    // Количество новостей
-	TopNews      []models.NewsItem  `json:"top_news"`      // Важные новости
-	MarketImpact map[string]float64 `json:"market_impact"` // Влияние на разные аспекты рынка
-}
-
-// NewsItem представляет отдельную новость
-type NewsItem struct {
-	Title       string    `json:"title"`        // Заголовок
-	Content     string    `json:"content"`      // Содержание
-	Sentiment   float64   `json:"sentiment"`    // Сентимент (-1 до 1)
-	Impact      float64   `json:"impact"`       // Влияние (0-1)
-	PublishedAt time.Time `json:"published_at"` // Время публикации
-	Symbol      string    `json:"symbol"`       // Символ
-}
-
 // FundamentalAnalysis представляет фундаментальный анализ
 type FundamentalAnalysis struct {
 	EconomicIndicators map[string]float64 `json:"economic_indicators"` // Экономические индикаторы
@@ -903,112 +921,91 @@ type FundamentalAnalysis struct {
 	RegimeStrength     float64            `json:"regime_strength"`     // Сила режима (0-1)
 }
 
-// fetchNews получает новости через API
-func fetchNews(_ string) ([]models.NewsItem, error) {
-	apiKey := "cv9mclpr01qpd9s82rngcv9mclpr01qpd9s82ro0"
-	apiUrl := "https://finnhub.io/api/v1/news"
-
-	// Формируем URL с параметрами
-	url := fmt.Sprintf("%s?category=forex&token=%s", apiUrl, apiKey)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var news []models.NewsItem
-	if err := json.NewDecoder(resp.Body).Decode(&news); err != nil {
-		return nil, err
-	}
-
-	return news, nil
-}
+// newsTimeout ограничивает запрос ленты новостей, чтобы анализ не ждал медленный провайдер
+const newsTimeout = 10 * time.Second
 
-// analyzeNews анализирует новости и их влияние на рынок
-func analyzeNews(symbol string, _ string) *models.NewsAnalysis {
+// analyzeNews анализирует новости, относящиеся к валютам пары, через активный провайдер.
+// Тональность каждой новости переводится в направление пары (хорошая новость о котируемой
+// валюте - медвежья для пары); новости, не касающиеся пары, не учитываются
+func analyzeNews(ctx context.Context, symbol string) *models.NewsAnalysis {
 	analysis := &models.NewsAnalysis{
 		MarketImpact: make(map[string]float64),
 	}
 
-	// Получение новостей через API
-	news, err := fetchNews(symbol)
+	provider := news.Active()
+	if provider == nil {
+		return analysis
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, newsTimeout)
+	defer cancel()
+	items, err := provider.Fetch(ctx, news.CategoryFor(symbol))
 	if err != nil {
-		log.Error().Err(err).Msg("Error fetching news")
+		log.Error().Err(err).Str("provider", provider.Name()).Msg("Error fetching news")
 		return analysis
 	}
 
-	// Анализ каждой новости
-	var totalSentiment, totalImpact float64
-	for _, item := range news {
-		// Устанавливаем символ для новости
+	// Отбираем новости, касающиеся пары
+	var relevant []models.NewsItem
+	for _, item := range items {
+		sentiment, relevance, currencies := news.PairSentiment(item, symbol)
+		if relevance == 0 {
+			continue
+		}
 		item.Symbol = symbol
+		item.Sentiment = sentiment
+		item.Relevance = relevance
+		item.Currencies = currencies
+		item.Impact = calculateNewsImpact(item)
+		relevant = append(relevant, item)
+	}
+	if len(relevant) == 0 {
+		return analysis
+	}
 
-		// Анализ сентимента
-		sentiment := analyzeNewsSentiment(item)
-		totalSentiment += sentiment
-
-		// Оценка влияния
-		impact := calculateNewsImpact(item, symbol)
-		totalImpact += impact
+	// Тональность взвешивается релевантностью и свежестью новости
+	var weightedSentiment, totalWeight, totalImpact, totalRelevance float64
+	for _, item := range relevant {
+		weight := item.Relevance * newsDecay(item)
+		weightedSentiment += item.Sentiment * weight
+		totalWeight += weight
+		totalImpact += item.Impact
+		totalRelevance += item.Relevance
 
 		// Добавление важных новостей
-		if impact > 0.7 {
+		if item.Impact > 0.3 {
 			analysis.TopNews = append(analysis.TopNews, item)
 		}
 	}
+	sort.SliceStable(analysis.TopNews, func(i, j int) bool {
+		return analysis.TopNews[i].Impact > analysis.TopNews[j].Impact
+	})
 
-	// Расчет общих метрик
-	if len(news) > 0 {
-		analysis.Sentiment = totalSentiment / float64(len(news))
-		analysis.Impact = totalImpact / float64(len(news))
-		analysis.NewsCount = len(news)
+	if totalWeight > 0 {
+		analysis.Sentiment = weightedSentiment / totalWeight
 	}
+	analysis.Impact = totalImpact / float64(len(relevant))
+	analysis.Relevance = totalRelevance / float64(len(relevant))
+	analysis.NewsCount = len(relevant)
 
 	// Анализ влияния на разные аспекты рынка
-	analysis.MarketImpact = calculateNewsMarketImpact(news)
+	analysis.MarketImpact = calculateNewsMarketImpact(relevant)
 
 	return analysis
 }
 
-// analyzeNewsSentiment анализирует сентимент новости
-func analyzeNewsSentiment(item models.NewsItem) float64 {
-	// Простой анализ на основе ключевых слов
-	positiveWords := []string{"bullish", "growth", "increase", "positive", "gain", "up", "rise", "strong"}
-	negativeWords := []string{"bearish", "decline", "decrease", "negative", "loss", "down", "fall", "weak"}
-
-	text := strings.ToLower(item.Title + " " + item.Content)
-
-	var score float64
-	for _, word := range positiveWords {
-		if strings.Contains(text, word) {
-			score += 0.2
-		}
-	}
-	for _, word := range negativeWords {
-		if strings.Contains(text, word) {
-			score -= 0.2
-		}
+// newsDecay - экспоненциальное затухание влияния новости с постоянной в сутки
+func newsDecay(item models.NewsItem) float64 {
+	age := time.Since(item.PublishedAt)
+	if age < 0 {
+		age = 0
 	}
-
-	return math.Max(-1, math.Min(1, score))
+	return math.Exp(-age.Hours() / 24.0)
 }
 
-// calculateNewsImpact рассчитывает влияние новости
-func calculateNewsImpact(item models.NewsItem, symbol string) float64 {
-	sentiment := analyzeNewsSentiment(item)
-
-	// Влияние уменьшается со временем
-	timeSincePublished := time.Since(item.PublishedAt)
-	timeFactor := math.Exp(-timeSincePublished.Hours() / 24.0) // Экспоненциальное затухание
-
-	// Учитываем релевантность для конкретного символа
-	relevanceFactor := 1.0
-	if symbol != "" && strings.Contains(strings.ToLower(item.Title), strings.ToLower(symbol)) {
-		relevanceFactor = 1.5
-	}
-
-	return math.Abs(sentiment) * timeFactor * relevanceFactor
+// calculateNewsImpact рассчитывает влияние новости на пару (0-1)
+func calculateNewsImpact(item models.NewsItem) float64 {
+	return math.Min(1, math.Abs(item.Sentiment)*item.Relevance*newsDecay(item))
 }
 
 // calculateNewsMarketImpact рассчитывает влияние новостей на рынок
@@ -1019,8 +1016,8 @@ func calculateNewsMarketImpact(news []models.NewsItem) map[string]float64 {
 	var sentimentImpact, volatilityImpact, liquidityImpact float64
 
 	for _, item := range news {
-		sentiment := analyzeNewsSentiment(item)
-		itemImpact := calculateNewsImpact(item, item.Symbol)
+		sentiment := item.Sentiment
+		itemImpact := item.Impact
 
 		// Влияние на настроения
 		sentimentImpact += sentiment * itemImpact
@@ -1051,7 +1048,7 @@ func calculateNewsMarketImpact(news []models.NewsItem) map[string]float64 {
 }
 
 // analyzeFundamentals анализирует фундаментальные факторы
-func analyzeFundamentals(symbol string, candles []models.Candle) *models.FundamentalAnalysis {
+func analyzeFundamentals(symbol string, candles []models.Candle, news *models.NewsAnalysis) *models.FundamentalAnalysis {
 	analysis := &models.FundamentalAnalysis{
 		EconomicIndicators: make(map[string]float64),
 		MarketConditions:   make(map[string]float64),
@@ -1069,7 +1066,7 @@ func analyzeFundamentals(symbol string, candles []models.Candle) *models.Fundame
 	analysis.MarketConditions = analyzeMarketConditions(candles)
 
 	// Анализ факторов риска
-	analysis.RiskFactors = analyzeRiskFactors(symbol, candles)
+	analysis.RiskFactors = analyzeRiskFactors(symbol, candles, news)
 
 	return analysis
 }
@@ -1139,7 +1136,7 @@ func calculateVolatilityRisk(candles []models.Candle) float64 {
 }
 
 // determineDirection определяет направление движения на основе всех анализов
-func determineDirection(sentiment *models.MarketSentiment, _ *models.CorrelationAnalysis,
+func determineDirection(sentiment *models.MarketSentiment, correlations *models.CorrelationAnalysis,
 	lLiquidity *models.LiquidityAnalysis, volume *models.VolumeAnalysis,
 	microstructure *models.MicrostructureAnalysis, news *models.NewsAnalysis,
 	fundamentals *models.FundamentalAnalysis) string {
@@ -1175,6 +1172,20 @@ func determineDirection(sentiment *models.MarketSentiment, _ *models.Correlation
 		bearishScore += 0.15
 	}
 
+	// Анализ бенчмарка: тесно связанный с рынком инструмент следует за ним
+	// (или против него при отрицательной корреляции)
+	if correlations != nil && correlations.MarketTrend != 0 {
+		implied := correlations.MarketCorrelation
+		if correlations.MarketTrend < 0 {
+			implied = -implied
+		}
+		if implied > 0.5 {
+			bullishScore += 0.15
+		} else if implied < -0.5 {
+			bearishScore += 0.15
+		}
+	}
+
 	// Анализ новостей
 	if news.Sentiment > 0.2 {
 		bullishScore += 0.2
@@ -1324,6 +1335,9 @@ func calculateTrendStrength(candles []models.Candle) float64 {
 	}
 
 	// Расчет Directional Indicators
+	if tr == 0 || plusDM+minusDM == 0 {
+		return 0.0
+	}
 	plusDI = (plusDM / tr) * 100
 	minusDI = (minusDM / tr) * 100
 
@@ -1374,108 +1388,16 @@ func calculateRegimeStrength(candles []models.Candle) float64 {
 	return math.Min(strength, 1.0)
 }
 
-// fetchEconomicData получает экономические данные
-func fetchEconomicData(_ string) (map[string]float64, error) {
-	// TODO: В будущем можно добавить реальный API для получения экономических данных
-	// Сейчас возвращаем тестовые данные
-
-	data := make(map[string]float64)
-
-	// Базовые экономические индикаторы
-	data["gdp_growth"] = 2.5
-	data["inflation"] = 3.2
-	data["interest_rate"] = 4.5
-	data["unemployment"] = 5.1
-
-	// Рыночные индикаторы
-	data["market_cap"] = 1000000.0
-	data["pe_ratio"] = 15.5
-	data["dividend_yield"] = 2.1
-
-	// Технические индикаторы
-	data["volatility"] = 0.8
-	data["momentum"] = 0.6
-	data["trend_strength"] = 0.7
-
-	return data, nil
-}
-
-// normalizeIndicator нормализует значение индикатора
-func normalizeIndicator(value float64) float64 {
-	// Определяем диапазоны для разных типов индикаторов
-	const (
-		minValue = -100.0
-		maxValue = 100.0
-	)
-
-	// Ограничиваем значение в допустимом диапазоне
-	normalized := math.Max(minValue, math.Min(maxValue, value))
-
-	// Нормализуем к диапазону 0-1
-	return (normalized - minValue) / (maxValue - minValue)
-}
-
 // calculateLiquidityRisk рассчитывает риск ликвидности
 func calculateLiquidityRisk(candles []models.Candle) float64 {
 	score := calculateLiquidityScore(candles)
 	return 1.0 - score
 }
 
-// calculateCorrelationRisk рассчитывает риск корреляции
-func calculateCorrelationRisk(_ string) float64 {
-	// TODO: В будущем можно добавить реальный API для получения исторических данных
-	// Сейчас используем фиксированные значения для тестирования
-
-	// Базовые корреляции с основными активами
-	correlations := map[string]float64{
-		"SPY": 0.65,  // S&P 500
-		"QQQ": 0.60,  // NASDAQ
-		"GLD": 0.30,  // Gold
-		"TLT": -0.40, // Treasury Bonds
-		"VIX": -0.50, // Volatility Index
-	}
-
-	// Расчет взвешенного риска корреляции
-	var totalRisk float64
-	var totalWeight float64
-
-	for _, correlation := range correlations {
-		// Вес зависит от абсолютного значения корреляции
-		weight := math.Abs(correlation)
-		totalWeight += weight
-
-		// Риск увеличивается с ростом абсолютной корреляции
-		risk := math.Abs(correlation) * weight
-		totalRisk += risk
-	}
-
-	if totalWeight == 0 {
-		return 0.0
-	}
-
-	// Нормализация риска
-	return totalRisk / totalWeight
-}
-
-// calculateNewsRisk рассчитывает риск новостей
-func calculateNewsRisk(symbol string) float64 {
-	news, err := fetchNews(symbol)
-	if err != nil {
-		return 0.0
-	}
-
-	var totalImpact float64
-	for _, item := range news {
-		item.Symbol = symbol
-		impact := calculateNewsImpact(item, symbol)
-		totalImpact += impact
-	}
-
-	if len(news) == 0 {
-		return 0.0
-	}
-
-	return math.Min(totalImpact/float64(len(news)), 1.0)
+// calculateCorrelationRisk рассчитывает риск корреляции: насколько инструмент движется
+// вместе с остальными отслеживаемыми парами (0, пока матрица корреляций не построена)
+func calculateCorrelationRisk(symbol, interval string) float64 {
+	return correlation.Risk(symbol, interval)
 }
 
 // determineMarketRegime определяет текущий режим рынка
@@ -1569,44 +1491,28 @@ func getVolatilityCategory(volatility float64) string {
 	}
 }
 
-// analyzeEconomicIndicators анализирует экономические индикаторы
+// analyzeEconomicIndicators описывает календарь релизов по валютам инструмента на ближайшие сутки:
+// число релизов, число важных, часы до ближайшего важного и оценку риска релизов
 func analyzeEconomicIndicators(symbol string) map[string]float64 {
 	indicators := make(map[string]float64)
-
-	// Получение экономических данных
-	data, err := fetchEconomicData(symbol)
-	if err != nil {
-		log.Error().Err(err).Msg("Error fetching economic data")
+	if calendar.Len() == 0 {
 		return indicators
 	}
 
-	// Анализ каждого индикатора
-	for name, value := range data {
-		// Нормализация значения
-		normalizedValue := normalizeIndicator(value)
-
-		// Применяем веса в зависимости от типа индикатора
-		switch name {
-		case "gdp_growth":
-			indicators[name] = normalizedValue * 0.3
-		case "inflation":
-			indicators[name] = normalizedValue * 0.25
-		case "interest_rate":
-			indicators[name] = normalizedValue * 0.2
-		case "unemployment":
-			indicators[name] = normalizedValue * 0.15
-		case "market_cap":
-			indicators[name] = normalizedValue * 0.1
-		default:
-			indicators[name] = normalizedValue
-		}
+	now := time.Now()
+	indicators["events_24h"] = float64(len(calendar.Upcoming(symbol, now, now.Add(24*time.Hour), calendar.ImpactLow)))
+	highImpact := calendar.Upcoming(symbol, now, now.Add(24*time.Hour), calendar.ImpactHigh)
+	indicators["high_impact_24h"] = float64(len(highImpact))
+	if len(highImpact) > 0 {
+		indicators["hours_to_high_impact"] = highImpact[0].Time.Sub(now).Hours()
 	}
+	indicators["event_risk"] = calendar.RiskScore(symbol, now)
 
 	return indicators
 }
 
 // analyzeRiskFactors анализирует факторы риска
-func analyzeRiskFactors(symbol string, candles []models.Candle) map[string]float64 {
+func analyzeRiskFactors(symbol string, candles []models.Candle, news *models.NewsAnalysis) map[string]float64 {
 	risks := make(map[string]float64)
 
 	// Анализ волатильности
@@ -1618,12 +1524,18 @@ func analyzeRiskFactors(symbol string, candles []models.Candle) map[string]float
 	risks["liquidity_risk"] = liquidityRisk
 
 	// Анализ корреляций
-	correlationRisk := calculateCorrelationRisk(symbol)
+	interval := ""
+	if len(candles) > 0 {
+		interval = candles[len(candles)-1].TimeFrame
+	}
+	correlationRisk := calculateCorrelationRisk(symbol, interval)
 	risks["correlation_risk"] = correlationRisk
 
-	// Анализ новостей
-	newsRisk := calculateNewsRisk(symbol)
-	risks["news_risk"] = newsRisk
+	// Риск новостей - среднее влияние уже полученных новостей
+	risks["news_risk"] = math.Min(news.Impact, 1.0)
+
+	// Риск запланированных релизов по валютам инструмента
+	risks["event_risk"] = calendar.RiskScore(symbol, time.Now())
 
 	// Анализ рыночных условий
 	marketConditions := analyzeMarketConditions(candles)
@@ -1642,7 +1554,17 @@ func analyzeRiskFactors(symbol string, candles []models.Candle) map[string]float
 }
 
 // AnalyzeMarket выполняет полный анализ рынка
-func AnalyzeMarket(candles []models.Candle, marketCandles []models.Candle) *models.MarketAnalysis {
+func AnalyzeMarket(ctx context.Context, candles []models.Candle, marketCandles []models.Candle) *models.MarketAnalysis {
+	return analyzeMarket(candles, marketCandles, analyzeNews(ctx, candles[len(candles)-1].Symbol))
+}
+
+// AnalyzeMarketStructure выполняет анализ рынка только по свечам, без запроса новостей.
+// Используется там, где анализ повторяется на каждой свече истории
+func AnalyzeMarketStructure(candles []models.Candle, marketCandles []models.Candle) *models.MarketAnalysis {
+	return analyzeMarket(candles, marketCandles, &models.NewsAnalysis{MarketImpact: make(map[string]float64)})
+}
+
+func analyzeMarket(candles []models.Candle, marketCandles []models.Candle, news *models.NewsAnalysis) *models.MarketAnalysis {
 	analysis := &models.MarketAnalysis{
 		Symbol:      candles[len(candles)-1].Symbol,
 		TimeFrame:   candles[len(candles)-1].TimeFrame,
@@ -1670,12 +1592,10 @@ func AnalyzeMarket(candles []models.Candle, marketCandles []models.Candle) *mode
 	microstructure := analyzeMicrostructure(candles)
 	analysis.Microstructure = microstructure
 
-	// Анализ новостей
-	news := analyzeNews(analysis.Symbol, analysis.TimeFrame)
 	analysis.News = news
 
 	// Фундаментальный анализ
-	fundamentals := analyzeFundamentals(analysis.Symbol, candles)
+	fundamentals := analyzeFundamentals(analysis.Symbol, candles, news)
 	analysis.Fundamentals = fundamentals
 
 	// Определение направления и уверенности
@@ -4,13 +4,21 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/Alias1177/Predictor/internal/calculate"
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/internal/params"
 	"github.com/Alias1177/Predictor/internal/patterns"
+	"github.com/Alias1177/Predictor/internal/strength"
 	"github.com/Alias1177/Predictor/internal/utils"
 	"github.com/Alias1177/Predictor/models"
+	"github.com/rs/zerolog/log"
 )
 
+// StrategyName - имя стратегии EnhancedPrediction, под которым учатся веса ее факторов
+const StrategyName = "enhanced"
+
 // EnhancedPrediction выполняет улучшенный анализ и предсказание
 func EnhancedPrediction(
 	ctx context.Context,
@@ -19,16 +27,23 @@ func EnhancedPrediction(
 	mtfData map[string][]models.Candle,
 	regime *models.MarketRegime,
 	anomaly *models.AnomalyDetection,
+	market *models.MarketAnalysis,
 	cfg *models.Config) (*models.Prediction, error) {
 
-	// Обновляем веса на основе исторических данных
+	// Обновляем веса факторов на основе проверенных исторических прогнозов
 	if cfg.EnableBacktest {
-		results, err := utils.RunBacktest(ctx, candles, cfg)
+		results, err := utils.RunBacktest(ctx, candles, cfg, StrategyName)
 		if err == nil && results != nil {
-			utils.UpdateFactorWeights(results.DetailedResults)
+			if err := utils.UpdateFactorWeights(StrategyName, cfg.Symbol, cfg.Interval, results.DetailedResults); err != nil {
+				log.Warn().Err(err).Msg("Failed to persist factor weights")
+			}
 		}
 	}
 
+	// Веса и пороги берутся из активного файла параметров; снимок не меняется до конца расчета
+	p := params.Active()
+	w, t := p.Weights, p.Thresholds
+
 	// 1. Multi-timeframe trend alignment
 	trendDirection, trendStrength := patterns.DetectTrendAlignment(mtfData, cfg)
 
@@ -65,73 +80,91 @@ func EnhancedPrediction(
 
 	// 6. Scoring model for direction prediction
 
-	// Base scores
-	bullishScore := 0.0
-	bearishScore := 0.0
+	// addFactor добавляет вклад фактора с выученным весом: положительный - бычий, отрицательный - медвежий
+	b := explain.NewBuilder()
+	addFactor := func(factorID string, value, score float64, text map[string]string) {
+		b.Add(factorID, value, score*utils.GetFactorWeight(StrategyName, cfg.Symbol, cfg.Interval, factorID), text)
+	}
 
 	// Market regime factor (higher weight)
 	if regime.Type == "TRENDING" {
 		if regime.Direction == "BULLISH" {
-			bullishScore += 2.0 * regime.Strength
+			addFactor("REGIME_TREND", regime.Strength, w.RegimeTrend*regime.Strength, explain.Text(
+				fmt.Sprintf("Bullish trending regime (%.2f strength)", regime.Strength),
+				fmt.Sprintf("Бычий трендовый режим (сила %.2f)", regime.Strength)))
 		} else if regime.Direction == "BEARISH" {
-			bearishScore += 2.0 * regime.Strength
+			addFactor("REGIME_TREND", regime.Strength, -w.RegimeTrend*regime.Strength, explain.Text(
+				fmt.Sprintf("Bearish trending regime (%.2f strength)", regime.Strength),
+				fmt.Sprintf("Медвежий трендовый режим (сила %.2f)", regime.Strength)))
 		}
 	} else if regime.Type == "RANGING" {
 		// In ranging markets, favor mean reversion
 		if currentPrice > indicators.BBMiddle {
-			bearishScore += 0.5 * regime.Strength
+			addFactor("REGIME_RANGE", regime.Strength, -w.RegimeRange*regime.Strength, explain.Text(
+				"Ranging regime: price above the Bollinger middle band tends to revert down",
+				"Флэт: цена выше средней полосы Боллинджера склонна вернуться вниз"))
 		} else if currentPrice < indicators.BBMiddle {
-			bullishScore += 0.5 * regime.Strength
+			addFactor("REGIME_RANGE", regime.Strength, w.RegimeRange*regime.Strength, explain.Text(
+				"Ranging regime: price below the Bollinger middle band tends to revert up",
+				"Флэт: цена ниже средней полосы Боллинджера склонна вернуться вверх"))
 		}
 	}
 
 	// Trend alignment factor (higher weight)
 	if trendDirection == "BULLISH" {
-		bullishScore += 1.5 * trendStrength
+		addFactor("TREND_ALIGNMENT", trendStrength, w.TrendAlignment*trendStrength, explain.Text(
+			fmt.Sprintf("Bullish alignment across timeframes (%.2f)", trendStrength),
+			fmt.Sprintf("Бычья согласованность таймфреймов (%.2f)", trendStrength)))
 	} else if trendDirection == "BEARISH" {
-		bearishScore += 1.5 * trendStrength
+		addFactor("TREND_ALIGNMENT", trendStrength, -w.TrendAlignment*trendStrength, explain.Text(
+			fmt.Sprintf("Bearish alignment across timeframes (%.2f)", trendStrength),
+			fmt.Sprintf("Медвежья согласованность таймфреймов (%.2f)", trendStrength)))
 	}
 
 	// RSI factor
-	if indicators.RSI < 30 {
-		bullishScore += 1.0
-	} else if indicators.RSI > 70 {
-		bearishScore += 1.0
+	if indicators.RSI < t.RSIOversold {
+		addFactor("RSI", indicators.RSI, w.RSI, explain.Text(
+			fmt.Sprintf("Oversold RSI at %.1f", indicators.RSI),
+			fmt.Sprintf("RSI в зоне перепроданности (%.1f)", indicators.RSI)))
+	} else if indicators.RSI > t.RSIOverbought {
+		addFactor("RSI", indicators.RSI, -w.RSI, explain.Text(
+			fmt.Sprintf("Overbought RSI at %.1f", indicators.RSI),
+			fmt.Sprintf("RSI в зоне перекупленности (%.1f)", indicators.RSI)))
 	}
 
 	// MACD factor
 	if indicators.MACDHist > 0 && indicators.MACDHist > indicators.MACD*0.1 {
-		bullishScore += 0.8
+		addFactor("MACD", indicators.MACDHist, w.MACD, explain.Text(
+			"Positive MACD histogram", "Положительная гистограмма MACD"))
 	} else if indicators.MACDHist < 0 && indicators.MACDHist < indicators.MACD*0.1 {
-		bearishScore += 0.8
+		addFactor("MACD", indicators.MACDHist, -w.MACD, explain.Text(
+			"Negative MACD histogram", "Отрицательная гистограмма MACD"))
 	}
 
 	// Stochastic factor
-	if indicators.Stochastic < 20 && indicators.Stochastic > indicators.StochasticSignal {
-		bullishScore += 0.7 // Oversold and turning up
-	} else if indicators.Stochastic > 80 && indicators.Stochastic < indicators.StochasticSignal {
-		bearishScore += 0.7 // Overbought and turning down
+	if indicators.Stochastic < t.StochasticOversold && indicators.Stochastic > indicators.StochasticSignal {
+		addFactor("STOCHASTIC", indicators.Stochastic, w.Stochastic, explain.Text( // Oversold and turning up
+			fmt.Sprintf("Stochastic turning up from oversold (%.1f)", indicators.Stochastic),
+			fmt.Sprintf("Стохастик разворачивается вверх из перепроданности (%.1f)", indicators.Stochastic)))
+	} else if indicators.Stochastic > t.StochasticOverbought && indicators.Stochastic < indicators.StochasticSignal {
+		addFactor("STOCHASTIC", indicators.Stochastic, -w.Stochastic, explain.Text( // Overbought and turning down
+			fmt.Sprintf("Stochastic turning down from overbought (%.1f)", indicators.Stochastic),
+			fmt.Sprintf("Стохастик разворачивается вниз из перекупленности (%.1f)", indicators.Stochastic)))
 	}
 
-	// Pattern factors (increased weight for specific patterns)
+	// Pattern factors: вес выводится из статистики исходов паттернов; без нее - базовые веса
 	for _, pattern := range patterns2 {
-		switch pattern {
-		case "BULLISH_ENGULFING", "HAMMER", "MORNING_STAR":
-			bullishScore += 1.8
-		case "BEARISH_ENGULFING", "SHOOTING_STAR", "EVENING_STAR":
-			bearishScore += 1.8
-		case "THREE_WHITE_SOLDIERS":
-			bullishScore += 2.0
-		case "THREE_BLACK_CROWS":
-			bearishScore += 2.0
-		case "STRONG_BULLISH_MOMENTUM":
-			bullishScore += 1.2
-		case "STRONG_BEARISH_MOMENTUM":
-			bearishScore += 1.2
-		case "DOUBLE_BOTTOM":
-			bullishScore += 1.5
-		case "DOUBLE_TOP":
-			bearishScore += 1.5
+		weight, ok := w.Patterns[pattern]
+		if !ok {
+			continue
+		}
+		switch patterns.PatternDirection(pattern) {
+		case "BULLISH":
+			addFactor("PATTERN_"+pattern, weight, patterns.PatternScore(cfg.Symbol, cfg.Interval, pattern, weight), explain.Text(
+				"Bullish pattern: "+pattern, "Бычий паттерн: "+pattern))
+		case "BEARISH":
+			addFactor("PATTERN_"+pattern, weight, -patterns.PatternScore(cfg.Symbol, cfg.Interval, pattern, weight), explain.Text(
+				"Bearish pattern: "+pattern, "Медвежий паттерн: "+pattern))
 		}
 	}
 
@@ -141,236 +174,136 @@ func EnhancedPrediction(
 		resistanceFactor := math.Min(1.0, expectedMove/distanceToResistance)
 
 		if distanceToSupport < distanceToResistance {
-			// Closer to support
-			bearishScore -= supportFactor * 0.8 // Reduce bearish score near support
-			bullishScore += supportFactor * 0.5 // Add bullish score near support
+			// Closer to support: reduce bearish and add bullish score
+			addFactor("SUPPORT_RESISTANCE", nearestSupport, supportFactor*(w.SupportPenalty+w.SupportBonus), explain.Text(
+				fmt.Sprintf("Price closer to support at %.5f", nearestSupport),
+				fmt.Sprintf("Цена ближе к поддержке %.5f", nearestSupport)))
 		} else {
-			// Closer to resistance
-			bullishScore -= resistanceFactor * 0.8 // Reduce bullish score near resistance
-			bearishScore += resistanceFactor * 0.5 // Add bearish score near resistance
+			// Closer to resistance: reduce bullish and add bearish score
+			addFactor("SUPPORT_RESISTANCE", nearestResistance, -resistanceFactor*(w.SupportPenalty+w.SupportBonus), explain.Text(
+				fmt.Sprintf("Price closer to resistance at %.5f", nearestResistance),
+				fmt.Sprintf("Цена ближе к сопротивлению %.5f", nearestResistance)))
 		}
 	}
 
 	// Order flow factor
 	if flowDirection == "BULLISH" {
-		bullishScore += 1.0
+		addFactor("ORDER_FLOW", 1, w.OrderFlow, explain.Text(
+			"Positive order flow with higher volume on up candles",
+			"Положительный поток ордеров: объем выше на растущих свечах"))
 	} else if flowDirection == "BEARISH" {
-		bearishScore += 1.0
+		addFactor("ORDER_FLOW", -1, -w.OrderFlow, explain.Text(
+			"Negative order flow with higher volume on down candles",
+			"Отрицательный поток ордеров: объем выше на падающих свечах"))
 	}
 
 	// Trade signal factor
+	tradeSignalText := explain.Text(
+		"Multiple indicators aligning: "+indicators.TradeSignal,
+		"Индикаторы согласованы: "+indicators.TradeSignal)
 	if indicators.TradeSignal == "STRONG_BUY" {
-		bullishScore += 1.5
+		addFactor("TRADE_SIGNAL", 2, w.StrongTradeSignal, tradeSignalText)
 	} else if indicators.TradeSignal == "BUY" {
-		bullishScore += 0.8
+		addFactor("TRADE_SIGNAL", 1, w.TradeSignal, tradeSignalText)
 	} else if indicators.TradeSignal == "STRONG_SELL" {
-		bearishScore += 1.5
+		addFactor("TRADE_SIGNAL", -2, -w.StrongTradeSignal, tradeSignalText)
 	} else if indicators.TradeSignal == "SELL" {
-		bearishScore += 0.8
-	}
-
-	// Anomaly adjustment
-	if anomaly.IsAnomaly {
-		// During anomalies, reduce overall confidence
-		bullishScore *= (1.0 - anomaly.AnomalyScore*0.3)
-		bearishScore *= (1.0 - anomaly.AnomalyScore*0.3)
+		addFactor("TRADE_SIGNAL", -1, -w.TradeSignal, tradeSignalText)
 	}
 
-	// Volatility adjustment
-	confidenceMultiplier := 1.0
-	if volatilityRegime == "HIGH" {
-		confidenceMultiplier = 0.8 // Reduce confidence in high volatility
-	} else if volatilityRegime == "LOW" {
-		confidenceMultiplier = 0.9 // Slightly reduce confidence in low volatility
-	}
-
-	// Final direction decision
-	direction := "NEUTRAL"
-	netScore := (bullishScore - bearishScore) * confidenceMultiplier
-
-	if netScore > 1.5 {
-		direction = "BUY"
-	} else if netScore < -1.5 {
-		direction = "SELL"
-	}
-
-	// Confidence calculation
-	confidence := "MEDIUM"
-	absoluteNetScore := math.Abs(netScore)
-
-	if absoluteNetScore > 3.0 {
-		confidence = "HIGH"
-	} else if absoluteNetScore < 2.0 {
-		confidence = "LOW"
-	}
-
-	// Decision factors for explanation
-	var factors []string
-
-	if direction == "BUY" {
-		if regime.Type == "TRENDING" && regime.Direction == "BULLISH" && regime.Strength > 0.6 {
-			factors = append(factors, fmt.Sprintf("Strong bullish market regime (%.1f strength)", regime.Strength))
-		}
-
-		if trendDirection == "BULLISH" && trendStrength > 0.5 {
-			factors = append(factors, fmt.Sprintf("Bullish alignment across timeframes (%.1f)", trendStrength))
-		}
-
-		if indicators.RSI < 40 {
-			factors = append(factors, fmt.Sprintf("Oversold RSI at %.1f", indicators.RSI))
-		}
-
-		if indicators.Stochastic < 20 && indicators.Stochastic > indicators.StochasticSignal {
-			factors = append(factors, fmt.Sprintf("Stochastic turning up from oversold (%.1f)", indicators.Stochastic))
-		}
-
-		for _, pattern := range patterns2 {
-			if pattern == "BULLISH_ENGULFING" || pattern == "HAMMER" || pattern == "MORNING_STAR" ||
-				pattern == "THREE_WHITE_SOLDIERS" || pattern == "DOUBLE_BOTTOM" {
-				factors = append(factors, "Bullish reversal pattern: "+pattern)
+	for _, divergence := range divergences {
+		strength := divergence.SignalStrength
+		switch divergence.Type {
+		case "REGULAR":
+			if divergence.Direction == "BULLISH" {
+				addFactor("DIVERGENCE_REGULAR", strength, w.RegularDivergence*strength, explain.Text(
+					fmt.Sprintf("Regular bullish divergence on %s (strength %.2f)", divergence.Indicator, strength),
+					fmt.Sprintf("Регулярная бычья дивергенция %s (сила %.2f)", divergence.Indicator, strength)))
+			} else if divergence.Direction == "BEARISH" {
+				addFactor("DIVERGENCE_REGULAR", strength, -w.RegularDivergence*strength, explain.Text(
+					fmt.Sprintf("Regular bearish divergence on %s (strength %.2f)", divergence.Indicator, strength),
+					fmt.Sprintf("Регулярная медвежья дивергенция %s (сила %.2f)", divergence.Indicator, strength)))
 			}
-		}
-
-		if flowDirection == "BULLISH" {
-			factors = append(factors, "Positive order flow with higher volume on up candles")
-		}
-
-		if distanceToSupport < expectedMove {
-			factors = append(factors, fmt.Sprintf("Price found support at %.5f", nearestSupport))
-		}
-
-		if indicators.TradeSignal == "STRONG_BUY" || indicators.TradeSignal == "BUY" {
-			factors = append(factors, "Multiple indicators aligning bullish: "+indicators.TradeSignal)
-		}
-	} else if direction == "SELL" {
-		if regime.Type == "TRENDING" && regime.Direction == "BEARISH" && regime.Strength > 0.6 {
-			factors = append(factors, fmt.Sprintf("Strong bearish market regime (%.1f strength)", regime.Strength))
-		}
-
-		if trendDirection == "BEARISH" && trendStrength > 0.5 {
-			factors = append(factors, fmt.Sprintf("Bearish alignment across timeframes (%.1f)", trendStrength))
-		}
-
-		if indicators.RSI > 60 {
-			factors = append(factors, fmt.Sprintf("Overbought RSI at %.1f", indicators.RSI))
-		}
-
-		if indicators.Stochastic > 80 && indicators.Stochastic < indicators.StochasticSignal {
-			factors = append(factors, fmt.Sprintf("Stochastic turning down from overbought (%.1f)", indicators.Stochastic))
-		}
-
-		for _, pattern := range patterns2 {
-			if pattern == "BEARISH_ENGULFING" || pattern == "SHOOTING_STAR" || pattern == "EVENING_STAR" ||
-				pattern == "THREE_BLACK_CROWS" || pattern == "DOUBLE_TOP" {
-				factors = append(factors, "Bearish reversal pattern: "+pattern)
+		case "HIDDEN":
+			// Скрытые дивергенции - сигналы продолжения тренда
+			if divergence.Direction == "BULLISH" {
+				addFactor("DIVERGENCE_HIDDEN", strength, w.HiddenDivergence*strength, explain.Text(
+					fmt.Sprintf("Hidden bullish divergence on %s (strength %.2f)", divergence.Indicator, strength),
+					fmt.Sprintf("Скрытая бычья дивергенция %s (сила %.2f)", divergence.Indicator, strength)))
+			} else if divergence.Direction == "BEARISH" {
+				addFactor("DIVERGENCE_HIDDEN", strength, -w.HiddenDivergence*strength, explain.Text(
+					fmt.Sprintf("Hidden bearish divergence on %s (strength %.2f)", divergence.Indicator, strength),
+					fmt.Sprintf("Скрытая медвежья дивергенция %s (сила %.2f)", divergence.Indicator, strength)))
 			}
 		}
+	}
 
-		if flowDirection == "BEARISH" {
-			factors = append(factors, "Negative order flow with higher volume on down candles")
+	// Конфлюенция: несколько индикаторов расходятся с ценой на одном свинге
+	for _, confluence := range patterns.FindDivergenceConfluence(divergences, 2) {
+		if len(confluence.Indicators) < 2 {
+			continue
 		}
 
-		if distanceToResistance < expectedMove {
-			factors = append(factors, fmt.Sprintf("Price rejected at resistance %.5f", nearestResistance))
+		bonus := w.DivergenceConfluence * float64(len(confluence.Indicators)-1) * confluence.AverageStrength
+		joined := strings.Join(confluence.Indicators, ", ")
+		if confluence.Direction == "BULLISH" {
+			addFactor("DIVERGENCE_CONFLUENCE", float64(len(confluence.Indicators)), bonus, explain.Text(
+				"Bullish divergence confluence: "+joined, "Конфлюенция бычьих дивергенций: "+joined))
+		} else if confluence.Direction == "BEARISH" {
+			addFactor("DIVERGENCE_CONFLUENCE", float64(len(confluence.Indicators)), -bonus, explain.Text(
+				"Bearish divergence confluence: "+joined, "Конфлюенция медвежьих дивергенций: "+joined))
 		}
+	}
 
-		if indicators.TradeSignal == "STRONG_SELL" || indicators.TradeSignal == "SELL" {
-			factors = append(factors, "Multiple indicators aligning bearish: "+indicators.TradeSignal)
-		}
+	// Комплексный анализ рынка с бенчмарком, если этап включен
+	if market != nil {
+		AddMarketAnalysisFactor(b, market, w.MarketAnalysis*utils.GetFactorWeight(StrategyName, cfg.Symbol, cfg.Interval, "MARKET_ANALYSIS"))
 	}
 
-	// If we don't have at least 2 factors, add more generic ones
-	if len(factors) < 2 {
-		if direction == "BUY" {
-			if indicators.MACDHist > 0 {
-				factors = append(factors, "Positive MACD histogram")
-			}
-			if currentPrice > indicators.EMA {
-				factors = append(factors, "Price above EMA")
-			}
-			if indicators.ADX > 20 && indicators.PlusDI > indicators.MinusDI {
-				factors = append(factors, "ADX shows bullish trend")
-			}
-			if currentPrice > indicators.BBMiddle {
-				factors = append(factors, "Price above Bollinger middle band")
-			}
-		} else if direction == "SELL" {
-			if indicators.MACDHist < 0 {
-				factors = append(factors, "Negative MACD histogram")
-			}
-			if currentPrice < indicators.EMA {
-				factors = append(factors, "Price below EMA")
-			}
-			if indicators.ADX > 20 && indicators.MinusDI > indicators.PlusDI {
-				factors = append(factors, "ADX shows bearish trend")
-			}
-			if currentPrice < indicators.BBMiddle {
-				factors = append(factors, "Price below Bollinger middle band")
-			}
-		}
+	// Сила валют: сильная базовая валюта против слабой котируемой поддерживает покупку пары
+	if snapshot := strength.Active(); cfg.EnableCurrencyStrength && snapshot != nil {
+		addCurrencyStrengthFactor(b, snapshot, cfg.Symbol, w.CurrencyStrength*utils.GetFactorWeight(StrategyName, cfg.Symbol, cfg.Interval, "CURRENCY_STRENGTH"))
 	}
 
-	// Still need more factors? Add very generic ones
-	if len(factors) < 2 {
-		if direction == "BUY" {
-			factors = append(factors, "General market strength")
-		} else if direction == "SELL" {
-			factors = append(factors, "General market weakness")
-		} else {
-			factors = append(factors, "Market in consolidation")
-		}
+	// Anomaly adjustment
+	if anomaly.IsAnomaly {
+		// During anomalies, reduce overall confidence
+		b.Scale("ANOMALY", 1.0-anomaly.AnomalyScore*w.AnomalyPenalty, explain.Text(
+			fmt.Sprintf("Market anomaly (score %.2f) damps the signal", anomaly.AnomalyScore),
+			fmt.Sprintf("Аномалия рынка (оценка %.2f) ослабляет сигнал", anomaly.AnomalyScore)))
 	}
 
-	for _, divergence := range divergences {
-		switch divergence.Type {
-		case "REGULAR":
-			if divergence.Direction == "BULLISH" {
-				bullishScore += 1.5 * divergence.SignalStrength
+	// Volatility adjustment
+	if volatilityRegime == "HIGH" {
+		b.Scale("VOLATILITY", w.HighVolatility, explain.Text( // Reduce confidence in high volatility
+			"High volatility reduces confidence", "Высокая волатильность снижает уверенность"))
+	} else if volatilityRegime == "LOW" {
+		b.Scale("VOLATILITY", w.LowVolatility, explain.Text( // Slightly reduce confidence in low volatility
+			"Low volatility reduces confidence", "Низкая волатильность снижает уверенность"))
+	}
 
-				// Добавляем в факторы для объяснения если направление совпадает
-				if direction == "BUY" || (netScore > 0 && direction == "NEUTRAL") {
-					factors = append(factors, fmt.Sprintf("Регулярная бычья дивергенция RSI (сила %.2f)",
-						divergence.SignalStrength))
-				}
-			} else if divergence.Direction == "BEARISH" {
-				bearishScore += 1.5 * divergence.SignalStrength
+	// Right after a structural break the regime signals are unreliable
+	if cp := regime.ChangePoint; cp != nil && cp.RecentBreak {
+		b.Scale("CHANGE_POINT", cp.Damping, explain.Text(
+			fmt.Sprintf("Recent regime shift %d bars ago (probability %.0f%%)", cp.RegimeAge, cp.BreakProbability*100),
+			fmt.Sprintf("Недавний сдвиг режима: %d свечей назад (вероятность %.0f%%)", cp.RegimeAge, cp.BreakProbability*100)))
+	}
 
-				// Добавляем в факторы для объяснения если направление совпадает
-				if direction == "SELL" || (netScore < 0 && direction == "NEUTRAL") {
-					factors = append(factors, fmt.Sprintf("Регулярная медвежья дивергенция RSI (сила %.2f)",
-						divergence.SignalStrength))
-				}
-			}
-		case "HIDDEN":
-			// Скрытые дивергенции - сигналы продолжения тренда
-			if divergence.Direction == "BULLISH" {
-				bullishScore += 1.0 * divergence.SignalStrength
+	// Final direction decision and confidence
+	netScore := b.Score()
+	direction, confidence := params.Direction(p, netScore)
 
-				if direction == "BUY" || (netScore > 0 && direction == "NEUTRAL") {
-					factors = append(factors, fmt.Sprintf("Скрытая бычья дивергенция RSI (сила %.2f)",
-						divergence.SignalStrength))
-				}
-			} else if divergence.Direction == "BEARISH" {
-				bearishScore += 1.0 * divergence.SignalStrength
+	// Decision factors for explanation
+	factors := b.Explain(direction, cfg.Language)
 
-				if direction == "SELL" || (netScore < 0 && direction == "NEUTRAL") {
-					factors = append(factors, fmt.Sprintf("Скрытая медвежья дивергенция RSI (сила %.2f)",
-						divergence.SignalStrength))
-				}
-			}
-		}
-	}
 	stopLossLevel := calculate.DetermineStopLoss(candles, indicators, direction)
 
-	accountSize := 10000.0 // Примерный размер счета, можно получать из конфига
-	riskPerTrade := 0.01   // 1% от счета на сделку
-
 	// Расчет размера позиции
 	positionSizing := calculate.CalculatePositionSize(
 		candles[len(candles)-1].Close,
 		stopLossLevel,
-		accountSize,
-		riskPerTrade,
+		p.Risk.AccountSize,
+		p.Risk.RiskPerTrade,
 	)
 
 	// Создаем торговую рекомендацию
@@ -398,16 +331,55 @@ func EnhancedPrediction(
 		}
 	}
 
-	// Применяем веса к факторам
-	for i := range factors {
-		factors[i] = fmt.Sprintf("%s (%.2f)", factors[i], utils.GetFactorWeight(factors[i]))
-	}
-
 	return &models.Prediction{
 		Direction:         direction,
 		Confidence:        confidence,
 		Score:             netScore,
 		Factors:           factors,
 		TradingSuggestion: tradingSuggestion,
+		ParamsVersion:     p.Version,
+		FactorScores:      b.FactorScores(),
+		Breakdown:         b.Breakdown(),
 	}, nil
 }
+
+// AddMarketAnalysisFactor добавляет направление AnalyzeMarket фактором MARKET_ANALYSIS
+// с вкладом weight * уверенность; нейтральный анализ записывается пояснением
+func AddMarketAnalysisFactor(b *explain.Builder, analysis *models.MarketAnalysis, weight float64) {
+	text := explain.Text(
+		fmt.Sprintf("Market analysis: %s (confidence %.0f%%)", analysis.Direction, analysis.Confidence*100),
+		fmt.Sprintf("Анализ рынка: %s (уверенность %.0f%%)", analysis.Direction, analysis.Confidence*100))
+	if c := analysis.Correlations; c != nil && c.Benchmark != "" {
+		text = explain.Text(
+			fmt.Sprintf("%s; %s %+.2f%%, correlation %.2f", text["en"], c.Benchmark, c.MarketTrend, c.MarketCorrelation),
+			fmt.Sprintf("%s; %s %+.2f%%, корреляция %.2f", text["ru"], c.Benchmark, c.MarketTrend, c.MarketCorrelation))
+	}
+
+	switch analysis.Direction {
+	case "bullish":
+		b.Add("MARKET_ANALYSIS", analysis.Confidence, weight*analysis.Confidence, text)
+	case "bearish":
+		b.Add("MARKET_ANALYSIS", analysis.Confidence, -weight*analysis.Confidence, text)
+	default:
+		b.Note(text)
+	}
+}
+
+// addCurrencyStrengthFactor добавляет фактор CURRENCY_STRENGTH: вклад пропорционален разнице
+// оценок базовой и котируемой валют и достигает weight при разнице в 2 единицы разброса.
+// Пары вне рейтинга (металлы, криптовалюты) пропускаются
+func addCurrencyStrengthFactor(b *explain.Builder, snapshot *models.CurrencyStrengthSnapshot, symbol string, weight float64) {
+	base, quote, diff, ok := strength.Differential(snapshot, symbol)
+	if !ok {
+		return
+	}
+
+	text := explain.Text(
+		fmt.Sprintf("Currency strength: %s %+.2f vs %s %+.2f", base.Currency, base.Score, quote.Currency, quote.Score),
+		fmt.Sprintf("Сила валют: %s %+.2f против %s %+.2f", base.Currency, base.Score, quote.Currency, quote.Score))
+	if math.Abs(diff) < strength.Neutral {
+		b.Note(text)
+		return
+	}
+	b.Add("CURRENCY_STRENGTH", diff, weight*math.Max(-1, math.Min(1, diff/2)), text)
+}
@@ -115,6 +115,49 @@ func DetectMarketAnomalies(candles []models.Candle) *models.AnomalyDetection {
 		}
 	}
 
+	// 5. Session-aware checks: expected FX events replace the generic labels for the same bar
+	if session := detectSessionAnomaly(candles, atr10); session != nil {
+		anomaly.Session = session.session
+		anomaly.Gap = session.gap
+
+		if anomaly.IsAnomaly && !session.currentBar {
+			// Older weekend gap is still open; keep the current bar anomaly and add the gap context
+			anomaly.Details += "; " + session.details
+		} else {
+			anomaly.IsAnomaly = true
+			anomaly.AnomalyType = session.anomalyType
+			anomaly.AnomalyScore = session.score
+			anomaly.Details = session.details
+			anomaly.RecommendedFlags = session.flags
+		}
+	}
+
+	// 6. Multivariate check with the isolation forest trained by cmd/anomalytrain; without
+	// a loaded model only the z-score checks above apply
+	last := candles[len(candles)-1]
+	if forest, ok := GetIsolationForest(last.Symbol, last.TimeFrame); ok {
+		if percentile, breakdown, ok := forest.Evaluate(candles); ok {
+			anomaly.ModelScore = percentile
+			anomaly.FeatureContributions = breakdown
+
+			if percentile >= forestAnomalyQuantile {
+				modelScore := math.Min((percentile-forestAnomalyQuantile)/(1-forestAnomalyQuantile)*0.5+0.5, 1.0)
+				summary := fmt.Sprintf("Isolation forest percentile %.1f%% (%s)", percentile*100, formatContributions(breakdown))
+
+				if anomaly.IsAnomaly {
+					anomaly.AnomalyScore = math.Max(anomaly.AnomalyScore, modelScore)
+					anomaly.Details += "; " + summary
+				} else {
+					anomaly.IsAnomaly = true
+					anomaly.AnomalyType = "MULTIVARIATE_OUTLIER"
+					anomaly.AnomalyScore = modelScore
+					anomaly.Details = summary
+					anomaly.RecommendedFlags = append(anomaly.RecommendedFlags, "WAIT_FOR_CONFIRMATION")
+				}
+			}
+		}
+	}
+
 	// If any anomaly was detected, add common flags
 	if anomaly.IsAnomaly {
 		anomaly.RecommendedFlags = append(anomaly.RecommendedFlags, "USE_CAUTION", "MONITOR_CLOSELY")
@@ -0,0 +1,228 @@
+package anomaly
+
+import (
+	"math"
+
+	"github.com/Alias1177/Predictor/internal/utils"
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	// changePointHazardLength - ожидаемая длительность режима в свечах (априорная частота сдвигов 1/N)
+	changePointHazardLength = 100.0
+
+	// changePointRecentBars - сколько свечей после сдвига прогноз считается ослабленным
+	changePointRecentBars = 10
+
+	// changePointMinCandles - минимальная история для поиска сдвигов
+	changePointMinCandles = 30
+
+	// Параметры CUSUM в единицах стандартного отклонения
+	cusumThreshold = 5.0
+	cusumDrift     = 0.5
+	cusumWarmup    = 20
+)
+
+// CUSUM выполняет двусторонний онлайн CUSUM: опорное распределение оценивается по
+// первым warmup значениям и переоценивается после каждого сдвига. Возвращает индексы срабатываний
+func CUSUM(values []float64, warmup int, threshold, drift float64) []int {
+	var breaks []int
+
+	start := 0
+	for start+warmup < len(values) {
+		mean, std := calculateMeanStd(values[start : start+warmup])
+		if std == 0 {
+			std = 1e-12
+		}
+
+		var positive, negative float64
+		found := false
+		for i := start + warmup; i < len(values); i++ {
+			z := (values[i] - mean) / std
+			positive = math.Max(0, positive+z-drift)
+			negative = math.Max(0, negative-z-drift)
+
+			if positive > threshold || negative > threshold {
+				breaks = append(breaks, i)
+				start = i
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	return breaks
+}
+
+// BayesianOnlineChangePoint реализует байесовское онлайн-обнаружение сдвигов (Adams & MacKay)
+// с нормально-гамма априорным распределением. Возвращает апостериорное распределение длины
+// текущего режима после последнего наблюдения: элемент r - вероятность того, что последние r
+// наблюдений принадлежат текущему режиму
+func BayesianOnlineChangePoint(values []float64, hazardLength float64) []float64 {
+	hazard := 1 / hazardLength
+
+	// Априорные параметры для стандартизированного ряда
+	const mu0, kappa0, alpha0, beta0 = 0.0, 1.0, 1.0, 1.0
+
+	runLength := []float64{1}
+	mu := []float64{mu0}
+	kappa := []float64{kappa0}
+	alpha := []float64{alpha0}
+	beta := []float64{beta0}
+
+	for _, x := range values {
+		next := make([]float64, len(runLength)+1)
+		var total float64
+
+		for r, prob := range runLength {
+			scale := beta[r] * (kappa[r] + 1) / (alpha[r] * kappa[r])
+			predictive := math.Exp(studentTLogPDF(x, 2*alpha[r], mu[r], scale))
+
+			next[r+1] = prob * predictive * (1 - hazard)
+			next[0] += prob * predictive * hazard
+		}
+
+		for _, p := range next {
+			total += p
+		}
+		if total == 0 {
+			// Наблюдение несовместимо со всеми режимами - считаем, что произошел сдвиг
+			next[0], total = 1, 1
+		}
+		for r := range next {
+			next[r] /= total
+		}
+
+		// Обновляем достаточные статистики; для нового режима берем априорные значения
+		newMu := []float64{mu0}
+		newKappa := []float64{kappa0}
+		newAlpha := []float64{alpha0}
+		newBeta := []float64{beta0}
+		for r := range mu {
+			newMu = append(newMu, (kappa[r]*mu[r]+x)/(kappa[r]+1))
+			newKappa = append(newKappa, kappa[r]+1)
+			newAlpha = append(newAlpha, alpha[r]+0.5)
+			newBeta = append(newBeta, beta[r]+kappa[r]*(x-mu[r])*(x-mu[r])/(2*(kappa[r]+1)))
+		}
+
+		runLength, mu, kappa, alpha, beta = next, newMu, newKappa, newAlpha, newBeta
+	}
+
+	return runLength
+}
+
+// studentTLogPDF возвращает логарифм плотности t-распределения Стьюдента
+func studentTLogPDF(x, df, loc, scale float64) float64 {
+	lgNum, _ := math.Lgamma((df + 1) / 2)
+	lgDen, _ := math.Lgamma(df / 2)
+	z := (x - loc) * (x - loc) / (df * scale)
+	return lgNum - lgDen - 0.5*math.Log(df*math.Pi*scale) - (df+1)/2*math.Log1p(z)
+}
+
+// DetectChangePoints ищет структурные сдвиги в доходностях и волатильности и оценивает
+// возраст текущего режима. Основной оценкой служит байесовский детектор, CUSUM используется
+// как запасной вариант и для списка всех сдвигов
+func DetectChangePoints(candles []models.Candle) *models.ChangePointAnalysis {
+	analysis := &models.ChangePointAnalysis{
+		RegimeAge: len(candles),
+		Damping:   1,
+	}
+
+	if len(candles) < changePointMinCandles {
+		return analysis
+	}
+
+	returns := make([]float64, 0, len(candles)-1)
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].Close > 0 && candles[i].Close > 0 {
+			returns = append(returns, math.Log(candles[i].Close/candles[i-1].Close))
+		} else {
+			returns = append(returns, 0)
+		}
+	}
+
+	mean, std := calculateMeanStd(returns)
+	if std == 0 {
+		return analysis
+	}
+
+	standardized := make([]float64, len(returns))
+	absolute := make([]float64, len(returns))
+	for i, r := range returns {
+		standardized[i] = (r - mean) / std
+		absolute[i] = math.Abs(standardized[i])
+	}
+
+	// Доходность i соответствует свече i+1
+	candlePoint := func(index int, method, series string, probability float64) models.ChangePoint {
+		return models.ChangePoint{
+			Index:       index + 1,
+			Timestamp:   candles[index+1].Timestamp,
+			Method:      method,
+			Series:      series,
+			Probability: probability,
+		}
+	}
+
+	posterior := BayesianOnlineChangePoint(standardized, changePointHazardLength)
+
+	// breakProbability - вероятность того, что текущий режим начался не раньше индекса доходности
+	breakProbability := func(index int) float64 {
+		age := len(returns) - index
+		tolerance := utils.MaxInt(2, age/10)
+		var probability float64
+		for r := 0; r <= age+tolerance && r < len(posterior); r++ {
+			probability += posterior[r]
+		}
+		return math.Min(probability, 1)
+	}
+
+	for _, index := range CUSUM(standardized, cusumWarmup, cusumThreshold, cusumDrift) {
+		analysis.Breaks = append(analysis.Breaks, candlePoint(index, "CUSUM", "RETURNS", breakProbability(index)))
+	}
+	for _, index := range CUSUM(absolute, cusumWarmup, cusumThreshold, cusumDrift) {
+		analysis.Breaks = append(analysis.Breaks, candlePoint(index, "CUSUM", "VOLATILITY", breakProbability(index)))
+	}
+
+	// Наиболее вероятная длина режима; нулевая длина исключается, так как ее
+	// вероятность всегда равна априорной частоте сдвигов
+	mapAge := 1
+	for r := 1; r < len(posterior); r++ {
+		if posterior[r] > posterior[mapAge] {
+			mapAge = r
+		}
+	}
+
+	if mapAge < len(returns) {
+		index := len(returns) - mapAge
+		point := candlePoint(index, "BOCPD", "RETURNS", breakProbability(index))
+		analysis.LastBreak = &point
+	} else {
+		for _, point := range analysis.Breaks {
+			if analysis.LastBreak == nil || point.Index > analysis.LastBreak.Index {
+				p := point
+				analysis.LastBreak = &p
+			}
+		}
+	}
+
+	if analysis.LastBreak == nil {
+		return analysis
+	}
+
+	analysis.BreakProbability = analysis.LastBreak.Probability
+	analysis.RegimeAge = len(candles) - analysis.LastBreak.Index
+	analysis.RecentBreak = analysis.RegimeAge <= changePointRecentBars && analysis.BreakProbability >= 0.5
+
+	if analysis.RecentBreak {
+		// Чем свежее и вероятнее сдвиг, тем сильнее ослабляется прогноз
+		freshness := 1 - float64(analysis.RegimeAge)/float64(changePointRecentBars+1)
+		analysis.Damping = 1 - 0.5*analysis.BreakProbability*freshness
+	}
+
+	return analysis
+}
@@ -0,0 +1,402 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	// forestLookback - история, необходимая для расчета признаков одной свечи
+	forestLookback = 20
+
+	// forestMinSamples - минимум векторов признаков для обучения леса
+	forestMinSamples = 100
+
+	// forestAnomalyQuantile - перцентиль калиброванной оценки, начиная с которого свеча считается аномальной
+	forestAnomalyQuantile = 0.99
+)
+
+// AnomalyFeatureNames - признаки свечи, по которым строится изолирующий лес
+var AnomalyFeatureNames = []string{"return", "range", "body", "gap", "volume", "volatility"}
+
+// IsolationNode - узел изолирующего дерева; у листа Feature = -1
+type IsolationNode struct {
+	Feature int     `json:"f"`
+	Split   float64 `json:"s,omitempty"`
+	Left    int     `json:"l,omitempty"`
+	Right   int     `json:"r,omitempty"`
+	Size    int     `json:"n"`
+}
+
+// IsolationTree - дерево случайных разбиений, хранится плоским массивом узлов
+type IsolationTree struct {
+	Nodes []IsolationNode `json:"nodes"`
+}
+
+// IsolationForest - детектор аномалий по многомерным признакам свечей
+type IsolationForest struct {
+	Symbol      string          `json:"symbol"`
+	Interval    string          `json:"interval"`
+	Features    []string        `json:"features"`
+	SampleSize  int             `json:"sample_size"`
+	Trees       []IsolationTree `json:"trees"`
+	Calibration []float64       `json:"calibration"` // Отсортированные оценки обучающей выборки
+	TrainedAt   time.Time       `json:"trained_at"`
+}
+
+// forestRegistry хранит обученные леса по ключу символ/таймфрейм
+var forestRegistry = struct {
+	mu      sync.RWMutex
+	forests map[string]*IsolationForest
+}{
+	forests: make(map[string]*IsolationForest),
+}
+
+// AnomalyFeatureMatrix рассчитывает векторы признаков для всех свечей, начиная с forestLookback.
+// Ценовые признаки нормируются на средний истинный диапазон предыдущих свечей
+func AnomalyFeatureMatrix(candles []models.Candle) [][]float64 {
+	if len(candles) <= forestLookback {
+		return nil
+	}
+
+	trueRanges := make([]float64, len(candles))
+	for i, c := range candles {
+		trueRanges[i] = c.High - c.Low
+		if i > 0 {
+			trueRanges[i] = math.Max(trueRanges[i], math.Max(
+				math.Abs(c.High-candles[i-1].Close),
+				math.Abs(c.Low-candles[i-1].Close)))
+		}
+	}
+
+	matrix := make([][]float64, 0, len(candles)-forestLookback)
+	for i := forestLookback; i < len(candles); i++ {
+		matrix = append(matrix, anomalyFeatureVector(candles, trueRanges, i))
+	}
+
+	return matrix
+}
+
+// anomalyFeatureVector рассчитывает признаки свечи i
+func anomalyFeatureVector(candles []models.Candle, trueRanges []float64, i int) []float64 {
+	current, prev := candles[i], candles[i-1]
+
+	// Базовый ATR по свечам, предшествующим текущей
+	var baseline float64
+	for j := i - forestLookback; j < i; j++ {
+		baseline += trueRanges[j]
+	}
+	baseline /= forestLookback
+	if baseline == 0 {
+		baseline = 1e-12
+	}
+
+	var recent float64
+	for j := i - 4; j <= i; j++ {
+		recent += trueRanges[j]
+	}
+	recent /= 5
+
+	body := 0.0
+	if current.High > current.Low {
+		body = math.Abs(current.Close-current.Open) / (current.High - current.Low)
+	}
+
+	volume := 0.0
+	if current.Volume > 0 {
+		var avgVolume float64
+		for j := i - forestLookback; j < i; j++ {
+			avgVolume += float64(candles[j].Volume)
+		}
+		avgVolume /= forestLookback
+		volume = math.Log((float64(current.Volume) + 1) / (avgVolume + 1))
+	}
+
+	return []float64{
+		(current.Close - prev.Close) / baseline,
+		(current.High - current.Low) / baseline,
+		body,
+		(current.Open - prev.Close) / baseline,
+		volume,
+		recent / baseline,
+	}
+}
+
+// TrainIsolationForest обучает изолирующий лес на векторах признаков исторических свечей
+func TrainIsolationForest(candles []models.Candle, trees, sampleSize int, seed int64) (*IsolationForest, error) {
+	matrix := AnomalyFeatureMatrix(candles)
+	if len(matrix) < forestMinSamples {
+		return nil, fmt.Errorf("insufficient data for isolation forest: need %d samples, got %d",
+			forestMinSamples, len(matrix))
+	}
+
+	if sampleSize > len(matrix) {
+		sampleSize = len(matrix)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	forest := &IsolationForest{
+		Features:   AnomalyFeatureNames,
+		SampleSize: sampleSize,
+		Trees:      make([]IsolationTree, trees),
+	}
+	if len(candles) > 0 {
+		forest.Symbol = candles[len(candles)-1].Symbol
+		forest.Interval = candles[len(candles)-1].TimeFrame
+	}
+
+	heightLimit := int(math.Ceil(math.Log2(float64(sampleSize))))
+	for t := range forest.Trees {
+		sample := make([][]float64, sampleSize)
+		for i, idx := range rng.Perm(len(matrix))[:sampleSize] {
+			sample[i] = matrix[idx]
+		}
+		tree := &forest.Trees[t]
+		tree.build(sample, 0, heightLimit, rng)
+	}
+
+	// Калибровка: распределение оценок на обучающей выборке
+	forest.Calibration = make([]float64, len(matrix))
+	for i, x := range matrix {
+		forest.Calibration[i] = forest.RawScore(x)
+	}
+	sort.Float64s(forest.Calibration)
+	forest.TrainedAt = time.Now()
+
+	return forest, nil
+}
+
+// build рекурсивно строит дерево и возвращает индекс созданного узла
+func (t *IsolationTree) build(sample [][]float64, depth, heightLimit int, rng *rand.Rand) int {
+	index := len(t.Nodes)
+	t.Nodes = append(t.Nodes, IsolationNode{Feature: -1, Size: len(sample)})
+
+	if depth >= heightLimit || len(sample) <= 1 {
+		return index
+	}
+
+	// Выбираем случайный признак среди тех, что не константны в узле
+	dims := len(sample[0])
+	for _, feature := range rng.Perm(dims) {
+		lo, hi := sample[0][feature], sample[0][feature]
+		for _, x := range sample {
+			lo = math.Min(lo, x[feature])
+			hi = math.Max(hi, x[feature])
+		}
+		if hi <= lo {
+			continue
+		}
+
+		split := lo + rng.Float64()*(hi-lo)
+		var left, right [][]float64
+		for _, x := range sample {
+			if x[feature] < split {
+				left = append(left, x)
+			} else {
+				right = append(right, x)
+			}
+		}
+
+		leftIndex := t.build(left, depth+1, heightLimit, rng)
+		rightIndex := t.build(right, depth+1, heightLimit, rng)
+
+		t.Nodes[index].Feature = feature
+		t.Nodes[index].Split = split
+		t.Nodes[index].Left = leftIndex
+		t.Nodes[index].Right = rightIndex
+		break
+	}
+
+	return index
+}
+
+// pathLength возвращает длину пути до листа и накапливает вклад признаков в изоляцию:
+// каждое разбиение добавляет признаку log2(размер родителя / размер потомка)
+func (t *IsolationTree) pathLength(x []float64, contributions []float64) float64 {
+	node := 0
+	depth := 0.0
+	for t.Nodes[node].Feature >= 0 {
+		n := t.Nodes[node]
+		next := n.Right
+		if x[n.Feature] < n.Split {
+			next = n.Left
+		}
+		if contributions != nil && t.Nodes[next].Size > 0 {
+			contributions[n.Feature] += math.Log2(float64(n.Size) / float64(t.Nodes[next].Size))
+		}
+		node = next
+		depth++
+	}
+	return depth + averagePathLength(t.Nodes[node].Size)
+}
+
+// averagePathLength - средняя длина пути неуспешного поиска в бинарном дереве из n элементов
+func averagePathLength(n int) float64 {
+	if n <= 1 {
+		return 0
+	}
+	if n == 2 {
+		return 1
+	}
+	harmonic := math.Log(float64(n-1)) + 0.5772156649
+	return 2*harmonic - 2*float64(n-1)/float64(n)
+}
+
+// RawScore возвращает стандартную оценку изолирующего леса 2^(-E[h(x)]/c(n))
+func (f *IsolationForest) RawScore(x []float64) float64 {
+	score, _ := f.score(x, false)
+	return score
+}
+
+func (f *IsolationForest) score(x []float64, explain bool) (float64, []float64) {
+	var contributions []float64
+	if explain {
+		contributions = make([]float64, len(x))
+	}
+
+	var total float64
+	for i := range f.Trees {
+		total += f.Trees[i].pathLength(x, contributions)
+	}
+	mean := total / float64(len(f.Trees))
+
+	return math.Pow(2, -mean/averagePathLength(f.SampleSize)), contributions
+}
+
+// Percentile возвращает калиброванную оценку: долю обучающих свечей с меньшей оценкой
+func (f *IsolationForest) Percentile(raw float64) float64 {
+	if len(f.Calibration) == 0 {
+		return raw
+	}
+	position := sort.SearchFloat64s(f.Calibration, raw)
+	return float64(position) / float64(len(f.Calibration))
+}
+
+// Evaluate оценивает последнюю свечу: калиброванную оценку и вклад каждого признака в процентах
+func (f *IsolationForest) Evaluate(candles []models.Candle) (float64, map[string]float64, bool) {
+	matrix := AnomalyFeatureMatrix(candles)
+	if len(matrix) == 0 {
+		return 0, nil, false
+	}
+
+	raw, contributions := f.score(matrix[len(matrix)-1], true)
+
+	var total float64
+	for _, c := range contributions {
+		total += c
+	}
+
+	breakdown := make(map[string]float64, len(f.Features))
+	for i, name := range f.Features {
+		if total > 0 {
+			breakdown[name] = contributions[i] / total * 100
+		}
+	}
+
+	return f.Percentile(raw), breakdown, true
+}
+
+// formatContributions выводит вклад признаков по убыванию
+func formatContributions(breakdown map[string]float64) string {
+	names := make([]string, 0, len(breakdown))
+	for name := range breakdown {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return breakdown[names[i]] > breakdown[names[j]]
+	})
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %.0f%%", name, breakdown[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// IsolationForestFileName возвращает имя файла леса для символа и таймфрейма
+func IsolationForestFileName(symbol, interval string) string {
+	return fmt.Sprintf("iforest_%s_%s.json", strings.ReplaceAll(symbol, "/", ""), interval)
+}
+
+// SaveIsolationForest сохраняет лес в каталог dir
+func SaveIsolationForest(dir string, forest *IsolationForest) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating model directory: %w", err)
+	}
+
+	data, err := json.Marshal(forest)
+	if err != nil {
+		return fmt.Errorf("encoding isolation forest: %w", err)
+	}
+
+	path := filepath.Join(dir, IsolationForestFileName(forest.Symbol, forest.Interval))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing isolation forest: %w", err)
+	}
+
+	return nil
+}
+
+// LoadIsolationForest читает лес из файла
+func LoadIsolationForest(path string) (*IsolationForest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading isolation forest: %w", err)
+	}
+
+	var forest IsolationForest
+	if err := json.Unmarshal(data, &forest); err != nil {
+		return nil, fmt.Errorf("parsing isolation forest %s: %w", path, err)
+	}
+
+	if len(forest.Trees) == 0 || len(forest.Features) != len(AnomalyFeatureNames) {
+		return nil, fmt.Errorf("invalid isolation forest in %s", path)
+	}
+
+	return &forest, nil
+}
+
+// LoadIsolationForestDir загружает все сохраненные леса из каталога
+func LoadIsolationForestDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "iforest_*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing isolation forests: %w", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		forest, err := LoadIsolationForest(file)
+		if err != nil {
+			return loaded, err
+		}
+		RegisterIsolationForest(forest)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// RegisterIsolationForest делает лес доступным для обнаружения аномалий
+func RegisterIsolationForest(forest *IsolationForest) {
+	forestRegistry.mu.Lock()
+	defer forestRegistry.mu.Unlock()
+	forestRegistry.forests[IsolationForestFileName(forest.Symbol, forest.Interval)] = forest
+}
+
+// GetIsolationForest возвращает обученный лес для символа и таймфрейма
+func GetIsolationForest(symbol, interval string) (*IsolationForest, bool) {
+	forestRegistry.mu.RLock()
+	defer forestRegistry.mu.RUnlock()
+	forest, ok := forestRegistry.forests[IsolationForestFileName(symbol, interval)]
+	return forest, ok
+}
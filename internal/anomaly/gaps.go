@@ -0,0 +1,128 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// GapStats - статистика заполнения гэпов выходного дня на длинной истории инструмента
+type GapStats struct {
+	Symbol      string    `json:"symbol"`
+	Interval    string    `json:"interval"`
+	Horizon     int       `json:"horizon"`     // За сколько свечей гэп должен закрыться
+	Occurrences int       `json:"occurrences"` // Гэпов с известным исходом
+	Filled      int       `json:"filled"`
+	Candles     int       `json:"candles"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// FillProbability возвращает долю заполненных гэпов, сглаженную к 50%
+func (s *GapStats) FillProbability() float64 {
+	return (float64(s.Filled) + gapFillPrior*gapFillPriorWeight) / (float64(s.Occurrences) + gapFillPriorWeight)
+}
+
+// gapRegistry хранит статистику гэпов по ключу символ/таймфрейм
+var gapRegistry = struct {
+	mu    sync.RWMutex
+	stats map[string]*GapStats
+}{
+	stats: make(map[string]*GapStats),
+}
+
+// BuildGapStats считает заполнение всех гэпов выходного дня в истории свечей
+func BuildGapStats(candles []models.Candle, symbol, interval string) *GapStats {
+	occurrences, filled := gapFillStatistics(candles, len(candles))
+	return &GapStats{
+		Symbol:      symbol,
+		Interval:    interval,
+		Horizon:     gapFillHorizon,
+		Occurrences: occurrences,
+		Filled:      filled,
+		Candles:     len(candles),
+		GeneratedAt: time.Now(),
+	}
+}
+
+// GapStatsFileName возвращает имя файла статистики гэпов для символа и таймфрейма
+func GapStatsFileName(symbol, interval string) string {
+	return fmt.Sprintf("gaps_%s_%s.json", strings.ReplaceAll(symbol, "/", ""), interval)
+}
+
+// SaveGapStats сохраняет статистику гэпов в каталог dir
+func SaveGapStats(dir string, stats *GapStats) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating model directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding gap stats: %w", err)
+	}
+
+	path := filepath.Join(dir, GapStatsFileName(stats.Symbol, stats.Interval))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing gap stats: %w", err)
+	}
+
+	return nil
+}
+
+// LoadGapStats читает статистику гэпов из файла
+func LoadGapStats(path string) (*GapStats, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading gap stats: %w", err)
+	}
+
+	var stats GapStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("parsing gap stats %s: %w", path, err)
+	}
+	if stats.Occurrences < 0 || stats.Filled < 0 || stats.Filled > stats.Occurrences {
+		return nil, fmt.Errorf("invalid gap stats in %s", path)
+	}
+
+	return &stats, nil
+}
+
+// LoadGapStatsDir загружает всю статистику гэпов из каталога
+func LoadGapStatsDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "gaps_*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing gap stats: %w", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		stats, err := LoadGapStats(file)
+		if err != nil {
+			return loaded, err
+		}
+		RegisterGapStats(stats)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// RegisterGapStats делает статистику доступной для оценки гэпов
+func RegisterGapStats(stats *GapStats) {
+	gapRegistry.mu.Lock()
+	defer gapRegistry.mu.Unlock()
+	gapRegistry.stats[GapStatsFileName(stats.Symbol, stats.Interval)] = stats
+}
+
+// GetGapStats возвращает статистику гэпов для символа и таймфрейма
+func GetGapStats(symbol, interval string) (*GapStats, bool) {
+	gapRegistry.mu.RLock()
+	defer gapRegistry.mu.RUnlock()
+	stats, ok := gapRegistry.stats[GapStatsFileName(symbol, interval)]
+	return stats, ok
+}
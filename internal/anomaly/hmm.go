@@ -0,0 +1,615 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	// hmmFeatureScale переводит доходности и волатильность в базисные пункты,
+	// чтобы дисперсии эмиссий не уходили в машинный ноль
+	hmmFeatureScale = 10000.0
+
+	// hmmTolerance - минимальный прирост логарифма правдоподобия для продолжения обучения
+	hmmTolerance = 1e-4
+
+	// maxHMMStates - наибольшее число состояний, которым можно дать различающиеся метки режимов
+	maxHMMStates = 4
+
+	// hmmDimensions - число признаков наблюдения: доходность и скользящая волатильность
+	hmmDimensions = 2
+)
+
+// GaussianHMM - скрытая марковская модель с диагональными гауссовскими эмиссиями
+// над признаками (доходность, скользящая волатильность)
+type GaussianHMM struct {
+	Symbol        string      `json:"symbol"`
+	Interval      string      `json:"interval"`
+	States        int         `json:"states"`
+	Window        int         `json:"window"` // Окно расчета скользящей волатильности
+	Initial       []float64   `json:"initial"`
+	Transition    [][]float64 `json:"transition"`
+	Means         [][]float64 `json:"means"`
+	Variances     [][]float64 `json:"variances"`
+	Labels        []string    `json:"labels"` // Режим рынка, соответствующий каждому состоянию
+	LogLikelihood float64     `json:"log_likelihood"`
+	Iterations    int         `json:"iterations"`
+	TrainedAt     time.Time   `json:"trained_at"`
+}
+
+// hmmRegistry хранит обученные модели по ключу символ/таймфрейм
+var hmmRegistry = struct {
+	mu     sync.RWMutex
+	models map[string]*GaussianHMM
+}{
+	models: make(map[string]*GaussianHMM),
+}
+
+// HMMFeatures рассчитывает последовательность наблюдений для HMM:
+// логарифмическую доходность и стандартное отклонение доходностей за window свечей
+func HMMFeatures(candles []models.Candle, window int) [][]float64 {
+	if window < 2 || len(candles) <= window {
+		return nil
+	}
+
+	returns := make([]float64, len(candles))
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].Close > 0 && candles[i].Close > 0 {
+			returns[i] = math.Log(candles[i].Close / candles[i-1].Close)
+		}
+	}
+
+	observations := make([][]float64, 0, len(candles)-window)
+	for i := window; i < len(candles); i++ {
+		volatility := calculateReturnsVolatility(returns[i-window+1 : i+1])
+		observations = append(observations, []float64{
+			returns[i] * hmmFeatureScale,
+			volatility * hmmFeatureScale,
+		})
+	}
+
+	return observations
+}
+
+// TrainHMM обучает модель алгоритмом Баума-Велша на исторических свечах
+func TrainHMM(candles []models.Candle, states, window, maxIterations int) (*GaussianHMM, error) {
+	if states < 2 || states > maxHMMStates {
+		return nil, fmt.Errorf("HMM needs 2 to %d states, got %d", maxHMMStates, states)
+	}
+
+	observations := HMMFeatures(candles, window)
+	if len(observations) < states*10 {
+		return nil, fmt.Errorf("insufficient data for HMM training: need %d observations, got %d",
+			states*10, len(observations))
+	}
+
+	model := initHMM(observations, states)
+	model.Window = window
+	if len(candles) > 0 {
+		model.Symbol = candles[len(candles)-1].Symbol
+		model.Interval = candles[len(candles)-1].TimeFrame
+	}
+
+	prevLogLikelihood := math.Inf(-1)
+	for iter := 0; iter < maxIterations; iter++ {
+		logLikelihood := model.baumWelchStep(observations)
+		model.Iterations = iter + 1
+		model.LogLikelihood = logLikelihood
+
+		if logLikelihood-prevLogLikelihood < hmmTolerance {
+			break
+		}
+		prevLogLikelihood = logLikelihood
+	}
+
+	model.assignLabels()
+	model.TrainedAt = time.Now()
+
+	return model, nil
+}
+
+// initHMM инициализирует параметры разбиением наблюдений на квантили по волатильности
+func initHMM(observations [][]float64, states int) *GaussianHMM {
+	dims := len(observations[0])
+
+	sorted := make([][]float64, len(observations))
+	copy(sorted, observations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i][1] < sorted[j][1]
+	})
+
+	model := &GaussianHMM{
+		States:     states,
+		Initial:    make([]float64, states),
+		Transition: make([][]float64, states),
+		Means:      make([][]float64, states),
+		Variances:  make([][]float64, states),
+	}
+
+	floor := varianceFloor(observations)
+	groupSize := len(sorted) / states
+
+	for k := 0; k < states; k++ {
+		model.Initial[k] = 1.0 / float64(states)
+
+		model.Transition[k] = make([]float64, states)
+		for j := 0; j < states; j++ {
+			if j == k {
+				model.Transition[k][j] = 0.9
+			} else {
+				model.Transition[k][j] = 0.1 / float64(states-1)
+			}
+		}
+
+		start := k * groupSize
+		end := start + groupSize
+		if k == states-1 {
+			end = len(sorted)
+		}
+		group := sorted[start:end]
+
+		model.Means[k] = make([]float64, dims)
+		model.Variances[k] = make([]float64, dims)
+		for d := 0; d < dims; d++ {
+			values := make([]float64, len(group))
+			for i, obs := range group {
+				values[i] = obs[d]
+			}
+			mean, std := calculateMeanStd(values)
+			model.Means[k][d] = mean
+			model.Variances[k][d] = math.Max(std*std, floor[d])
+		}
+	}
+
+	return model
+}
+
+// varianceFloor возвращает минимально допустимую дисперсию по каждому признаку
+func varianceFloor(observations [][]float64) []float64 {
+	dims := len(observations[0])
+	floor := make([]float64, dims)
+	for d := 0; d < dims; d++ {
+		values := make([]float64, len(observations))
+		for i, obs := range observations {
+			values[i] = obs[d]
+		}
+		_, std := calculateMeanStd(values)
+		floor[d] = std*std*1e-3 + 1e-8
+	}
+	return floor
+}
+
+// logEmission возвращает логарифм плотности наблюдения в состоянии k
+func (m *GaussianHMM) logEmission(k int, obs []float64) float64 {
+	var logProb float64
+	for d, x := range obs {
+		variance := m.Variances[k][d]
+		diff := x - m.Means[k][d]
+		logProb += -0.5*math.Log(2*math.Pi*variance) - diff*diff/(2*variance)
+	}
+	return logProb
+}
+
+// emissions рассчитывает масштабированные вероятности эмиссий; для каждого шага
+// возвращается сдвиг логарифма, который нужно вернуть в правдоподобие
+func (m *GaussianHMM) emissions(observations [][]float64) ([][]float64, []float64) {
+	probs := make([][]float64, len(observations))
+	shifts := make([]float64, len(observations))
+
+	for t, obs := range observations {
+		logProbs := make([]float64, m.States)
+		maxLog := math.Inf(-1)
+		for k := 0; k < m.States; k++ {
+			logProbs[k] = m.logEmission(k, obs)
+			maxLog = math.Max(maxLog, logProbs[k])
+		}
+
+		probs[t] = make([]float64, m.States)
+		for k := 0; k < m.States; k++ {
+			probs[t][k] = math.Exp(logProbs[k] - maxLog)
+		}
+		shifts[t] = maxLog
+	}
+
+	return probs, shifts
+}
+
+// forward выполняет нормированный прямой проход; возвращает alpha, коэффициенты нормировки и log-правдоподобие
+func (m *GaussianHMM) forward(emission [][]float64, shifts []float64) ([][]float64, []float64, float64) {
+	T := len(emission)
+	alpha := make([][]float64, T)
+	scale := make([]float64, T)
+	var logLikelihood float64
+
+	for t := 0; t < T; t++ {
+		alpha[t] = make([]float64, m.States)
+		for j := 0; j < m.States; j++ {
+			if t == 0 {
+				alpha[t][j] = m.Initial[j] * emission[t][j]
+				continue
+			}
+			var sum float64
+			for i := 0; i < m.States; i++ {
+				sum += alpha[t-1][i] * m.Transition[i][j]
+			}
+			alpha[t][j] = sum * emission[t][j]
+		}
+
+		for j := 0; j < m.States; j++ {
+			scale[t] += alpha[t][j]
+		}
+		if scale[t] == 0 {
+			scale[t] = math.SmallestNonzeroFloat64
+		}
+		for j := 0; j < m.States; j++ {
+			alpha[t][j] /= scale[t]
+		}
+
+		logLikelihood += math.Log(scale[t]) + shifts[t]
+	}
+
+	return alpha, scale, logLikelihood
+}
+
+// backward выполняет нормированный обратный проход с коэффициентами прямого прохода
+func (m *GaussianHMM) backward(emission [][]float64, scale []float64) [][]float64 {
+	T := len(emission)
+	beta := make([][]float64, T)
+	beta[T-1] = make([]float64, m.States)
+	for i := range beta[T-1] {
+		beta[T-1][i] = 1
+	}
+
+	for t := T - 2; t >= 0; t-- {
+		beta[t] = make([]float64, m.States)
+		for i := 0; i < m.States; i++ {
+			var sum float64
+			for j := 0; j < m.States; j++ {
+				sum += m.Transition[i][j] * emission[t+1][j] * beta[t+1][j]
+			}
+			beta[t][i] = sum / scale[t+1]
+		}
+	}
+
+	return beta
+}
+
+// baumWelchStep выполняет одну итерацию EM и возвращает log-правдоподобие до обновления
+func (m *GaussianHMM) baumWelchStep(observations [][]float64) float64 {
+	T := len(observations)
+	dims := len(observations[0])
+
+	emission, shifts := m.emissions(observations)
+	alpha, scale, logLikelihood := m.forward(emission, shifts)
+	beta := m.backward(emission, scale)
+
+	// E-шаг: апостериорные вероятности состояний и переходов
+	gamma := make([][]float64, T)
+	for t := 0; t < T; t++ {
+		gamma[t] = make([]float64, m.States)
+		var norm float64
+		for i := 0; i < m.States; i++ {
+			gamma[t][i] = alpha[t][i] * beta[t][i]
+			norm += gamma[t][i]
+		}
+		if norm > 0 {
+			for i := 0; i < m.States; i++ {
+				gamma[t][i] /= norm
+			}
+		}
+	}
+
+	xiSum := make([][]float64, m.States)
+	for i := range xiSum {
+		xiSum[i] = make([]float64, m.States)
+	}
+	for t := 0; t < T-1; t++ {
+		for i := 0; i < m.States; i++ {
+			for j := 0; j < m.States; j++ {
+				xiSum[i][j] += alpha[t][i] * m.Transition[i][j] * emission[t+1][j] * beta[t+1][j] / scale[t+1]
+			}
+		}
+	}
+
+	// M-шаг: обновляем параметры
+	floor := varianceFloor(observations)
+	for i := 0; i < m.States; i++ {
+		m.Initial[i] = gamma[0][i]
+
+		var gammaSum float64
+		for t := 0; t < T-1; t++ {
+			gammaSum += gamma[t][i]
+		}
+		if gammaSum > 0 {
+			var rowSum float64
+			for j := 0; j < m.States; j++ {
+				m.Transition[i][j] = xiSum[i][j] / gammaSum
+				rowSum += m.Transition[i][j]
+			}
+			for j := 0; j < m.States; j++ {
+				m.Transition[i][j] /= rowSum
+			}
+		}
+
+		var weight float64
+		for t := 0; t < T; t++ {
+			weight += gamma[t][i]
+		}
+		if weight == 0 {
+			continue
+		}
+
+		for d := 0; d < dims; d++ {
+			var mean float64
+			for t := 0; t < T; t++ {
+				mean += gamma[t][i] * observations[t][d]
+			}
+			mean /= weight
+
+			var variance float64
+			for t := 0; t < T; t++ {
+				diff := observations[t][d] - mean
+				variance += gamma[t][i] * diff * diff
+			}
+			variance /= weight
+
+			m.Means[i][d] = mean
+			m.Variances[i][d] = math.Max(variance, floor[d])
+		}
+	}
+
+	return logLikelihood
+}
+
+// Viterbi возвращает наиболее вероятную последовательность скрытых состояний
+func (m *GaussianHMM) Viterbi(observations [][]float64) []int {
+	T := len(observations)
+	if T == 0 {
+		return nil
+	}
+
+	logA := make([][]float64, m.States)
+	for i := range logA {
+		logA[i] = make([]float64, m.States)
+		for j := range logA[i] {
+			logA[i][j] = safeLog(m.Transition[i][j])
+		}
+	}
+
+	delta := make([][]float64, T)
+	psi := make([][]int, T)
+	for t := 0; t < T; t++ {
+		delta[t] = make([]float64, m.States)
+		psi[t] = make([]int, m.States)
+		for j := 0; j < m.States; j++ {
+			emission := m.logEmission(j, observations[t])
+			if t == 0 {
+				delta[t][j] = safeLog(m.Initial[j]) + emission
+				continue
+			}
+			best, bestState := math.Inf(-1), 0
+			for i := 0; i < m.States; i++ {
+				if v := delta[t-1][i] + logA[i][j]; v > best {
+					best, bestState = v, i
+				}
+			}
+			delta[t][j] = best + emission
+			psi[t][j] = bestState
+		}
+	}
+
+	path := make([]int, T)
+	best := math.Inf(-1)
+	for j := 0; j < m.States; j++ {
+		if delta[T-1][j] > best {
+			best, path[T-1] = delta[T-1][j], j
+		}
+	}
+	for t := T - 1; t > 0; t-- {
+		path[t-1] = psi[t][path[t]]
+	}
+
+	return path
+}
+
+// Posterior возвращает распределение вероятностей состояний на последнем наблюдении
+func (m *GaussianHMM) Posterior(observations [][]float64) []float64 {
+	if len(observations) == 0 {
+		return nil
+	}
+	emission, shifts := m.emissions(observations)
+	alpha, _, _ := m.forward(emission, shifts)
+	return alpha[len(alpha)-1]
+}
+
+// assignLabels сопоставляет состояниям режимы рынка по средней волатильности: самое
+// спокойное состояние - RANGING, самое волатильное - VOLATILE. Промежуточные состояния
+// различаются знаком средней доходности: TRENDING_UP и TRENDING_DOWN; при двух
+// промежуточных состояниях нисходящим считается то, у которого доходность ниже
+func (m *GaussianHMM) assignLabels() {
+	order := make([]int, m.States)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return m.Means[order[a]][1] < m.Means[order[b]][1]
+	})
+
+	m.Labels = make([]string, m.States)
+	m.Labels[order[0]] = "RANGING"
+	m.Labels[order[m.States-1]] = "VOLATILE"
+
+	middle := append([]int(nil), order[1:m.States-1]...)
+	sort.Slice(middle, func(a, b int) bool {
+		return m.Means[middle[a]][0] < m.Means[middle[b]][0]
+	})
+	switch len(middle) {
+	case 1:
+		m.Labels[middle[0]] = "TRENDING_UP"
+		if m.Means[middle[0]][0] < 0 {
+			m.Labels[middle[0]] = "TRENDING_DOWN"
+		}
+	case 2:
+		m.Labels[middle[0]] = "TRENDING_DOWN"
+		m.Labels[middle[1]] = "TRENDING_UP"
+	}
+}
+
+// Annotate дополняет режим рынка декодированным состоянием HMM и вероятностями переходов
+func (m *GaussianHMM) Annotate(regime *models.MarketRegime, candles []models.Candle) bool {
+	observations := HMMFeatures(candles, m.Window)
+	if len(observations) == 0 {
+		return false
+	}
+
+	path := m.Viterbi(observations)
+	current := path[len(path)-1]
+	posterior := m.Posterior(observations)
+
+	regime.HMMState = m.Labels[current]
+	regime.StateProbabilities = make(map[string]float64)
+	regime.TransitionProbabilities = make(map[string]float64)
+	for k := 0; k < m.States; k++ {
+		regime.StateProbabilities[m.Labels[k]] += posterior[k]
+		regime.TransitionProbabilities[m.Labels[k]] += m.Transition[current][k]
+	}
+
+	if stay := m.Transition[current][current]; stay < 1 {
+		regime.ExpectedDuration = 1 / (1 - stay)
+	}
+
+	return true
+}
+
+// HMMFileName возвращает имя файла модели для символа и таймфрейма
+func HMMFileName(symbol, interval string) string {
+	return fmt.Sprintf("hmm_%s_%s.json", strings.ReplaceAll(symbol, "/", ""), interval)
+}
+
+// SaveHMM сохраняет параметры модели в каталог dir
+func SaveHMM(dir string, model *GaussianHMM) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating model directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding HMM: %w", err)
+	}
+
+	path := filepath.Join(dir, HMMFileName(model.Symbol, model.Interval))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing HMM: %w", err)
+	}
+
+	return nil
+}
+
+// LoadHMM читает параметры модели из файла
+func LoadHMM(path string) (*GaussianHMM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading HMM: %w", err)
+	}
+
+	var model GaussianHMM
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("parsing HMM %s: %w", path, err)
+	}
+
+	if err := validateHMM(&model); err != nil {
+		return nil, fmt.Errorf("invalid HMM in %s: %w", path, err)
+	}
+
+	return &model, nil
+}
+
+// validateHMM проверяет размеры матриц и векторов модели, окно и дисперсии, чтобы
+// поврежденный файл не приводил к выходу за границы в Viterbi и Annotate
+func validateHMM(model *GaussianHMM) error {
+	n := model.States
+	if n < 2 || n > maxHMMStates {
+		return fmt.Errorf("%d states, need 2 to %d", n, maxHMMStates)
+	}
+	if model.Window < 2 {
+		return fmt.Errorf("window %d, need at least 2", model.Window)
+	}
+	if len(model.Labels) != n || len(model.Initial) != n || len(model.Transition) != n ||
+		len(model.Means) != n || len(model.Variances) != n {
+		return fmt.Errorf("labels, initial, transition, means and variances must have %d entries", n)
+	}
+
+	seen := make(map[string]bool, n)
+	for _, label := range model.Labels {
+		if seen[label] {
+			return fmt.Errorf("duplicate state label %s", label)
+		}
+		seen[label] = true
+	}
+	for i := 0; i < n; i++ {
+		if len(model.Transition[i]) != n {
+			return fmt.Errorf("transition row %d has %d entries, want %d", i, len(model.Transition[i]), n)
+		}
+		if len(model.Means[i]) != hmmDimensions || len(model.Variances[i]) != hmmDimensions {
+			return fmt.Errorf("state %d: means and variances must have %d features", i, hmmDimensions)
+		}
+		for d, variance := range model.Variances[i] {
+			if !(variance > 0) || math.IsInf(variance, 0) {
+				return fmt.Errorf("state %d: variance %d is %g, must be positive", i, d, variance)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadHMMDir загружает все сохраненные модели из каталога
+func LoadHMMDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "hmm_*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing HMM models: %w", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		model, err := LoadHMM(file)
+		if err != nil {
+			return loaded, err
+		}
+		RegisterHMM(model)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// RegisterHMM делает модель доступной для определения режима рынка
+func RegisterHMM(model *GaussianHMM) {
+	hmmRegistry.mu.Lock()
+	defer hmmRegistry.mu.Unlock()
+	hmmRegistry.models[HMMFileName(model.Symbol, model.Interval)] = model
+}
+
+// GetHMM возвращает обученную модель для символа и таймфрейма
+func GetHMM(symbol, interval string) (*GaussianHMM, bool) {
+	hmmRegistry.mu.RLock()
+	defer hmmRegistry.mu.RUnlock()
+	model, ok := hmmRegistry.models[HMMFileName(symbol, interval)]
+	return model, ok
+}
+
+func safeLog(x float64) float64 {
+	if x <= 0 {
+		return math.Inf(-1)
+	}
+	return math.Log(x)
+}
@@ -0,0 +1,256 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Alias1177/Predictor/internal/instrument"
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	// weekendGapMinATR - минимальный размер гэпа выходного дня в долях ATR
+	weekendGapMinATR = 0.5
+
+	// gapLookbackBars - сколько последних свечей проверяется на незакрытый гэп выходного дня
+	gapLookbackBars = 12
+
+	// gapFillHorizon - за сколько свечей гэп должен закрыться, чтобы считаться заполненным
+	gapFillHorizon = 48
+
+	// Сглаживание исторической вероятности заполнения гэпа к 50%
+	gapFillPrior       = 0.5
+	gapFillPriorWeight = 2.0
+
+	// sessionSpikeATR - порог импульса на открытии сессии в долях ATR
+	sessionSpikeATR = 2.0
+
+	// rolloverRangeATR - порог диапазона свечи во время ролловера в долях ATR
+	rolloverRangeATR = 2.0
+)
+
+// tradingSession - открытие торговой сессии в местном времени биржевого центра
+type tradingSession struct {
+	name     string
+	location *time.Location
+	openHour int
+}
+
+var fxSessions = []tradingSession{
+	{name: "SYDNEY", location: instrument.LoadLocation("Australia/Sydney", 10), openHour: 7},
+	{name: "TOKYO", location: instrument.LoadLocation("Asia/Tokyo", 9), openHour: 9},
+	{name: "LONDON", location: instrument.LoadLocation("Europe/London", 0), openHour: 8},
+	{name: "NEW_YORK", location: instrument.NewYorkLocation, openHour: 8},
+}
+
+// sessionAnomaly - результат сессионной проверки
+type sessionAnomaly struct {
+	anomalyType string
+	score       float64
+	details     string
+	flags       []string
+	session     string
+	gap         *models.GapInfo
+	currentBar  bool // Аномалия относится к последней свече
+}
+
+// barDuration оценивает длительность свечи по минимальному интервалу между соседними свечами
+func barDuration(candles []models.Candle) time.Duration {
+	var duration time.Duration
+	for i := len(candles) - 1; i > 0 && i >= len(candles)-20; i-- {
+		diff := candles[i].Timestamp.Sub(candles[i-1].Timestamp)
+		if diff > 0 && (duration == 0 || diff < duration) {
+			duration = diff
+		}
+	}
+	return duration
+}
+
+// isWeekendGap проверяет, что между свечами i-1 и i прошли выходные.
+// Время свечей Twelve Data для FX приходит в UTC
+func isWeekendGap(candles []models.Candle, i int) bool {
+	prev, current := candles[i-1].Timestamp, candles[i].Timestamp
+	if current.Sub(prev) < 24*time.Hour {
+		return false
+	}
+	for t := prev; t.Before(current); t = t.Add(24 * time.Hour) {
+		if t.Weekday() == time.Saturday {
+			return true
+		}
+	}
+	return false
+}
+
+// gapFilled проверяет, вернулась ли цена к закрытию перед гэпом в пределах horizon свечей
+func gapFilled(candles []models.Candle, i, horizon int) (bool, bool) {
+	target := candles[i-1].Close
+	gapUp := candles[i].Open > target
+
+	end := i + horizon
+	complete := end < len(candles)
+	if !complete {
+		end = len(candles) - 1
+	}
+
+	for j := i; j <= end; j++ {
+		if (gapUp && candles[j].Low <= target) || (!gapUp && candles[j].High >= target) {
+			return true, true
+		}
+	}
+	return false, complete
+}
+
+// gapFillStatistics считает гэпы выходного дня до свечи before и сколько из них закрылось
+func gapFillStatistics(candles []models.Candle, before int) (int, int) {
+	occurrences, filled := 0, 0
+	for i := 1; i < before; i++ {
+		if !isWeekendGap(candles, i) || candles[i].Open == candles[i-1].Close {
+			continue
+		}
+		isFilled, complete := gapFilled(candles, i, gapFillHorizon)
+		if !complete && !isFilled {
+			continue // Исход еще неизвестен
+		}
+		occurrences++
+		if isFilled {
+			filled++
+		}
+	}
+	return occurrences, filled
+}
+
+// detectWeekendGap ищет незакрытый гэп выходного дня среди последних свечей
+func detectWeekendGap(candles []models.Candle, atr float64) *sessionAnomaly {
+	last := len(candles) - 1
+	for i := last; i > 0 && i > last-gapLookbackBars; i-- {
+		if !isWeekendGap(candles, i) {
+			continue
+		}
+
+		prevClose := candles[i-1].Close
+		size := candles[i].Open - prevClose
+		sizeATR := math.Abs(size) / atr
+		if sizeATR < weekendGapMinATR {
+			return nil
+		}
+
+		filled, _ := gapFilled(candles, i, last-i)
+		if filled {
+			return nil
+		}
+
+		direction := "UP"
+		if size < 0 {
+			direction = "DOWN"
+		}
+
+		gap := &models.GapInfo{
+			Direction:   direction,
+			Size:        math.Abs(size),
+			SizeATR:     sizeATR,
+			OpenTime:    candles[i].Timestamp,
+			FillTargets: []float64{prevClose + size/2, prevClose},
+		}
+		details := fmt.Sprintf("Weekend gap %s of %.1f ATR, target %.5f", direction, sizeATR, prevClose)
+
+		// Вероятность заполнения берется только из статистики длинной истории (cmd/anomalytrain):
+		// в окне прогноза обычно нет ни одного завершенного гэпа
+		if stats, ok := GetGapStats(candles[i].Symbol, candles[i].TimeFrame); ok && stats.Occurrences > 0 {
+			gap.FillProbability = stats.FillProbability()
+			gap.Occurrences = stats.Occurrences
+			details = fmt.Sprintf("Weekend gap %s of %.1f ATR, fill probability %.0f%% (%d historical gaps), target %.5f",
+				direction, sizeATR, gap.FillProbability*100, gap.Occurrences, prevClose)
+		}
+
+		return &sessionAnomaly{
+			anomalyType: "WEEKEND_GAP",
+			score:       math.Min(sizeATR/2, 1.0),
+			details:     details,
+			flags:       []string{"GAP_FILL_SETUP", "EXPECT_VOLATILE_TRADING"},
+			gap:         gap,
+			currentBar:  i == last,
+		}
+	}
+	return nil
+}
+
+// detectSessionOpenSpike проверяет импульс на первой свече открытия торговой сессии
+func detectSessionOpenSpike(candles []models.Candle, atr float64, duration time.Duration) *sessionAnomaly {
+	current := candles[len(candles)-1]
+	move := math.Max(current.High-current.Low, math.Abs(current.Close-candles[len(candles)-2].Close)) / atr
+	if move < sessionSpikeATR {
+		return nil
+	}
+
+	for _, session := range fxSessions {
+		local := current.Timestamp.In(session.location)
+		if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+			continue
+		}
+		open := time.Date(local.Year(), local.Month(), local.Day(), session.openHour, 0, 0, 0, session.location)
+		if local.Before(open) || !local.Before(open.Add(duration)) {
+			continue
+		}
+
+		return &sessionAnomaly{
+			anomalyType: "SESSION_OPEN_SPIKE",
+			score:       math.Min(move/(sessionSpikeATR*2), 1.0),
+			details:     fmt.Sprintf("%s session open moved %.1f times the normal range", session.name, move),
+			flags:       []string{"WAIT_FOR_CONFIRMATION", "USE_WIDER_STOPS"},
+			session:     session.name,
+			currentBar:  true,
+		}
+	}
+	return nil
+}
+
+// detectRolloverSpread проверяет расширение спреда во время ежедневного ролловера (17:00 по Нью-Йорку):
+// широкий диапазон свечи при малом теле указывает на спредовые тени, а не на движение цены
+func detectRolloverSpread(candles []models.Candle, atr float64) *sessionAnomaly {
+	current := candles[len(candles)-1]
+	local := current.Timestamp.In(instrument.NewYorkLocation)
+	rollover := time.Date(local.Year(), local.Month(), local.Day(), 17, 0, 0, 0, instrument.NewYorkLocation)
+	if local.Before(rollover.Add(-15*time.Minute)) || !local.Before(rollover.Add(30*time.Minute)) {
+		return nil
+	}
+
+	candleRange := current.High - current.Low
+	rangeATR := candleRange / atr
+	if rangeATR < rolloverRangeATR || candleRange == 0 {
+		return nil
+	}
+	if math.Abs(current.Close-current.Open)/candleRange > 0.3 {
+		return nil
+	}
+
+	return &sessionAnomaly{
+		anomalyType: "ROLLOVER_SPREAD",
+		score:       math.Min(rangeATR/(rolloverRangeATR*2), 1.0) * 0.5,
+		details:     fmt.Sprintf("Rollover spread widening: range %.1f ATR with small body", rangeATR),
+		flags:       []string{"AVOID_ENTRY_DURING_ROLLOVER", "USE_WIDER_STOPS"},
+		session:     "ROLLOVER",
+		currentBar:  true,
+	}
+}
+
+// detectSessionAnomaly выполняет сессионные проверки для FX и сырьевых инструментов
+func detectSessionAnomaly(candles []models.Candle, atr float64) *sessionAnomaly {
+	if len(candles) < 2 || atr <= 0 {
+		return nil
+	}
+	if instrument.Class(candles[len(candles)-1].Symbol) == instrument.ClassCrypto {
+		return nil
+	}
+
+	if gap := detectWeekendGap(candles, atr); gap != nil {
+		return gap
+	}
+	if rollover := detectRolloverSpread(candles, atr); rollover != nil {
+		return rollover
+	}
+	if duration := barDuration(candles); duration > 0 && duration < 24*time.Hour {
+		return detectSessionOpenSpike(candles, atr, duration)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package anomaly
+
+import (
+	"github.com/Alias1177/Predictor/internal/utils"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// ClassifyRegimeHistory определяет режим рынка для каждой свечи истории по скользящему окну
+// window свечей, заканчивающемуся этой свечой. Для первых window-1 свечей режим равен nil
+func ClassifyRegimeHistory(candles []models.Candle, window int) []*models.MarketRegime {
+	regimes := make([]*models.MarketRegime, len(candles))
+
+	for i := window - 1; i < len(candles); i++ {
+		regime, err := EnhancedMarketRegimeClassification(candles[i-window+1 : i+1])
+		if err != nil {
+			continue
+		}
+		regimes[i] = regime
+	}
+
+	return regimes
+}
+
+// BuildRegimeTimeline объединяет последовательные свечи с одинаковым типом режима в сегменты
+// и рассчитывает статистику доходности и волатильности по каждому режиму
+func BuildRegimeTimeline(candles []models.Candle, regimes []*models.MarketRegime) *models.RegimeTimeline {
+	timeline := &models.RegimeTimeline{
+		Stats: make(map[string]models.RegimeStats),
+	}
+	if len(candles) > 0 {
+		timeline.Symbol = candles[len(candles)-1].Symbol
+		timeline.Interval = candles[len(candles)-1].TimeFrame
+	}
+
+	var current *regimeSegmentBuilder
+	for i, regime := range regimes {
+		if regime == nil || i >= len(candles) {
+			continue
+		}
+
+		if current == nil || current.regimeType != regime.Type {
+			if current != nil {
+				timeline.Segments = append(timeline.Segments, current.build(candles))
+			}
+			current = &regimeSegmentBuilder{
+				regimeType: regime.Type,
+				start:      i,
+				directions: make(map[string]int),
+			}
+		}
+
+		current.end = i
+		current.strength += regime.Strength
+		current.directions[regime.Direction]++
+	}
+	if current != nil {
+		timeline.Segments = append(timeline.Segments, current.build(candles))
+	}
+
+	timeline.Stats = calculateRegimeStats(candles, timeline.Segments)
+
+	return timeline
+}
+
+// regimeSegmentBuilder накапливает данные сегмента до его завершения
+type regimeSegmentBuilder struct {
+	regimeType string
+	start, end int
+	strength   float64
+	directions map[string]int
+}
+
+func (b *regimeSegmentBuilder) build(candles []models.Candle) models.RegimeSegment {
+	bars := b.end - b.start + 1
+
+	// Преобладающее направление внутри сегмента
+	direction, best := "NEUTRAL", 0
+	for d, count := range b.directions {
+		if count > best || (count == best && d < direction) {
+			direction, best = d, count
+		}
+	}
+
+	segment := models.RegimeSegment{
+		Type:       b.regimeType,
+		Direction:  direction,
+		Strength:   b.strength / float64(bars),
+		StartIndex: b.start,
+		EndIndex:   b.end,
+		Start:      candles[b.start].Timestamp,
+		End:        candles[b.end].Timestamp,
+		Bars:       bars,
+	}
+
+	returns := segmentReturns(candles, b.start, b.end)
+	if len(returns) > 0 {
+		var total float64
+		for _, r := range returns {
+			total += r
+		}
+		segment.Return = total
+		_, std := calculateMeanStd(returns)
+		segment.Volatility = std
+	}
+
+	return segment
+}
+
+// segmentReturns возвращает доходности свечей сегмента в процентах
+func segmentReturns(candles []models.Candle, start, end int) []float64 {
+	var returns []float64
+	for i := start; i <= end; i++ {
+		if i == 0 || candles[i-1].Close == 0 {
+			continue
+		}
+		returns = append(returns, (candles[i].Close-candles[i-1].Close)/candles[i-1].Close*100)
+	}
+	return returns
+}
+
+// calculateRegimeStats агрегирует сегменты по типу режима
+func calculateRegimeStats(candles []models.Candle, segments []models.RegimeSegment) map[string]models.RegimeStats {
+	stats := make(map[string]models.RegimeStats)
+	returnsByType := make(map[string][]float64)
+
+	totalBars := 0
+	for _, segment := range segments {
+		s := stats[segment.Type]
+		s.Type = segment.Type
+		s.Segments++
+		s.TotalBars += segment.Bars
+		s.MaxDuration = utils.MaxInt(s.MaxDuration, segment.Bars)
+		stats[segment.Type] = s
+
+		returnsByType[segment.Type] = append(returnsByType[segment.Type],
+			segmentReturns(candles, segment.StartIndex, segment.EndIndex)...)
+		totalBars += segment.Bars
+	}
+
+	for regimeType, s := range stats {
+		s.AvgDuration = float64(s.TotalBars) / float64(s.Segments)
+		if totalBars > 0 {
+			s.Share = float64(s.TotalBars) / float64(totalBars) * 100
+		}
+		s.AvgReturn, s.Volatility = calculateMeanStd(returnsByType[regimeType])
+		stats[regimeType] = s
+	}
+
+	return stats
+}
+
+// RegimeAt возвращает сегмент временной шкалы, содержащий свечу с индексом index
+func RegimeAt(timeline *models.RegimeTimeline, index int) (models.RegimeSegment, bool) {
+	for _, segment := range timeline.Segments {
+		if index >= segment.StartIndex && index <= segment.EndIndex {
+			return segment, true
+		}
+	}
+	return models.RegimeSegment{}, false
+}
@@ -4,11 +4,12 @@ import (
 	"math/rand"
 	"sort"
 
-	"github.com/Alias1177/Predictor/config"
-	"github.com/Alias1177/Predictor/internal/analyze"
 	"github.com/Alias1177/Predictor/internal/anomaly"
 	"github.com/Alias1177/Predictor/internal/calculate"
-	"github.com/Alias1177/Predictor/internal/utils"
+	"github.com/Alias1177/Predictor/internal/calibration"
+	"github.com/Alias1177/Predictor/internal/horizon"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/internal/strategy"
 
 	"github.com/Alias1177/Predictor/models"
 
@@ -19,34 +20,92 @@ import (
 	"time"
 )
 
-func RunBacktest(ctx context.Context, client *config.Client, config *models.Config) (*models.BacktestResults, error) {
+// Options - параметры бэктеста
+type Options struct {
+	// From и To ограничивают время последней свечи окна, по которому строится прогноз;
+	// нулевое значение снимает границу. Свечи для разогрева окна и проверки прогнозов
+	// загружаются из источника за пределами диапазона
+	From time.Time
+	To   time.Time
+	// Strategy - проверяемая стратегия; nil - выбирается по настройкам, как для живых прогнозов
+	Strategy strategy.Strategy
+	// Simulation - правила исполнения рекомендаций; nil - DefaultSimulation
+	Simulation *Simulation
+}
+
+// checkBars - самый дальний фиксированный горизонт, прогнозы на котором проверяются
+const checkBars = 12
+
+// rangePadding - запас времени на bars свечей с учетом выходных и праздников
+func rangePadding(duration time.Duration, bars int) time.Duration {
+	return duration*time.Duration(2*bars) + 96*time.Hour
+}
+
+// RunBacktest проверяет стратегию из настроек на истории клиента данных за config.BacktestDays дней
+func RunBacktest(ctx context.Context, client models.CandleClient, config *models.Config) (*models.BacktestResults, error) {
+	return Run(ctx, ClientSource{Client: client, Days: config.BacktestDays}, config, Options{})
+}
+
+// Run проверяет стратегию на свечах источника: скользящим окном из config.CandleCount свечей
+// строит прогнозы в диапазоне opts и сравнивает их со следующими свечами. С источником
+// без сети (Candles, FileSource, DirStore) результат воспроизводим
+func Run(ctx context.Context, source CandleSource, config *models.Config, opts Options) (*models.BacktestResults, error) {
+	simulation := DefaultSimulation()
+	if opts.Simulation != nil {
+		simulation = *opts.Simulation
+	}
+	if err := simulation.CheckCurrency(config.Symbol); err != nil {
+		return nil, err
+	}
+
+	duration := models.IntervalDuration(config.Interval)
+	loadFrom, loadTo := opts.From, opts.To
+	if !loadFrom.IsZero() {
+		loadFrom = loadFrom.Add(-rangePadding(duration, config.CandleCount))
+	}
+	if !loadTo.IsZero() {
+		loadTo = loadTo.Add(rangePadding(duration, checkBars))
+	}
+
 	// Загружаем исторические свечи
-	historicalCandles, err := client.GetHistoricalCandles(ctx, config.BacktestDays)
+	historicalCandles, err := source.Candles(ctx, loadFrom, loadTo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch historical data: %w", err)
+		return nil, fmt.Errorf("loading historical candles: %w", err)
 	}
 
 	if len(historicalCandles) < 100 {
 		return nil, fmt.Errorf("insufficient historical data for backtesting, got %d candles", len(historicalCandles))
 	}
 
+	// Без явной стратегии она выбирается так же, как для живых прогнозов
+	selected := opts.Strategy
+	if selected == nil {
+		selected, err = strategy.Select(config, "")
+		if err != nil {
+			return nil, fmt.Errorf("selecting strategy: %w", err)
+		}
+	}
+
 	// Инициализируем результаты
 	results := &models.BacktestResults{
 		MaxConsecutive: struct {
 			Wins  int `json:"wins"`
 			Loses int `json:"loses"`
 		}{},
+		Strategy:                selected.Name(),
+		ParamsVersion:           params.Active().Version,
 		MarketRegimePerformance: make(map[string]float64),
 		TimeframePerformance:    make(map[string]float64),
 		DetailedResults:         []models.PredictionResult{},
 	}
 
-	// Отслеживаем прибыль и убытки
-	var totalProfit, totalLoss float64
-
 	// Устанавливаем размер окна для проверки
 	windowSize := config.CandleCount
 	predictionInterval := 1 // Сколько свечей вперёд проверяем
+	barDuration := horizon.BarDuration(config.Interval, historicalCandles)
+
+	// Проверенные прогнозы по горизонтам для оценки точности и калибровки
+	horizonSamples := make(map[string][]calibration.Sample)
 
 	// Устанавливаем лимит для валидации
 	validationLimit := len(historicalCandles) - predictionInterval
@@ -65,30 +124,52 @@ func RunBacktest(ctx context.Context, client *config.Client, config *models.Conf
 		"UNKNOWN":  {0, 0},
 	}
 
-	// Виртуальный баланс счета для отслеживания эквити
-	accountBalance := 10000.0 // Начальный баланс
-	balanceHistory := []float64{accountBalance}
-	highWaterMark := accountBalance
-	maxDrawdown := 0.0
+	// Торговые рекомендации прошедших фильтры прогнозов для симулятора сделок
+	var signals []Signal
+
+	// Классифицируем режим для каждой свечи истории и строим временную шкалу режимов
+	regimeHistory := anomaly.ClassifyRegimeHistory(historicalCandles, windowSize)
+	timeline := anomaly.BuildRegimeTimeline(historicalCandles, regimeHistory)
+	results.RegimeTimeline = timeline
+	results.RegimeMonthlyPerformance = make(map[string]map[string]float64)
+	regimeMonthlyStats := make(map[string]map[string]struct{ correct, total int })
 
 	// Для каждой позиции в окне
 	for i := windowSize; i < validationLimit; i += predictionInterval {
-		// Извлекаем тестовое окно
+		last := historicalCandles[i-1].Timestamp
+		if !opts.From.IsZero() && last.Before(opts.From) {
+			continue
+		}
+		if !opts.To.IsZero() && last.After(opts.To) {
+			break
+		}
+		if results.From.IsZero() {
+			results.From = last
+		}
+		results.To = last
+
+		// Извлекаем тестовое окно; последняя свеча окна - historicalCandles[i-1]
 		testWindow := historicalCandles[i-windowSize : i]
+		targetCandle := historicalCandles[i-1+predictionInterval]
 
 		// Рассчитываем индикаторы для этого окна
 		indicators := calculate.CalculateAllIndicators(testWindow, config)
 
-		// Получаем рыночный режим и аномалии
-		regime, err := anomaly.EnhancedMarketRegimeClassification(testWindow)
-		if err != nil {
-			log.Printf("Failed to classify market regime: %v", err)
+		// Режим последней свечи окна берем из временной шкалы
+		regime := regimeHistory[i-1]
+		if regime == nil {
 			regime = &models.MarketRegime{
 				Type:      "UNKNOWN",
 				Strength:  0,
 				Direction: "NEUTRAL",
 			}
 		}
+		regimeType := regime.Type
+		if segment, ok := anomaly.RegimeAt(timeline, i-1); ok {
+			regimeType = segment.Type
+		}
+		month := historicalCandles[i-1].Timestamp.Format("2006-01")
+
 		anomaly := anomaly.DetectMarketAnomalies(testWindow)
 
 		// Получаем мультитаймфреймовые данные
@@ -97,8 +178,14 @@ func RunBacktest(ctx context.Context, client *config.Client, config *models.Conf
 		}
 
 		// Генерируем прогноз
-		prediction, err := analyze.EnhancedPrediction(
-			ctx, testWindow, indicators, mtfData, regime, anomaly, config)
+		prediction, err := selected.Predict(ctx, &models.StrategyInput{
+			Candles:    testWindow,
+			Indicators: indicators,
+			MTFData:    mtfData,
+			Regime:     regime,
+			Anomaly:    anomaly,
+			Config:     config,
+		})
 		if err != nil {
 			log.Printf("Prediction failed: %v", err)
 			continue
@@ -114,9 +201,28 @@ func RunBacktest(ctx context.Context, client *config.Client, config *models.Conf
 			Confidence:       confidence,
 			Score:            score,
 			Factors:          factors,
-			Timestamp:        time.Now().Add(-time.Duration(validationLimit-i) * time.Minute * 5),
-			PredictionID:     fmt.Sprintf("BT-%d", i),
-			PredictionTarget: time.Now().Add(-time.Duration(validationLimit-i-predictionInterval) * time.Minute * 5),
+			Timestamp:        historicalCandles[i-1].Timestamp,
+			PredictionID:     fmt.Sprintf("BT-%s-%s-%d", config.Symbol, config.Interval, historicalCandles[i-1].Timestamp.Unix()),
+			PredictionTarget: targetCandle.Timestamp.Add(barDuration),
+			ParamsVersion:    prediction.ParamsVersion,
+			FactorScores:     prediction.FactorScores,
+			Probability:      prediction.Probability,
+		}
+
+		// Прогнозы на горизонтах проверяются до фильтрации сигналов
+		for _, forecast := range prediction.Horizons {
+			target := i - 1 + forecast.Bars
+			if forecast.Direction == "NEUTRAL" || target >= len(historicalCandles) {
+				continue
+			}
+			change := historicalCandles[target].Close - historicalCandles[i-1].Close
+			if change == 0 {
+				continue
+			}
+			horizonSamples[forecast.Horizon] = append(horizonSamples[forecast.Horizon], calibration.Sample{
+				Score:   forecast.Score,
+				Correct: (change > 0) == (forecast.Direction == "BUY"),
+			})
 		}
 
 		// Фильтрация сигналов (только высокая уверенность или сильный сигнал)
@@ -129,9 +235,20 @@ func RunBacktest(ctx context.Context, client *config.Client, config *models.Conf
 			continue // Пропускаем высоко хаотичные рынки
 		}
 
-		// Определяем будущую цену для проверки
+		// Сделка открывается по рекомендации на следующей свече и ведется симулятором
+		if suggestion := prediction.TradingSuggestion; suggestion != nil {
+			signals = append(signals, Signal{
+				Bar:          i - 1,
+				PredictionID: result.PredictionID,
+				Action:       suggestion.Action,
+				StopLoss:     suggestion.StopLoss,
+				TakeProfit:   suggestion.TakeProfit,
+			})
+		}
+
+		// Направление прогноза проверяется по закрытию следующей свечи
 		currentPrice := testWindow[len(testWindow)-1].Close
-		futurePrice := historicalCandles[i+predictionInterval].Close
+		futurePrice := targetCandle.Close
 		priceChange := futurePrice - currentPrice
 
 		// Определяем фактический результат
@@ -152,40 +269,15 @@ func RunBacktest(ctx context.Context, client *config.Client, config *models.Conf
 		results.DetailedResults = append(results.DetailedResults, result)
 		results.TotalTrades++
 
-		// Обновляем счетчики побед/поражений и баланс счета
+		// Обновляем счетчики верных и неверных прогнозов
 		if wasCorrect {
 			results.WinningTrades++
 			consecutiveWins++
 			consecutiveLosses = 0
-
-			// Расчет прибыли
-			profit := math.Abs(priceChange) * 100 // Примерная прибыль в пунктах
-			totalProfit += profit
-
-			// Обновляем баланс счета
-			accountBalance += profit
 		} else {
 			results.LosingTrades++
 			consecutiveLosses++
 			consecutiveWins = 0
-
-			// Расчет убытка
-			loss := math.Abs(priceChange) * 100 // Примерная потеря в пунктах
-			totalLoss += loss
-
-			// Обновляем баланс счета
-			accountBalance -= loss
-		}
-
-		// Отслеживаем баланс и просадку
-		balanceHistory = append(balanceHistory, accountBalance)
-		if accountBalance > highWaterMark {
-			highWaterMark = accountBalance
-		} else {
-			currentDrawdown := (highWaterMark - accountBalance) / highWaterMark
-			if currentDrawdown > maxDrawdown {
-				maxDrawdown = currentDrawdown
-			}
 		}
 
 		// Обновляем максимальные последовательные значения
@@ -197,150 +289,143 @@ func RunBacktest(ctx context.Context, client *config.Client, config *models.Conf
 		}
 
 		// Обновляем статистику по рыночным режимам
-		if stats, exists := regimeStats[regime.Type]; exists {
+		if stats, exists := regimeStats[regimeType]; exists {
 			stats.total++
 			if wasCorrect {
 				stats.correct++
 			}
-			regimeStats[regime.Type] = stats
+			regimeStats[regimeType] = stats
 		}
+
+		if regimeMonthlyStats[month] == nil {
+			regimeMonthlyStats[month] = make(map[string]struct{ correct, total int })
+		}
+		monthStats := regimeMonthlyStats[month][regimeType]
+		monthStats.total++
+		if wasCorrect {
+			monthStats.correct++
+		}
+		regimeMonthlyStats[month][regimeType] = monthStats
 	}
 
-	// Рассчитываем процентные метрики
+	// Точность направления по следующей свече
 	if results.TotalTrades > 0 {
 		results.WinPercentage = float64(results.WinningTrades) / float64(results.TotalTrades) * 100
 	}
 
-	// Средний выигрыш и проигрыш
-	if results.WinningTrades > 0 {
-		results.AverageGain = totalProfit / float64(results.WinningTrades)
-	}
-	if results.LosingTrades > 0 {
-		results.AverageLoss = totalLoss / float64(results.LosingTrades)
-	}
-
 	// Нормализуем статистику рыночных режимов в проценты
 	for regime, stats := range regimeStats {
 		if stats.total > 0 {
 			results.MarketRegimePerformance[regime] = float64(stats.correct) / float64(stats.total) * 100
 		}
 	}
-	if len(balanceHistory) > 0 {
-		initialBalance := balanceHistory[0]
-		finalBalance := balanceHistory[len(balanceHistory)-1]
-		results.EquityGrowthPercent = ((finalBalance - initialBalance) / initialBalance) * 100
-	}
-	monthlyReturns := make(map[string]float64)
-	if len(results.DetailedResults) > 0 {
-		for _, result := range results.DetailedResults {
-			month := result.Timestamp.Format("2006-01")
-			if result.WasCorrect {
-				monthlyReturns[month] += results.AverageGain
-			} else {
-				monthlyReturns[month] -= results.AverageLoss
-			}
-		}
 
-		// Преобразуем абсолютные значения в проценты
-		initialBalance := 10000.0
-		for month, value := range monthlyReturns {
-			monthlyReturns[month] = (value / initialBalance) * 100
+	// Процент верных прогнозов по режимам в разрезе месяцев
+	for month, byRegime := range regimeMonthlyStats {
+		results.RegimeMonthlyPerformance[month] = make(map[string]float64)
+		for regime, stats := range byRegime {
+			results.RegimeMonthlyPerformance[month][regime] = float64(stats.correct) / float64(stats.total) * 100
 		}
-
-		results.MonthlyReturns = monthlyReturns
-	}
-	if results.TotalTrades > 0 {
-		results.WinPercentage = float64(results.WinningTrades) / float64(results.TotalTrades) * 100
 	}
 
-	// Средний выигрыш и проигрыш
-	if results.WinningTrades > 0 {
-		results.AverageGain = totalProfit / float64(results.WinningTrades)
-	}
-	if results.LosingTrades > 0 {
-		results.AverageLoss = totalLoss / float64(results.LosingTrades)
-	}
+	// Денежные метрики считаются по сделкам симулятора
+	results.Trades, results.TradeStats = Simulate(historicalCandles, signals, config.Symbol, simulation)
+	applyTradeMetrics(results)
 
-	// Расчет коэффициента прибыли
-	if totalLoss > 0 {
-		results.ProfitFactor = totalProfit / totalLoss
+	// Калибровка вероятностей: обучение на ранних прогнозах, диаграмма надежности на поздних
+	if report, err := calibration.Fit(calibration.SamplesFromResults(results.DetailedResults)); err != nil {
+		log.Printf("Calibration skipped: %v", err)
 	} else {
-		results.ProfitFactor = totalProfit // Если нет убытков
+		report.Calibrator.Symbol = config.Symbol
+		report.Calibrator.Interval = config.Interval
+		report.Calibrator.Strategy = results.Strategy
+		results.Calibration = report
 	}
 
-	// Сохраняем максимальную просадку
-	results.MaxDrawdown = maxDrawdown * 100 // В процентах
+	// Точность и калибровка по горизонтам; следующая свеча калибруется выше
+	results.HorizonPerformance = make(map[string]float64)
+	results.HorizonCalibration = make(map[string]*models.CalibrationReport)
+	for name, samples := range horizonSamples {
+		correct := 0
+		for _, sample := range samples {
+			if sample.Correct {
+				correct++
+			}
+		}
+		results.HorizonPerformance[name] = float64(correct) / float64(len(samples)) * 100
 
-	// Нормализуем статистику рыночных режимов в проценты
-	for regime, stats := range regimeStats {
-		if stats.total > 0 {
-			results.MarketRegimePerformance[regime] = float64(stats.correct) / float64(stats.total) * 100
+		if name == horizon.NextBar {
+			continue
 		}
+		report, err := calibration.Fit(samples)
+		if err != nil {
+			log.Printf("Calibration for horizon %s skipped: %v", name, err)
+			continue
+		}
+		report.Calibrator.Symbol = config.Symbol
+		report.Calibrator.Interval = config.Interval
+		report.Calibrator.Strategy = results.Strategy
+		report.Calibrator.Horizon = name
+		results.HorizonCalibration[name] = report
 	}
 
-	// Рост капитала в процентах
-	if len(balanceHistory) > 0 {
-		initialBalance := balanceHistory[0]
-		finalBalance := balanceHistory[len(balanceHistory)-1]
-		results.EquityGrowthPercent = ((finalBalance - initialBalance) / initialBalance) * 100
-	}
+	return results, nil
+}
 
-	// Расчет месячной доходности
+// applyTradeMetrics переносит итоги симуляции в денежные метрики результатов: средний
+// выигрыш и проигрыш в пунктах и в процентах от цены входа, фактор прибыли, просадку,
+// кривую капитала и доходность по месяцам закрытия сделок
+func applyTradeMetrics(results *models.BacktestResults) {
+	stats := results.TradeStats
+	results.AverageGain = stats.AverageWinPips
+	results.AverageLoss = stats.AverageLossPips
+	results.ProfitFactor = stats.ProfitFactor
+	results.MaxDrawdown = stats.MaxDrawdown
+	results.EquityGrowthPercent = stats.NetPnL / stats.InitialBalance * 100
+	results.TotalReturnPercent = results.EquityGrowthPercent
+
+	results.EquityCurve = []float64{stats.InitialBalance}
 	results.MonthlyReturns = make(map[string]float64)
-	if len(results.DetailedResults) > 0 {
-		for _, result := range results.DetailedResults {
-			month := result.Timestamp.Format("2006-01")
-			if result.WasCorrect {
-				results.MonthlyReturns[month] += results.AverageGain
-			} else {
-				results.MonthlyReturns[month] -= results.AverageLoss
-			}
-		}
-
-		// Преобразуем абсолютные значения в проценты
-		initialBalance := 10000.0
-		for month, value := range results.MonthlyReturns {
-			results.MonthlyReturns[month] = (value / initialBalance) * 100
+	var gainPercent, lossPercent float64
+	for _, trade := range results.Trades {
+		results.EquityCurve = append(results.EquityCurve, trade.Balance)
+		results.MonthlyReturns[trade.ExitTime.Format("2006-01")] += trade.PnL / stats.InitialBalance * 100
+
+		move := math.Abs(trade.ExitPrice-trade.EntryPrice) / trade.EntryPrice * 100
+		if trade.PnL > 0 {
+			gainPercent += move
+		} else {
+			lossPercent += move
 		}
 	}
-
-	// Расчет процентных показателей относительно цены
-	basePrice := 0.0
-	if len(historicalCandles) > 0 {
-		for i := len(historicalCandles) - utils.MinInt(20, len(historicalCandles)); i < len(historicalCandles); i++ {
-			basePrice += historicalCandles[i].Close
-		}
-		basePrice /= float64(utils.MinInt(20, len(historicalCandles)))
+	if stats.Wins > 0 {
+		results.AverageGainPercent = gainPercent / float64(stats.Wins)
 	}
-
-	if basePrice > 0 {
-		// Преобразуем абсолютные значения пунктов в проценты
-		results.AverageGainPercent = (results.AverageGain / basePrice) * 100
-		results.AverageLossPercent = (results.AverageLoss / basePrice) * 100
+	if stats.Losses > 0 {
+		results.AverageLossPercent = lossPercent / float64(stats.Losses)
 	}
-
-	// Рассчитываем общий процент прибыли/убытка
-	initialBalance := 10000.0
-	finalBalance := initialBalance + (float64(results.WinningTrades) * results.AverageGain) -
-		(float64(results.LosingTrades) * results.AverageLoss)
-	results.TotalReturnPercent = ((finalBalance - initialBalance) / initialBalance) * 100
-
-	return results, nil
 }
 
 func MonteCarloSimulation(results *models.BacktestResults, simulations int) *models.MonteCarloResults {
-	if len(results.DetailedResults) < 10 {
-		return nil
-	}
-
-	// Извлекаем результаты сделок для симуляции
+	// Перемешиваются результаты сделок симулятора; без них - средние выигрыш и проигрыш прогнозов
 	var trades []float64
-	for _, result := range results.DetailedResults {
-		if result.WasCorrect {
-			trades = append(trades, results.AverageGain)
-		} else {
-			trades = append(trades, -results.AverageLoss)
+	initialBalance := 10000.0
+	if len(results.Trades) > 0 {
+		for _, trade := range results.Trades {
+			trades = append(trades, trade.PnL)
 		}
+		initialBalance = results.TradeStats.InitialBalance
+	} else {
+		for _, result := range results.DetailedResults {
+			if result.WasCorrect {
+				trades = append(trades, results.AverageGain)
+			} else {
+				trades = append(trades, -results.AverageLoss)
+			}
+		}
+	}
+	if len(trades) < 10 {
+		return nil
 	}
 
 	// Инициализируем генератор случайных чисел
@@ -361,7 +446,6 @@ func MonteCarloSimulation(results *models.BacktestResults, simulations int) *mod
 		})
 
 		// Отслеживаем кривую капитала
-		initialBalance := 10000.0
 		balance := initialBalance
 		equity := []float64{initialBalance}
 		maxBalance := initialBalance
@@ -0,0 +1,195 @@
+package baktest
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// momentum - детерминированная стратегия для тестов: продолжает изменение последней свечи
+type momentum struct{}
+
+func (momentum) Name() string        { return "test_momentum" }
+func (momentum) Description() string { return "Continues the last candle move" }
+
+func (momentum) Predict(_ context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	candles := input.Candles
+	last := candles[len(candles)-1]
+	change := last.Close - candles[len(candles)-2].Close
+
+	prediction := &models.Prediction{Direction: "BUY", Confidence: "HIGH", Score: 1}
+	stop, target := last.Close-0.0020, last.Close+0.0030
+	if change < 0 {
+		prediction.Direction, prediction.Score = "SELL", -1
+		stop, target = last.Close+0.0020, last.Close-0.0030
+	}
+	prediction.TradingSuggestion = &models.TradingSuggestion{
+		Action:     prediction.Direction,
+		StopLoss:   stop,
+		TakeProfit: target,
+	}
+	return prediction, nil
+}
+
+// syntheticCandles строит часовые свечи с волной и трендом без случайности
+func syntheticCandles(n int) Candles {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	candles := make(Candles, n)
+	price := 1.1000
+	for i := range candles {
+		open := price
+		price = 1.1000 + 0.0004*float64(i)/24 + 0.0030*math.Sin(float64(i)/5) + 0.0008*math.Sin(float64(i)*1.7)
+		candles[i] = models.Candle{
+			Symbol:    "EUR/USD",
+			TimeFrame: "1h",
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Open:      open,
+			High:      math.Max(open, price) + 0.0003,
+			Low:       math.Min(open, price) - 0.0003,
+			Close:     price,
+			Volume:    int64(1000 + i%7*100),
+		}
+	}
+	return candles
+}
+
+func testConfig() *models.Config {
+	return &models.Config{
+		Symbol:           "EUR/USD",
+		Interval:         "1h",
+		CandleCount:      30,
+		RSIPeriod:        14,
+		MACDFastPeriod:   12,
+		MACDSlowPeriod:   26,
+		MACDSignalPeriod: 9,
+		BBPeriod:         20,
+		BBStdDev:         2,
+		EMAPeriod:        20,
+		ADXPeriod:        14,
+		ATRPeriod:        14,
+	}
+}
+
+func TestRunRangeAndStability(t *testing.T) {
+	candles := syntheticCandles(400)
+	cfg := testConfig()
+	opts := Options{
+		From:     candles[100].Timestamp,
+		To:       candles[299].Timestamp,
+		Strategy: momentum{},
+	}
+
+	results, err := Run(context.Background(), candles, cfg, opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if !results.From.Equal(opts.From) || !results.To.Equal(opts.To) {
+		t.Errorf("range = %s..%s, want %s..%s", results.From, results.To, opts.From, opts.To)
+	}
+	if results.Strategy != "test_momentum" {
+		t.Errorf("Strategy = %q, want test_momentum", results.Strategy)
+	}
+
+	// Прогнозы строятся по последней свече окна в диапазоне и проверяются следующей свечой
+	wantTotal, wantWins := 0, 0
+	for i := 100; i <= 299; i++ {
+		predicted := candles[i].Close-candles[i-1].Close >= 0
+		change := candles[i+1].Close - candles[i].Close
+		wantTotal++
+		if change != 0 && predicted == (change > 0) {
+			wantWins++
+		}
+	}
+	if results.TotalTrades != wantTotal || results.WinningTrades != wantWins {
+		t.Errorf("trades = %d (%d wins), want %d (%d wins)", results.TotalTrades, results.WinningTrades, wantTotal, wantWins)
+	}
+	if results.WinningTrades+results.LosingTrades != results.TotalTrades {
+		t.Errorf("wins %d + losses %d != total %d", results.WinningTrades, results.LosingTrades, results.TotalTrades)
+	}
+	for _, result := range results.DetailedResults {
+		if result.Timestamp.Before(opts.From) || result.Timestamp.After(opts.To) {
+			t.Fatalf("prediction at %s outside %s..%s", result.Timestamp, opts.From, opts.To)
+		}
+	}
+	if len(results.Trades) == 0 || results.TradeStats.Trades != len(results.Trades) {
+		t.Errorf("simulated %d trades, stats report %d", len(results.Trades), results.TradeStats.Trades)
+	}
+
+	// Повторный прогон на тех же свечах дает те же результаты
+	again, err := Run(context.Background(), candles, cfg, opts)
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if !reflect.DeepEqual(results.DetailedResults, again.DetailedResults) {
+		t.Error("detailed results differ between identical runs")
+	}
+	if !reflect.DeepEqual(results.Trades, again.Trades) || !reflect.DeepEqual(results.TradeStats, again.TradeStats) {
+		t.Error("simulated trades differ between identical runs")
+	}
+	if results.WinPercentage != again.WinPercentage || !reflect.DeepEqual(results.EquityCurve, again.EquityCurve) {
+		t.Error("metrics differ between identical runs")
+	}
+}
+
+func TestRunInsufficientData(t *testing.T) {
+	if _, err := Run(context.Background(), syntheticCandles(50), testConfig(), Options{Strategy: momentum{}}); err == nil {
+		t.Fatal("Run with 50 candles returned no error")
+	}
+}
+
+func TestDirStoreSaveMergesAndDedupes(t *testing.T) {
+	ctx := context.Background()
+	store := DirStore{Dir: t.TempDir()}
+	candles := syntheticCandles(10)
+
+	if err := store.Save(ctx, "EUR/USD", "1h", candles[:6]); err != nil {
+		t.Fatalf("first Save: %v", err)
+	}
+
+	// Вторая выгрузка пересекается с первой и заменяет свечу с тем же временем
+	updated := append(Candles(nil), candles[4:]...)
+	updated[1].Close = 1.2345
+	if err := store.Save(ctx, "EUR/USD", "1h", updated); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+
+	file, err := os.Open(filepath.Join(store.Dir, "EUR-USD_1h.csv"))
+	if err != nil {
+		t.Fatalf("opening stored file: %v", err)
+	}
+	defer file.Close()
+	stored, err := ParseCandlesCSV(file)
+	if err != nil {
+		t.Fatalf("ParseCandlesCSV: %v", err)
+	}
+
+	if len(stored) != len(candles) {
+		t.Fatalf("stored %d candles, want %d without duplicates", len(stored), len(candles))
+	}
+	for i, candle := range stored {
+		want := candles[i]
+		if i == 5 {
+			want.Close = 1.2345
+		}
+		if !candle.Timestamp.Equal(want.Timestamp) || candle.Open != want.Open || candle.High != want.High ||
+			candle.Low != want.Low || candle.Close != want.Close || candle.Volume != want.Volume {
+			t.Errorf("candle %d = %+v, want %+v", i, candle, want)
+		}
+	}
+
+	// Load подставляет символ и таймфрейм и ограничивает диапазон
+	loaded, err := store.Load(ctx, "EUR/USD", "1h", candles[2].Timestamp, candles[4].Timestamp)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != 3 || loaded[0].Symbol != "EUR/USD" || loaded[0].TimeFrame != "1h" {
+		t.Errorf("Load returned %+v", loaded)
+	}
+}
@@ -0,0 +1,417 @@
+package baktest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Alias1177/Predictor/internal/costs"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// Порядок срабатывания уровней, когда свеча задевает и стоп-лосс, и тейк-профит:
+// по high/low нельзя узнать, что было раньше
+const (
+	AmbiguityStopFirst   = "stop_first"   // Сначала стоп: консервативная оценка
+	AmbiguityTargetFirst = "target_first" // Сначала тейк: оптимистичная оценка
+	AmbiguityNearest     = "nearest"      // Первым срабатывает уровень, ближайший к открытию свечи
+)
+
+// Причины закрытия сделки
+const (
+	ExitStopLoss       = "STOP_LOSS"
+	ExitTakeProfit     = "TAKE_PROFIT"
+	ExitTimeStop       = "TIME_STOP"
+	ExitOppositeSignal = "OPPOSITE_SIGNAL"
+	ExitEndOfData      = "END_OF_DATA"
+)
+
+// Simulation - правила симуляции сделок
+type Simulation struct {
+	Ambiguity      string // AmbiguityStopFirst, AmbiguityTargetFirst или AmbiguityNearest
+	MaxBars        int    // Тайм-стоп: закрытие по цене закрытия через MaxBars свечей; 0 - без ограничения
+	ExitOnOpposite bool   // Противоположный сигнал закрывает позицию и открывает обратную
+	// InitialBalance и RiskPerTrade (доля баланса, теряемая на стоп-лоссе); 0 - из параметров риска
+	InitialBalance float64
+	RiskPerTrade   float64
+	// AccountCurrency - валюта счета, по умолчанию USD. ConversionRates - стоимость единицы
+	// котируемой валюты в валюте счета для кроссов, в которых она не участвует; без курса
+	// PnL остается в котируемой валюте, поэтому Run такие прогоны отклоняет (CheckCurrency)
+	AccountCurrency string
+	ConversionRates map[string]float64
+	// Costs - издержки инструмента; nil - профиль costs.For, пустой профиль - без издержек
+	Costs *models.CostProfile
+}
+
+// DefaultSimulation - консервативный порядок уровней, тайм-стоп на дальнем горизонте
+// прогноза и разворот по противоположному сигналу
+func DefaultSimulation() Simulation {
+	return Simulation{
+		Ambiguity:      AmbiguityStopFirst,
+		MaxBars:        checkBars,
+		ExitOnOpposite: true,
+	}
+}
+
+// Signal - торговая рекомендация, выданная на закрытии свечи Bar
+type Signal struct {
+	Bar          int
+	PredictionID string
+	Action       string // BUY или SELL; остальные значения сделку не открывают
+	StopLoss     float64
+	TakeProfit   float64
+}
+
+// pipSizes - размер пункта для инструментов, котируемых не как валютные пары
+var pipSizes = map[string]float64{
+	"XAU": 0.1, "XAG": 0.01, "XBR": 0.01,
+	"BTC": 1, "ETH": 0.1, "BNB": 0.1, "AAVE": 0.1, "SOL": 0.01,
+	"DOT": 0.001, "XRP": 0.0001, "ADA": 0.0001,
+}
+
+// PipSize возвращает размер пункта инструмента: 0.01 для пар к иене, 0.0001 для остальных
+// валютных пар и условный пункт для металлов, нефти и криптовалют
+func PipSize(symbol string) float64 {
+	base, quote, _ := strings.Cut(strings.ToUpper(symbol), "/")
+	if size, ok := pipSizes[base]; ok {
+		return size
+	}
+	if quote == "JPY" {
+		return 0.01
+	}
+	return 0.0001
+}
+
+// conversion возвращает множитель перевода суммы в котируемой валюте в валюту результата
+// при цене инструмента price и саму валюту результата
+func (s Simulation) conversion(symbol string, price float64) (float64, string) {
+	account := s.accountCurrency()
+	base, quote, _ := strings.Cut(strings.ToUpper(symbol), "/")
+	switch {
+	case quote == account:
+		return 1, account
+	case base == account && price > 0:
+		return 1 / price, account
+	}
+	if rate, ok := s.ConversionRates[quote]; ok && rate > 0 {
+		return rate, account
+	}
+	return 1, quote
+}
+
+// accountCurrency возвращает валюту счета, по умолчанию USD
+func (s Simulation) accountCurrency() string {
+	if s.AccountCurrency == "" {
+		return "USD"
+	}
+	return strings.ToUpper(s.AccountCurrency)
+}
+
+// CheckCurrency проверяет, что результат сделок по symbol пересчитывается в валюту счета.
+// Без курса PnL кросса оставался бы в котируемой валюте и смешивался с балансом счета
+func (s Simulation) CheckCurrency(symbol string) error {
+	account := s.accountCurrency()
+	if _, currency := s.conversion(symbol, 1); currency != account {
+		return fmt.Errorf("no conversion rate from %s to account currency %s for %s", currency, account, symbol)
+	}
+	return nil
+}
+
+// position - открытая позиция симулятора
+type position struct {
+	trade     models.SimulatedTrade
+	sign      float64 // +1 для покупки, -1 для продажи
+	entryBar  int
+	closeNext bool    // Закрыть на открытии следующей свечи по противоположному сигналу
+	spread    float64 // Половина спреда при входе, в пунктах
+	slippage  float64 // Проскальзывание входа, в пунктах
+}
+
+// Simulate проходит свечи по одной и исполняет сигналы: вход на открытии следующей свечи,
+// выход по стоп-лоссу или тейк-профиту внутри свечи (гэп за уровень исполняется по цене
+// открытия), по тайм-стопу, противоположному сигналу или в конце данных. Одновременно
+// открыта не больше одной позиции; размер позиции рассчитывается от текущего баланса так,
+// чтобы стоп-лосс стоил RiskPerTrade баланса. Когда баланс после закрытия сделки не
+// положителен, симуляция останавливается с TradeStats.Ruined.
+//
+// Уровни проверяются по средним ценам свечей, а издержки списываются при исполнении:
+// половина спреда на входе и на выходе, проскальзывание на рыночных исполнениях (все,
+// кроме тейк-профита), комиссия за обе стороны и свопы за ролловеры. Итоги того же прогона
+// без издержек, с объемами от валового баланса, возвращаются в TradeStats.Gross
+func Simulate(candles []models.Candle, signals []Signal, symbol string, sim Simulation) ([]models.SimulatedTrade, *models.TradeStats) {
+	risk := params.Active().Risk
+	if sim.InitialBalance <= 0 {
+		sim.InitialBalance = risk.AccountSize
+	}
+	if sim.InitialBalance <= 0 {
+		sim.InitialBalance = 10000
+	}
+	if sim.RiskPerTrade <= 0 {
+		sim.RiskPerTrade = risk.RiskPerTrade
+	}
+	if sim.RiskPerTrade <= 0 {
+		sim.RiskPerTrade = 0.01
+	}
+
+	profile := costs.For(symbol)
+	if sim.Costs != nil {
+		profile = *sim.Costs
+	}
+
+	pip := PipSize(symbol)
+	_, currency := sim.conversion(symbol, 1)
+	stats := &models.TradeStats{
+		Currency:       currency,
+		InitialBalance: sim.InitialBalance,
+		ExitReasons:    make(map[string]int),
+	}
+
+	bySignalBar := make(map[int]Signal, len(signals))
+	for _, signal := range signals {
+		if signal.Action == "BUY" || signal.Action == "SELL" {
+			bySignalBar[signal.Bar] = signal
+		}
+	}
+
+	balance := sim.InitialBalance
+	var trades []models.SimulatedTrade
+	var open *position
+	var pending *Signal
+
+	closePosition := func(bar int, price float64, reason string) {
+		candle := candles[bar]
+		trade := open.trade
+		trade.ExitTime = candle.Timestamp
+		trade.ExitPrice = price
+		trade.ExitReason = reason
+		trade.Bars = bar - open.entryBar + 1
+		move := (price - trade.EntryPrice) * open.sign
+		rate, _ := sim.conversion(symbol, price)
+		trade.GrossPnLPips = move / pip
+		trade.GrossPnL = move * trade.Units * rate
+
+		// Выход по закрытию свечи происходит в конце ее интервала
+		exitAt := candle.Timestamp
+		if reason == ExitTimeStop || reason == ExitEndOfData {
+			exitAt = exitAt.Add(models.IntervalDuration(candle.TimeFrame))
+			if bar+1 < len(candles) {
+				exitAt = candles[bar+1].Timestamp
+			}
+		}
+		spreadPips := open.spread + costs.Spread(profile, exitAt)/2
+		slippagePips := open.slippage
+		if reason != ExitTakeProfit {
+			slippagePips += costs.Slippage(profile, candle, pip)
+		}
+		swapPips := costs.Swap(profile, open.sign > 0, trade.EntryPrice, pip, trade.EntryTime, exitAt)
+
+		pipValue := pip * trade.Units * rate
+		trade.Costs = models.TradeCosts{
+			Spread:     spreadPips * pipValue,
+			Slippage:   slippagePips * pipValue,
+			Commission: 2 * costs.Commission(profile, trade.Units),
+			Swap:       -swapPips * pipValue,
+		}
+		trade.Costs.Total = trade.Costs.Spread + trade.Costs.Slippage + trade.Costs.Commission + trade.Costs.Swap
+		trade.PnLPips = trade.GrossPnLPips - spreadPips - slippagePips + swapPips
+		trade.PnL = trade.GrossPnL - trade.Costs.Total
+		balance += trade.PnL
+		trade.Balance = balance
+		trades = append(trades, trade)
+		open = nil
+	}
+
+	for bar, candle := range candles {
+		// Разворот по противоположному сигналу исполняется на открытии свечи
+		if open != nil && open.closeNext {
+			closePosition(bar, candle.Open, ExitOppositeSignal)
+		}
+
+		// Без положительного баланса объем позиции не определен: счет разорен
+		if open == nil && balance <= 0 {
+			stats.Ruined = true
+			break
+		}
+
+		if pending != nil {
+			open = openPosition(*pending, candle, bar, balance, sim, symbol)
+			if open == nil {
+				stats.SkippedSignals++
+			} else {
+				open.spread = costs.Spread(profile, candle.Timestamp) / 2
+				open.slippage = costs.Slippage(profile, candle, pip)
+			}
+			pending = nil
+		}
+
+		if open != nil {
+			if price, reason, ok := exitLevel(open, candle, bar, sim.Ambiguity); ok {
+				closePosition(bar, price, reason)
+			} else if sim.MaxBars > 0 && bar-open.entryBar+1 >= sim.MaxBars {
+				closePosition(bar, candle.Close, ExitTimeStop)
+			}
+		}
+
+		signal, ok := bySignalBar[bar]
+		if !ok || bar+1 >= len(candles) {
+			continue
+		}
+		switch {
+		case open == nil:
+			pending = &signal
+		case sim.ExitOnOpposite && signal.Action != open.trade.Direction:
+			open.closeNext = true
+			pending = &signal
+		}
+	}
+	if open != nil {
+		last := len(candles) - 1
+		closePosition(last, candles[last].Close, ExitEndOfData)
+	}
+
+	summarize(stats, trades, balance)
+
+	// Прогон без издержек: те же входы и выходы, но объем считается от валового баланса.
+	// Объем пропорционален балансу на входе, поэтому достаточно масштабировать каждую сделку
+	stats.Gross = &models.TradeStats{
+		Currency:       currency,
+		InitialBalance: sim.InitialBalance,
+		SkippedSignals: stats.SkippedSignals,
+		ExitReasons:    make(map[string]int),
+	}
+	grossTrades := make([]models.SimulatedTrade, len(trades))
+	grossBalance, netBalance := sim.InitialBalance, sim.InitialBalance
+	for i, trade := range trades {
+		if netBalance > 0 {
+			scale := grossBalance / netBalance
+			trade.Units *= scale
+			trade.GrossPnL *= scale
+		}
+		netBalance = trade.Balance
+		grossBalance += trade.GrossPnL
+		trade.PnLPips, trade.PnL, trade.Balance = trade.GrossPnLPips, trade.GrossPnL, grossBalance
+		trade.Costs = models.TradeCosts{}
+		grossTrades[i] = trade
+	}
+	summarize(stats.Gross, grossTrades, grossBalance)
+	return trades, stats
+}
+
+// openPosition открывает позицию по сигналу на открытии свечи. Возвращает nil, если
+// уровни сигнала не окружают цену входа (в том числе после гэпа за уровень)
+func openPosition(signal Signal, candle models.Candle, bar int, balance float64, sim Simulation, symbol string) *position {
+	entry := candle.Open
+	sign := 1.0
+	if signal.Action == "SELL" {
+		sign = -1
+	}
+	if (signal.StopLoss-entry)*sign >= 0 || (signal.TakeProfit-entry)*sign <= 0 {
+		return nil
+	}
+
+	rate, _ := sim.conversion(symbol, entry)
+	units := balance * sim.RiskPerTrade / (math.Abs(entry-signal.StopLoss) * rate)
+	return &position{
+		trade: models.SimulatedTrade{
+			PredictionID: signal.PredictionID,
+			Direction:    signal.Action,
+			EntryTime:    candle.Timestamp,
+			EntryPrice:   entry,
+			StopLoss:     signal.StopLoss,
+			TakeProfit:   signal.TakeProfit,
+			Units:        units,
+		},
+		sign:     sign,
+		entryBar: bar,
+	}
+}
+
+// exitLevel проверяет, задела ли свеча стоп-лосс или тейк-профит позиции. Гэп за уровень
+// на открытии исполняется по цене открытия; если внутри свечи задеты оба уровня,
+// порядок определяет ambiguity
+func exitLevel(open *position, candle models.Candle, bar int, ambiguity string) (float64, string, bool) {
+	trade := open.trade
+	if bar > open.entryBar {
+		if (trade.StopLoss-candle.Open)*open.sign >= 0 {
+			return candle.Open, ExitStopLoss, true
+		}
+		if (trade.TakeProfit-candle.Open)*open.sign <= 0 {
+			return candle.Open, ExitTakeProfit, true
+		}
+	}
+
+	hitStop, hitTarget := candle.Low <= trade.StopLoss, candle.High >= trade.TakeProfit
+	if open.sign < 0 {
+		hitStop, hitTarget = candle.High >= trade.StopLoss, candle.Low <= trade.TakeProfit
+	}
+
+	switch {
+	case hitStop && hitTarget:
+		stopFirst := ambiguity != AmbiguityTargetFirst
+		if ambiguity == AmbiguityNearest {
+			stopFirst = math.Abs(candle.Open-trade.StopLoss) <= math.Abs(trade.TakeProfit-candle.Open)
+		}
+		if stopFirst {
+			return trade.StopLoss, ExitStopLoss, true
+		}
+		return trade.TakeProfit, ExitTakeProfit, true
+	case hitStop:
+		return trade.StopLoss, ExitStopLoss, true
+	case hitTarget:
+		return trade.TakeProfit, ExitTakeProfit, true
+	}
+	return 0, "", false
+}
+
+// summarize заполняет итоги по закрытым сделкам
+func summarize(stats *models.TradeStats, trades []models.SimulatedTrade, balance float64) {
+	stats.Trades = len(trades)
+	stats.FinalBalance = balance
+	stats.NetPnL = balance - stats.InitialBalance
+
+	var grossProfit, grossLoss, winPips, lossPips, bars float64
+	highWaterMark := stats.InitialBalance
+	for _, trade := range trades {
+		stats.ExitReasons[trade.ExitReason]++
+		stats.TotalPips += trade.PnLPips
+		bars += float64(trade.Bars)
+		if trade.PnL > 0 {
+			stats.Wins++
+			grossProfit += trade.PnL
+			winPips += trade.PnLPips
+		} else {
+			stats.Losses++
+			grossLoss -= trade.PnL
+			lossPips -= trade.PnLPips
+		}
+
+		stats.Costs.Spread += trade.Costs.Spread
+		stats.Costs.Slippage += trade.Costs.Slippage
+		stats.Costs.Commission += trade.Costs.Commission
+		stats.Costs.Swap += trade.Costs.Swap
+		stats.Costs.Total += trade.Costs.Total
+
+		highWaterMark = math.Max(highWaterMark, trade.Balance)
+		if highWaterMark > 0 {
+			stats.MaxDrawdown = math.Max(stats.MaxDrawdown, (highWaterMark-trade.Balance)/highWaterMark*100)
+		}
+	}
+	if stats.Trades == 0 {
+		return
+	}
+
+	stats.WinRate = float64(stats.Wins) / float64(stats.Trades) * 100
+	stats.AverageBars = bars / float64(stats.Trades)
+	if stats.Wins > 0 {
+		stats.AverageWinPips = winPips / float64(stats.Wins)
+	}
+	if stats.Losses > 0 {
+		stats.AverageLossPips = lossPips / float64(stats.Losses)
+	}
+	if grossLoss > 0 {
+		stats.ProfitFactor = grossProfit / grossLoss
+	} else {
+		stats.ProfitFactor = grossProfit
+	}
+}
@@ -0,0 +1,298 @@
+package baktest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// hourCandles строит часовые свечи EUR/USD из значений open, high, low, close
+func hourCandles(ohlc ...[4]float64) []models.Candle {
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	candles := make([]models.Candle, len(ohlc))
+	for i, v := range ohlc {
+		candles[i] = models.Candle{
+			Symbol:    "EUR/USD",
+			TimeFrame: "1h",
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Open:      v[0],
+			High:      v[1],
+			Low:       v[2],
+			Close:     v[3],
+		}
+	}
+	return candles
+}
+
+// noCosts - симуляция без издержек с риском 100 на сделку, чтобы цены и PnL были точными
+func noCosts(ambiguity string) Simulation {
+	return Simulation{
+		Ambiguity:      ambiguity,
+		InitialBalance: 10000,
+		RiskPerTrade:   0.01,
+		Costs:          &models.CostProfile{},
+	}
+}
+
+var (
+	buy  = Signal{Bar: 0, PredictionID: "buy", Action: "BUY", StopLoss: 1.0980, TakeProfit: 1.1030}
+	sell = Signal{Bar: 0, PredictionID: "sell", Action: "SELL", StopLoss: 1.1020, TakeProfit: 1.0970}
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func simulateOne(t *testing.T, candles []models.Candle, signals []Signal, sim Simulation) models.SimulatedTrade {
+	t.Helper()
+	trades, stats := Simulate(candles, signals, "EUR/USD", sim)
+	if len(trades) != 1 {
+		t.Fatalf("got %d trades, want 1: %+v", len(trades), trades)
+	}
+	if stats.Trades != 1 || stats.ExitReasons[trades[0].ExitReason] != 1 {
+		t.Errorf("stats = %+v, want one %s exit", stats, trades[0].ExitReason)
+	}
+	return trades[0]
+}
+
+func TestSimulateAmbiguity(t *testing.T) {
+	// Вход на открытии второй свечи по 1.1000; третья свеча задевает оба уровня
+	nearTarget := hourCandles(
+		[4]float64{1.0995, 1.1002, 1.0993, 1.1000},
+		[4]float64{1.1000, 1.1010, 1.0990, 1.1020},
+		[4]float64{1.1020, 1.1040, 1.0970, 1.1000},
+	)
+	nearStop := hourCandles(
+		[4]float64{1.0995, 1.1002, 1.0993, 1.1000},
+		[4]float64{1.1000, 1.1010, 1.0985, 1.0990},
+		[4]float64{1.0990, 1.1040, 1.0970, 1.1000},
+	)
+
+	tests := []struct {
+		name      string
+		candles   []models.Candle
+		ambiguity string
+		reason    string
+		price     float64
+		pnl       float64
+	}{
+		{"stop first", nearTarget, AmbiguityStopFirst, ExitStopLoss, 1.0980, -100},
+		{"target first", nearStop, AmbiguityTargetFirst, ExitTakeProfit, 1.1030, 150},
+		{"nearest is target", nearTarget, AmbiguityNearest, ExitTakeProfit, 1.1030, 150},
+		{"nearest is stop", nearStop, AmbiguityNearest, ExitStopLoss, 1.0980, -100},
+		// Пустой режим работает как stop_first
+		{"default", nearTarget, "", ExitStopLoss, 1.0980, -100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trade := simulateOne(t, tt.candles, []Signal{buy}, noCosts(tt.ambiguity))
+			if trade.ExitReason != tt.reason || !near(trade.ExitPrice, tt.price) {
+				t.Errorf("exit = %s at %.5f, want %s at %.5f", trade.ExitReason, trade.ExitPrice, tt.reason, tt.price)
+			}
+			if !near(trade.EntryPrice, 1.1000) || !trade.EntryTime.Equal(tt.candles[1].Timestamp) {
+				t.Errorf("entry = %.5f at %s, want 1.10000 at the open of the next candle", trade.EntryPrice, trade.EntryTime)
+			}
+			if !near(trade.PnL, tt.pnl) || trade.Bars != 2 {
+				t.Errorf("PnL = %.4f over %d bars, want %.0f over 2", trade.PnL, trade.Bars, tt.pnl)
+			}
+		})
+	}
+}
+
+func TestSimulateSingleLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		signal Signal
+		bar    [4]float64
+		reason string
+		price  float64
+	}{
+		{"buy stop", buy, [4]float64{1.1000, 1.1010, 1.0975, 1.0990}, ExitStopLoss, 1.0980},
+		{"buy target", buy, [4]float64{1.1000, 1.1035, 1.0990, 1.1020}, ExitTakeProfit, 1.1030},
+		{"sell stop", sell, [4]float64{1.1000, 1.1025, 1.0990, 1.1010}, ExitStopLoss, 1.1020},
+		{"sell target", sell, [4]float64{1.1000, 1.1010, 1.0965, 1.0980}, ExitTakeProfit, 1.0970},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candles := hourCandles([4]float64{1.0995, 1.1002, 1.0993, 1.1000}, tt.bar)
+			trade := simulateOne(t, candles, []Signal{tt.signal}, noCosts(AmbiguityStopFirst))
+			if trade.ExitReason != tt.reason || !near(trade.ExitPrice, tt.price) {
+				t.Errorf("exit = %s at %.5f, want %s at %.5f", trade.ExitReason, trade.ExitPrice, tt.reason, tt.price)
+			}
+		})
+	}
+}
+
+func TestSimulateGapThroughLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		bar    [4]float64
+		reason string
+		price  float64
+	}{
+		// Гэп за уровень исполняется по цене открытия, а не по уровню
+		{"gap through stop", [4]float64{1.0960, 1.0970, 1.0950, 1.0965}, ExitStopLoss, 1.0960},
+		{"gap through target", [4]float64{1.1050, 1.1060, 1.1045, 1.1055}, ExitTakeProfit, 1.1050},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candles := hourCandles(
+				[4]float64{1.0995, 1.1002, 1.0993, 1.1000},
+				[4]float64{1.1000, 1.1010, 1.0990, 1.1005},
+				tt.bar,
+			)
+			trade := simulateOne(t, candles, []Signal{buy}, noCosts(AmbiguityTargetFirst))
+			if trade.ExitReason != tt.reason || !near(trade.ExitPrice, tt.price) {
+				t.Errorf("exit = %s at %.5f, want %s at %.5f", trade.ExitReason, trade.ExitPrice, tt.reason, tt.price)
+			}
+		})
+	}
+
+	// Гэп за стоп на свече входа: уровни не окружают цену, сигнал пропускается
+	candles := hourCandles(
+		[4]float64{1.0995, 1.1002, 1.0993, 1.1000},
+		[4]float64{1.0970, 1.0975, 1.0960, 1.0965},
+	)
+	trades, stats := Simulate(candles, []Signal{buy}, "EUR/USD", noCosts(AmbiguityStopFirst))
+	if len(trades) != 0 || stats.SkippedSignals != 1 {
+		t.Errorf("got %d trades and %d skipped signals, want the signal skipped", len(trades), stats.SkippedSignals)
+	}
+}
+
+func TestSimulateTimeStop(t *testing.T) {
+	quiet := [4]float64{1.1000, 1.1010, 1.0990, 1.1005}
+	candles := hourCandles(quiet, quiet, quiet, quiet, quiet, quiet)
+	candles[3].Close = 1.1008
+
+	sim := noCosts(AmbiguityStopFirst)
+	sim.MaxBars = 3
+	trade := simulateOne(t, candles, []Signal{buy}, sim)
+	if trade.ExitReason != ExitTimeStop || trade.Bars != 3 || !near(trade.ExitPrice, 1.1008) {
+		t.Errorf("exit = %s at %.5f after %d bars, want %s at 1.10080 after 3", trade.ExitReason, trade.ExitPrice, trade.Bars, ExitTimeStop)
+	}
+	if !near(trade.GrossPnLPips, 8) {
+		t.Errorf("GrossPnLPips = %.2f, want 8", trade.GrossPnLPips)
+	}
+}
+
+func TestSimulateEndOfData(t *testing.T) {
+	quiet := [4]float64{1.1000, 1.1010, 1.0990, 1.1005}
+	candles := hourCandles(quiet, quiet, quiet)
+
+	trade := simulateOne(t, candles, []Signal{buy}, noCosts(AmbiguityStopFirst))
+	if trade.ExitReason != ExitEndOfData || trade.Bars != 2 || !near(trade.ExitPrice, 1.1005) {
+		t.Errorf("exit = %s at %.5f after %d bars, want %s at 1.10050 after 2", trade.ExitReason, trade.ExitPrice, trade.Bars, ExitEndOfData)
+	}
+}
+
+func TestSimulateOppositeSignal(t *testing.T) {
+	candles := hourCandles(
+		[4]float64{1.0995, 1.1002, 1.0993, 1.1000},
+		[4]float64{1.1000, 1.1010, 1.0990, 1.1005},
+		[4]float64{1.1005, 1.1010, 1.0995, 1.1000},
+		[4]float64{1.1002, 1.1008, 1.0992, 1.0996},
+		[4]float64{1.0996, 1.1000, 1.0990, 1.0994},
+	)
+	reverse := Signal{Bar: 2, PredictionID: "reverse", Action: "SELL", StopLoss: 1.1030, TakeProfit: 1.0950}
+	signals := []Signal{buy, reverse}
+
+	// Противоположный сигнал закрывает покупку на открытии следующей свечи и открывает продажу
+	sim := noCosts(AmbiguityStopFirst)
+	sim.ExitOnOpposite = true
+	trades, stats := Simulate(candles, signals, "EUR/USD", sim)
+	if len(trades) != 2 {
+		t.Fatalf("got %d trades, want 2: %+v", len(trades), trades)
+	}
+	first, second := trades[0], trades[1]
+	if first.ExitReason != ExitOppositeSignal || !near(first.ExitPrice, 1.1002) || !first.ExitTime.Equal(candles[3].Timestamp) {
+		t.Errorf("first trade exit = %s at %.5f (%s), want %s at 1.10020", first.ExitReason, first.ExitPrice, first.ExitTime, ExitOppositeSignal)
+	}
+	if second.Direction != "SELL" || second.PredictionID != "reverse" || !near(second.EntryPrice, 1.1002) ||
+		!second.EntryTime.Equal(candles[3].Timestamp) {
+		t.Errorf("second trade = %+v, want SELL from the open of candle 3", second)
+	}
+	if second.ExitReason != ExitEndOfData || !near(second.ExitPrice, 1.0994) {
+		t.Errorf("second trade exit = %s at %.5f, want %s at 1.09940", second.ExitReason, second.ExitPrice, ExitEndOfData)
+	}
+	if stats.ExitReasons[ExitOppositeSignal] != 1 || stats.ExitReasons[ExitEndOfData] != 1 {
+		t.Errorf("ExitReasons = %v", stats.ExitReasons)
+	}
+
+	// Без ExitOnOpposite сигнал при открытой позиции игнорируется
+	sim.ExitOnOpposite = false
+	trade := simulateOne(t, candles, signals, sim)
+	if trade.Direction != "BUY" || trade.ExitReason != ExitEndOfData {
+		t.Errorf("trade = %+v, want the BUY held to the end of data", trade)
+	}
+}
+
+func TestSimulateGrossSizedFromGrossBalance(t *testing.T) {
+	candles := hourCandles(
+		[4]float64{1.0995, 1.1002, 1.0993, 1.1000},
+		[4]float64{1.1000, 1.1005, 1.0975, 1.0985},
+		[4]float64{1.0985, 1.0995, 1.0980, 1.0990},
+		[4]float64{1.1000, 1.1005, 1.0975, 1.0985},
+	)
+	second := buy
+	second.Bar = 2
+
+	sim := noCosts(AmbiguityStopFirst)
+	sim.Costs = &models.CostProfile{SpreadPips: 1}
+	trades, stats := Simulate(candles, []Signal{buy, second}, "EUR/USD", sim)
+	if len(trades) != 2 {
+		t.Fatalf("got %d trades, want 2: %+v", len(trades), trades)
+	}
+
+	// Без издержек каждый стоп стоит 1% валового баланса, а не баланса после издержек
+	if !near(stats.Gross.FinalBalance, 10000*0.99*0.99) {
+		t.Errorf("gross final balance = %.4f, want %.4f", stats.Gross.FinalBalance, 10000*0.99*0.99)
+	}
+	if stats.FinalBalance >= stats.Gross.FinalBalance || stats.Costs.Spread <= 0 {
+		t.Errorf("net balance %.4f with spread %.4f, want below gross %.4f", stats.FinalBalance, stats.Costs.Spread, stats.Gross.FinalBalance)
+	}
+}
+
+func TestSimulateStopsAfterRuin(t *testing.T) {
+	candles := hourCandles(
+		[4]float64{1.0995, 1.1002, 1.0993, 1.1000},
+		[4]float64{1.1000, 1.1005, 1.0975, 1.0985},
+		[4]float64{1.0985, 1.0995, 1.0980, 1.0990},
+		[4]float64{1.1000, 1.1035, 1.0995, 1.1020},
+	)
+	second := buy
+	second.Bar = 2
+
+	// Комиссия 10000 за сторону при объеме 50000 съедает весь баланс на первой сделке
+	sim := noCosts(AmbiguityStopFirst)
+	sim.Costs = &models.CostProfile{LotSize: 100000, CommissionPerLot: 20000}
+	trades, stats := Simulate(candles, []Signal{buy, second}, "EUR/USD", sim)
+	if len(trades) != 1 {
+		t.Fatalf("got %d trades, want only the trade that ruined the account: %+v", len(trades), trades)
+	}
+	if !stats.Ruined || stats.FinalBalance > 0 {
+		t.Errorf("Ruined = %t with final balance %.2f, want ruin", stats.Ruined, stats.FinalBalance)
+	}
+	if trades[0].Units <= 0 {
+		t.Errorf("Units = %.2f, want positive", trades[0].Units)
+	}
+}
+
+func TestSimulationCheckCurrency(t *testing.T) {
+	sim := DefaultSimulation()
+	for _, symbol := range []string{"EUR/USD", "USD/JPY"} {
+		if err := sim.CheckCurrency(symbol); err != nil {
+			t.Errorf("CheckCurrency(%s): %v", symbol, err)
+		}
+	}
+
+	// Кросс без валюты счета требует курса котируемой валюты
+	if err := sim.CheckCurrency("EUR/JPY"); err == nil {
+		t.Error("CheckCurrency(EUR/JPY) without a JPY rate returned no error")
+	}
+	sim.ConversionRates = map[string]float64{"JPY": 0.0067}
+	if err := sim.CheckCurrency("EUR/JPY"); err != nil {
+		t.Errorf("CheckCurrency(EUR/JPY) with a JPY rate: %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package baktest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// CandleSource - источник исторических свечей для бэктеста. Нулевые from и to
+// означают отсутствие границы с соответствующей стороны
+type CandleSource interface {
+	Candles(ctx context.Context, from, to time.Time) ([]models.Candle, error)
+}
+
+// Candles - свечи в памяти, например заранее загруженные или синтетические в тестах
+type Candles []models.Candle
+
+// Candles возвращает свечи из диапазона, отсортированные по времени
+func (c Candles) Candles(_ context.Context, from, to time.Time) ([]models.Candle, error) {
+	return inRange(c, from, to), nil
+}
+
+// ClientSource загружает свечи через клиент данных. Глубина истории считается от from
+// до текущего момента; без from берется Days дней
+type ClientSource struct {
+	Client models.CandleClient
+	Days   int
+}
+
+// Candles загружает историю клиента и оставляет свечи из диапазона
+func (s ClientSource) Candles(ctx context.Context, from, to time.Time) ([]models.Candle, error) {
+	days := s.Days
+	if !from.IsZero() {
+		days = int(math.Ceil(time.Since(from).Hours()/24)) + 1
+	}
+	if days <= 0 {
+		return nil, fmt.Errorf("history depth is not set")
+	}
+
+	candles, err := s.Client.GetHistoricalCandles(ctx, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical data: %w", err)
+	}
+	return inRange(candles, from, to), nil
+}
+
+// FileSource читает свечи из файла: JSON-массив models.Candle или CSV с заголовком
+// (колонки time/datetime/timestamp, open, high, low, close и необязательная volume).
+// Symbol и Interval подставляются в свечи, у которых они не указаны
+type FileSource struct {
+	Path     string
+	Symbol   string
+	Interval string
+}
+
+// Candles читает файл и возвращает свечи из диапазона
+func (s FileSource) Candles(_ context.Context, from, to time.Time) ([]models.Candle, error) {
+	candles, err := ReadCandlesFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range candles {
+		if candles[i].Symbol == "" {
+			candles[i].Symbol = s.Symbol
+		}
+		if candles[i].TimeFrame == "" {
+			candles[i].TimeFrame = s.Interval
+		}
+	}
+	return inRange(candles, from, to), nil
+}
+
+// CandleStore - хранилище свечей по инструменту и таймфрейму
+type CandleStore interface {
+	Load(ctx context.Context, symbol, interval string, from, to time.Time) ([]models.Candle, error)
+	Save(ctx context.Context, symbol, interval string, candles []models.Candle) error
+}
+
+// StoreSource берет свечи инструмента из хранилища
+type StoreSource struct {
+	Store    CandleStore
+	Symbol   string
+	Interval string
+}
+
+// Candles загружает свечи из хранилища
+func (s StoreSource) Candles(ctx context.Context, from, to time.Time) ([]models.Candle, error) {
+	return s.Store.Load(ctx, s.Symbol, s.Interval, from, to)
+}
+
+// DirStore хранит свечи в каталоге CSV-файлами вида "EUR-USD_1h.csv". Save дополняет
+// файл новыми свечами, заменяя свечи с совпадающим временем, так что повторная выгрузка
+// из сети наращивает историю для воспроизводимых бэктестов
+type DirStore struct {
+	Dir string
+}
+
+func (s DirStore) path(symbol, interval string) string {
+	name := strings.NewReplacer("/", "-", " ", "").Replace(strings.ToUpper(symbol))
+	return filepath.Join(s.Dir, name+"_"+interval+".csv")
+}
+
+// Load читает свечи инструмента из диапазона
+func (s DirStore) Load(ctx context.Context, symbol, interval string, from, to time.Time) ([]models.Candle, error) {
+	return FileSource{Path: s.path(symbol, interval), Symbol: symbol, Interval: interval}.Candles(ctx, from, to)
+}
+
+// Save объединяет свечи с уже сохраненными и перезаписывает файл
+func (s DirStore) Save(_ context.Context, symbol, interval string, candles []models.Candle) error {
+	path := s.path(symbol, interval)
+	merged := make(map[time.Time]models.Candle)
+	if existing, err := ReadCandlesFile(path); err == nil {
+		for _, candle := range existing {
+			merged[candle.Timestamp] = candle
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	for _, candle := range candles {
+		merged[candle.Timestamp] = candle
+	}
+
+	all := make([]models.Candle, 0, len(merged))
+	for _, candle := range merged {
+		all = append(all, candle)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Timestamp.Before(all[j].Timestamp) })
+
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("creating candle store: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating candle file: %w", err)
+	}
+	defer file.Close()
+	return WriteCandlesCSV(file, all)
+}
+
+// ReadCandlesFile читает свечи из JSON (расширение .json) или CSV
+func ReadCandlesFile(path string) ([]models.Candle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var candles []models.Candle
+		if err := json.NewDecoder(file).Decode(&candles); err != nil {
+			return nil, fmt.Errorf("decoding candles %s: %w", path, err)
+		}
+		return candles, nil
+	}
+	candles, err := ParseCandlesCSV(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading candles %s: %w", path, err)
+	}
+	return candles, nil
+}
+
+// candleTimeLayouts - форматы времени свечей в CSV; время без зоны считается UTC
+var candleTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseCandlesCSV читает свечи из CSV с заголовком. Время - в одном из candleTimeLayouts
+// или unix-секундах; необязательные колонки symbol и timeframe заполняют поля свечи
+func ParseCandlesCSV(r io.Reader) ([]models.Candle, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, alias := range []string{"datetime", "timestamp", "date"} {
+		if _, ok := columns["time"]; ok {
+			break
+		}
+		if i, ok := columns[alias]; ok {
+			columns["time"] = i
+		}
+	}
+	for _, required := range []string{"time", "open", "high", "low", "close"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("candle CSV has no %q column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(record []string, name string, line int) (float64, error) {
+		value, err := strconv.ParseFloat(field(record, name), 64)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: invalid %s: %w", line, name, err)
+		}
+		return value, nil
+	}
+
+	var candles []models.Candle
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading line %d: %w", line, err)
+		}
+
+		at, err := parseCandleTime(field(record, "time"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		candle := models.Candle{
+			Symbol:    field(record, "symbol"),
+			TimeFrame: field(record, "timeframe"),
+			Timestamp: at,
+		}
+		if candle.Open, err = number(record, "open", line); err != nil {
+			return nil, err
+		}
+		if candle.High, err = number(record, "high", line); err != nil {
+			return nil, err
+		}
+		if candle.Low, err = number(record, "low", line); err != nil {
+			return nil, err
+		}
+		if candle.Close, err = number(record, "close", line); err != nil {
+			return nil, err
+		}
+		if volume := field(record, "volume"); volume != "" {
+			value, err := strconv.ParseFloat(volume, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid volume: %w", line, err)
+			}
+			candle.Volume = int64(value)
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+func parseCandleTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && len(value) >= 9 {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	for _, layout := range candleTimeLayouts {
+		if at, err := time.Parse(layout, value); err == nil {
+			return at.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// WriteCandlesCSV записывает свечи в формате, который читает ParseCandlesCSV
+func WriteCandlesCSV(w io.Writer, candles []models.Candle) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"time", "open", "high", "low", "close", "volume"}); err != nil {
+		return err
+	}
+	for _, candle := range candles {
+		if err := writer.Write([]string{
+			candle.Timestamp.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(candle.Open, 'f', -1, 64),
+			strconv.FormatFloat(candle.High, 'f', -1, 64),
+			strconv.FormatFloat(candle.Low, 'f', -1, 64),
+			strconv.FormatFloat(candle.Close, 'f', -1, 64),
+			strconv.FormatInt(candle.Volume, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// inRange возвращает отсортированную по времени копию свечей из диапазона [from, to]
+func inRange(candles []models.Candle, from, to time.Time) []models.Candle {
+	result := make([]models.Candle, 0, len(candles))
+	for _, candle := range candles {
+		if (!from.IsZero() && candle.Timestamp.Before(from)) || (!to.IsZero() && candle.Timestamp.After(to)) {
+			continue
+		}
+		result = append(result, candle)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	return result
+}
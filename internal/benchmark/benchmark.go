@@ -0,0 +1,202 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/internal/analyze"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// USDIndexSymbol - символ синтетического индекса доллара в свечах бенчмарка
+const USDIndexSymbol = "USD_INDEX"
+
+// Component - пара корзины бенчмарка и ее вес. Знак веса задает направление:
+// положительный, если доллар - базовая валюта пары, отрицательный, если котируемая
+type Component struct {
+	Symbol string
+	Weight float64
+}
+
+// USDBasket - прокси индекса доллара DXY из пар, доступных в Twelve Data.
+// Веса DXY без шведской кроны; при расчете нормируются на сумму модулей
+var USDBasket = []Component{
+	{Symbol: "EUR/USD", Weight: -0.576},
+	{Symbol: "USD/JPY", Weight: 0.136},
+	{Symbol: "GBP/USD", Weight: -0.119},
+	{Symbol: "USD/CAD", Weight: 0.091},
+	{Symbol: "USD/CHF", Weight: 0.036},
+}
+
+// Without возвращает корзину без пары symbol, чтобы анализируемый инструмент
+// не коррелировал сам с собой через индекс
+func Without(basket []Component, symbol string) []Component {
+	filtered := make([]Component, 0, len(basket))
+	for _, component := range basket {
+		if component.Symbol != symbol {
+			filtered = append(filtered, component)
+		}
+	}
+	return filtered
+}
+
+// Build строит геометрический индекс по закрытиям пар корзины на общих отметках времени:
+// 100 * exp(Σ w_i * ln(close_i / close_i0)) с весами, нормированными на Σ|w_i|.
+// Объем индекса нулевой, High/Low - максимум и минимум из Open и Close
+func Build(symbol string, basket []Component, series map[string][]models.Candle) ([]models.Candle, error) {
+	if len(basket) == 0 {
+		return nil, fmt.Errorf("empty benchmark basket")
+	}
+
+	totalWeight := 0.0
+	closes := make([]map[time.Time]models.Candle, len(basket))
+	for i, component := range basket {
+		candles := series[component.Symbol]
+		if len(candles) == 0 {
+			return nil, fmt.Errorf("no candles for benchmark component %s", component.Symbol)
+		}
+		closes[i] = make(map[time.Time]models.Candle, len(candles))
+		for _, candle := range candles {
+			closes[i][candle.Timestamp] = candle
+		}
+		totalWeight += math.Abs(component.Weight)
+	}
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("benchmark basket has zero weight")
+	}
+
+	// Отметки времени, присутствующие у всех пар корзины
+	var timestamps []time.Time
+	for _, candle := range series[basket[0].Symbol] {
+		common := true
+		for i := 1; i < len(basket); i++ {
+			if _, ok := closes[i][candle.Timestamp]; !ok {
+				common = false
+				break
+			}
+		}
+		if common {
+			timestamps = append(timestamps, candle.Timestamp)
+		}
+	}
+	if len(timestamps) < 2 {
+		return nil, fmt.Errorf("benchmark components share only %d timestamps", len(timestamps))
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+
+	level := func(at time.Time, price func(models.Candle) float64) float64 {
+		logLevel := 0.0
+		for i, component := range basket {
+			base := price(closes[i][timestamps[0]])
+			current := price(closes[i][at])
+			if base <= 0 || current <= 0 {
+				continue
+			}
+			logLevel += component.Weight / totalWeight * math.Log(current/base)
+		}
+		return 100 * math.Exp(logLevel)
+	}
+	closePrice := func(c models.Candle) float64 { return c.Close }
+	openPrice := func(c models.Candle) float64 { return c.Open }
+
+	timeFrame := series[basket[0].Symbol][0].TimeFrame
+	index := make([]models.Candle, 0, len(timestamps))
+	for _, at := range timestamps {
+		openLevel, closeLevel := level(at, openPrice), level(at, closePrice)
+		index = append(index, models.Candle{
+			Symbol:    symbol,
+			TimeFrame: timeFrame,
+			Open:      openLevel,
+			High:      math.Max(openLevel, closeLevel),
+			Low:       math.Min(openLevel, closeLevel),
+			Close:     closeLevel,
+			Timestamp: at,
+		})
+	}
+	return index, nil
+}
+
+// Align сопоставляет каждой свече инструмента последнее значение бенчмарка не позже ее времени,
+// чтобы ряды имели одинаковую длину для корреляции. Свечи до начала бенчмарка получают его первое значение
+func Align(candles, benchmark []models.Candle) []models.Candle {
+	if len(benchmark) == 0 {
+		return nil
+	}
+
+	aligned := make([]models.Candle, len(candles))
+	j := 0
+	for i, candle := range candles {
+		for j+1 < len(benchmark) && !benchmark[j+1].Timestamp.After(candle.Timestamp) {
+			j++
+		}
+		aligned[i] = benchmark[j]
+	}
+	return aligned
+}
+
+// cache хранит свечи пар корзины, чтобы бот не запрашивал их заново для каждого пользователя
+// в пределах одной свечи
+var cache = struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+}{entries: make(map[string]cacheEntry)}
+
+type cacheEntry struct {
+	candles []models.Candle
+	expires time.Time
+}
+
+// Fetch загружает свечи пар корзины с параметрами cfg (интервал, число свечей, ключ API)
+// и строит индекс доллара. Свечи каждой пары кэшируются на длительность интервала
+func Fetch(ctx context.Context, cfg *models.Config, basket []Component) ([]models.Candle, error) {
+	ttl := models.IntervalDuration(cfg.Interval)
+	if ttl < time.Minute {
+		ttl = time.Minute
+	}
+
+	series := make(map[string][]models.Candle, len(basket))
+	for _, component := range basket {
+		key := fmt.Sprintf("%s|%s|%d", component.Symbol, cfg.Interval, cfg.CandleCount)
+
+		cache.mu.RLock()
+		entry, ok := cache.entries[key]
+		cache.mu.RUnlock()
+		if ok && time.Now().Before(entry.expires) {
+			series[component.Symbol] = entry.candles
+			continue
+		}
+
+		componentCfg := *cfg
+		componentCfg.Symbol = component.Symbol
+		candles, err := config.NewClient(&componentCfg).GetCandles(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetching benchmark component %s: %w", component.Symbol, err)
+		}
+
+		cache.mu.Lock()
+		cache.entries[key] = cacheEntry{candles: candles, expires: time.Now().Add(ttl)}
+		cache.mu.Unlock()
+		series[component.Symbol] = candles
+	}
+
+	return Build(USDIndexSymbol, basket, series)
+}
+
+// Analyze выполняет комплексный анализ рынка инструмента, сопоставляя его свечи
+// с индексом доллара, построенным без самого инструмента
+func Analyze(ctx context.Context, cfg *models.Config, candles []models.Candle) (*models.MarketAnalysis, error) {
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("no candles to analyze")
+	}
+
+	index, err := Fetch(ctx, cfg, Without(USDBasket, cfg.Symbol))
+	if err != nil {
+		return nil, fmt.Errorf("building benchmark: %w", err)
+	}
+	return analyze.AnalyzeMarket(ctx, candles, Align(candles, index)), nil
+}
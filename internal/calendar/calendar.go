@@ -0,0 +1,273 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// Уровни важности релизов
+const (
+	ImpactLow    = "LOW"
+	ImpactMedium = "MEDIUM"
+	ImpactHigh   = "HIGH"
+)
+
+// Window - окно вокруг релиза, в котором прогнозы по его валюте ненадежны
+type Window struct {
+	Before    time.Duration // До релиза
+	After     time.Duration // После релиза
+	MinImpact string        // Минимальная важность релиза, открывающего окно
+	Suppress  bool          // Снимать торговую рекомендацию, а не только помечать прогноз
+}
+
+// DefaultWindow - полчаса до и после релизов высокой важности с отменой рекомендации
+func DefaultWindow() Window {
+	return Window{
+		Before:    30 * time.Minute,
+		After:     30 * time.Minute,
+		MinImpact: ImpactHigh,
+		Suppress:  true,
+	}
+}
+
+// state - загруженный календарь, отсортированный по времени, и действующее окно
+var state = struct {
+	mu     sync.RWMutex
+	events []models.EconomicEvent
+	window Window
+}{window: DefaultWindow()}
+
+// Set заменяет события календаря
+func Set(events []models.EconomicEvent) {
+	sorted := append([]models.EconomicEvent(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.events = sorted
+}
+
+// SetWindow задает окно вокруг релизов
+func SetWindow(window Window) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.window = window
+}
+
+// ActiveWindow возвращает действующее окно
+func ActiveWindow() Window {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.window
+}
+
+// Len возвращает число загруженных событий
+func Len() int {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return len(state.events)
+}
+
+// ImpactRank переводит важность в число для сравнения: 0 - неизвестная
+func ImpactRank(impact string) int {
+	switch impact {
+	case ImpactLow:
+		return 1
+	case ImpactMedium:
+		return 2
+	case ImpactHigh:
+		return 3
+	}
+	return 0
+}
+
+// ParseImpact распознает важность релиза в распространенных обозначениях:
+// high/medium/low, 3/2/1, red/orange/yellow, H/M/L
+func ParseImpact(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "high", "h", "3", "red", "high impact expected":
+		return ImpactHigh
+	case "medium", "med", "m", "2", "orange", "moderate", "medium impact expected":
+		return ImpactMedium
+	case "low", "l", "1", "yellow", "low impact expected":
+		return ImpactLow
+	}
+	return ""
+}
+
+// Load читает календарь из локального файла или по URL (http/https). Формат определяется
+// по расширению: .ics - iCalendar, иначе CSV
+func Load(ctx context.Context, location string) ([]models.EconomicEvent, error) {
+	var reader io.Reader
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating calendar request: %w", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching calendar: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching calendar: status %d", resp.StatusCode)
+		}
+		reader = resp.Body
+	} else {
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, fmt.Errorf("opening calendar: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	path := strings.ToLower(location)
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	if filepath.Ext(path) == ".ics" {
+		return ParseICS(reader)
+	}
+	return ParseCSV(reader)
+}
+
+// LoadFile загружает календарь и делает его активным
+func LoadFile(ctx context.Context, location string) (int, error) {
+	events, err := Load(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+	Set(events)
+	return len(events), nil
+}
+
+// Refresh периодически перезагружает календарь. При ошибке остаются прежние события;
+// onReload вызывается после каждой попытки
+func Refresh(ctx context.Context, location string, interval time.Duration, onReload func(int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			loaded, err := LoadFile(ctx, location)
+			if onReload != nil {
+				onReload(loaded, err)
+			}
+		}
+	}
+}
+
+// pairCurrencies возвращает валюты инструмента "EUR/USD"
+func pairCurrencies(symbol string) []string {
+	base, quote, ok := strings.Cut(strings.ToUpper(symbol), "/")
+	if !ok {
+		return []string{base}
+	}
+	return []string{base, quote}
+}
+
+// Upcoming возвращает события по валютам инструмента с важностью не ниже minImpact
+// в интервале [from, to]
+func Upcoming(symbol string, from, to time.Time, minImpact string) []models.EconomicEvent {
+	currencies := pairCurrencies(symbol)
+	minRank := ImpactRank(minImpact)
+
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+
+	start := sort.Search(len(state.events), func(i int) bool { return !state.events[i].Time.Before(from) })
+	var events []models.EconomicEvent
+	for _, event := range state.events[start:] {
+		if event.Time.After(to) {
+			break
+		}
+		if ImpactRank(event.Impact) < minRank {
+			continue
+		}
+		for _, currency := range currencies {
+			if event.Currency == currency {
+				events = append(events, event)
+				break
+			}
+		}
+	}
+	return events
+}
+
+// Check проверяет, пересекает ли интервал прогноза [from, to] окно вокруг важного
+// релиза по валютам инструмента. Возвращает nil, если календарь пуст
+func Check(symbol string, from, to time.Time) *models.EventRisk {
+	if Len() == 0 {
+		return nil
+	}
+	window := ActiveWindow()
+
+	risk := &models.EventRisk{
+		Events: Upcoming(symbol, from.Add(-window.After), to.Add(window.Before), window.MinImpact),
+	}
+	risk.Blackout = len(risk.Events) > 0
+
+	if next := Upcoming(symbol, from, from.Add(7*24*time.Hour), window.MinImpact); len(next) > 0 {
+		risk.Next = &next[0]
+	}
+	return risk
+}
+
+// Apply помечает прогноз, интервал которого попадает в окно вокруг важного релиза,
+// и при включенном подавлении снимает торговую рекомендацию
+func Apply(prediction *models.Prediction, candles []models.Candle, symbol, interval string) {
+	if len(candles) == 0 {
+		return
+	}
+
+	duration := models.IntervalDuration(interval)
+	from := candles[len(candles)-1].Timestamp.Add(duration)
+	to := prediction.PredictionTarget
+	if to.Before(from) {
+		to = from.Add(duration)
+	}
+
+	risk := Check(symbol, from, to)
+	if risk == nil {
+		return
+	}
+	prediction.EventRisk = risk
+
+	if risk.Blackout && ActiveWindow().Suppress && prediction.TradingSuggestion != nil {
+		prediction.TradingSuggestion.Action = "NO_TRADE"
+		risk.Suppressed = true
+	}
+}
+
+// RiskScore оценивает риск релизов для инструмента в момент at (0-1): растет по мере
+// приближения ближайшего релиза средней или высокой важности в пределах суток.
+// Релизы средней важности весят вдвое меньше
+func RiskScore(symbol string, at time.Time) float64 {
+	score := 0.0
+	for _, event := range Upcoming(symbol, at, at.Add(24*time.Hour), ImpactMedium) {
+		proximity := 1 - event.Time.Sub(at).Hours()/24
+		if event.Impact == ImpactMedium {
+			proximity /= 2
+		}
+		if proximity > score {
+			score = proximity
+		}
+	}
+	return score
+}
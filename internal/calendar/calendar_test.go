@@ -0,0 +1,267 @@
+package calendar
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", value, err)
+	}
+	return at
+}
+
+// useEvents делает events активным календарем с окном window до конца теста
+func useEvents(t *testing.T, events []models.EconomicEvent, window Window) {
+	t.Helper()
+	Set(events)
+	SetWindow(window)
+	t.Cleanup(func() {
+		Set(nil)
+		SetWindow(DefaultWindow())
+	})
+}
+
+func TestParseImpactAliases(t *testing.T) {
+	tests := map[string]string{
+		"High": ImpactHigh, " h ": ImpactHigh, "3": ImpactHigh, "red": ImpactHigh, "High Impact Expected": ImpactHigh,
+		"medium": ImpactMedium, "MED": ImpactMedium, "2": ImpactMedium, "orange": ImpactMedium, "moderate": ImpactMedium,
+		"low": ImpactLow, "L": ImpactLow, "1": ImpactLow, "yellow": ImpactLow,
+		"holiday": "", "": "",
+	}
+	for value, want := range tests {
+		if got := ParseImpact(value); got != want {
+			t.Errorf("ParseImpact(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestLoadCSVFixture(t *testing.T) {
+	events, err := Load(context.Background(), "testdata/calendar.csv")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(events) != 8 {
+		t.Fatalf("got %d events, want 8", len(events))
+	}
+
+	first := events[0]
+	want := models.EconomicEvent{
+		Time:     mustTime(t, "2026-10-19T08:00:00Z"),
+		Currency: "EUR",
+		Impact:   ImpactMedium,
+		Title:    "German PPI m/m",
+		Forecast: "0.1%",
+		Previous: "-0.2%",
+	}
+	if !first.Time.Equal(want.Time) || first.Currency != want.Currency || first.Impact != want.Impact ||
+		first.Title != want.Title || first.Forecast != want.Forecast || first.Previous != want.Previous {
+		t.Errorf("first event = %+v, want %+v", first, want)
+	}
+	if last := events[len(events)-1]; last.Currency != "USD" || last.Impact != ImpactHigh ||
+		!last.Time.Equal(mustTime(t, "2026-10-24T12:30:00Z")) {
+		t.Errorf("last event = %+v", last)
+	}
+}
+
+func TestParseCSVColumnsAndTimezones(t *testing.T) {
+	data := strings.Join([]string{
+		"Date,Time,Currency,Impact,Event",
+		"2026-10-19,08:00,eur,H,German PPI m/m",
+		// Время с зоной переводится в UTC
+		",2026-10-19T08:30:00-04:00,USD,orange,Retail Sales m/m",
+		"2026-10-19,09:00,EUR,Holiday,Bank Holiday",
+		"2026-10-19,10:00,,high,G20 Meeting",
+	}, "\n")
+
+	events, err := ParseCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2 (unknown impact and missing currency skipped): %+v", len(events), events)
+	}
+	if e := events[0]; e.Currency != "EUR" || e.Impact != ImpactHigh || e.Title != "German PPI m/m" ||
+		!e.Time.Equal(mustTime(t, "2026-10-19T08:00:00Z")) {
+		t.Errorf("date and time columns: got %+v", e)
+	}
+	if e := events[1]; e.Impact != ImpactMedium || !e.Time.Equal(mustTime(t, "2026-10-19T12:30:00Z")) ||
+		e.Time.Location() != time.UTC {
+		t.Errorf("RFC3339 time with offset: got %+v", e)
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing column": "time,currency,title\n2026-10-19 08:00,EUR,PPI",
+		"bad time":       "time,currency,impact,title\nyesterday,EUR,high,PPI",
+	}
+	for name, data := range tests {
+		if _, err := ParseCSV(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: ParseCSV returned no error", name)
+		}
+	}
+}
+
+func TestLoadICSFixture(t *testing.T) {
+	events, err := Load(context.Background(), "testdata/calendar.ics")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := []models.EconomicEvent{
+		// Валюта и важность из CATEGORIES, код в квадратных скобках снимается с названия
+		{Time: mustTime(t, "2026-10-19T12:30:00Z"), Currency: "CAD", Impact: ImpactHigh, Title: "CPI m/m"},
+		// Лондонское летнее время (UTC+1), важность из PRIORITY
+		{Time: mustTime(t, "2026-10-20T06:00:00Z"), Currency: "GBP", Impact: ImpactHigh, Title: "CPI y/y"},
+		// Важность из DESCRIPTION
+		{Time: mustTime(t, "2026-10-24T12:30:00Z"), Currency: "USD", Impact: ImpactHigh, Title: "Non-Farm Employment Change"},
+		// Важность из X-IMPACT
+		{Time: mustTime(t, "2026-10-21T13:45:00Z"), Currency: "USD", Impact: ImpactLow, Title: "Flash Services PMI"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, e := range events {
+		if !e.Time.Equal(want[i].Time) || e.Currency != want[i].Currency || e.Impact != want[i].Impact || e.Title != want[i].Title {
+			t.Errorf("event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestParseICSFoldingAndSkipping(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=America/New_York:20260115T083000",
+		"SUMMARY:USD Core CPI",
+		"  m/m",
+		"PRIORITY:5",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20260115T090000Z",
+		"SUMMARY:Speech without currency",
+		"PRIORITY:1",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := ParseICS(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseICS: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1: %+v", len(events), events)
+	}
+	// Нью-Йорк зимой UTC-5
+	if e := events[0]; e.Title != "Core CPI m/m" || e.Impact != ImpactMedium ||
+		!e.Time.Equal(mustTime(t, "2026-01-15T13:30:00Z")) {
+		t.Errorf("got %+v", e)
+	}
+}
+
+func TestCheckWindowEdges(t *testing.T) {
+	release := mustTime(t, "2026-10-23T12:30:00Z")
+	useEvents(t, []models.EconomicEvent{
+		{Time: release, Currency: "USD", Impact: ImpactHigh, Title: "Core PCE"},
+		{Time: release.Add(-2 * time.Hour), Currency: "USD", Impact: ImpactMedium, Title: "Jobless Claims"},
+	}, Window{Before: 30 * time.Minute, After: 15 * time.Minute, MinImpact: ImpactHigh, Suppress: true})
+
+	tests := []struct {
+		name     string
+		symbol   string
+		from, to time.Time
+		blackout bool
+	}{
+		{"ends exactly Before ahead of release", "EUR/USD", release.Add(-time.Hour), release.Add(-30 * time.Minute), true},
+		{"ends just before the window", "EUR/USD", release.Add(-time.Hour), release.Add(-30*time.Minute - time.Second), false},
+		{"starts exactly After past release", "USD/JPY", release.Add(15 * time.Minute), release.Add(time.Hour), true},
+		{"starts just after the window", "USD/JPY", release.Add(15*time.Minute + time.Second), release.Add(time.Hour), false},
+		{"spans the release", "USD/CAD", release.Add(-5 * time.Minute), release.Add(5 * time.Minute), true},
+		{"other currencies", "EUR/GBP", release.Add(-5 * time.Minute), release.Add(5 * time.Minute), false},
+		{"medium release below threshold", "EUR/USD", release.Add(-2 * time.Hour), release.Add(-2*time.Hour + time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risk := Check(tt.symbol, tt.from, tt.to)
+			if risk == nil {
+				t.Fatal("Check returned nil with a loaded calendar")
+			}
+			if risk.Blackout != tt.blackout {
+				t.Errorf("Blackout = %t, want %t (events %+v)", risk.Blackout, tt.blackout, risk.Events)
+			}
+		})
+	}
+
+	risk := Check("EUR/USD", release.Add(-3*time.Hour), release.Add(-2*time.Hour))
+	if risk.Next == nil || !risk.Next.Time.Equal(release) {
+		t.Errorf("Next = %+v, want the high impact release at %s", risk.Next, release)
+	}
+}
+
+func TestCheckEmptyCalendar(t *testing.T) {
+	useEvents(t, nil, DefaultWindow())
+	if risk := Check("EUR/USD", time.Now(), time.Now().Add(time.Hour)); risk != nil {
+		t.Errorf("Check with an empty calendar = %+v, want nil", risk)
+	}
+}
+
+func TestApplySuppression(t *testing.T) {
+	last := mustTime(t, "2026-10-23T11:00:00Z")
+	candles := []models.Candle{{Timestamp: last.Add(-time.Hour)}, {Timestamp: last}}
+	release := last.Add(90 * time.Minute)
+	events := []models.EconomicEvent{{Time: release, Currency: "USD", Impact: ImpactHigh, Title: "Core PCE"}}
+
+	newPrediction := func() *models.Prediction {
+		return &models.Prediction{
+			Direction:         "BUY",
+			PredictionTarget:  last.Add(2 * time.Hour),
+			TradingSuggestion: &models.TradingSuggestion{Action: "BUY"},
+		}
+	}
+
+	t.Run("suppress", func(t *testing.T) {
+		useEvents(t, events, DefaultWindow())
+		prediction := newPrediction()
+		Apply(prediction, candles, "EUR/USD", "1h")
+		if prediction.EventRisk == nil || !prediction.EventRisk.Blackout || !prediction.EventRisk.Suppressed {
+			t.Fatalf("EventRisk = %+v, want suppressed blackout", prediction.EventRisk)
+		}
+		if prediction.TradingSuggestion.Action != "NO_TRADE" {
+			t.Errorf("Action = %q, want NO_TRADE", prediction.TradingSuggestion.Action)
+		}
+	})
+
+	t.Run("flag only", func(t *testing.T) {
+		window := DefaultWindow()
+		window.Suppress = false
+		useEvents(t, events, window)
+		prediction := newPrediction()
+		Apply(prediction, candles, "EUR/USD", "1h")
+		if prediction.EventRisk == nil || !prediction.EventRisk.Blackout || prediction.EventRisk.Suppressed {
+			t.Fatalf("EventRisk = %+v, want unsuppressed blackout", prediction.EventRisk)
+		}
+		if prediction.TradingSuggestion.Action != "BUY" {
+			t.Errorf("Action = %q, want BUY", prediction.TradingSuggestion.Action)
+		}
+	})
+
+	t.Run("outside window", func(t *testing.T) {
+		useEvents(t, events, DefaultWindow())
+		prediction := newPrediction()
+		Apply(prediction, candles, "GBP/JPY", "1h")
+		if prediction.EventRisk == nil || prediction.EventRisk.Blackout {
+			t.Fatalf("EventRisk = %+v, want no blackout", prediction.EventRisk)
+		}
+		if prediction.TradingSuggestion.Action != "BUY" {
+			t.Errorf("Action = %q, want BUY", prediction.TradingSuggestion.Action)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package calendar
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// csvTimeLayouts - поддерживаемые форматы времени в CSV; время без зоны считается UTC
+var csvTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// ParseCSV читает календарь из CSV с заголовком. Обязательные колонки: time (или date и time),
+// currency, impact, title (или event); необязательные: forecast, previous.
+// Строки с неизвестной валютой или важностью пропускаются
+func ParseCSV(r io.Reader) ([]models.EconomicEvent, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading calendar header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		if i, ok := columns["event"]; ok {
+			columns["title"] = i
+		}
+	}
+	for _, required := range []string{"time", "currency", "impact", "title"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("calendar CSV has no %q column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var events []models.EconomicEvent
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading calendar line %d: %w", line, err)
+		}
+
+		value := field(record, "time")
+		if date := field(record, "date"); date != "" {
+			value = date + " " + value
+		}
+		at, err := parseCSVTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("calendar line %d: %w", line, err)
+		}
+
+		event := models.EconomicEvent{
+			Time:     at,
+			Currency: strings.ToUpper(field(record, "currency")),
+			Impact:   ParseImpact(field(record, "impact")),
+			Title:    field(record, "title"),
+			Forecast: field(record, "forecast"),
+			Previous: field(record, "previous"),
+		}
+		if len(event.Currency) != 3 || event.Impact == "" {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func parseCSVTime(value string) (time.Time, error) {
+	for _, layout := range csvTimeLayouts {
+		if at, err := time.Parse(layout, value); err == nil {
+			return at.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// summaryCurrency выделяет код валюты в начале названия: "USD Nonfarm Payrolls",
+// "[USD] CPI", "USD: Retail Sales", "USD - GDP"
+var summaryCurrency = regexp.MustCompile(`^\[?([A-Z]{3})\]?\s*[:\-–]?\s+(.+)$`)
+
+// ParseICS читает календарь iCalendar. Валюта берется из CATEGORIES или из кода в начале
+// SUMMARY, важность - из X-IMPACT, CATEGORIES, PRIORITY (1-4 высокая, 5 средняя, 6-9 низкая)
+// или DESCRIPTION вида "Impact: High". События без валюты или важности пропускаются
+func ParseICS(r io.Reader) ([]models.EconomicEvent, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading calendar: %w", err)
+	}
+
+	var events []models.EconomicEvent
+	var properties map[string]icsProperty
+	for _, line := range lines {
+		switch line {
+		case "BEGIN:VEVENT":
+			properties = make(map[string]icsProperty)
+			continue
+		case "END:VEVENT":
+			if event, ok := icsEvent(properties); ok {
+				events = append(events, event)
+			}
+			properties = nil
+			continue
+		}
+		if properties == nil {
+			continue
+		}
+
+		nameParams, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(nameParams, ";")
+		properties[strings.ToUpper(name)] = icsProperty{params: params, value: icsUnescape(value)}
+	}
+	return events, nil
+}
+
+type icsProperty struct {
+	params string
+	value  string
+}
+
+// unfoldICS склеивает перенесенные строки: продолжение начинается с пробела или табуляции
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func icsUnescape(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+func icsEvent(properties map[string]icsProperty) (models.EconomicEvent, bool) {
+	start, ok := properties["DTSTART"]
+	if !ok {
+		return models.EconomicEvent{}, false
+	}
+	at, err := parseICSTime(start)
+	if err != nil {
+		return models.EconomicEvent{}, false
+	}
+
+	event := models.EconomicEvent{Time: at, Title: strings.TrimSpace(properties["SUMMARY"].value)}
+
+	var categories []string
+	for _, category := range strings.Split(properties["CATEGORIES"].value, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			categories = append(categories, category)
+		}
+	}
+	for _, category := range categories {
+		if len(category) == 3 && strings.ToUpper(category) == category {
+			event.Currency = category
+		} else if impact := ParseImpact(category); impact != "" {
+			event.Impact = impact
+		}
+	}
+	if match := summaryCurrency.FindStringSubmatch(event.Title); match != nil {
+		if event.Currency == "" || event.Currency == match[1] {
+			event.Currency = match[1]
+			event.Title = match[2]
+		}
+	}
+
+	if impact := ParseImpact(properties["X-IMPACT"].value); impact != "" {
+		event.Impact = impact
+	}
+	if event.Impact == "" {
+		if priority, err := strconv.Atoi(properties["PRIORITY"].value); err == nil && priority > 0 {
+			switch {
+			case priority <= 4:
+				event.Impact = ImpactHigh
+			case priority == 5:
+				event.Impact = ImpactMedium
+			default:
+				event.Impact = ImpactLow
+			}
+		}
+	}
+	if event.Impact == "" {
+		description := strings.ToLower(properties["DESCRIPTION"].value)
+		if _, rest, ok := strings.Cut(description, "impact:"); ok {
+			if fields := strings.Fields(rest); len(fields) > 0 {
+				event.Impact = ParseImpact(fields[0])
+			}
+		}
+	}
+
+	return event, event.Currency != "" && event.Impact != ""
+}
+
+// parseICSTime разбирает DTSTART: UTC (суффикс Z), локальное время с TZID или дату
+func parseICSTime(property icsProperty) (time.Time, error) {
+	value := property.value
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	if len(value) == len("20060102") {
+		return time.Parse("20060102", value)
+	}
+
+	location := time.UTC
+	for _, param := range strings.Split(property.params, ";") {
+		if tzid, ok := strings.CutPrefix(param, "TZID="); ok {
+			if loaded, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+				location = loaded
+			}
+		}
+	}
+	at, err := time.ParseInLocation("20060102T150405", value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return at.UTC(), nil
+}
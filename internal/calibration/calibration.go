@@ -0,0 +1,462 @@
+package calibration
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	MethodPlatt    = "PLATT"
+	MethodIsotonic = "ISOTONIC"
+
+	// minSamples - минимальное число проверенных прогнозов для обучения калибратора
+	minSamples = 20
+
+	// minIsotonicSamples - меньше наблюдений изотоническая регрессия переобучается
+	minIsotonicSamples = 50
+
+	// trainShare - доля ранних прогнозов для обучения, остальные - для проверки
+	trainShare = 0.7
+
+	// reliabilityBins - число корзин диаграммы надежности
+	reliabilityBins = 10
+
+	// Вероятности не доводятся до 0 и 1: на малой выборке это переуверенность
+	minProbability = 0.01
+	maxProbability = 0.99
+)
+
+// Sample - проверенный прогноз: счет и то, оказалось ли направление верным
+type Sample struct {
+	Score   float64
+	Correct bool
+}
+
+// registry хранит калибраторы по ключу стратегия/символ/таймфрейм
+var registry = struct {
+	mu          sync.RWMutex
+	calibrators map[string]*models.Calibrator
+}{
+	calibrators: make(map[string]*models.Calibrator),
+}
+
+// SamplesFromResults извлекает выборку из направленных прогнозов бэктеста в хронологическом порядке
+func SamplesFromResults(results []models.PredictionResult) []Sample {
+	ordered := make([]models.PredictionResult, 0, len(results))
+	for _, result := range results {
+		if result.Direction == "NEUTRAL" || result.ActualOutcome == "" || result.ActualOutcome == "NEUTRAL" {
+			continue
+		}
+		ordered = append(ordered, result)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Timestamp.Before(ordered[j].Timestamp) })
+
+	samples := make([]Sample, len(ordered))
+	for i, result := range ordered {
+		samples[i] = Sample{Score: result.Score, Correct: result.WasCorrect}
+	}
+	return samples
+}
+
+// FitPlatt обучает логистическую регрессию вероятности верного направления по |score|
+// методом Ньютона с целевыми значениями Платта, сглаженными против переобучения
+func FitPlatt(samples []Sample) (*models.Calibrator, error) {
+	if len(samples) < minSamples {
+		return nil, fmt.Errorf("insufficient samples for calibration: need %d, got %d", minSamples, len(samples))
+	}
+
+	positives := 0
+	for _, s := range samples {
+		if s.Correct {
+			positives++
+		}
+	}
+	negatives := len(samples) - positives
+	highTarget := (float64(positives) + 1) / (float64(positives) + 2)
+	lowTarget := 1 / (float64(negatives) + 2)
+
+	var a, b float64
+	for iter := 0; iter < 100; iter++ {
+		// Градиент и гессиан логарифмической функции потерь с небольшой L2-регуляризацией
+		var gA, gB, hAA, hAB, hBB float64
+		for _, s := range samples {
+			x := math.Abs(s.Score)
+			target := lowTarget
+			if s.Correct {
+				target = highTarget
+			}
+			p := sigmoid(a*x + b)
+			diff := p - target
+			weight := p * (1 - p)
+			gA += diff * x
+			gB += diff
+			hAA += weight * x * x
+			hAB += weight * x
+			hBB += weight
+		}
+		gA += 1e-3 * a
+		hAA += 1e-3
+		hBB += 1e-9
+
+		det := hAA*hBB - hAB*hAB
+		if det <= 0 {
+			break
+		}
+		stepA := (hBB*gA - hAB*gB) / det
+		stepB := (hAA*gB - hAB*gA) / det
+		a -= stepA
+		b -= stepB
+		if math.Abs(stepA) < 1e-8 && math.Abs(stepB) < 1e-8 {
+			break
+		}
+	}
+
+	return &models.Calibrator{
+		Method:    MethodPlatt,
+		PlattA:    a,
+		PlattB:    b,
+		Samples:   len(samples),
+		BaseRate:  float64(positives) / float64(len(samples)),
+		TrainedAt: time.Now(),
+	}, nil
+}
+
+// FitIsotonic обучает монотонно неубывающую зависимость вероятности от |score|
+// алгоритмом PAV (pool adjacent violators)
+func FitIsotonic(samples []Sample) (*models.Calibrator, error) {
+	if len(samples) < minSamples {
+		return nil, fmt.Errorf("insufficient samples for calibration: need %d, got %d", minSamples, len(samples))
+	}
+
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return math.Abs(sorted[i].Score) < math.Abs(sorted[j].Score) })
+
+	type block struct {
+		sumX, sumY, weight float64
+	}
+	var blocks []block
+	positives := 0
+	for _, s := range sorted {
+		y := 0.0
+		if s.Correct {
+			y = 1
+			positives++
+		}
+		blocks = append(blocks, block{sumX: math.Abs(s.Score), sumY: y, weight: 1})
+
+		// Объединяем соседние блоки, пока нарушена монотонность
+		for len(blocks) > 1 {
+			last, prev := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if prev.sumY/prev.weight <= last.sumY/last.weight {
+				break
+			}
+			blocks = blocks[:len(blocks)-2]
+			blocks = append(blocks, block{
+				sumX:   prev.sumX + last.sumX,
+				sumY:   prev.sumY + last.sumY,
+				weight: prev.weight + last.weight,
+			})
+		}
+	}
+
+	calibrator := &models.Calibrator{
+		Method:    MethodIsotonic,
+		Samples:   len(samples),
+		BaseRate:  float64(positives) / float64(len(samples)),
+		TrainedAt: time.Now(),
+	}
+	for _, b := range blocks {
+		calibrator.Knots = append(calibrator.Knots, b.sumX/b.weight)
+		calibrator.Values = append(calibrator.Values, b.sumY/b.weight)
+	}
+
+	return calibrator, nil
+}
+
+// Probability возвращает откалиброванную вероятность верного направления для счета
+func Probability(c *models.Calibrator, score float64) float64 {
+	x := math.Abs(score)
+
+	var p float64
+	switch c.Method {
+	case MethodPlatt:
+		p = sigmoid(c.PlattA*x + c.PlattB)
+	case MethodIsotonic:
+		p = interpolate(c.Knots, c.Values, x)
+	default:
+		p = c.BaseRate
+	}
+
+	return math.Max(minProbability, math.Min(maxProbability, p))
+}
+
+// interpolate линейно интерполирует значение между узлами, за пределами - крайние значения
+func interpolate(knots, values []float64, x float64) float64 {
+	if len(knots) == 0 {
+		return 0.5
+	}
+	if x <= knots[0] {
+		return values[0]
+	}
+	last := len(knots) - 1
+	if x >= knots[last] {
+		return values[last]
+	}
+
+	i := sort.SearchFloat64s(knots, x)
+	left, right := knots[i-1], knots[i]
+	if right == left {
+		return values[i]
+	}
+	weight := (x - left) / (right - left)
+	return values[i-1] + weight*(values[i]-values[i-1])
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// Fit обучает калибратор на хронологически упорядоченной выборке: оба метода обучаются
+// на первых 70% прогнозов, лучший по Брайеру на оставшихся 30% переобучается на всей
+// выборке. Отчет содержит диаграмму надежности на отложенной части
+func Fit(samples []Sample) (*models.CalibrationReport, error) {
+	// Обучающая часть тоже должна содержать не меньше minSamples прогнозов
+	required := int(math.Ceil(minSamples / trainShare))
+	if len(samples) < required {
+		return nil, fmt.Errorf("insufficient samples for calibration: need %d, got %d", required, len(samples))
+	}
+
+	split := int(float64(len(samples)) * trainShare)
+	train, test := samples[:split], samples[split:]
+
+	candidates := []func([]Sample) (*models.Calibrator, error){FitPlatt}
+	if len(train) >= minIsotonicSamples {
+		candidates = append(candidates, FitIsotonic)
+	}
+
+	var (
+		best      *models.CalibrationReport
+		bestFit   func([]Sample) (*models.Calibrator, error)
+		trainRate = baseRate(train)
+	)
+	for _, fit := range candidates {
+		calibrator, err := fit(train)
+		if err != nil {
+			return nil, err
+		}
+
+		probabilities := make([]float64, len(test))
+		outcomes := make([]bool, len(test))
+		for i, s := range test {
+			probabilities[i] = Probability(calibrator, s.Score)
+			outcomes[i] = s.Correct
+		}
+
+		report := Evaluate(probabilities, outcomes)
+		report.Method = calibrator.Method
+		report.TrainSamples = len(train)
+		report.BaselineBrier = constantBrier(trainRate, test)
+		if best == nil || report.BrierScore < best.BrierScore {
+			best, bestFit = report, fit
+		}
+	}
+
+	calibrator, err := bestFit(samples)
+	if err != nil {
+		return nil, err
+	}
+	calibrator.BrierScore = best.BrierScore
+	best.Method = calibrator.Method
+	best.Calibrator = calibrator
+
+	return best, nil
+}
+
+// Evaluate строит диаграмму надежности, оценку Брайера и ожидаемую ошибку калибровки
+func Evaluate(probabilities []float64, outcomes []bool) *models.CalibrationReport {
+	report := &models.CalibrationReport{
+		TestSamples: len(probabilities),
+		Bins:        make([]models.ReliabilityBin, reliabilityBins),
+	}
+
+	sums := make([]float64, reliabilityBins)
+	hits := make([]float64, reliabilityBins)
+	for i := range report.Bins {
+		report.Bins[i].Lower = float64(i) / reliabilityBins
+		report.Bins[i].Upper = float64(i+1) / reliabilityBins
+	}
+
+	for i, p := range probabilities {
+		y := 0.0
+		if outcomes[i] {
+			y = 1
+		}
+		report.BrierScore += (p - y) * (p - y)
+
+		bin := int(p * reliabilityBins)
+		if bin >= reliabilityBins {
+			bin = reliabilityBins - 1
+		}
+		report.Bins[bin].Count++
+		sums[bin] += p
+		hits[bin] += y
+	}
+
+	if len(probabilities) == 0 {
+		return report
+	}
+	report.BrierScore /= float64(len(probabilities))
+
+	for i := range report.Bins {
+		count := float64(report.Bins[i].Count)
+		if count == 0 {
+			continue
+		}
+		report.Bins[i].MeanPredicted = sums[i] / count
+		report.Bins[i].ObservedRate = hits[i] / count
+		report.ECE += count / float64(len(probabilities)) *
+			math.Abs(report.Bins[i].MeanPredicted-report.Bins[i].ObservedRate)
+	}
+
+	return report
+}
+
+func baseRate(samples []Sample) float64 {
+	if len(samples) == 0 {
+		return 0.5
+	}
+	positives := 0
+	for _, s := range samples {
+		if s.Correct {
+			positives++
+		}
+	}
+	return float64(positives) / float64(len(samples))
+}
+
+// constantBrier - оценка Брайера прогноза, всегда равного базовой частоте обучающей выборки
+func constantBrier(rate float64, samples []Sample) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var total float64
+	for _, s := range samples {
+		y := 0.0
+		if s.Correct {
+			y = 1
+		}
+		total += (rate - y) * (rate - y)
+	}
+	return total / float64(len(samples))
+}
+
+// FileName возвращает имя файла калибратора для стратегии, символа и таймфрейма
+func FileName(strategy, symbol, interval string) string {
+	return HorizonFileName(strategy, symbol, interval, "")
+}
+
+// HorizonFileName возвращает имя файла калибратора прогнозов на горизонт horizon;
+// пустой горизонт соответствует следующей свече
+func HorizonFileName(strategy, symbol, interval, horizon string) string {
+	if horizon != "" {
+		interval += "_" + horizon
+	}
+	return fmt.Sprintf("calibration_%s_%s_%s.json", strategy, strings.ReplaceAll(symbol, "/", ""), interval)
+}
+
+// Save сохраняет калибратор в каталог dir
+func Save(dir string, c *models.Calibrator) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating calibration directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding calibrator: %w", err)
+	}
+
+	path := filepath.Join(dir, HorizonFileName(c.Strategy, c.Symbol, c.Interval, c.Horizon))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing calibrator: %w", err)
+	}
+
+	return nil
+}
+
+// Load читает калибратор из файла
+func Load(path string) (*models.Calibrator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading calibrator: %w", err)
+	}
+
+	var c models.Calibrator
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing calibrator %s: %w", path, err)
+	}
+	if c.Method == MethodIsotonic && len(c.Knots) != len(c.Values) {
+		return nil, fmt.Errorf("calibrator %s: knots and values differ in length", path)
+	}
+
+	return &c, nil
+}
+
+// LoadDir загружает все калибраторы из каталога
+func LoadDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "calibration_*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing calibrators: %w", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		c, err := Load(file)
+		if err != nil {
+			return loaded, err
+		}
+		Register(c)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// Register регистрирует калибратор для использования в прогнозах
+func Register(c *models.Calibrator) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.calibrators[HorizonFileName(c.Strategy, c.Symbol, c.Interval, c.Horizon)] = c
+}
+
+// Get возвращает калибратор для стратегии, символа и таймфрейма
+func Get(strategy, symbol, interval string) (*models.Calibrator, bool) {
+	return GetHorizon(strategy, symbol, interval, "")
+}
+
+// GetHorizon возвращает калибратор прогнозов на горизонт horizon
+func GetHorizon(strategy, symbol, interval, horizon string) (*models.Calibrator, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	c, ok := registry.calibrators[HorizonFileName(strategy, symbol, interval, horizon)]
+	return c, ok
+}
+
+// Apply проставляет прогнозу откалиброванную вероятность, если калибратор обучен
+func Apply(prediction *models.Prediction, symbol, interval string) {
+	c, ok := Get(prediction.Strategy, symbol, interval)
+	if !ok {
+		return
+	}
+	prediction.Probability = Probability(c, prediction.Score)
+	prediction.CalibrationMethod = c.Method
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Alias1177/Predictor/models"
+	"github.com/joho/godotenv"
+)
+
+var (
+	// AllSymbols - все инструменты бота
+	AllSymbols = []string{
+		"EUR/USD", "GBP/USD", "USD/JPY", "AUD/USD",
+		"USD/CAD", "USD/CHF", "NZD/USD", "EUR/GBP",
+		"EUR/JPY", "GBP/JPY", "AUD/CAD", "EUR/CAD",
+		"XBR/USD", "XAU/USD", "XAG/USD",
+		"ETH/USD", "SOL/USD", "XRP/USD", "ADA/USD",
+		"AAVE/USD", "BNB/USD", "DOT/USD", "BTC/USD",
+	}
+
+	// AllIntervals - все таймфреймы бота
+	AllIntervals = []string{
+		"1min", "5min", "15min", "30min", "1h", "4h",
+	}
+
+	// CoreSymbols и CoreIntervals - основной набор для обучения моделей, требующих
+	// прогона стратегий по истории
+	CoreSymbols = []string{
+		"EUR/USD", "GBP/USD", "USD/JPY", "AUD/USD",
+		"USD/CAD", "USD/CHF", "NZD/USD", "EUR/GBP",
+		"XAU/USD", "BTC/USD",
+	}
+	CoreIntervals = []string{
+		"5min", "15min", "1h",
+	}
+)
+
+// LoadEnv загружает .env, если он есть
+func LoadEnv() {
+	if err := godotenv.Load(); err != nil {
+		log.Println(".env file not found, relying on actual environment variables")
+	}
+}
+
+// APIKey возвращает ключ Twelve Data и завершает программу, если он обязателен и не задан
+func APIKey(required bool) string {
+	apiKey := os.Getenv("TWELVE_API_KEY")
+	if apiKey == "" && required {
+		log.Fatal("TWELVE_API_KEY not set in environment")
+	}
+	return apiKey
+}
+
+// String возвращает строковую переменную окружения или значение по умолчанию
+func String(key, defaultVal string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultVal
+}
+
+// List возвращает список через запятую из переменной окружения или значение по умолчанию
+func List(key string, defaultVal []string) []string {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultVal
+	}
+
+	var values []string
+	for _, v := range strings.Split(valueStr, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Int возвращает целую переменную окружения или значение по умолчанию
+func Int(key string, defaultVal int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultVal
+	}
+	return value
+}
+
+// Float возвращает дробную переменную окружения или значение по умолчанию
+func Float(key string, defaultVal float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultVal
+	}
+	return value
+}
+
+// Bool возвращает логическую переменную окружения (true, 1 или yes) или значение по умолчанию
+func Bool(key string, defaultVal bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultVal
+	}
+	return value == "true" || value == "1" || value == "yes"
+}
+
+// Holdout отбрасывает свечи последних days дней истории, чтобы модель не видела отрезок,
+// на котором ensembletrain собирает голоса участников вне обучающей выборки
+func Holdout(candles []models.Candle, days int) []models.Candle {
+	if days <= 0 || len(candles) == 0 {
+		return candles
+	}
+	cutoff := candles[len(candles)-1].Timestamp.AddDate(0, 0, -days)
+	end := sort.Search(len(candles), func(i int) bool { return candles[i].Timestamp.After(cutoff) })
+	return candles[:end]
+}
+
+// Config возвращает конфигурацию инструмента с параметрами индикаторов как в боте
+// (те же переменные окружения и значения по умолчанию), чтобы модели обучались на тех же
+// признаках, что используются в прогнозах
+func Config(apiKey, symbol, interval string) *models.Config {
+	return &models.Config{
+		TwelveAPIKey:      apiKey,
+		Symbol:            symbol,
+		Interval:          interval,
+		CandleCount:       Int("CANDLE_COUNT", 42),
+		RSIPeriod:         Int("RSI_PERIOD", 11),
+		MACDFastPeriod:    Int("MACD_FAST_PERIOD", 3),
+		MACDSlowPeriod:    Int("MACD_SLOW_PERIOD", 11),
+		MACDSignalPeriod:  Int("MACD_SIGNAL_PERIOD", 3),
+		BBPeriod:          Int("BB_PERIOD", 19),
+		BBStdDev:          Float("BB_STD_DEV", 3.4),
+		EMAPeriod:         Int("EMA_PERIOD", 7),
+		ADXPeriod:         Int("ADX_PERIOD", 28),
+		ATRPeriod:         Int("ATR_PERIOD", 10),
+		RequestTimeout:    30,
+		AdaptiveIndicator: Bool("ADAPTIVE_INDICATOR", true),
+	}
+}
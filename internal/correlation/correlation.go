@@ -0,0 +1,320 @@
+package correlation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// DefaultWindows - окна корреляции в свечах: текущее состояние, среднее и фон
+var DefaultWindows = []int{20, 50, 100}
+
+// ParseWindows разбирает список окон через запятую: "20,50,100"
+func ParseWindows(value string) ([]int, error) {
+	var windows []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		window, err := strconv.Atoi(field)
+		if err != nil || window < 2 {
+			return nil, fmt.Errorf("invalid correlation window %q", field)
+		}
+		windows = append(windows, window)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no correlation windows in %q", value)
+	}
+	return windows, nil
+}
+
+// Пороги разрыва корреляции: пара, устойчиво связанная на длинном окне
+// (|ρ| >= breakdownLong), на коротком окне отошла от нее на breakdownChange и больше
+const (
+	breakdownLong   = 0.5
+	breakdownChange = 0.5
+)
+
+// minSamplesShare - доля окна, которую должны покрывать общие доходности пары;
+// иначе корреляция не считается (например, криптовалюта против валюты в выходные)
+const minSamplesShare = 0.6
+
+// Compute строит матрицы корреляций на окнах windows по свечам series. Доходности каждой
+// пары инструментов выравниваются попарно по общим отметкам времени, поэтому торговля
+// криптовалют в выходные не выбрасывает будние свечи валютных пар
+func Compute(interval string, series map[string][]models.Candle, windows []int) *models.CorrelationSnapshot {
+	symbols := make([]string, 0, len(series))
+	for symbol := range series {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	sortedWindows := append([]int(nil), windows...)
+	sort.Ints(sortedWindows)
+
+	snapshot := &models.CorrelationSnapshot{Interval: interval, UpdatedAt: time.Now()}
+	for _, window := range sortedWindows {
+		matrix := models.CorrelationMatrix{
+			Window:  window,
+			Symbols: symbols,
+			Values:  make([][]float64, len(symbols)),
+			Samples: make([][]int, len(symbols)),
+		}
+		for i := range symbols {
+			matrix.Values[i] = make([]float64, len(symbols))
+			matrix.Samples[i] = make([]int, len(symbols))
+		}
+
+		for i := range symbols {
+			matrix.Values[i][i] = 1
+			for j := i + 1; j < len(symbols); j++ {
+				a, b := alignedReturns(series[symbols[i]], series[symbols[j]], window)
+				matrix.Samples[i][j], matrix.Samples[j][i] = len(a), len(a)
+				if float64(len(a)) < float64(window)*minSamplesShare {
+					continue
+				}
+				rho := pearson(a, b)
+				matrix.Values[i][j], matrix.Values[j][i] = rho, rho
+			}
+		}
+		snapshot.Matrices = append(snapshot.Matrices, matrix)
+	}
+
+	snapshot.Breakdowns = breakdowns(snapshot)
+	return snapshot
+}
+
+// alignedReturns возвращает последние window пар логарифмических доходностей двух рядов,
+// у которых обе соседние свечи есть в обоих рядах
+func alignedReturns(a, b []models.Candle, window int) ([]float64, []float64) {
+	closes := make(map[time.Time]float64, len(b))
+	for _, candle := range b {
+		closes[candle.Timestamp] = candle.Close
+	}
+
+	var ra, rb []float64
+	for i := 1; i < len(a); i++ {
+		prevB, okPrev := closes[a[i-1].Timestamp]
+		currB, okCurr := closes[a[i].Timestamp]
+		if !okPrev || !okCurr || prevB <= 0 || currB <= 0 || a[i-1].Close <= 0 || a[i].Close <= 0 {
+			continue
+		}
+		ra = append(ra, math.Log(a[i].Close/a[i-1].Close))
+		rb = append(rb, math.Log(currB/prevB))
+	}
+
+	if len(ra) > window {
+		ra, rb = ra[len(ra)-window:], rb[len(rb)-window:]
+	}
+	return ra, rb
+}
+
+// pearson - коэффициент корреляции Пирсона; 0, если у ряда нет разброса
+func pearson(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return 0
+	}
+
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// breakdowns сравнивает самое короткое и самое длинное окно снимка и возвращает пары,
+// корреляция которых разошлась, по убыванию изменения
+func breakdowns(snapshot *models.CorrelationSnapshot) []models.CorrelationBreakdown {
+	if len(snapshot.Matrices) < 2 {
+		return nil
+	}
+	short, long := snapshot.Matrices[0], snapshot.Matrices[len(snapshot.Matrices)-1]
+	minShort := int(float64(short.Window) * minSamplesShare)
+	minLong := int(float64(long.Window) * minSamplesShare)
+
+	var result []models.CorrelationBreakdown
+	for i := range long.Symbols {
+		for j := i + 1; j < len(long.Symbols); j++ {
+			if short.Samples[i][j] < minShort || long.Samples[i][j] < minLong {
+				continue
+			}
+			shortRho, longRho := short.Values[i][j], long.Values[i][j]
+			if math.Abs(longRho) < breakdownLong || math.Abs(shortRho-longRho) < breakdownChange {
+				continue
+			}
+			result = append(result, models.CorrelationBreakdown{
+				SymbolA:     long.Symbols[i],
+				SymbolB:     long.Symbols[j],
+				ShortWindow: short.Window,
+				LongWindow:  long.Window,
+				Short:       shortRho,
+				Long:        longRho,
+				Change:      shortRho - longRho,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return math.Abs(result[i].Change) > math.Abs(result[j].Change) })
+	return result
+}
+
+// Related возвращает корреляции инструмента symbol с остальными на всех окнах снимка,
+// по убыванию |ρ| на самом коротком окне, то есть по тому, насколько пары связаны сейчас
+func Related(snapshot *models.CorrelationSnapshot, symbol string) []models.PairCorrelation {
+	if snapshot == nil || len(snapshot.Matrices) == 0 {
+		return nil
+	}
+	symbols := snapshot.Matrices[0].Symbols
+	row := sort.SearchStrings(symbols, symbol)
+	if row == len(symbols) || symbols[row] != symbol {
+		return nil
+	}
+
+	broken := make(map[string]bool)
+	for _, breakdown := range snapshot.Breakdowns {
+		if breakdown.SymbolA == symbol {
+			broken[breakdown.SymbolB] = true
+		} else if breakdown.SymbolB == symbol {
+			broken[breakdown.SymbolA] = true
+		}
+	}
+
+	var related []models.PairCorrelation
+	for j, other := range symbols {
+		if j == row || snapshot.Matrices[0].Samples[row][j] == 0 {
+			continue
+		}
+		pair := models.PairCorrelation{Symbol: other, Breakdown: broken[other]}
+		for _, matrix := range snapshot.Matrices {
+			pair.Correlations = append(pair.Correlations, matrix.Values[row][j])
+		}
+		related = append(related, pair)
+	}
+	sort.SliceStable(related, func(i, j int) bool {
+		return math.Abs(related[i].Correlations[0]) > math.Abs(related[j].Correlations[0])
+	})
+	return related
+}
+
+// Risk оценивает концентрацию риска инструмента (0-1): среднее |ρ| с остальными
+// инструментами на самом длинном окне последнего снимка таймфрейма
+func Risk(symbol, interval string) float64 {
+	snapshot, ok := Get(interval)
+	if !ok {
+		return 0
+	}
+	related := Related(snapshot, symbol)
+	if len(related) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, pair := range related {
+		total += math.Abs(pair.Correlations[len(pair.Correlations)-1])
+	}
+	return total / float64(len(related))
+}
+
+// snapshots - последние снимки по таймфреймам
+var snapshots = struct {
+	mu         sync.RWMutex
+	byInterval map[string]*models.CorrelationSnapshot
+}{byInterval: make(map[string]*models.CorrelationSnapshot)}
+
+// Register сохраняет снимок для его таймфрейма
+func Register(snapshot *models.CorrelationSnapshot) {
+	snapshots.mu.Lock()
+	defer snapshots.mu.Unlock()
+	snapshots.byInterval[snapshot.Interval] = snapshot
+}
+
+// Get возвращает последний снимок для таймфрейма
+func Get(interval string) (*models.CorrelationSnapshot, bool) {
+	snapshots.mu.RLock()
+	defer snapshots.mu.RUnlock()
+	snapshot, ok := snapshots.byInterval[interval]
+	return snapshot, ok
+}
+
+// Update загружает свечи symbols на таймфрейме cfg.Interval (ключ API и таймаут берутся из cfg),
+// строит снимок и регистрирует его. Инструменты, свечи которых не загрузились, пропускаются
+func Update(ctx context.Context, cfg *models.Config, symbols []string, windows []int) (*models.CorrelationSnapshot, error) {
+	longest := 0
+	for _, window := range windows {
+		longest = max(longest, window)
+	}
+
+	series := make(map[string][]models.Candle, len(symbols))
+	var lastErr error
+	for _, symbol := range symbols {
+		symbolCfg := *cfg
+		symbolCfg.Symbol = symbol
+		symbolCfg.CandleCount = longest + 1
+		candles, err := config.NewClient(&symbolCfg).GetCandles(ctx)
+		if err != nil {
+			lastErr = fmt.Errorf("fetching %s: %w", symbol, err)
+			continue
+		}
+		series[symbol] = candles
+	}
+	if len(series) < 2 {
+		if lastErr == nil {
+			return nil, fmt.Errorf("not enough symbols for a correlation matrix: got %d", len(series))
+		}
+		return nil, fmt.Errorf("not enough symbols for a correlation matrix: %w", lastErr)
+	}
+
+	snapshot := Compute(cfg.Interval, series, windows)
+	Register(snapshot)
+	return snapshot, nil
+}
+
+// Refresh периодически пересчитывает снимки для таймфреймов intervals. При ошибке
+// остается прежний снимок; onUpdate вызывается после каждой попытки
+func Refresh(ctx context.Context, cfg *models.Config, symbols, intervals []string, windows []int,
+	every time.Duration, onUpdate func(string, *models.CorrelationSnapshot, error)) {
+	update := func() {
+		for _, interval := range intervals {
+			intervalCfg := *cfg
+			intervalCfg.Interval = interval
+			snapshot, err := Update(ctx, &intervalCfg, symbols, windows)
+			if onUpdate != nil {
+				onUpdate(interval, snapshot, err)
+			}
+		}
+	}
+
+	update()
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			update()
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package costs
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/internal/instrument"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// defaultSpreads - типичный спред в пунктах на счетах без комиссии
+var defaultSpreads = map[string]float64{
+	"EUR/USD": 1.0, "USD/JPY": 1.2, "GBP/USD": 1.4, "USD/CHF": 1.6, "AUD/USD": 1.4,
+	"USD/CAD": 1.8, "NZD/USD": 1.9, "EUR/GBP": 1.6, "EUR/JPY": 1.9, "GBP/JPY": 2.8,
+	"XAU/USD": 3.0, "XAG/USD": 3.0, "XBR/USD": 4.0,
+	"BTC/USD": 30, "ETH/USD": 20,
+}
+
+// lotSizes - размер лота для инструментов, у которых он не равен 100000 единиц
+var lotSizes = map[string]float64{
+	"XAU": 100, "XAG": 5000, "XBR": 1000,
+	"BTC": 1, "ETH": 1, "BNB": 1, "AAVE": 1, "SOL": 1, "DOT": 1, "XRP": 1, "ADA": 1,
+}
+
+// Ролловер валютного рынка в 17:00 по Нью-Йорку; в первые часы после него
+// ликвидность минимальна и спред у валютных пар расширяется
+const (
+	rolloverHour          = 17
+	rolloverSpreadFactor  = 3
+	rolloverSpreadFromUTC = 21
+	rolloverSpreadToUTC   = 23
+)
+
+// Default возвращает встроенный профиль инструмента: типичный спред без комиссии,
+// расширение спреда на ролловере для валютных пар и проскальзывание рыночных исполнений.
+// Свопы зависят от брокера и ставок, поэтому по умолчанию не начисляются
+func Default(symbol string) models.CostProfile {
+	symbol = strings.ToUpper(symbol)
+	base, _, _ := strings.Cut(symbol, "/")
+	// Криптовалюты торгуются без выходных, своп начисляется каждую ночь
+	crypto := instrument.Class(symbol) == instrument.ClassCrypto
+
+	profile := models.CostProfile{
+		Symbol:        symbol,
+		SpreadPips:    2.0,
+		LotSize:       100000,
+		SlippagePips:  0.2,
+		SlippageRange: 0.05,
+		DailySwap:     crypto,
+	}
+	if spread, ok := defaultSpreads[symbol]; ok {
+		profile.SpreadPips = spread
+	} else if crypto {
+		profile.SpreadPips = 10
+	}
+	if size, ok := lotSizes[base]; ok {
+		profile.LotSize = size
+	} else {
+		profile.SessionSpreads = []models.SpreadWindow{{
+			FromHour:   rolloverSpreadFromUTC,
+			ToHour:     rolloverSpreadToUTC,
+			SpreadPips: profile.SpreadPips * rolloverSpreadFactor,
+		}}
+	}
+	return profile
+}
+
+// profiles - профили, загруженные из файла
+var profiles = struct {
+	mu       sync.RWMutex
+	bySymbol map[string]models.CostProfile
+}{bySymbol: make(map[string]models.CostProfile)}
+
+// Set заменяет загруженные профили
+func Set(list []models.CostProfile) {
+	bySymbol := make(map[string]models.CostProfile, len(list))
+	for _, profile := range list {
+		bySymbol[strings.ToUpper(profile.Symbol)] = profile
+	}
+
+	profiles.mu.Lock()
+	defer profiles.mu.Unlock()
+	profiles.bySymbol = bySymbol
+}
+
+// For возвращает профиль инструмента: загруженный или встроенный
+func For(symbol string) models.CostProfile {
+	profiles.mu.RLock()
+	profile, ok := profiles.bySymbol[strings.ToUpper(symbol)]
+	profiles.mu.RUnlock()
+	if ok {
+		return profile
+	}
+	return Default(symbol)
+}
+
+// Load читает профили из JSON-массива. Отсутствующие в файле поля берутся из Default
+// для инструмента, поэтому файл может переопределять, например, только свопы
+func Load(path string) ([]models.CostProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cost profiles: %w", err)
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing cost profiles %s: %w", path, err)
+	}
+
+	list := make([]models.CostProfile, 0, len(raw))
+	for i, entry := range raw {
+		var header struct {
+			Symbol string `json:"symbol"`
+		}
+		if err := json.Unmarshal(entry, &header); err != nil || header.Symbol == "" {
+			return nil, fmt.Errorf("cost profile %d in %s has no symbol", i, path)
+		}
+		profile := Default(header.Symbol)
+		if err := json.Unmarshal(entry, &profile); err != nil {
+			return nil, fmt.Errorf("parsing cost profile %s: %w", header.Symbol, err)
+		}
+		profile.Symbol = strings.ToUpper(profile.Symbol)
+		if err := Validate(profile); err != nil {
+			return nil, fmt.Errorf("invalid cost profile %s: %w", profile.Symbol, err)
+		}
+		list = append(list, profile)
+	}
+	return list, nil
+}
+
+// LoadFile загружает профили из файла и делает их активными
+func LoadFile(path string) (int, error) {
+	list, err := Load(path)
+	if err != nil {
+		return 0, err
+	}
+	Set(list)
+	return len(list), nil
+}
+
+// Validate проверяет профиль на отрицательные издержки и корректность окон спреда
+func Validate(profile models.CostProfile) error {
+	if profile.SpreadPips < 0 || profile.CommissionPerLot < 0 || profile.SlippagePips < 0 || profile.SlippageRange < 0 {
+		return fmt.Errorf("spread, commission and slippage must not be negative")
+	}
+	if profile.LotSize <= 0 {
+		return fmt.Errorf("lot size must be positive")
+	}
+	for _, window := range profile.SessionSpreads {
+		if window.FromHour < 0 || window.FromHour > 23 || window.ToHour < 0 || window.ToHour > 24 ||
+			window.FromHour == window.ToHour || window.SpreadPips < 0 {
+			return fmt.Errorf("invalid session spread %d-%d", window.FromHour, window.ToHour)
+		}
+	}
+	if math.Abs(profile.SwapLongPercent) >= 100 || math.Abs(profile.SwapShortPercent) >= 100 {
+		return fmt.Errorf("percent swap must be within 100%% of the position value")
+	}
+	if _, ok := tripleDay(profile); !ok {
+		return fmt.Errorf("unknown triple swap day %q", profile.TripleSwapDay)
+	}
+	return nil
+}
+
+// Spread возвращает спред в пунктах в момент at: из первого окна SessionSpreads,
+// в которое попадает час UTC, иначе SpreadPips
+func Spread(profile models.CostProfile, at time.Time) float64 {
+	hour := at.UTC().Hour()
+	for _, window := range profile.SessionSpreads {
+		inside := hour >= window.FromHour && hour < window.ToHour
+		if window.FromHour > window.ToHour {
+			inside = hour >= window.FromHour || hour < window.ToHour
+		}
+		if inside {
+			return window.SpreadPips
+		}
+	}
+	return profile.SpreadPips
+}
+
+// Slippage возвращает проскальзывание рыночного исполнения на свече в пунктах:
+// постоянную часть и долю диапазона свечи, которая растет с волатильностью
+func Slippage(profile models.CostProfile, candle models.Candle, pip float64) float64 {
+	slippage := profile.SlippagePips
+	if pip > 0 {
+		slippage += profile.SlippageRange * (candle.High - candle.Low) / pip
+	}
+	return slippage
+}
+
+// Commission возвращает комиссию за одну сторону сделки размером units
+func Commission(profile models.CostProfile, units float64) float64 {
+	if profile.LotSize <= 0 {
+		return 0
+	}
+	return units / profile.LotSize * profile.CommissionPerLot
+}
+
+// Rollovers возвращает число начисленных свопов за удержание позиции с from до to:
+// по одному за каждый ролловер в 17:00 по Нью-Йорку в будни и три в день тройного свопа.
+// С DailySwap своп начисляется каждую ночь, включая выходные
+func Rollovers(profile models.CostProfile, from, to time.Time) int {
+	triple, _ := tripleDay(profile)
+	start := from.In(instrument.NewYorkLocation)
+	rollover := time.Date(start.Year(), start.Month(), start.Day(), rolloverHour, 0, 0, 0, instrument.NewYorkLocation)
+	if !rollover.After(from) {
+		rollover = rollover.AddDate(0, 0, 1)
+	}
+
+	nights := 0
+	for ; !rollover.After(to); rollover = rollover.AddDate(0, 0, 1) {
+		weekday := rollover.Weekday()
+		switch {
+		case profile.DailySwap:
+			nights++
+		case weekday == time.Saturday || weekday == time.Sunday:
+		case weekday == triple:
+			nights += 3
+		default:
+			nights++
+		}
+	}
+	return nights
+}
+
+// Swap возвращает своп в пунктах за удержание позиции с from до to: SwapLong или SwapShort
+// за каждый ролловер плюс SwapLongPercent или SwapShortPercent от цены входа price,
+// пересчитанные в пункты размером pip. Положительный своп начисляется в пользу позиции
+func Swap(profile models.CostProfile, long bool, price, pip float64, from, to time.Time) float64 {
+	perNight, percent := profile.SwapShort, profile.SwapShortPercent
+	if long {
+		perNight, percent = profile.SwapLong, profile.SwapLongPercent
+	}
+	if pip > 0 {
+		perNight += percent / 100 * price / pip
+	}
+	return perNight * float64(Rollovers(profile, from, to))
+}
+
+// tripleDay разбирает день тройного свопа; пустое значение - среда
+func tripleDay(profile models.CostProfile) (time.Weekday, bool) {
+	if profile.TripleSwapDay == "" {
+		return time.Wednesday, true
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), profile.TripleSwapDay) {
+			return day, true
+		}
+	}
+	return time.Wednesday, false
+}
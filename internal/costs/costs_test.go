@@ -0,0 +1,50 @@
+package costs
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+func TestDefaultCryptoSwapsDaily(t *testing.T) {
+	if profile := Default("btc/usd"); !profile.DailySwap || profile.LotSize != 1 {
+		t.Errorf("Default(BTC/USD) = %+v, want daily swap and lot size 1", profile)
+	}
+	if profile := Default("EUR/USD"); profile.DailySwap || len(profile.SessionSpreads) == 0 {
+		t.Errorf("Default(EUR/USD) = %+v, want weekday swaps and a rollover spread window", profile)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	// С пятницы 12:00 до понедельника 12:00 UTC проходят три ролловера в 17:00 по Нью-Йорку
+	from := time.Date(2026, 3, 6, 12, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 3)
+
+	crypto := models.CostProfile{DailySwap: true, SwapLongPercent: -0.03, SwapShortPercent: -0.01}
+	if got, want := Swap(crypto, true, 60000, 1, from, to), -0.0003*60000*3; math.Abs(got-want) > 1e-9 {
+		t.Errorf("crypto long swap = %.4f pips, want %.4f", got, want)
+	}
+	if got, want := Swap(crypto, false, 60000, 1, from, to), -0.0001*60000*3; math.Abs(got-want) > 1e-9 {
+		t.Errorf("crypto short swap = %.4f pips, want %.4f", got, want)
+	}
+
+	// У валютной пары выходные не считаются: только ролловер в пятницу
+	fx := models.CostProfile{SwapLong: -0.7, SwapShort: 0.2}
+	if got := Swap(fx, true, 1.1, 0.0001, from, to); math.Abs(got+0.7) > 1e-9 {
+		t.Errorf("fx long swap = %.4f pips, want -0.7", got)
+	}
+}
+
+func TestValidatePercentSwap(t *testing.T) {
+	profile := Default("BTC/USD")
+	profile.SwapLongPercent = -0.03
+	if err := Validate(profile); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	profile.SwapShortPercent = -150
+	if err := Validate(profile); err == nil {
+		t.Error("Validate accepted a swap of 150% a night")
+	}
+}
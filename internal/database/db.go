@@ -97,7 +97,7 @@ func createTables(db *sql.DB) error {
 		ON processed_events(created_at)
 	`)
 
-	return nil
+	return createFactorTables(db)
 }
 
 // CreateSubscription creates a new subscription for a user
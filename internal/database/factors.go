@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// createFactorTables creates tables for learned factor weights and factor outcomes.
+// Weights are learned per strategy, symbol and timeframe
+func createFactorTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS factor_weights (
+			strategy TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			timeframe TEXT NOT NULL,
+			factor_id TEXT NOT NULL,
+			weight DOUBLE PRECISION NOT NULL,
+			occurrences DOUBLE PRECISION NOT NULL,
+			hits DOUBLE PRECISION NOT NULL,
+			contribution DOUBLE PRECISION NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (strategy, symbol, timeframe, factor_id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS factor_outcomes (
+			strategy TEXT NOT NULL,
+			factor_id TEXT NOT NULL,
+			prediction_id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			timeframe TEXT NOT NULL,
+			predicted_at TIMESTAMP NOT NULL,
+			contribution DOUBLE PRECISION NOT NULL,
+			correct BOOLEAN NOT NULL,
+			PRIMARY KEY (strategy, factor_id, prediction_id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, _ = db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_factor_outcomes_predicted
+		ON factor_outcomes(predicted_at)
+	`)
+
+	return nil
+}
+
+// SaveFactorWeights upserts learned factor weights
+func (db *DB) SaveFactorWeights(weights []models.FactorWeight) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, w := range weights {
+		_, err := tx.Exec(`
+			INSERT INTO factor_weights (
+				strategy, symbol, timeframe, factor_id, weight, occurrences, hits, contribution, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (strategy, symbol, timeframe, factor_id)
+			DO UPDATE SET
+				weight = EXCLUDED.weight,
+				occurrences = EXCLUDED.occurrences,
+				hits = EXCLUDED.hits,
+				contribution = EXCLUDED.contribution,
+				updated_at = EXCLUDED.updated_at
+		`, w.Strategy, w.Symbol, w.Interval, w.FactorID, w.Weight, w.Occurrences, w.Hits, w.Contribution, w.LastUpdate)
+		if err != nil {
+			return fmt.Errorf("saving weight %s/%s/%s/%s: %w", w.Strategy, w.Symbol, w.Interval, w.FactorID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadFactorWeights returns all learned factor weights
+func (db *DB) LoadFactorWeights() ([]models.FactorWeight, error) {
+	rows, err := db.Query(`
+		SELECT strategy, symbol, timeframe, factor_id, weight, occurrences, hits, contribution, updated_at
+		FROM factor_weights
+		ORDER BY strategy, symbol, timeframe, factor_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var weights []models.FactorWeight
+	for rows.Next() {
+		var w models.FactorWeight
+		if err := rows.Scan(&w.Strategy, &w.Symbol, &w.Interval, &w.FactorID, &w.Weight, &w.Occurrences, &w.Hits, &w.Contribution, &w.LastUpdate); err != nil {
+			return nil, err
+		}
+		weights = append(weights, w)
+	}
+
+	return weights, rows.Err()
+}
+
+// RecordFactorOutcomes stores factor outcomes of validated predictions; duplicates are ignored
+func (db *DB) RecordFactorOutcomes(outcomes []models.FactorOutcome) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, o := range outcomes {
+		_, err := tx.Exec(`
+			INSERT INTO factor_outcomes (
+				strategy, factor_id, prediction_id, symbol, timeframe, predicted_at, contribution, correct
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (strategy, factor_id, prediction_id) DO NOTHING
+		`, o.Strategy, o.FactorID, o.PredictionID, o.Symbol, o.Interval, o.Timestamp, o.Contribution, o.Correct)
+		if err != nil {
+			return fmt.Errorf("recording outcome %s/%s: %w", o.FactorID, o.PredictionID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FactorReport aggregates factor accuracy and contribution of each strategy since the given time.
+// period is a PostgreSQL date_trunc unit (day, week, month); rows are ordered by strategy, factor and period.
+// Weight is the average learned weight of the factor over the strategy's symbols and timeframes
+func (db *DB) FactorReport(since time.Time, period string) ([]models.FactorReportRow, error) {
+	switch period {
+	case "day", "week", "month":
+	default:
+		return nil, fmt.Errorf("unsupported report period %q", period)
+	}
+
+	rows, err := db.Query(`
+		SELECT o.strategy,
+		       o.factor_id,
+		       TO_CHAR(DATE_TRUNC($2, o.predicted_at), 'YYYY-MM-DD'),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE o.correct),
+		       AVG(ABS(o.contribution)),
+		       COALESCE(MAX(w.weight), 1.0)
+		FROM factor_outcomes o
+		LEFT JOIN (
+			SELECT strategy, factor_id, AVG(weight) AS weight
+			FROM factor_weights
+			GROUP BY strategy, factor_id
+		) w ON w.strategy = o.strategy AND w.factor_id = o.factor_id
+		WHERE o.predicted_at >= $1
+		GROUP BY o.strategy, o.factor_id, DATE_TRUNC($2, o.predicted_at)
+		ORDER BY o.strategy, o.factor_id, DATE_TRUNC($2, o.predicted_at)
+	`, since, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []models.FactorReportRow
+	for rows.Next() {
+		var row models.FactorReportRow
+		if err := rows.Scan(&row.Strategy, &row.FactorID, &row.Period, &row.Occurrences, &row.Hits,
+			&row.AvgContribution, &row.Weight); err != nil {
+			return nil, err
+		}
+		if row.Occurrences > 0 {
+			row.Accuracy = float64(row.Hits) / float64(row.Occurrences) * 100
+		}
+		report = append(report, row)
+	}
+
+	return report, rows.Err()
+}
@@ -0,0 +1,185 @@
+package ensemble
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/internal/ml"
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	MethodStacking = "STACKING"
+
+	// validationShare - доля последних наблюдений, отложенная для проверки весов
+	validationShare = 0.3
+
+	// minSamples - минимум наблюдений для обучения весов
+	minSamples = 50
+)
+
+// registry хранит веса ансамбля по символу и таймфрейму
+var registry = struct {
+	mu      sync.RWMutex
+	weights map[string]*models.EnsembleWeights
+}{
+	weights: make(map[string]*models.EnsembleWeights),
+}
+
+// Dataset - голоса участников и метки (1 - цена выросла) в хронологическом порядке
+type Dataset struct {
+	Members []string
+	Votes   [][]float64
+	Y       []float64
+}
+
+// Fit обучает веса стекинга на первых 70% наблюдений, оценивает ансамбль и участников
+// на последних 30% и затем переобучает веса на всей выборке
+func Fit(dataset *Dataset) (*models.EnsembleWeights, error) {
+	n := len(dataset.Votes)
+	if n < minSamples {
+		return nil, fmt.Errorf("insufficient samples: need %d, got %d", minSamples, n)
+	}
+	split := int(float64(n) * (1 - validationShare))
+
+	weights, bias, err := ml.FitLogistic(dataset.Votes[:split], dataset.Y[:split])
+	if err != nil {
+		return nil, fmt.Errorf("fitting validation weights: %w", err)
+	}
+	validation := &models.EnsembleWeights{Members: dataset.Members, Weights: weights, Bias: bias}
+
+	correct := 0
+	memberCorrect := make([]int, len(dataset.Members))
+	memberVotes := make([]int, len(dataset.Members))
+	for s := split; s < n; s++ {
+		up := dataset.Y[s] == 1
+		if (Probability(validation, dataset.Votes[s]) >= 0.5) == up {
+			correct++
+		}
+		// Воздержавшиеся участники не учитываются в их точности
+		for m, vote := range dataset.Votes[s] {
+			if vote == 0 {
+				continue
+			}
+			memberVotes[m]++
+			if (vote > 0) == up {
+				memberCorrect[m]++
+			}
+		}
+	}
+
+	weights, bias, err = ml.FitLogistic(dataset.Votes, dataset.Y)
+	if err != nil {
+		return nil, fmt.Errorf("fitting ensemble weights: %w", err)
+	}
+
+	result := &models.EnsembleWeights{
+		Method:             MethodStacking,
+		Members:            append([]string(nil), dataset.Members...),
+		Weights:            weights,
+		Bias:               bias,
+		ValidationAccuracy: float64(correct) / float64(n-split) * 100,
+		MemberAccuracy:     make(map[string]float64, len(dataset.Members)),
+		Samples:            n,
+		TrainedAt:          time.Now(),
+	}
+	for m, name := range dataset.Members {
+		if memberVotes[m] > 0 {
+			result.MemberAccuracy[name] = float64(memberCorrect[m]) / float64(memberVotes[m]) * 100
+		}
+	}
+
+	return result, nil
+}
+
+// Probability возвращает вероятность роста цены по голосам участников
+func Probability(weights *models.EnsembleWeights, votes []float64) float64 {
+	logit := weights.Bias
+	for i, vote := range votes {
+		logit += weights.Weights[i] * vote
+	}
+	return 1 / (1 + math.Exp(-logit))
+}
+
+// FileName возвращает имя файла весов для символа и таймфрейма
+func FileName(symbol, interval string) string {
+	return fmt.Sprintf("ensemble_%s_%s.json", strings.ReplaceAll(symbol, "/", ""), interval)
+}
+
+// Save сохраняет веса в каталог dir
+func Save(dir string, weights *models.EnsembleWeights) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating ensemble directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(weights, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding ensemble weights: %w", err)
+	}
+
+	path := filepath.Join(dir, FileName(weights.Symbol, weights.Interval))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing ensemble weights: %w", err)
+	}
+
+	return nil
+}
+
+// Load читает веса из файла
+func Load(path string) (*models.EnsembleWeights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading ensemble weights: %w", err)
+	}
+
+	var weights models.EnsembleWeights
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return nil, fmt.Errorf("parsing ensemble weights %s: %w", path, err)
+	}
+	if len(weights.Weights) != len(weights.Members) {
+		return nil, fmt.Errorf("ensemble weights %s: weights do not match members", path)
+	}
+
+	return &weights, nil
+}
+
+// LoadDir загружает все веса ансамбля из каталога
+func LoadDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "ensemble_*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing ensemble weights: %w", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		weights, err := Load(file)
+		if err != nil {
+			return loaded, err
+		}
+		Register(weights)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// Register регистрирует веса для символа и таймфрейма
+func Register(weights *models.EnsembleWeights) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.weights[FileName(weights.Symbol, weights.Interval)] = weights
+}
+
+// Get возвращает веса ансамбля для символа и таймфрейма
+func Get(symbol, interval string) (*models.EnsembleWeights, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	weights, ok := registry.weights[FileName(symbol, interval)]
+	return weights, ok
+}
@@ -0,0 +1,223 @@
+package explain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// DefaultLanguage - язык описаний факторов, если другой не выбран
+const DefaultLanguage = "en"
+
+// Категории факторов
+const (
+	CategoryTrend      = "TREND"
+	CategoryMomentum   = "MOMENTUM"
+	CategoryPattern    = "PATTERN"
+	CategoryLevels     = "LEVELS"
+	CategoryVolume     = "VOLUME"
+	CategoryIndicators = "INDICATORS"
+	CategoryDivergence = "DIVERGENCE"
+	CategoryRegime     = "REGIME"
+	CategoryModel      = "MODEL"
+	CategoryAdjustment = "ADJUSTMENT"
+	CategoryOther      = "OTHER"
+)
+
+// categoryPrefixes сопоставляет префиксы идентификаторов факторов категориям;
+// проверяются по порядку, поэтому точные идентификаторы идут раньше префиксов
+var categoryPrefixes = []struct {
+	prefix   string
+	category string
+}{
+	{"REGIME_", CategoryRegime},
+	{"TREND_ALIGNMENT", CategoryTrend},
+	{"TREND_PROXY", CategoryTrend},
+	{"EMA_POSITION", CategoryTrend},
+	{"DI_DIRECTION", CategoryTrend},
+	{"CHANNEL_BREAKOUT", CategoryTrend},
+	{"CURRENCY_STRENGTH", CategoryTrend},
+	{"RSI", CategoryMomentum},
+	{"MACD", CategoryMomentum},
+	{"STOCHASTIC", CategoryMomentum},
+	{"BOLLINGER_POSITION", CategoryMomentum},
+	{"PATTERN_", CategoryPattern},
+	{"SUPPORT_RESISTANCE", CategoryLevels},
+	{"ORDER_FLOW", CategoryVolume},
+	{"TRADE_SIGNAL", CategoryIndicators},
+	{"DIVERGENCE_", CategoryDivergence},
+	{"ML_", CategoryModel},
+	{"MEMBER_", CategoryModel},
+	{"MARKET_ANALYSIS", CategoryModel},
+}
+
+// Category возвращает категорию фактора по его идентификатору
+func Category(id string) string {
+	for _, entry := range categoryPrefixes {
+		if strings.HasPrefix(id, entry.prefix) {
+			return entry.category
+		}
+	}
+	return CategoryOther
+}
+
+// Text собирает описание фактора на английском и русском
+func Text(en, ru string) map[string]string {
+	return map[string]string{"en": en, "ru": ru}
+}
+
+// Language нормализует код языка; неизвестные языки заменяются языком по умолчанию
+func Language(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang != "en" && lang != "ru" {
+		return DefaultLanguage
+	}
+	return lang
+}
+
+// Describe возвращает описание фактора на языке lang, затем на языке по умолчанию, затем идентификатор
+func Describe(factor models.Factor, lang string) string {
+	if text := factor.Text[Language(lang)]; text != "" {
+		return text
+	}
+	if text := factor.Text[DefaultLanguage]; text != "" {
+		return text
+	}
+	return factor.ID
+}
+
+// Builder накапливает вклады факторов и корректировки счета так, чтобы
+// разложение всегда суммировалось в итоговый счет
+type Builder struct {
+	factors     []models.Factor
+	adjustments []models.Factor
+	notes       []map[string]string
+	raw         float64
+	score       float64
+}
+
+// NewBuilder создает пустое разложение счета
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Add добавляет вклад фактора в счет; нулевые вклады не записываются
+func (b *Builder) Add(id string, value, contribution float64, text map[string]string) {
+	if contribution == 0 {
+		return
+	}
+	b.factors = append(b.factors, newFactor(id, Category(id), value, contribution, text))
+	b.raw += contribution
+	b.score += contribution
+}
+
+// Scale умножает текущий счет на multiplier и записывает изменение как корректировку
+func (b *Builder) Scale(id string, multiplier float64, text map[string]string) {
+	if multiplier == 1 || b.score == 0 {
+		return
+	}
+	b.adjustments = append(b.adjustments, newFactor(id, CategoryAdjustment, multiplier, b.score*(multiplier-1), text))
+	b.score *= multiplier
+}
+
+// SettleTo приводит счет к значению target (нелинейная связь вкладов со счетом,
+// например через вероятность) и записывает разницу как корректировку
+func (b *Builder) SettleTo(id string, target float64, text map[string]string) {
+	if math.Abs(target-b.score) < 1e-12 {
+		return
+	}
+	b.adjustments = append(b.adjustments, newFactor(id, CategoryAdjustment, target, target-b.score, text))
+	b.score = target
+}
+
+// Clamp ограничивает счет диапазоном [-limit, limit]
+func (b *Builder) Clamp(limit float64) {
+	b.SettleTo("CLAMP", math.Max(-limit, math.Min(limit, b.score)), Text(
+		fmt.Sprintf("Score capped at ±%.0f", limit), fmt.Sprintf("Счет ограничен ±%.0f", limit)))
+}
+
+// Note добавляет пояснение без вклада в счет
+func (b *Builder) Note(text map[string]string) {
+	b.notes = append(b.notes, text)
+}
+
+// Score возвращает итоговый счет
+func (b *Builder) Score() float64 {
+	return b.score
+}
+
+// FactorScores возвращает вклады факторов по идентификаторам для обучения весов
+func (b *Builder) FactorScores() map[string]float64 {
+	scores := make(map[string]float64, len(b.factors))
+	for _, factor := range b.factors {
+		scores[factor.ID] += factor.Contribution
+	}
+	return scores
+}
+
+// Breakdown возвращает разложение итогового счета
+func (b *Builder) Breakdown() *models.ScoreBreakdown {
+	return &models.ScoreBreakdown{
+		Factors:     append([]models.Factor(nil), b.factors...),
+		RawScore:    b.raw,
+		Adjustments: append([]models.Factor(nil), b.adjustments...),
+		Score:       b.score,
+	}
+}
+
+// Explain возвращает описания на языке lang: пояснения, затем факторы, поддерживающие
+// направление прогноза (для NEUTRAL - все), по убыванию вклада, и корректировки.
+// Факторы и корректировки без описания видны только в разложении счета
+func (b *Builder) Explain(direction, lang string) []string {
+	var lines []string
+	for _, note := range b.notes {
+		lines = append(lines, Describe(models.Factor{Text: note}, lang))
+	}
+
+	supporting := make([]models.Factor, 0, len(b.factors))
+	for _, factor := range b.factors {
+		if len(factor.Text) == 0 || direction == "BUY" && factor.Contribution < 0 || direction == "SELL" && factor.Contribution > 0 {
+			continue
+		}
+		supporting = append(supporting, factor)
+	}
+	sort.SliceStable(supporting, func(i, j int) bool {
+		return math.Abs(supporting[i].Contribution) > math.Abs(supporting[j].Contribution)
+	})
+
+	if len(lines) == 0 && len(supporting) == 0 {
+		lines = append(lines, Describe(models.Factor{Text: Text(
+			"No factor dominates: market in consolidation",
+			"Ни один фактор не преобладает: рынок в консолидации",
+		)}, lang))
+	}
+	for _, factor := range supporting {
+		lines = append(lines, Describe(factor, lang))
+	}
+	for _, adjustment := range b.adjustments {
+		if len(adjustment.Text) > 0 {
+			lines = append(lines, Describe(adjustment, lang))
+		}
+	}
+	return lines
+}
+
+func newFactor(id, category string, value, contribution float64, text map[string]string) models.Factor {
+	direction := "NEUTRAL"
+	if contribution > 0 {
+		direction = "BULLISH"
+	} else if contribution < 0 {
+		direction = "BEARISH"
+	}
+	return models.Factor{
+		ID:           id,
+		Category:     category,
+		Direction:    direction,
+		Value:        value,
+		Contribution: contribution,
+		Text:         text,
+	}
+}
@@ -0,0 +1,268 @@
+package horizon
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Predictor/internal/calibration"
+	"github.com/Alias1177/Predictor/internal/instrument"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	NextBar    = "NEXT_BAR"
+	ThreeBars  = "3_BARS"
+	TwelveBars = "12_BARS"
+	SessionEnd = "SESSION_END"
+
+	// sessionCloseHour - закрытие торгового дня FX и сырья (17:00 по Нью-Йорку, ролловер)
+	sessionCloseHour = 17
+
+	// defaultHalfLife - период полураспада влияния фактора в свечах, если фактор не описан
+	defaultHalfLife = 6.0
+
+	// minMoveSamples - минимум исторических окон для эмпирической оценки ожидаемого движения
+	minMoveSamples = 20
+)
+
+// fixedHorizons - горизонты с постоянным числом свечей
+var fixedHorizons = []struct {
+	name string
+	bars int
+}{
+	{NextBar, 1},
+	{ThreeBars, 3},
+	{TwelveBars, 12},
+}
+
+// factorHalfLives - за сколько свечей влияние фактора на направление ослабевает вдвое.
+// Осцилляторы и поток ордеров быстро теряют силу, трендовые факторы сохраняются дольше
+var factorHalfLives = map[string]float64{
+	"ORDER_FLOW":         2,
+	"RSI":                3,
+	"STOCHASTIC":         3,
+	"TRADE_SIGNAL":       4,
+	"SUPPORT_RESISTANCE": 4,
+	"BOLLINGER_POSITION": 4,
+	"MACD":               6,
+	"REGIME_RANGE":       6,
+	"CHANNEL_BREAKOUT":   12,
+	"TREND_ALIGNMENT":    24,
+	"EMA_POSITION":       24,
+	"DI_DIRECTION":       24,
+	"REGIME_TREND":       48,
+}
+
+// factorPrefixHalfLives - периоды полураспада для семейств факторов
+var factorPrefixHalfLives = map[string]float64{
+	"PATTERN_":    3,
+	"DIVERGENCE_": 6,
+}
+
+// Apply добавляет к прогнозу прогнозы на горизонты и целевое время следующей свечи
+func Apply(prediction *models.Prediction, candles []models.Candle, symbol, interval string) {
+	prediction.Horizons = Forecast(prediction, candles, symbol, interval)
+	if len(prediction.Horizons) > 0 {
+		prediction.PredictionTarget = prediction.Horizons[0].PredictionTarget
+	}
+}
+
+// Forecast строит прогнозы на 1, 3 и 12 свечей и до конца торговой сессии.
+// Счет горизонта - счет прогноза, в котором вклад каждого фактора затухает
+// со своим периодом полураспада, поэтому направление на разных горизонтах может различаться
+func Forecast(prediction *models.Prediction, candles []models.Candle, symbol, interval string) []models.HorizonForecast {
+	if prediction == nil || len(candles) == 0 {
+		return nil
+	}
+
+	duration := BarDuration(interval, candles)
+	last := candles[len(candles)-1]
+	class := instrument.Class(symbol)
+	p := params.Active()
+
+	type horizonSpec struct {
+		name string
+		bars int
+	}
+	specs := make([]horizonSpec, 0, len(fixedHorizons)+1)
+	for _, h := range fixedHorizons {
+		specs = append(specs, horizonSpec{h.name, h.bars})
+	}
+	if duration > 0 {
+		specs = append(specs, horizonSpec{SessionEnd, SessionEndBars(last.Timestamp, duration, class)})
+	}
+
+	forecasts := make([]models.HorizonForecast, 0, len(specs))
+	for _, spec := range specs {
+		score := Score(prediction, spec.bars)
+		direction, confidence := params.Direction(p, score)
+
+		forecast := models.HorizonForecast{
+			Horizon:    spec.name,
+			Bars:       spec.bars,
+			Direction:  direction,
+			Confidence: confidence,
+			Score:      score,
+		}
+
+		// Калибратор следующей свечи уже применен к самому прогнозу
+		if spec.name == NextBar {
+			forecast.Probability = prediction.Probability
+		} else if c, ok := calibration.GetHorizon(prediction.Strategy, symbol, interval, spec.name); ok {
+			forecast.Probability = calibration.Probability(c, score)
+		}
+
+		forecast.ExpectedMove = ExpectedMove(candles, spec.bars)
+		if last.Close > 0 {
+			forecast.ExpectedMovePct = forecast.ExpectedMove / last.Close * 100
+		}
+		if duration > 0 {
+			forecast.PredictionTarget = TargetTime(last.Timestamp, spec.bars, duration, class)
+		}
+
+		forecasts = append(forecasts, forecast)
+	}
+
+	return forecasts
+}
+
+// Score пересчитывает счет прогноза для горизонта bars: вклад каждого фактора
+// уменьшается по его периоду полураспада, остальные поправки счета сохраняются
+func Score(prediction *models.Prediction, bars int) float64 {
+	score := prediction.Score
+	if bars <= 1 {
+		return score
+	}
+	// Порядок суммирования фиксирован, чтобы счет не зависел от обхода map
+	factorIDs := make([]string, 0, len(prediction.FactorScores))
+	for factorID := range prediction.FactorScores {
+		factorIDs = append(factorIDs, factorID)
+	}
+	sort.Strings(factorIDs)
+
+	for _, factorID := range factorIDs {
+		decay := math.Pow(0.5, float64(bars-1)/halfLife(factorID))
+		score -= prediction.FactorScores[factorID] * (1 - decay)
+	}
+	return score
+}
+
+func halfLife(factorID string) float64 {
+	if value, ok := factorHalfLives[factorID]; ok {
+		return value
+	}
+	for prefix, value := range factorPrefixHalfLives {
+		if strings.HasPrefix(factorID, prefix) {
+			return value
+		}
+	}
+	return defaultHalfLife
+}
+
+// BarDuration возвращает длительность свечи по интервалу, а для неизвестного
+// интервала - минимальный шаг между последними свечами
+func BarDuration(interval string, candles []models.Candle) time.Duration {
+	if duration := models.IntervalDuration(interval); duration > 0 {
+		return duration
+	}
+
+	var duration time.Duration
+	for i := len(candles) - 1; i > 0 && i >= len(candles)-20; i-- {
+		diff := candles[i].Timestamp.Sub(candles[i-1].Timestamp)
+		if diff > 0 && (duration == 0 || diff < duration) {
+			duration = diff
+		}
+	}
+	return duration
+}
+
+// SessionEndBars возвращает число свечей до конца текущего торгового дня:
+// 17:00 по Нью-Йорку для FX и сырья, полночь UTC для криптовалют.
+// lastOpen - время открытия последней закрытой свечи
+func SessionEndBars(lastOpen time.Time, duration time.Duration, class string) int {
+	if duration <= 0 || duration >= 24*time.Hour {
+		return 1
+	}
+
+	lastClose := lastOpen.Add(duration)
+	var sessionEnd time.Time
+	if class == instrument.ClassCrypto {
+		utc := lastClose.UTC()
+		sessionEnd = time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC).Add(24 * time.Hour)
+	} else {
+		local := lastClose.In(instrument.NewYorkLocation)
+		sessionEnd = time.Date(local.Year(), local.Month(), local.Day(), sessionCloseHour, 0, 0, 0, instrument.NewYorkLocation)
+		if !sessionEnd.After(lastClose) {
+			sessionEnd = sessionEnd.AddDate(0, 0, 1)
+		}
+		// Торговый день, начавшийся в пятницу вечером, заканчивается в понедельник
+		for sessionEnd.Weekday() == time.Saturday || sessionEnd.Weekday() == time.Sunday {
+			sessionEnd = sessionEnd.AddDate(0, 0, 1)
+		}
+	}
+
+	bars := 0
+	for t := lastClose; t.Before(sessionEnd); t = t.Add(duration) {
+		if class == instrument.ClassCrypto || !marketClosed(t) {
+			bars++
+		}
+	}
+	return max(bars, 1)
+}
+
+// TargetTime возвращает время закрытия свечи через bars свечей после последней,
+// пропуская выходные для FX и сырья
+func TargetTime(lastOpen time.Time, bars int, duration time.Duration, class string) time.Time {
+	open := lastOpen
+	for i := 0; i < bars; i++ {
+		open = open.Add(duration)
+		if class != instrument.ClassCrypto {
+			for marketClosed(open) {
+				open = open.Add(duration)
+			}
+		}
+	}
+	return open.Add(duration)
+}
+
+// marketClosed проверяет, что рынок FX закрыт: с 17:00 пятницы до 17:00 воскресенья по Нью-Йорку
+func marketClosed(t time.Time) bool {
+	local := t.In(instrument.NewYorkLocation)
+	switch local.Weekday() {
+	case time.Saturday:
+		return true
+	case time.Friday:
+		return local.Hour() >= sessionCloseHour
+	case time.Sunday:
+		return local.Hour() < sessionCloseHour
+	}
+	return false
+}
+
+// ExpectedMove оценивает типичное абсолютное изменение цены закрытия за bars свечей
+// по истории; при короткой истории масштабирует движение за одну свечу на sqrt(bars)
+func ExpectedMove(candles []models.Candle, bars int) float64 {
+	if bars < 1 || len(candles) < 2 {
+		return 0
+	}
+
+	if len(candles)-bars >= minMoveSamples {
+		return meanAbsChange(candles, bars)
+	}
+	return meanAbsChange(candles, 1) * math.Sqrt(float64(bars))
+}
+
+func meanAbsChange(candles []models.Candle, bars int) float64 {
+	sum, count := 0.0, 0
+	for i := bars; i < len(candles); i++ {
+		sum += math.Abs(candles[i].Close - candles[i-bars].Close)
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
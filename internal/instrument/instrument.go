@@ -0,0 +1,50 @@
+package instrument
+
+import (
+	"strings"
+	"time"
+	_ "time/tzdata" // Часовые пояса торговых сессий должны быть доступны и в минимальных образах
+)
+
+// Классы инструментов
+const (
+	ClassFX        = "FX"
+	ClassCommodity = "COMMODITY"
+	ClassCrypto    = "CRYPTO"
+)
+
+var (
+	// NewYorkLocation - часовой пояс Нью-Йорка: по нему определяются ролловер в 17:00
+	// и закрытие торговой недели
+	NewYorkLocation = LoadLocation("America/New_York", -5)
+
+	cryptoAssets    = []string{"BTC", "ETH", "SOL", "XRP", "ADA", "AAVE", "BNB", "DOT"}
+	commodityAssets = []string{"XAU", "XAG", "XBR", "WTI"}
+)
+
+// LoadLocation загружает часовой пояс по имени; без базы часовых поясов возвращает
+// фиксированное смещение fallbackOffset часов от UTC
+func LoadLocation(name string, fallbackOffset int) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone(name, fallbackOffset*3600)
+	}
+	return location
+}
+
+// Class определяет класс инструмента по символу: ClassFX, ClassCommodity или ClassCrypto.
+// Криптовалюты торгуются круглосуточно и без выходных
+func Class(symbol string) string {
+	base := strings.ToUpper(strings.Split(symbol, "/")[0])
+	for _, asset := range cryptoAssets {
+		if base == asset {
+			return ClassCrypto
+		}
+	}
+	for _, asset := range commodityAssets {
+		if base == asset {
+			return ClassCommodity
+		}
+	}
+	return ClassFX
+}
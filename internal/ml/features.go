@@ -0,0 +1,123 @@
+package ml
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alias1177/Predictor/internal/calculate"
+	"github.com/Alias1177/Predictor/internal/utils"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// FeatureNames - признаки модели в порядке вектора Features
+var FeatureNames = []string{
+	"ATR_PCT",          // ATR к цене
+	"EMA_SLOPE",        // Относительный наклон EMA(20)
+	"RSI",              // RSI(14) из рыночных признаков
+	"VOLUME_CHANGE",    // Изменение объема, 0 без объемов
+	"BB_POSITION",      // Положение цены в полосах Боллинджера, -1..1 внутри полос
+	"MACD_HIST_ATR",    // Гистограмма MACD в ATR
+	"EMA_DISTANCE_ATR", // Отклонение цены от EMA в ATR
+	"ADX",
+	"DI_SPREAD", // +DI минус -DI
+	"STOCHASTIC",
+	"STOCHASTIC_CROSS", // %K минус %D
+	"PRICE_CHANGE",     // Изменение цены за 5 свечей, %
+	"MOMENTUM_ATR",
+	"VOLATILITY_RATIO",
+	"LAST_RETURN_ATR", // Изменение цены последней свечи в ATR
+	"BODY_RATIO",      // Тело последней свечи к ее диапазону
+}
+
+// Features строит вектор признаков по окну свечей и рассчитанным по нему индикаторам
+func Features(candles []models.Candle, indicators *models.TechnicalIndicators) ([]float64, error) {
+	if indicators == nil || len(candles) < 2 {
+		return nil, fmt.Errorf("insufficient data for ML features")
+	}
+
+	market, err := utils.CalculateMarketFeatures(candles)
+	if err != nil && !errors.Is(err, utils.ErrNoVolumeData) {
+		return nil, fmt.Errorf("calculating market features: %w", err)
+	}
+
+	last, prev := candles[len(candles)-1], candles[len(candles)-2]
+	perATR := func(value float64) float64 {
+		if indicators.ATR <= 0 {
+			return 0
+		}
+		return value / indicators.ATR
+	}
+
+	bbPosition := 0.0
+	if halfWidth := indicators.BBUpper - indicators.BBMiddle; halfWidth > 0 {
+		bbPosition = (last.Close - indicators.BBMiddle) / halfWidth
+	}
+	bodyRatio := 0.0
+	if rng := last.High - last.Low; rng > 0 {
+		bodyRatio = (last.Close - last.Open) / rng
+	}
+
+	return []float64{
+		market[0],
+		market[1],
+		market[2],
+		market[3],
+		bbPosition,
+		perATR(indicators.MACDHist),
+		perATR(last.Close - indicators.EMA),
+		indicators.ADX,
+		indicators.PlusDI - indicators.MinusDI,
+		indicators.Stochastic,
+		indicators.Stochastic - indicators.StochasticSignal,
+		indicators.PriceChange,
+		perATR(indicators.Momentum),
+		indicators.VolatilityRatio,
+		perATR(last.Close - prev.Close),
+		bodyRatio,
+	}, nil
+}
+
+// Dataset - признаки и метки (1 - цена выросла через горизонт) в хронологическом порядке
+type Dataset struct {
+	X     [][]float64
+	Y     []float64
+	Times []time.Time
+}
+
+// BuildDataset строит обучающую выборку: для каждой свечи признаки считаются по окну
+// из window предыдущих свечей так же, как при прогнозе, а метка - по изменению
+// закрытия через horizon свечей. Свечи без изменения цены пропускаются
+func BuildDataset(candles []models.Candle, cfg *models.Config, window, horizon int) (*Dataset, error) {
+	if window < 20 || horizon < 1 {
+		return nil, fmt.Errorf("invalid dataset window %d or horizon %d", window, horizon)
+	}
+	if len(candles) < window+horizon {
+		return nil, fmt.Errorf("insufficient candles for dataset: need %d, got %d", window+horizon, len(candles))
+	}
+
+	dataset := &Dataset{}
+	for i := window; i-1+horizon < len(candles); i++ {
+		testWindow := candles[i-window : i]
+		change := candles[i-1+horizon].Close - candles[i-1].Close
+		if change == 0 {
+			continue
+		}
+
+		indicators := calculate.CalculateAllIndicators(testWindow, cfg)
+		features, err := Features(testWindow, indicators)
+		if err != nil {
+			continue
+		}
+
+		label := 0.0
+		if change > 0 {
+			label = 1
+		}
+		dataset.X = append(dataset.X, features)
+		dataset.Y = append(dataset.Y, label)
+		dataset.Times = append(dataset.Times, candles[i-1].Timestamp)
+	}
+
+	return dataset, nil
+}
@@ -0,0 +1,230 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// gbtBins - число квантильных корзин признака при поиске разбиений
+const gbtBins = 32
+
+// GBTOptions - параметры градиентного бустинга деревьев
+type GBTOptions struct {
+	Trees        int
+	Depth        int
+	MinLeaf      int     // Минимум наблюдений в листе
+	LearningRate float64 // Сжатие вклада каждого дерева
+	L2           float64 // Регуляризация значений листьев
+}
+
+// DefaultGBTOptions - небольшая модель, устойчивая на шумных доходностях
+func DefaultGBTOptions() GBTOptions {
+	return GBTOptions{Trees: 50, Depth: 3, MinLeaf: 30, LearningRate: 0.1, L2: 1}
+}
+
+// gbtBuilder хранит состояние обучения одного дерева
+type gbtBuilder struct {
+	opts       GBTOptions
+	thresholds [][]float64 // Границы корзин по признакам
+	bins       [][]int     // Номер корзины признака для каждого наблюдения
+	g, h       []float64   // Градиент и гессиан лог-лосса
+	nodes      []models.MLTreeNode
+}
+
+// fitGBT обучает бустинг деревьев регрессии по градиенту и гессиану лог-лосса
+// (шаг Ньютона в листьях). Возвращает начальный логит и деревья
+func fitGBT(X [][]float64, y []float64, opts GBTOptions) (float64, [][]models.MLTreeNode, error) {
+	if len(X) < 2*opts.MinLeaf {
+		return 0, nil, fmt.Errorf("insufficient samples for GBT: need %d, got %d", 2*opts.MinLeaf, len(X))
+	}
+	dims := len(X[0])
+
+	b := &gbtBuilder{
+		opts:       opts,
+		thresholds: make([][]float64, dims),
+		bins:       make([][]int, len(X)),
+		g:          make([]float64, len(X)),
+		h:          make([]float64, len(X)),
+	}
+	for f := 0; f < dims; f++ {
+		b.thresholds[f] = quantileThresholds(X, f)
+	}
+	for s, x := range X {
+		b.bins[s] = make([]int, dims)
+		for f := 0; f < dims; f++ {
+			b.bins[s][f] = sort.SearchFloat64s(b.thresholds[f], x[f])
+		}
+	}
+
+	rate := 0.0
+	for _, label := range y {
+		rate += label
+	}
+	rate = math.Min(math.Max(rate/float64(len(y)), 0.01), 0.99)
+	bias := math.Log(rate / (1 - rate))
+
+	scores := make([]float64, len(X))
+	for s := range scores {
+		scores[s] = bias
+	}
+
+	indices := make([]int, len(X))
+	for s := range indices {
+		indices[s] = s
+	}
+
+	trees := make([][]models.MLTreeNode, 0, opts.Trees)
+	for t := 0; t < opts.Trees; t++ {
+		for s := range X {
+			p := sigmoid(scores[s])
+			b.g[s] = y[s] - p
+			b.h[s] = math.Max(p*(1-p), 1e-6)
+		}
+
+		b.nodes = nil
+		b.build(append([]int(nil), indices...), 0)
+		tree := b.nodes
+		for s, x := range X {
+			scores[s] += treeValue(tree, x)
+		}
+		trees = append(trees, tree)
+	}
+
+	return bias, trees, nil
+}
+
+// build добавляет узел для наблюдений indices и возвращает его номер
+func (b *gbtBuilder) build(indices []int, depth int) int {
+	var G, H float64
+	for _, s := range indices {
+		G += b.g[s]
+		H += b.h[s]
+	}
+
+	node := len(b.nodes)
+	b.nodes = append(b.nodes, models.MLTreeNode{
+		Feature: -1,
+		Value:   b.opts.LearningRate * G / (H + b.opts.L2),
+	})
+	if depth >= b.opts.Depth || len(indices) < 2*b.opts.MinLeaf {
+		return node
+	}
+
+	bestFeature, bestBin, bestGain := -1, 0, 0.0
+	parent := G * G / (H + b.opts.L2)
+	for f, thresholds := range b.thresholds {
+		if len(thresholds) == 0 {
+			continue
+		}
+		histG := make([]float64, len(thresholds)+1)
+		histH := make([]float64, len(thresholds)+1)
+		histN := make([]int, len(thresholds)+1)
+		for _, s := range indices {
+			bin := b.bins[s][f]
+			histG[bin] += b.g[s]
+			histH[bin] += b.h[s]
+			histN[bin]++
+		}
+
+		var GL, HL float64
+		NL := 0
+		for k := range thresholds {
+			GL += histG[k]
+			HL += histH[k]
+			NL += histN[k]
+			if NL < b.opts.MinLeaf || len(indices)-NL < b.opts.MinLeaf {
+				continue
+			}
+			GR, HR := G-GL, H-HL
+			gain := GL*GL/(HL+b.opts.L2) + GR*GR/(HR+b.opts.L2) - parent
+			if gain > bestGain {
+				bestFeature, bestBin, bestGain = f, k, gain
+			}
+		}
+	}
+	if bestFeature < 0 {
+		return node
+	}
+
+	var left, right []int
+	for _, s := range indices {
+		if b.bins[s][bestFeature] <= bestBin {
+			left = append(left, s)
+		} else {
+			right = append(right, s)
+		}
+	}
+
+	leftNode := b.build(left, depth+1)
+	rightNode := b.build(right, depth+1)
+	b.nodes[node] = models.MLTreeNode{
+		Feature:   bestFeature,
+		Threshold: b.thresholds[bestFeature][bestBin],
+		Left:      leftNode,
+		Right:     rightNode,
+	}
+	return node
+}
+
+// quantileThresholds возвращает различные квантили признака f как границы корзин
+func quantileThresholds(X [][]float64, f int) []float64 {
+	values := make([]float64, len(X))
+	for s, x := range X {
+		values[s] = x[f]
+	}
+	sort.Float64s(values)
+
+	var thresholds []float64
+	for k := 1; k < gbtBins; k++ {
+		value := values[k*(len(values)-1)/gbtBins]
+		if len(thresholds) == 0 || value > thresholds[len(thresholds)-1] {
+			thresholds = append(thresholds, value)
+		}
+	}
+	// Верхняя граница не делит выборку
+	if len(thresholds) > 0 && thresholds[len(thresholds)-1] >= values[len(values)-1] {
+		thresholds = thresholds[:len(thresholds)-1]
+	}
+	return thresholds
+}
+
+// validateTrees проверяет, что признаки узлов существуют, а дочерние узлы лежат в дереве
+// после родителя, как их строит gbtBuilder; иначе treeValue упала бы или зациклилась
+func validateTrees(trees [][]models.MLTreeNode) error {
+	if len(trees) == 0 {
+		return fmt.Errorf("no trees")
+	}
+	for t, tree := range trees {
+		if len(tree) == 0 {
+			return fmt.Errorf("tree %d is empty", t)
+		}
+		for i, node := range tree {
+			if node.Feature < 0 {
+				continue
+			}
+			if node.Feature >= len(FeatureNames) {
+				return fmt.Errorf("tree %d node %d: feature %d out of range", t, i, node.Feature)
+			}
+			if node.Left <= i || node.Left >= len(tree) || node.Right <= i || node.Right >= len(tree) {
+				return fmt.Errorf("tree %d node %d: children %d/%d out of range", t, i, node.Left, node.Right)
+			}
+		}
+	}
+	return nil
+}
+
+// treeValue возвращает вклад дерева в логит для стандартизованных признаков x
+func treeValue(tree []models.MLTreeNode, x []float64) float64 {
+	node := 0
+	for tree[node].Feature >= 0 {
+		if x[tree[node].Feature] <= tree[node].Threshold {
+			node = tree[node].Left
+		} else {
+			node = tree[node].Right
+		}
+	}
+	return tree[node].Value
+}
@@ -0,0 +1,137 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// logisticL2 - L2-регуляризация весов на стандартизованных признаках
+	logisticL2 = 1.0
+
+	logisticMaxIterations = 50
+	logisticTolerance     = 1e-8
+)
+
+// FitLogistic обучает логистическую регрессию на признаках, уже приведенных к общему масштабу
+func FitLogistic(X [][]float64, y []float64) ([]float64, float64, error) {
+	return fitLogistic(X, y)
+}
+
+// fitLogistic обучает логистическую регрессию методом Ньютона (IRLS) с L2-регуляризацией.
+// X уже стандартизован; возвращает веса и свободный член
+func fitLogistic(X [][]float64, y []float64) ([]float64, float64, error) {
+	if len(X) == 0 {
+		return nil, 0, fmt.Errorf("empty training set")
+	}
+	dims := len(X[0])
+	n := dims + 1 // Последний коэффициент - свободный член
+
+	beta := make([]float64, n)
+	for iteration := 0; iteration < logisticMaxIterations; iteration++ {
+		gradient := make([]float64, n)
+		hessian := make([][]float64, n)
+		for i := range hessian {
+			hessian[i] = make([]float64, n)
+		}
+
+		row := make([]float64, n)
+		for s, x := range X {
+			copy(row, x)
+			row[dims] = 1
+
+			p := sigmoid(dot(beta, row))
+			weight := math.Max(p*(1-p), 1e-10)
+			for i := 0; i < n; i++ {
+				gradient[i] += (y[s] - p) * row[i]
+				for j := 0; j <= i; j++ {
+					hessian[i][j] += weight * row[i] * row[j]
+				}
+			}
+		}
+
+		// Свободный член не регуляризуется
+		for i := 0; i < dims; i++ {
+			gradient[i] -= logisticL2 * beta[i]
+			hessian[i][i] += logisticL2
+		}
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				hessian[i][j] = hessian[j][i]
+			}
+		}
+
+		step, err := solve(hessian, gradient)
+		if err != nil {
+			return nil, 0, fmt.Errorf("logistic regression: %w", err)
+		}
+
+		change := 0.0
+		for i := range beta {
+			beta[i] += step[i]
+			change += step[i] * step[i]
+		}
+		if change < logisticTolerance {
+			break
+		}
+	}
+
+	return beta[:dims], beta[dims], nil
+}
+
+// solve решает систему A*x = b методом Гаусса с выбором главного элемента
+func solve(A [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range A {
+		m[i] = make([]float64, n+1)
+		copy(m[i], A[i])
+		m[i][n] = b[i]
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, fmt.Errorf("singular matrix")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := m[row][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[row][k] -= factor * m[col][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+	return x, nil
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func sigmoid(x float64) float64 {
+	if x >= 0 {
+		return 1 / (1 + math.Exp(-x))
+	}
+	e := math.Exp(x)
+	return e / (1 + e)
+}
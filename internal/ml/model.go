@@ -0,0 +1,308 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	TypeLogistic = "LOGISTIC"
+	TypeGBT      = "GBT"
+
+	// minTrainSamples - минимум наблюдений в обучающей части каждого фолда
+	minTrainSamples = 100
+)
+
+// registry хранит обученные модели по символу и таймфрейму
+var registry = struct {
+	mu     sync.RWMutex
+	models map[string][]*models.MLModel
+}{
+	models: make(map[string][]*models.MLModel),
+}
+
+// Train обучает модель типа modelType на всей выборке
+func Train(dataset *Dataset, modelType string, opts GBTOptions) (*models.MLModel, error) {
+	if len(dataset.X) < minTrainSamples {
+		return nil, fmt.Errorf("insufficient samples: need %d, got %d", minTrainSamples, len(dataset.X))
+	}
+
+	mean, std := standardization(dataset.X)
+	X := standardize(dataset.X, mean, std)
+
+	model := &models.MLModel{
+		Type:      modelType,
+		Features:  append([]string(nil), FeatureNames...),
+		Mean:      mean,
+		Std:       std,
+		Samples:   len(X),
+		TrainedAt: time.Now(),
+	}
+
+	switch modelType {
+	case TypeLogistic:
+		weights, bias, err := fitLogistic(X, dataset.Y)
+		if err != nil {
+			return nil, err
+		}
+		model.Weights, model.Bias = weights, bias
+	case TypeGBT:
+		bias, trees, err := fitGBT(X, dataset.Y, opts)
+		if err != nil {
+			return nil, err
+		}
+		model.Bias, model.Trees, model.LearningRate = bias, trees, opts.LearningRate
+	default:
+		return nil, fmt.Errorf("unknown model type %q", modelType)
+	}
+
+	return model, nil
+}
+
+// CrossValidate оценивает модель на расширяющемся окне: выборка делится на folds+1
+// последовательных блоков, фолд k обучается на блоках до k и проверяется на блоке k.
+// Между обучением и проверкой пропускается gap наблюдений, чтобы метки не пересекались
+func CrossValidate(dataset *Dataset, modelType string, opts GBTOptions, folds, gap int) (models.MLCVReport, error) {
+	report := models.MLCVReport{Folds: folds}
+	n := len(dataset.X)
+	blockSize := n / (folds + 1)
+	if folds < 1 || blockSize == 0 {
+		return report, fmt.Errorf("not enough samples for %d folds", folds)
+	}
+
+	var logLoss, baseLogLoss float64
+	correct := 0
+	for k := 1; k <= folds; k++ {
+		trainEnd := k*blockSize - gap
+		testEnd := (k + 1) * blockSize
+		if k == folds {
+			testEnd = n
+		}
+		if trainEnd < minTrainSamples {
+			continue
+		}
+
+		train := &Dataset{X: dataset.X[:trainEnd], Y: dataset.Y[:trainEnd]}
+		model, err := Train(train, modelType, opts)
+		if err != nil {
+			return report, fmt.Errorf("fold %d: %w", k, err)
+		}
+
+		baseRate := 0.0
+		for _, label := range train.Y {
+			baseRate += label
+		}
+		baseRate /= float64(len(train.Y))
+
+		for s := k * blockSize; s < testEnd; s++ {
+			p := Probability(model, dataset.X[s])
+			logLoss += binaryLogLoss(p, dataset.Y[s])
+			baseLogLoss += binaryLogLoss(baseRate, dataset.Y[s])
+			if (p >= 0.5) == (dataset.Y[s] == 1) {
+				correct++
+			}
+			report.TestSamples++
+		}
+	}
+	if report.TestSamples == 0 {
+		return report, fmt.Errorf("no fold had at least %d training samples", minTrainSamples)
+	}
+
+	report.LogLoss = logLoss / float64(report.TestSamples)
+	report.BaseLogLoss = baseLogLoss / float64(report.TestSamples)
+	report.Accuracy = float64(correct) / float64(report.TestSamples) * 100
+	return report, nil
+}
+
+// Probability возвращает вероятность роста цены для исходного (нестандартизованного) вектора признаков
+func Probability(model *models.MLModel, features []float64) float64 {
+	return sigmoid(Logit(model, features))
+}
+
+// Logit возвращает логит вероятности роста цены
+func Logit(model *models.MLModel, features []float64) float64 {
+	x := standardizeRow(features, model.Mean, model.Std)
+	logit := model.Bias
+	if model.Type == TypeLogistic {
+		return logit + dot(model.Weights, x)
+	}
+	for _, tree := range model.Trees {
+		logit += treeValue(tree, x)
+	}
+	return logit
+}
+
+// Contributions возвращает вклад признаков в логит логистической регрессии;
+// для бустинга деревьев вклады не разлагаются по признакам, и результат пуст
+func Contributions(model *models.MLModel, features []float64) map[string]float64 {
+	contributions := make(map[string]float64)
+	if model.Type != TypeLogistic {
+		return contributions
+	}
+	x := standardizeRow(features, model.Mean, model.Std)
+	for i, name := range model.Features {
+		contributions[name] = model.Weights[i] * x[i]
+	}
+	return contributions
+}
+
+func binaryLogLoss(p, y float64) float64 {
+	p = math.Min(math.Max(p, 1e-6), 1-1e-6)
+	if y == 1 {
+		return -math.Log(p)
+	}
+	return -math.Log(1 - p)
+}
+
+// standardization возвращает средние и стандартные отклонения признаков
+func standardization(X [][]float64) ([]float64, []float64) {
+	dims := len(X[0])
+	mean := make([]float64, dims)
+	std := make([]float64, dims)
+	for _, x := range X {
+		for f, value := range x {
+			mean[f] += value
+		}
+	}
+	for f := range mean {
+		mean[f] /= float64(len(X))
+	}
+	for _, x := range X {
+		for f, value := range x {
+			std[f] += (value - mean[f]) * (value - mean[f])
+		}
+	}
+	for f := range std {
+		std[f] = math.Sqrt(std[f] / float64(len(X)))
+		if std[f] == 0 {
+			std[f] = 1 // Постоянный признак не влияет на модель
+		}
+	}
+	return mean, std
+}
+
+func standardize(X [][]float64, mean, std []float64) [][]float64 {
+	result := make([][]float64, len(X))
+	for s, x := range X {
+		result[s] = standardizeRow(x, mean, std)
+	}
+	return result
+}
+
+func standardizeRow(x, mean, std []float64) []float64 {
+	row := make([]float64, len(x))
+	for f, value := range x {
+		row[f] = (value - mean[f]) / std[f]
+	}
+	return row
+}
+
+// FileName возвращает имя файла модели для типа, символа и таймфрейма
+func FileName(modelType, symbol, interval string) string {
+	return fmt.Sprintf("ml_%s_%s_%s.json", strings.ToLower(modelType), strings.ReplaceAll(symbol, "/", ""), interval)
+}
+
+// Save сохраняет модель в каталог dir
+func Save(dir string, model *models.MLModel) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating ML model directory: %w", err)
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		return fmt.Errorf("encoding ML model: %w", err)
+	}
+
+	path := filepath.Join(dir, FileName(model.Type, model.Symbol, model.Interval))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing ML model: %w", err)
+	}
+
+	return nil
+}
+
+// Load читает модель из файла и проверяет совместимость признаков
+func Load(path string) (*models.MLModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading ML model: %w", err)
+	}
+
+	var model models.MLModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("parsing ML model %s: %w", path, err)
+	}
+	if strings.Join(model.Features, ",") != strings.Join(FeatureNames, ",") {
+		return nil, fmt.Errorf("ML model %s was trained on a different feature set", path)
+	}
+	if len(model.Mean) != len(FeatureNames) || len(model.Std) != len(FeatureNames) {
+		return nil, fmt.Errorf("ML model %s: standardization does not match features", path)
+	}
+	if model.Type == TypeLogistic && len(model.Weights) != len(FeatureNames) {
+		return nil, fmt.Errorf("ML model %s: weights do not match features", path)
+	}
+	if model.Type == TypeGBT {
+		if err := validateTrees(model.Trees); err != nil {
+			return nil, fmt.Errorf("ML model %s: %w", path, err)
+		}
+	}
+
+	return &model, nil
+}
+
+// LoadDir загружает все модели из каталога
+func LoadDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "ml_*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing ML models: %w", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		model, err := Load(file)
+		if err != nil {
+			return loaded, err
+		}
+		Register(model)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// Register регистрирует модель; модель того же типа для символа и таймфрейма заменяется
+func Register(model *models.MLModel) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	key := FileName("", model.Symbol, model.Interval)
+	registered := registry.models[key][:0:0]
+	for _, existing := range registry.models[key] {
+		if existing.Type != model.Type {
+			registered = append(registered, existing)
+		}
+	}
+	registry.models[key] = append(registered, model)
+}
+
+// Get возвращает модель символа и таймфрейма с наименьшим лог-лоссом на проверке по времени
+func Get(symbol, interval string) (*models.MLModel, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	var best *models.MLModel
+	for _, model := range registry.models[FileName("", symbol, interval)] {
+		if best == nil || model.CV.LogLoss < best.CV.LogLoss {
+			best = model
+		}
+	}
+	return best, best != nil
+}
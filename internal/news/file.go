@@ -0,0 +1,43 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// File читает новости из JSON-файла: массив models.NewsItem или объект
+// с массивами по категориям {"forex": [...], "crypto": [...]}. Используется для
+// воспроизводимых прогонов без сети
+type File struct {
+	path string
+}
+
+// NewFile создает провайдер, читающий новости из файла path
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Name() string { return "file" }
+
+// Fetch перечитывает файл при каждом вызове, поэтому фикстуру можно править без перезапуска
+func (f *File) Fetch(_ context.Context, category string) ([]models.NewsItem, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading news fixture: %w", err)
+	}
+
+	var items []models.NewsItem
+	if err := json.Unmarshal(data, &items); err == nil {
+		return items, nil
+	}
+
+	var byCategory map[string][]models.NewsItem
+	if err := json.Unmarshal(data, &byCategory); err != nil {
+		return nil, fmt.Errorf("parsing news fixture %s: %w", f.path, err)
+	}
+	return byCategory[category], nil
+}
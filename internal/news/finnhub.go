@@ -0,0 +1,80 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+const finnhubNewsURL = "https://finnhub.io/api/v1/news"
+
+// Finnhub - лента рыночных новостей Finnhub
+type Finnhub struct {
+	apiKey     string
+	httpClient *http.Client
+}
+
+// finnhubItem - новость в ответе Finnhub
+type finnhubItem struct {
+	Headline string `json:"headline"`
+	Summary  string `json:"summary"`
+	Datetime int64  `json:"datetime"`
+	Source   string `json:"source"`
+	URL      string `json:"url"`
+	Related  string `json:"related"`
+}
+
+// NewFinnhub создает провайдер Finnhub с таймаутом запроса
+func NewFinnhub(apiKey string, timeout time.Duration) *Finnhub {
+	return &Finnhub{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (f *Finnhub) Name() string { return "finnhub" }
+
+func (f *Finnhub) Fetch(ctx context.Context, category string) ([]models.NewsItem, error) {
+	query := url.Values{}
+	query.Set("category", category)
+	query.Set("token", f.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, finnhubNewsURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating news request: %w", err)
+	}
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching finnhub news: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("finnhub news: status %d: %s", resp.StatusCode, body)
+	}
+
+	var raw []finnhubItem
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("parsing finnhub news: %w", err)
+	}
+
+	items := make([]models.NewsItem, 0, len(raw))
+	for _, item := range raw {
+		items = append(items, models.NewsItem{
+			Title:       item.Headline,
+			Content:     item.Summary,
+			PublishedAt: time.Unix(item.Datetime, 0),
+			Source:      item.Source,
+			URL:         item.URL,
+		})
+	}
+	return items, nil
+}
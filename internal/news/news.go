@@ -0,0 +1,125 @@
+package news
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// Категории новостей провайдера
+const (
+	CategoryForex  = "forex"
+	CategoryCrypto = "crypto"
+)
+
+// Provider - источник новостей
+type Provider interface {
+	// Name возвращает имя провайдера для логов
+	Name() string
+	// Fetch возвращает последние новости категории category
+	Fetch(ctx context.Context, category string) ([]models.NewsItem, error)
+}
+
+// active - провайдер, используемый анализом рынка; nil отключает новости
+var active = struct {
+	mu       sync.RWMutex
+	provider Provider
+}{}
+
+// SetProvider делает провайдер активным; nil отключает новости
+func SetProvider(p Provider) {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	active.provider = p
+}
+
+// Active возвращает активный провайдер или nil, если новости отключены
+func Active() Provider {
+	active.mu.RLock()
+	defer active.mu.RUnlock()
+	return active.provider
+}
+
+// Configure создает провайдер по имени: finnhub (нужен apiKey), file (нужен fixturePath)
+// или none. Пустое имя выбирает finnhub при наличии ключа, иначе none. При ttl > 0
+// провайдер оборачивается кэшем
+func Configure(name, apiKey, fixturePath string, timeout, ttl time.Duration) (Provider, error) {
+	if name == "" {
+		name = "none"
+		if apiKey != "" {
+			name = "finnhub"
+		}
+	}
+
+	var provider Provider
+	switch name {
+	case "none":
+		return nil, nil
+	case "finnhub":
+		if apiKey == "" {
+			return nil, fmt.Errorf("finnhub news provider requires an API key")
+		}
+		provider = NewFinnhub(apiKey, timeout)
+	case "file":
+		if fixturePath == "" {
+			return nil, fmt.Errorf("file news provider requires a fixture path")
+		}
+		provider = NewFile(fixturePath)
+	default:
+		return nil, fmt.Errorf("unknown news provider %q", name)
+	}
+
+	if ttl > 0 {
+		provider = NewCached(provider, ttl)
+	}
+	return provider, nil
+}
+
+// Cached кэширует новости провайдера по категориям на время ttl, чтобы анализ
+// каждой пары и каждого пользователя не запрашивал ленту заново
+type Cached struct {
+	provider Provider
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedNews
+}
+
+type cachedNews struct {
+	items   []models.NewsItem
+	expires time.Time
+}
+
+// NewCached оборачивает провайдер кэшем
+func NewCached(provider Provider, ttl time.Duration) *Cached {
+	return &Cached{
+		provider: provider,
+		ttl:      ttl,
+		entries:  make(map[string]cachedNews),
+	}
+}
+
+func (c *Cached) Name() string { return c.provider.Name() }
+
+// Fetch возвращает новости из кэша или запрашивает их у провайдера. Ошибка не кэшируется
+func (c *Cached) Fetch(ctx context.Context, category string) ([]models.NewsItem, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[category]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.items, nil
+	}
+
+	items, err := c.provider.Fetch(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[category] = cachedNews{items: items, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return items, nil
+}
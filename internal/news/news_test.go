@@ -0,0 +1,235 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+const fixturePath = "testdata/news.json"
+
+func fixtureItem(t *testing.T, category, title string) models.NewsItem {
+	t.Helper()
+	items, err := NewFile(fixturePath).Fetch(context.Background(), category)
+	if err != nil {
+		t.Fatalf("Fetch(%q): %v", category, err)
+	}
+	for _, item := range items {
+		if item.Title == title {
+			return item
+		}
+	}
+	t.Fatalf("fixture item %q not found in %s", title, category)
+	return models.NewsItem{}
+}
+
+func TestFileFetchByCategory(t *testing.T) {
+	provider := NewFile(fixturePath)
+	for category, want := range map[string]int{CategoryForex: 6, CategoryCrypto: 2, "stocks": 0} {
+		items, err := provider.Fetch(context.Background(), category)
+		if err != nil {
+			t.Fatalf("Fetch(%q): %v", category, err)
+		}
+		if len(items) != want {
+			t.Errorf("Fetch(%q) returned %d items, want %d", category, len(items), want)
+		}
+	}
+}
+
+func TestSentimentNegation(t *testing.T) {
+	tests := []struct {
+		text string
+		sign float64
+	}{
+		{"Sterling recovers on upbeat UK retail sales", 1},
+		{"Sterling does not recover despite upbeat UK retail sales", -1},
+		{"Euro didn't rise after the data", -1},
+		{"Euro isn’t weaker", 1},
+		{"Ethereum upgrade fails to lift prices", 0},
+		// Отрицание действует только на negationWindow следующих слов
+		{"No comment from officials today, dollar gains", 1},
+	}
+	for _, tt := range tests {
+		got := Sentiment(tt.text)
+		if sign(got) != tt.sign {
+			t.Errorf("Sentiment(%q) = %.3f, want sign %+.0f", tt.text, got, tt.sign)
+		}
+	}
+}
+
+func TestSentimentWholeWords(t *testing.T) {
+	if got := Sentiment("Yen steady as BoJ update leaves policy unchanged"); got != 0 {
+		t.Errorf("Sentiment matched a word inside %q: %.3f", "update", got)
+	}
+	if got := Sentiment("Dollar up against the yen"); got <= 0 {
+		t.Errorf("Sentiment(%q) = %.3f, want positive", "Dollar up", got)
+	}
+	if got := Sentiment("Downtown traffic"); got != 0 {
+		t.Errorf("Sentiment matched %q inside %q: %.3f", "down", "Downtown", got)
+	}
+}
+
+func TestSentimentSaturation(t *testing.T) {
+	one := Sentiment("Dollar gains")
+	many := Sentiment("Dollar gains, rallies and surges on robust data")
+	if one <= 0 || many <= one || many >= 1 {
+		t.Errorf("Sentiment should grow with agreeing words below 1: one=%.3f many=%.3f", one, many)
+	}
+}
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		category string
+		title    string
+		want     []string
+	}{
+		{CategoryForex, "Dollar slides as Fed signals rate cuts later this year", []string{"USD"}},
+		{CategoryForex, "Euro firms after ECB keeps hawkish tone", []string{"EUR"}},
+		{CategoryForex, "EUR/USD jumps to a two-week high", []string{"EUR", "USD"}},
+		{CategoryForex, "Gold climbs to record as bullion demand stays strong", []string{"XAU"}},
+		{CategoryCrypto, "Bitcoin tumbles as ETF outflows accelerate", []string{"BTC"}},
+	}
+	for _, tt := range tests {
+		if got := Tag(fixtureItem(t, tt.category, tt.title)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tag(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+
+	// Ключевые слова сравниваются целиком: "uk" не находится внутри "duke"
+	if got := Tag(models.NewsItem{Title: "Duke of York visits the port"}); len(got) != 0 {
+		t.Errorf("Tag matched a keyword inside a word: %v", got)
+	}
+}
+
+func TestPairSentimentSigns(t *testing.T) {
+	dollar := fixtureItem(t, CategoryForex, "Dollar slides as Fed signals rate cuts later this year")
+	pair := fixtureItem(t, CategoryForex, "EUR/USD jumps to a two-week high")
+
+	tests := []struct {
+		name      string
+		item      models.NewsItem
+		symbol    string
+		sign      float64
+		relevance float64
+	}{
+		// Слабый доллар как котируемая валюта - рост пары, как базовая - падение
+		{"bad news for quote", dollar, "EUR/USD", 1, relevanceSingle},
+		{"bad news for base", dollar, "USD/JPY", -1, relevanceSingle},
+		{"pair mentioned", pair, "EUR/USD", 1, relevancePair},
+		{"pair mentioned without slash", models.NewsItem{Title: "EURUSD rallies"}, "EUR/USD", 1, relevancePair},
+		// Рост EUR/USD - рост евро и падение доллара, поэтому для USD/CAD это медвежья новость
+		{"pair quote is other base", pair, "USD/CAD", -1, relevanceSingle},
+		{"both in one role", models.NewsItem{Title: "Euro and dollar rise"}, "EUR/USD", 0, relevanceBoth},
+		{"unrelated", dollar, "AUD/NZD", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentiment, relevance, _ := PairSentiment(tt.item, tt.symbol)
+			if sign(sentiment) != tt.sign {
+				t.Errorf("sentiment = %.3f, want sign %+.0f", sentiment, tt.sign)
+			}
+			if relevance != tt.relevance {
+				t.Errorf("relevance = %.2f, want %.2f", relevance, tt.relevance)
+			}
+		})
+	}
+}
+
+func TestCategoryFor(t *testing.T) {
+	for symbol, want := range map[string]string{
+		"BTC/USD": CategoryCrypto,
+		"eth/usd": CategoryCrypto,
+		"EUR/USD": CategoryForex,
+		"XAU/USD": CategoryForex,
+	} {
+		if got := CategoryFor(symbol); got != want {
+			t.Errorf("CategoryFor(%q) = %q, want %q", symbol, got, want)
+		}
+	}
+}
+
+// countingProvider считает обращения и возвращает ошибку, пока она задана
+type countingProvider struct {
+	calls int
+	err   error
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Fetch(_ context.Context, category string) ([]models.NewsItem, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []models.NewsItem{{Title: category}}, nil
+}
+
+func TestCachedExpiry(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingProvider{}
+	cached := NewCached(provider, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cached.Fetch(ctx, CategoryForex); err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Fatalf("provider called %d times within ttl, want 1", provider.calls)
+	}
+
+	// Категории кэшируются раздельно
+	if _, err := cached.Fetch(ctx, CategoryCrypto); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Fatalf("provider called %d times for a new category, want 2", provider.calls)
+	}
+
+	// Истекшая запись запрашивается заново
+	cached.mu.Lock()
+	entry := cached.entries[CategoryForex]
+	entry.expires = time.Now().Add(-time.Second)
+	cached.entries[CategoryForex] = entry
+	cached.mu.Unlock()
+
+	if _, err := cached.Fetch(ctx, CategoryForex); err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if provider.calls != 3 {
+		t.Fatalf("provider called %d times after expiry, want 3", provider.calls)
+	}
+}
+
+func TestCachedDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingProvider{err: errors.New("unavailable")}
+	cached := NewCached(provider, time.Hour)
+
+	if _, err := cached.Fetch(ctx, CategoryForex); err == nil {
+		t.Fatal("Fetch returned no error from a failing provider")
+	}
+
+	provider.err = nil
+	items, err := cached.Fetch(ctx, CategoryForex)
+	if err != nil {
+		t.Fatalf("Fetch after recovery: %v", err)
+	}
+	if len(items) != 1 || provider.calls != 2 {
+		t.Errorf("got %d items after %d calls, want 1 item after 2 calls", len(items), provider.calls)
+	}
+}
+
+func sign(value float64) float64 {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package news
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// currencyKeywords - слова и фразы заголовков, относящие новость к валюте или активу
+var currencyKeywords = map[string][]string{
+	"USD":  {"usd", "dollar", "greenback", "fed", "federal reserve", "fomc", "powell", "treasury", "treasuries", "nonfarm", "payrolls", "u s"},
+	"EUR":  {"eur", "euro", "eurozone", "euro zone", "ecb", "lagarde", "germany", "german", "bund"},
+	"GBP":  {"gbp", "sterling", "pound", "cable", "boe", "bank of england", "bailey", "uk", "britain", "british"},
+	"JPY":  {"jpy", "yen", "boj", "bank of japan", "ueda", "japan", "japanese"},
+	"CHF":  {"chf", "franc", "swiss", "snb"},
+	"CAD":  {"cad", "loonie", "canada", "canadian", "boc", "bank of canada"},
+	"AUD":  {"aud", "aussie", "australia", "australian", "rba"},
+	"NZD":  {"nzd", "kiwi", "new zealand", "rbnz"},
+	"XAU":  {"xau", "gold", "bullion"},
+	"XAG":  {"xag", "silver"},
+	"XBR":  {"xbr", "brent", "crude", "oil", "opec"},
+	"BTC":  {"btc", "bitcoin"},
+	"ETH":  {"eth", "ethereum", "ether"},
+	"SOL":  {"sol", "solana"},
+	"XRP":  {"xrp", "ripple"},
+	"ADA":  {"ada", "cardano"},
+	"AAVE": {"aave"},
+	"BNB":  {"bnb", "binance"},
+	"DOT":  {"polkadot"},
+}
+
+// cryptoAssets - активы, новости которых берутся из криптовалютной ленты
+var cryptoAssets = map[string]bool{
+	"BTC": true, "ETH": true, "SOL": true, "XRP": true, "ADA": true, "AAVE": true, "BNB": true, "DOT": true,
+}
+
+// Релевантность новости для пары в зависимости от того, что в ней упомянуто
+const (
+	relevancePair   = 1.0 // Пара целиком, например "EUR/USD"
+	relevanceSingle = 0.7 // Только одна из валют пары
+	relevanceBoth   = 0.4 // Обе валюты без указания пары: направление неоднозначно
+)
+
+// Currencies возвращает базовую и котируемую валюты пары "EUR/USD"
+func Currencies(symbol string) (string, string) {
+	base, quote, _ := strings.Cut(strings.ToUpper(symbol), "/")
+	return base, quote
+}
+
+// CategoryFor возвращает категорию ленты новостей для пары
+func CategoryFor(symbol string) string {
+	if base, _ := Currencies(symbol); cryptoAssets[base] {
+		return CategoryCrypto
+	}
+	return CategoryForex
+}
+
+// Tag возвращает валюты и активы, упомянутые в заголовке и тексте новости, по алфавиту.
+// Пара "GBP/USD" относит новость к обеим валютам
+func Tag(item models.NewsItem) []string {
+	text := " " + strings.ReplaceAll(strings.Join(Tokenize(item.Title+" "+item.Content), " "), "/", " ") + " "
+
+	var tags []string
+	for currency, keywords := range currencyKeywords {
+		for _, keyword := range keywords {
+			if strings.Contains(text, " "+keyword+" ") {
+				tags = append(tags, currency)
+				break
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// currencySigns возвращает, в какую сторону тональность новости действует на каждую
+// упомянутую валюту: +1 - по тональности, -1 - против нее. Упоминание пары "EUR/USD"
+// означает +1 для базовой валюты и -1 для котируемой; остальные упоминания дают +1
+func currencySigns(item models.NewsItem) map[string]float64 {
+	signs := make(map[string]float64)
+	for _, token := range Tokenize(item.Title + " " + item.Content) {
+		base, quote, ok := strings.Cut(token, "/")
+		if !ok {
+			continue
+		}
+		base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+		if _, known := currencyKeywords[base]; !known {
+			continue
+		}
+		if _, known := currencyKeywords[quote]; !known {
+			continue
+		}
+		if _, set := signs[base]; !set {
+			signs[base] = 1
+		}
+		if _, set := signs[quote]; !set {
+			signs[quote] = -1
+		}
+	}
+	for _, currency := range Tag(item) {
+		if _, set := signs[currency]; !set {
+			signs[currency] = 1
+		}
+	}
+	return signs
+}
+
+// PairSentiment переводит новость в тональность для пары: хорошая новость о базовой валюте
+// бычья для пары, о котируемой - медвежья. Возвращает тональность для пары, релевантность
+// (0, если новость не касается пары) и упомянутые валюты
+func PairSentiment(item models.NewsItem, symbol string) (float64, float64, []string) {
+	base, quote := Currencies(symbol)
+	tags := Tag(item)
+	signs := currencySigns(item)
+	sentiment := Sentiment(item.Title + ". " + item.Content)
+
+	pair := strings.ToLower(base + "/" + quote)
+	for _, token := range Tokenize(item.Title + " " + item.Content) {
+		if token == pair || token == strings.ReplaceAll(pair, "/", "") {
+			return sentiment, relevancePair, tags
+		}
+	}
+
+	baseSign, hasBase := signs[base]
+	quoteSign, hasQuote := signs[quote]
+	direction := baseSign - quoteSign
+	switch {
+	case hasBase && hasQuote && direction == 0:
+		// Обе валюты упомянуты в одной роли: направление для пары не определить
+		return 0, relevanceBoth, tags
+	case hasBase || hasQuote:
+		return sentiment * math.Max(-1, math.Min(1, direction)), relevanceSingle, tags
+	}
+	return 0, 0, tags
+}
@@ -0,0 +1,98 @@
+package news
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// negationWindow - число слов после отрицания, полярность которых инвертируется
+const negationWindow = 3
+
+// sentimentSaturation сглаживает сумму полярностей: score = sum / sqrt(sum² + saturation),
+// так что одно слово дает около ±0.45, а несколько согласных слов приближают оценку к ±1
+const sentimentSaturation = 4.0
+
+// lexicon - полярность слов финансовых заголовков для валюты или актива, о котором идет речь
+var lexicon = map[string]float64{
+	// Рост и сила
+	"rise": 1, "rises": 1, "rising": 1, "rose": 1,
+	"gain": 1, "gains": 1, "gained": 1,
+	"climb": 1, "climbs": 1, "climbed": 1,
+	"rally": 1, "rallies": 1, "rallied": 1,
+	"surge": 1.5, "surges": 1.5, "surged": 1.5, "soar": 1.5, "soars": 1.5,
+	"jump": 1, "jumps": 1, "jumped": 1,
+	"advance": 1, "advances": 1, "rebound": 1, "rebounds": 1, "recovery": 1, "recovers": 1,
+	"strong": 1, "stronger": 1, "strength": 1, "strengthens": 1, "firm": 0.5, "firmer": 0.5,
+	"robust": 1, "upbeat": 1, "optimism": 1, "optimistic": 1, "bullish": 1.5,
+	"beat": 1, "beats": 1, "boost": 1, "boosts": 1, "growth": 0.5, "expands": 0.5, "expansion": 0.5,
+	"hawkish": 1, "hike": 1, "hikes": 1, "higher": 0.5, "up": 0.5,
+
+	// Падение и слабость
+	"fall": -1, "falls": -1, "fell": -1, "falling": -1,
+	"drop": -1, "drops": -1, "dropped": -1,
+	"slide": -1, "slides": -1, "slid": -1, "slip": -0.5, "slips": -0.5,
+	"decline": -1, "declines": -1, "declined": -1,
+	"slump": -1.5, "slumps": -1.5, "plunge": -1.5, "plunges": -1.5, "tumble": -1.5, "tumbles": -1.5,
+	"crash": -2, "crashes": -2, "selloff": -1.5, "sell-off": -1.5,
+	"retreat": -1, "retreats": -1, "weak": -1, "weaker": -1, "weakness": -1, "weakens": -1,
+	"soft": -0.5, "softer": -0.5, "downbeat": -1, "pessimism": -1, "bearish": -1.5,
+	"miss": -1, "misses": -1, "missed": -1, "slowdown": -1, "slows": -0.5, "contraction": -1, "contracts": -1,
+	"recession": -1.5, "crisis": -1.5, "fears": -1, "concern": -0.5, "concerns": -0.5,
+	"dovish": -1, "cut": -1, "cuts": -1, "lower": -0.5, "loss": -1, "losses": -1, "down": -0.5,
+}
+
+// negations инвертируют полярность следующих слов
+var negations = map[string]bool{
+	"not": true, "no": true, "never": true, "without": true, "nor": true,
+	"neither": true, "hardly": true, "barely": true, "fails": true, "failed": true,
+}
+
+// intensifiers усиливают полярность следующего слова
+var intensifiers = map[string]float64{
+	"sharply": 1.5, "strongly": 1.5, "significantly": 1.5, "sharp": 1.5, "steep": 1.5,
+	"slightly": 0.5, "modestly": 0.5, "marginally": 0.5, "mildly": 0.5,
+}
+
+// Tokenize разбивает текст на слова в нижнем регистре; апострофы и дефисы остаются внутри слова
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’' && r != '-' && r != '/'
+	})
+}
+
+// Sentiment оценивает тональность текста по словарю в диапазоне -1..1. Слова сравниваются
+// целиком, поэтому "up" не находится внутри "update". Отрицание ("not", "no", "didn't")
+// инвертирует полярность следующих negationWindow слов
+func Sentiment(text string) float64 {
+	var sum float64
+	negatedFor := 0
+	intensity := 1.0
+
+	for _, token := range Tokenize(text) {
+		if negations[token] || strings.HasSuffix(token, "n't") || strings.HasSuffix(token, "n’t") {
+			negatedFor = negationWindow
+			continue
+		}
+		if boost, ok := intensifiers[token]; ok {
+			intensity = boost
+			continue
+		}
+
+		if polarity, ok := lexicon[token]; ok {
+			if negatedFor > 0 {
+				polarity = -polarity
+			}
+			sum += polarity * intensity
+		}
+		intensity = 1.0
+		if negatedFor > 0 {
+			negatedFor--
+		}
+	}
+
+	if sum == 0 {
+		return 0
+	}
+	return sum / math.Sqrt(sum*sum+sentimentSaturation)
+}
@@ -0,0 +1,218 @@
+package params
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// DefaultVersion - версия встроенных параметров, используемых без файла
+const DefaultVersion = "builtin"
+
+// active хранит текущий набор параметров скоринга и время изменения загруженного файла
+var active = struct {
+	mu      sync.RWMutex
+	params  *models.StrategyParams
+	modTime time.Time
+}{
+	params: Default(),
+}
+
+// Default возвращает встроенные веса и пороги EnhancedPrediction
+func Default() *models.StrategyParams {
+	return &models.StrategyParams{
+		Version:     DefaultVersion,
+		Description: "Built-in scoring weights",
+		Weights: models.ScoringWeights{
+			RegimeTrend:    2.0,
+			RegimeRange:    0.5,
+			TrendAlignment: 1.5,
+			RSI:            1.0,
+			MACD:           0.8,
+			Stochastic:     0.7,
+			Patterns: map[string]float64{
+				"BULLISH_ENGULFING":       1.8,
+				"HAMMER":                  1.8,
+				"MORNING_STAR":            1.8,
+				"BEARISH_ENGULFING":       1.8,
+				"SHOOTING_STAR":           1.8,
+				"EVENING_STAR":            1.8,
+				"THREE_WHITE_SOLDIERS":    2.0,
+				"THREE_BLACK_CROWS":       2.0,
+				"STRONG_BULLISH_MOMENTUM": 1.2,
+				"STRONG_BEARISH_MOMENTUM": 1.2,
+				"DOUBLE_BOTTOM":           1.5,
+				"DOUBLE_TOP":              1.5,
+			},
+			SupportPenalty:       0.8,
+			SupportBonus:         0.5,
+			OrderFlow:            1.0,
+			StrongTradeSignal:    1.5,
+			TradeSignal:          0.8,
+			RegularDivergence:    1.5,
+			HiddenDivergence:     1.0,
+			DivergenceConfluence: 0.5,
+			AnomalyPenalty:       0.3,
+			HighVolatility:       0.8,
+			LowVolatility:        0.9,
+			MarketAnalysis:       1.5,
+			CurrencyStrength:     1.0,
+		},
+		Thresholds: models.ScoringThresholds{
+			Direction:            1.5,
+			HighConfidence:       3.0,
+			LowConfidence:        2.0,
+			RSIOversold:          30,
+			RSIOverbought:        70,
+			StochasticOversold:   20,
+			StochasticOverbought: 80,
+		},
+		Risk: models.RiskParams{
+			AccountSize:    10000,
+			RiskPerTrade:   0.01,
+			VolatilityBars: 12,
+		},
+	}
+}
+
+// Active возвращает текущий набор параметров. Возвращаемое значение нельзя изменять:
+// при перезагрузке подменяется указатель целиком
+func Active() *models.StrategyParams {
+	active.mu.RLock()
+	defer active.mu.RUnlock()
+	return active.params
+}
+
+// Set делает набор параметров активным
+func Set(p *models.StrategyParams) {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	active.params = p
+}
+
+// Load читает параметры из JSON-файла. Отсутствующие в файле поля берутся из Default,
+// поэтому файл может переопределять только часть весов
+func Load(path string) (*models.StrategyParams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading strategy params: %w", err)
+	}
+
+	p := Default()
+	p.Version = ""
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("parsing strategy params %s: %w", path, err)
+	}
+	if err := Validate(p); err != nil {
+		return nil, fmt.Errorf("invalid strategy params %s: %w", path, err)
+	}
+
+	return p, nil
+}
+
+// Validate проверяет согласованность порогов
+func Validate(p *models.StrategyParams) error {
+	if p.Version == "" {
+		return fmt.Errorf("version is required")
+	}
+	t := p.Thresholds
+	if t.Direction <= 0 {
+		return fmt.Errorf("direction threshold must be positive")
+	}
+	if t.LowConfidence > t.HighConfidence {
+		return fmt.Errorf("low confidence threshold %.2f exceeds high confidence threshold %.2f",
+			t.LowConfidence, t.HighConfidence)
+	}
+	if t.RSIOversold >= t.RSIOverbought || t.StochasticOversold >= t.StochasticOverbought {
+		return fmt.Errorf("oversold thresholds must be below overbought thresholds")
+	}
+	if p.Risk.AccountSize <= 0 || p.Risk.RiskPerTrade <= 0 || p.Risk.RiskPerTrade > 1 || p.Risk.VolatilityBars < 1 {
+		return fmt.Errorf("risk parameters out of range")
+	}
+	return nil
+}
+
+// LoadFile загружает параметры из файла и делает их активными
+func LoadFile(path string) (*models.StrategyParams, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading strategy params: %w", err)
+	}
+
+	p, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	active.mu.Lock()
+	active.params = p
+	active.modTime = info.ModTime()
+	active.mu.Unlock()
+
+	return p, nil
+}
+
+// Watch периодически проверяет время изменения файла и перезагружает параметры.
+// При ошибке загрузки остаются прежние параметры; onReload вызывается после каждой попытки
+func Watch(ctx context.Context, path string, interval time.Duration, onReload func(*models.StrategyParams, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+
+			active.mu.RLock()
+			unchanged := info.ModTime().Equal(active.modTime)
+			active.mu.RUnlock()
+			if unchanged {
+				continue
+			}
+
+			p, err := LoadFile(path)
+			if err != nil {
+				// Запоминаем время изменения, чтобы не повторять ошибку до следующей правки файла
+				active.mu.Lock()
+				active.modTime = info.ModTime()
+				active.mu.Unlock()
+			}
+			if onReload != nil {
+				onReload(p, err)
+			}
+		}
+	}
+}
+
+// Direction переводит итоговый счет в направление и уверенность по порогам параметров
+func Direction(p *models.StrategyParams, netScore float64) (string, string) {
+	t := p.Thresholds
+
+	direction := "NEUTRAL"
+	if netScore > t.Direction {
+		direction = "BUY"
+	} else if netScore < -t.Direction {
+		direction = "SELL"
+	}
+
+	confidence := "MEDIUM"
+	absoluteNetScore := math.Abs(netScore)
+	if absoluteNetScore > t.HighConfidence {
+		confidence = "HIGH"
+	} else if absoluteNetScore < t.LowConfidence {
+		confidence = "LOW"
+	}
+
+	return direction, confidence
+}
@@ -6,97 +6,200 @@ import (
 	"github.com/Alias1177/Predictor/models"
 )
 
+// DivergenceConfig задает параметры поиска дивергенций
+type DivergenceConfig struct {
+	SwingStrength int // Количество свечей слева и справа для подтверждения свинга
+	Lookback      int // Сколько последних свечей учитывать (0 - вся история)
+}
+
+// DefaultDivergenceConfig возвращает параметры поиска дивергенций по умолчанию
+func DefaultDivergenceConfig() DivergenceConfig {
+	return DivergenceConfig{
+		SwingStrength: 3,
+		Lookback:      0,
+	}
+}
+
 // DetectDivergences находит расхождения между ценой и индикаторами импульса
+// (RSI, гистограмма MACD, Stochastic, OBV, CCI, MFI) с параметрами по умолчанию
 func DetectDivergences(candles []models.Candle, indicators *models.TechnicalIndicators) []models.Divergence {
+	return DetectMultiIndicatorDivergences(candles, indicators, DefaultDivergenceConfig())
+}
+
+// DetectMultiIndicatorDivergences находит дивергенции по всем поддерживаемым осцилляторам
+func DetectMultiIndicatorDivergences(candles []models.Candle, indicators *models.TechnicalIndicators, cfg DivergenceConfig) []models.Divergence {
 	if len(candles) < 30 {
 		return nil
 	}
 
-	var divergences []models.Divergence
+	rsiValues := RSISeries(candles, 14)
+	// Используем значение из indicators для последней свечи
+	if indicators != nil {
+		rsiValues[len(rsiValues)-1] = indicators.RSI
+	}
+
+	series := []struct {
+		name   string
+		values []float64
+	}{
+		{"RSI", rsiValues},
+		{"MACD", MACDHistogramSeries(candles, 12, 26, 9)},
+		{"STOCHASTIC", StochasticSeries(candles, 14)},
+		{"OBV", OBVSeries(candles)},
+		{"CCI", CCISeries(candles, 20)},
+		{"MFI", MFISeries(candles, 14)},
+	}
 
-	// Получаем исторические значения RSI
-	rsiValues := make([]float64, len(candles))
-	for i := 0; i < len(candles); i++ {
-		windowSize := min(i+1, 14)
-		if windowSize < 5 {
-			rsiValues[i] = 50 // Значение по умолчанию при недостатке данных
+	var divergences []models.Divergence
+	for _, s := range series {
+		// OBV и MFI недоступны без данных объема
+		if s.values == nil {
 			continue
 		}
+		divergences = append(divergences, DetectIndicatorDivergences(candles, s.values, s.name, cfg)...)
+	}
 
-		// Используем значение из indicators для последней свечи, иначе вычисляем сами
-		if i == len(candles)-1 && indicators != nil {
-			rsiValues[i] = indicators.RSI
-		} else {
-			// Рассчитываем RSI самостоятельно без зависимости от calculate
-			window := candles[max(0, i-windowSize+1) : i+1]
-			rsiValues[i] = calculateRSI(window, windowSize)
-		}
+	return divergences
+}
+
+// DetectIndicatorDivergences находит дивергенции между ценой и произвольным рядом осциллятора.
+// Ряд должен совпадать по длине со свечами; результаты помечаются именем индикатора.
+func DetectIndicatorDivergences(candles []models.Candle, values []float64, indicator string, cfg DivergenceConfig) []models.Divergence {
+	if len(candles) < 30 || len(values) != len(candles) {
+		return nil
+	}
+
+	strength := cfg.SwingStrength
+	if strength <= 0 {
+		strength = DefaultDivergenceConfig().SwingStrength
 	}
 
+	// Приводим ряд к положительной шкале, чтобы отношения значений имели смысл
+	// для индикаторов, меняющих знак (MACD, CCI, OBV)
+	normalized := normalizeOscillator(values)
+
 	// Находим ценовые свинг-хаи и лоу
-	swingHighs, swingLows := findSwingPoints(candles, 3)
+	swingHighs, swingLows := findSwingPoints(candles, strength)
+
+	// Находим свинг-хаи и лоу индикатора
+	indSwingHighs, indSwingLows := findIndicatorSwings(normalized, strength)
+
+	// Отбрасываем свинги за пределами окна анализа
+	if cfg.Lookback > 0 {
+		from := len(candles) - cfg.Lookback
+		swingHighs = swingsFrom(swingHighs, from)
+		swingLows = swingsFrom(swingLows, from)
+		indSwingHighs = swingsFrom(indSwingHighs, from)
+		indSwingLows = swingsFrom(indSwingLows, from)
+	}
 
-	// Находим свинг-хаи и лоу RSI
-	rsiSwingHighs, rsiSwingLows := findIndicatorSwings(rsiValues, 3)
+	var divergences []models.Divergence
 
 	// Проверяем на обычные медвежьи дивергенции
-	// Цена делает более высокий максимум, а RSI - более низкий максимум
-	divergences = append(divergences, detectRegularBearishDivergence(candles, rsiValues, swingHighs, rsiSwingHighs)...)
+	// Цена делает более высокий максимум, а индикатор - более низкий максимум
+	divergences = append(divergences, detectRegularBearishDivergence(candles, normalized, swingHighs, indSwingHighs, indicator)...)
 
 	// Проверяем на обычные бычьи дивергенции
-	// Цена делает более низкий минимум, а RSI - более высокий минимум
-	divergences = append(divergences, detectRegularBullishDivergence(candles, rsiValues, swingLows, rsiSwingLows)...)
+	// Цена делает более низкий минимум, а индикатор - более высокий минимум
+	divergences = append(divergences, detectRegularBullishDivergence(candles, normalized, swingLows, indSwingLows, indicator)...)
 
 	// Проверяем на скрытые медвежьи дивергенции
-	// Цена делает более низкий максимум, а RSI - более высокий максимум
-	divergences = append(divergences, detectHiddenBearishDivergence(candles, rsiValues, swingHighs, rsiSwingHighs)...)
+	// Цена делает более низкий максимум, а индикатор - более высокий максимум
+	divergences = append(divergences, detectHiddenBearishDivergence(candles, normalized, swingHighs, indSwingHighs, indicator)...)
 
 	// Проверяем на скрытые бычьи дивергенции
-	// Цена делает более высокий минимум, а RSI - более низкий минимум
-	divergences = append(divergences, detectHiddenBullishDivergence(candles, rsiValues, swingLows, rsiSwingLows)...)
+	// Цена делает более высокий минимум, а индикатор - более низкий минимум
+	divergences = append(divergences, detectHiddenBullishDivergence(candles, normalized, swingLows, indSwingLows, indicator)...)
+
+	// Возвращаем исходные значения индикатора в точках дивергенции
+	for i := range divergences {
+		for j := range divergences[i].IndicatorPoints {
+			idx := divergences[i].IndicatorPoints[j].Index
+			divergences[i].IndicatorPoints[j].Value = values[idx]
+		}
+	}
 
 	return divergences
 }
 
-// calculateRSI рассчитывает RSI напрямую, чтобы избежать зависимости от пакета calculate
-func calculateRSI(candles []models.Candle, period int) float64 {
-	if len(candles) < period+1 {
-		return 50.0 // Значение по умолчанию при недостатке данных
-	}
+// FindDivergenceConfluence группирует дивергенции одного направления, завершившиеся
+// в пределах tolerance свечей друг от друга на разных индикаторах
+func FindDivergenceConfluence(divergences []models.Divergence, tolerance int) []models.DivergenceConfluence {
+	var confluences []models.DivergenceConfluence
+	used := make([]bool, len(divergences))
 
-	var gains, losses float64
-	// Рассчитываем начальные средние
-	for i := 1; i <= period; i++ {
-		change := candles[i].Close - candles[i-1].Close
-		if change > 0 {
-			gains += change
-		} else {
-			losses -= change
+	for i, base := range divergences {
+		if used[i] || len(base.PricePoints) == 0 {
+			continue
+		}
+		baseIndex := base.PricePoints[len(base.PricePoints)-1].Index
+
+		group := models.DivergenceConfluence{
+			Direction:  base.Direction,
+			SwingIndex: baseIndex,
+		}
+		seen := make(map[string]bool)
+		var totalStrength float64
+
+		for j := i; j < len(divergences); j++ {
+			d := divergences[j]
+			if used[j] || d.Direction != base.Direction || len(d.PricePoints) == 0 {
+				continue
+			}
+			if abs(d.PricePoints[len(d.PricePoints)-1].Index-baseIndex) > tolerance {
+				continue
+			}
+			used[j] = true
+			if !seen[d.Indicator] {
+				seen[d.Indicator] = true
+				group.Indicators = append(group.Indicators, d.Indicator)
+			}
+			totalStrength += d.SignalStrength
+			group.Count++
+		}
+
+		if group.Count > 0 {
+			group.AverageStrength = totalStrength / float64(group.Count)
 		}
+		confluences = append(confluences, group)
 	}
 
-	// Рассчитываем начальные средние
-	avgGain := gains / float64(period)
-	avgLoss := losses / float64(period)
+	return confluences
+}
+
+// normalizeOscillator масштабирует ряд в диапазон [1, 101], сохраняя порядок значений
+func normalizeOscillator(values []float64) []float64 {
+	if len(values) == 0 {
+		return nil
+	}
 
-	// Используем EMA (экспоненциальное скользящее среднее) для остальных данных
-	for i := period + 1; i < len(candles); i++ {
-		change := candles[i].Close - candles[i-1].Close
-		if change > 0 {
-			avgGain = (avgGain*float64(period-1) + change) / float64(period)
-			avgLoss = (avgLoss*float64(period-1) + 0) / float64(period)
+	lowest, highest := values[0], values[0]
+	for _, v := range values {
+		lowest = math.Min(lowest, v)
+		highest = math.Max(highest, v)
+	}
+
+	normalized := make([]float64, len(values))
+	for i, v := range values {
+		if highest > lowest {
+			normalized[i] = 1 + (v-lowest)/(highest-lowest)*100
 		} else {
-			avgGain = (avgGain*float64(period-1) + 0) / float64(period)
-			avgLoss = (avgLoss*float64(period-1) - change) / float64(period)
+			normalized[i] = 51
 		}
 	}
 
-	if avgLoss == 0 {
-		return 100.0
-	}
+	return normalized
+}
 
-	rs := avgGain / avgLoss
-	return 100.0 - (100.0 / (1.0 + rs))
+// swingsFrom оставляет только свинги с индексом не меньше from
+func swingsFrom(swings []int, from int) []int {
+	var result []int
+	for _, s := range swings {
+		if s >= from {
+			result = append(result, s)
+		}
+	}
+	return result
 }
 
 // findIndicatorSwings находит точки разворота в значениях индикатора
@@ -146,11 +249,11 @@ func findIndicatorSwings(values []float64, strength int) ([]int, []int) {
 	return swingHighs, swingLows
 }
 
-func detectRegularBearishDivergence(candles []models.Candle, rsiValues []float64, priceSwingHighs, rsiSwingHighs []int) []models.Divergence {
+func detectRegularBearishDivergence(candles []models.Candle, values []float64, priceSwingHighs, indSwingHighs []int, indicator string) []models.Divergence {
 	var divergences []models.Divergence
 
 	// Необходимо как минимум 2 свинг-хая для сравнения
-	if len(priceSwingHighs) < 2 || len(rsiSwingHighs) < 2 {
+	if len(priceSwingHighs) < 2 || len(indSwingHighs) < 2 {
 		return divergences
 	}
 
@@ -164,14 +267,14 @@ func detectRegularBearishDivergence(candles []models.Candle, rsiValues []float64
 			continue
 		}
 
-		// Находим ближайшие свинг-хаи RSI
-		r1, r2 := findClosestSwings(p1, p2, rsiSwingHighs)
+		// Находим ближайшие свинг-хаи индикатора
+		r1, r2 := findClosestSwings(p1, p2, indSwingHighs)
 		if r1 < 0 || r2 < 0 {
 			continue
 		}
 
-		// Проверяем, сделал ли RSI более низкий максимум
-		if rsiValues[r2] >= rsiValues[r1] {
+		// Проверяем, сделал ли индикатор более низкий максимум
+		if values[r2] >= values[r1] {
 			continue
 		}
 
@@ -184,13 +287,13 @@ func detectRegularBearishDivergence(candles []models.Candle, rsiValues []float64
 				{Index: p2, Value: candles[p2].High},
 			},
 			IndicatorPoints: []models.DivergencePoint{
-				{Index: r1, Value: rsiValues[r1]},
-				{Index: r2, Value: rsiValues[r2]},
+				{Index: r1, Value: values[r1]},
+				{Index: r2, Value: values[r2]},
 			},
-			Indicator: "RSI",
+			Indicator: indicator,
 			SignalStrength: calculateDivergenceStrength(
 				candles[p2].High/candles[p1].High,
-				rsiValues[r1]/rsiValues[r2],
+				values[r1]/values[r2],
 			),
 		}
 
@@ -201,11 +304,11 @@ func detectRegularBearishDivergence(candles []models.Candle, rsiValues []float64
 }
 
 // Реализации других функций обнаружения дивергенций
-func detectRegularBullishDivergence(candles []models.Candle, rsiValues []float64, priceSwingLows, rsiSwingLows []int) []models.Divergence {
+func detectRegularBullishDivergence(candles []models.Candle, values []float64, priceSwingLows, indSwingLows []int, indicator string) []models.Divergence {
 	var divergences []models.Divergence
 
 	// Необходимо как минимум 2 свинг-лоу для сравнения
-	if len(priceSwingLows) < 2 || len(rsiSwingLows) < 2 {
+	if len(priceSwingLows) < 2 || len(indSwingLows) < 2 {
 		return divergences
 	}
 
@@ -219,14 +322,14 @@ func detectRegularBullishDivergence(candles []models.Candle, rsiValues []float64
 			continue
 		}
 
-		// Находим ближайшие свинг-лоу RSI
-		r1, r2 := findClosestSwings(p1, p2, rsiSwingLows)
+		// Находим ближайшие свинг-лоу индикатора
+		r1, r2 := findClosestSwings(p1, p2, indSwingLows)
 		if r1 < 0 || r2 < 0 {
 			continue
 		}
 
-		// Проверяем, сделал ли RSI более высокий минимум
-		if rsiValues[r2] <= rsiValues[r1] {
+		// Проверяем, сделал ли индикатор более высокий минимум
+		if values[r2] <= values[r1] {
 			continue
 		}
 
@@ -239,13 +342,13 @@ func detectRegularBullishDivergence(candles []models.Candle, rsiValues []float64
 				{Index: p2, Value: candles[p2].Low},
 			},
 			IndicatorPoints: []models.DivergencePoint{
-				{Index: r1, Value: rsiValues[r1]},
-				{Index: r2, Value: rsiValues[r2]},
+				{Index: r1, Value: values[r1]},
+				{Index: r2, Value: values[r2]},
 			},
-			Indicator: "RSI",
+			Indicator: indicator,
 			SignalStrength: calculateDivergenceStrength(
 				candles[p1].Low/candles[p2].Low, // Инвертируем соотношение
-				rsiValues[r2]/rsiValues[r1],
+				values[r2]/values[r1],
 			),
 		}
 
@@ -255,11 +358,11 @@ func detectRegularBullishDivergence(candles []models.Candle, rsiValues []float64
 	return divergences
 }
 
-func detectHiddenBearishDivergence(candles []models.Candle, rsiValues []float64, priceSwingHighs, rsiSwingHighs []int) []models.Divergence {
+func detectHiddenBearishDivergence(candles []models.Candle, values []float64, priceSwingHighs, indSwingHighs []int, indicator string) []models.Divergence {
 	var divergences []models.Divergence
 
 	// Необходимо как минимум 2 свинг-хая для сравнения
-	if len(priceSwingHighs) < 2 || len(rsiSwingHighs) < 2 {
+	if len(priceSwingHighs) < 2 || len(indSwingHighs) < 2 {
 		return divergences
 	}
 
@@ -273,14 +376,14 @@ func detectHiddenBearishDivergence(candles []models.Candle, rsiValues []float64,
 			continue
 		}
 
-		// Находим ближайшие свинг-хаи RSI
-		r1, r2 := findClosestSwings(p1, p2, rsiSwingHighs)
+		// Находим ближайшие свинг-хаи индикатора
+		r1, r2 := findClosestSwings(p1, p2, indSwingHighs)
 		if r1 < 0 || r2 < 0 {
 			continue
 		}
 
-		// Проверяем, сделал ли RSI более высокий максимум
-		if rsiValues[r2] <= rsiValues[r1] {
+		// Проверяем, сделал ли индикатор более высокий максимум
+		if values[r2] <= values[r1] {
 			continue
 		}
 
@@ -293,13 +396,13 @@ func detectHiddenBearishDivergence(candles []models.Candle, rsiValues []float64,
 				{Index: p2, Value: candles[p2].High},
 			},
 			IndicatorPoints: []models.DivergencePoint{
-				{Index: r1, Value: rsiValues[r1]},
-				{Index: r2, Value: rsiValues[r2]},
+				{Index: r1, Value: values[r1]},
+				{Index: r2, Value: values[r2]},
 			},
-			Indicator: "RSI",
+			Indicator: indicator,
 			SignalStrength: calculateDivergenceStrength(
 				candles[p1].High/candles[p2].High,
-				rsiValues[r2]/rsiValues[r1],
+				values[r2]/values[r1],
 			),
 		}
 
@@ -309,11 +412,11 @@ func detectHiddenBearishDivergence(candles []models.Candle, rsiValues []float64,
 	return divergences
 }
 
-func detectHiddenBullishDivergence(candles []models.Candle, rsiValues []float64, priceSwingLows, rsiSwingLows []int) []models.Divergence {
+func detectHiddenBullishDivergence(candles []models.Candle, values []float64, priceSwingLows, indSwingLows []int, indicator string) []models.Divergence {
 	var divergences []models.Divergence
 
 	// Необходимо как минимум 2 свинг-лоу для сравнения
-	if len(priceSwingLows) < 2 || len(rsiSwingLows) < 2 {
+	if len(priceSwingLows) < 2 || len(indSwingLows) < 2 {
 		return divergences
 	}
 
@@ -327,14 +430,14 @@ func detectHiddenBullishDivergence(candles []models.Candle, rsiValues []float64,
 			continue
 		}
 
-		// Находим ближайшие свинг-лоу RSI
-		r1, r2 := findClosestSwings(p1, p2, rsiSwingLows)
+		// Находим ближайшие свинг-лоу индикатора
+		r1, r2 := findClosestSwings(p1, p2, indSwingLows)
 		if r1 < 0 || r2 < 0 {
 			continue
 		}
 
-		// Проверяем, сделал ли RSI более низкий минимум
-		if rsiValues[r2] >= rsiValues[r1] {
+		// Проверяем, сделал ли индикатор более низкий минимум
+		if values[r2] >= values[r1] {
 			continue
 		}
 
@@ -347,13 +450,13 @@ func detectHiddenBullishDivergence(candles []models.Candle, rsiValues []float64,
 				{Index: p2, Value: candles[p2].Low},
 			},
 			IndicatorPoints: []models.DivergencePoint{
-				{Index: r1, Value: rsiValues[r1]},
-				{Index: r2, Value: rsiValues[r2]},
+				{Index: r1, Value: values[r1]},
+				{Index: r2, Value: values[r2]},
 			},
-			Indicator: "RSI",
+			Indicator: indicator,
 			SignalStrength: calculateDivergenceStrength(
 				candles[p2].Low/candles[p1].Low,
-				rsiValues[r1]/rsiValues[r2],
+				values[r1]/values[r2],
 			),
 		}
 
@@ -390,11 +493,12 @@ func findClosestSwings(p1, p2 int, swings []int) (int, int) {
 }
 
 func calculateDivergenceStrength(priceRatio, indicatorRatio float64) float64 {
-	// Простой расчет силы на основе разницы между соотношениями
+	// Простой расчет силы на основе разницы между соотношениями; у осцилляторов
+	// около нуля соотношение неустойчиво, поэтому сила ограничена единицей
 	if indicatorRatio == 0 {
 		return 0
 	}
-	return math.Abs(priceRatio - indicatorRatio)
+	return math.Min(1, math.Abs(priceRatio-indicatorRatio))
 }
 
 func abs(x int) int {
@@ -403,17 +507,3 @@ func abs(x int) int {
 	}
 	return x
 }
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
@@ -0,0 +1,232 @@
+package patterns
+
+import (
+	"math"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+// RSISeries рассчитывает полный ряд RSI (сглаживание Уайлдера) для каждой свечи
+func RSISeries(candles []models.Candle, period int) []float64 {
+	values := make([]float64, len(candles))
+	for i := range values {
+		values[i] = 50 // Значение по умолчанию при недостатке данных
+	}
+
+	if period <= 0 || len(candles) < period+1 {
+		return values
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		change := candles[i].Close - candles[i-1].Close
+		if change > 0 {
+			avgGain += change
+		} else {
+			avgLoss -= change
+		}
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	values[period] = rsiFromAverages(avgGain, avgLoss)
+
+	for i := period + 1; i < len(candles); i++ {
+		change := candles[i].Close - candles[i-1].Close
+		gain, loss := 0.0, 0.0
+		if change > 0 {
+			gain = change
+		} else {
+			loss = -change
+		}
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		values[i] = rsiFromAverages(avgGain, avgLoss)
+	}
+
+	return values
+}
+
+func rsiFromAverages(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		return 100.0
+	}
+	rs := avgGain / avgLoss
+	return 100.0 - (100.0 / (1.0 + rs))
+}
+
+// MACDHistogramSeries рассчитывает ряд гистограммы MACD (MACD - сигнальная линия)
+func MACDHistogramSeries(candles []models.Candle, fastPeriod, slowPeriod, signalPeriod int) []float64 {
+	values := make([]float64, len(candles))
+	if len(candles) < slowPeriod+signalPeriod {
+		return values
+	}
+
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+
+	fast := EMASeries(closes, fastPeriod)
+	slow := EMASeries(closes, slowPeriod)
+
+	macd := make([]float64, len(closes))
+	for i := range closes {
+		macd[i] = fast[i] - slow[i]
+	}
+
+	signal := EMASeries(macd[slowPeriod-1:], signalPeriod)
+	for i := slowPeriod - 1; i < len(closes); i++ {
+		values[i] = macd[i] - signal[i-(slowPeriod-1)]
+	}
+
+	return values
+}
+
+// EMASeries рассчитывает ряд EMA; первое значение - SMA первых period элементов
+func EMASeries(values []float64, period int) []float64 {
+	result := make([]float64, len(values))
+	if period <= 0 || len(values) < period {
+		copy(result, values)
+		return result
+	}
+
+	var sum float64
+	for i := 0; i < period; i++ {
+		sum += values[i]
+		result[i] = sum / float64(i+1)
+	}
+
+	multiplier := 2.0 / float64(period+1)
+	for i := period; i < len(values); i++ {
+		result[i] = (values[i]-result[i-1])*multiplier + result[i-1]
+	}
+
+	return result
+}
+
+// StochasticSeries рассчитывает ряд %K стохастического осциллятора
+func StochasticSeries(candles []models.Candle, kPeriod int) []float64 {
+	values := make([]float64, len(candles))
+	for i := range candles {
+		if i < kPeriod-1 {
+			values[i] = 50
+			continue
+		}
+
+		highest, lowest := candles[i].High, candles[i].Low
+		for j := i - kPeriod + 1; j <= i; j++ {
+			highest = math.Max(highest, candles[j].High)
+			lowest = math.Min(lowest, candles[j].Low)
+		}
+
+		if highest-lowest > 0 {
+			values[i] = (candles[i].Close - lowest) / (highest - lowest) * 100
+		} else {
+			values[i] = 50
+		}
+	}
+
+	return values
+}
+
+// OBVSeries рассчитывает ряд On-Balance Volume; возвращает nil при отсутствии объема
+func OBVSeries(candles []models.Candle) []float64 {
+	if !hasVolume(candles) {
+		return nil
+	}
+
+	values := make([]float64, len(candles))
+	values[0] = float64(candles[0].Volume)
+	for i := 1; i < len(candles); i++ {
+		values[i] = values[i-1]
+		if candles[i].Close > candles[i-1].Close {
+			values[i] += float64(candles[i].Volume)
+		} else if candles[i].Close < candles[i-1].Close {
+			values[i] -= float64(candles[i].Volume)
+		}
+	}
+
+	return values
+}
+
+// CCISeries рассчитывает ряд Commodity Channel Index
+func CCISeries(candles []models.Candle, period int) []float64 {
+	values := make([]float64, len(candles))
+	if period <= 0 {
+		return values
+	}
+
+	typical := make([]float64, len(candles))
+	for i, c := range candles {
+		typical[i] = (c.High + c.Low + c.Close) / 3
+	}
+
+	for i := period - 1; i < len(candles); i++ {
+		window := typical[i-period+1 : i+1]
+
+		var mean float64
+		for _, tp := range window {
+			mean += tp
+		}
+		mean /= float64(period)
+
+		var meanDeviation float64
+		for _, tp := range window {
+			meanDeviation += math.Abs(tp - mean)
+		}
+		meanDeviation /= float64(period)
+
+		if meanDeviation > 0 {
+			values[i] = (typical[i] - mean) / (0.015 * meanDeviation)
+		}
+	}
+
+	return values
+}
+
+// MFISeries рассчитывает ряд Money Flow Index; возвращает nil при отсутствии объема
+func MFISeries(candles []models.Candle, period int) []float64 {
+	if !hasVolume(candles) || period <= 0 {
+		return nil
+	}
+
+	values := make([]float64, len(candles))
+	for i := range values {
+		values[i] = 50
+	}
+
+	for i := period; i < len(candles); i++ {
+		var positiveFlow, negativeFlow float64
+		for j := i - period + 1; j <= i; j++ {
+			tp := (candles[j].High + candles[j].Low + candles[j].Close) / 3
+			prevTP := (candles[j-1].High + candles[j-1].Low + candles[j-1].Close) / 3
+			flow := tp * float64(candles[j].Volume)
+			if tp > prevTP {
+				positiveFlow += flow
+			} else if tp < prevTP {
+				negativeFlow += flow
+			}
+		}
+
+		if negativeFlow == 0 {
+			values[i] = 100
+		} else {
+			values[i] = 100 - 100/(1+positiveFlow/negativeFlow)
+		}
+	}
+
+	return values
+}
+
+// hasVolume проверяет, что у всех свечей есть данные объема
+func hasVolume(candles []models.Candle) bool {
+	if len(candles) == 0 {
+		return false
+	}
+	for _, c := range candles {
+		if c.Volume == 0 {
+			return false
+		}
+	}
+	return true
+}
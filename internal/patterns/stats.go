@@ -0,0 +1,258 @@
+package patterns
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	// patternWindowSize - окно свечей, в котором ищутся паттерны при сканировании истории
+	patternWindowSize = 30
+
+	// patternStatsPrior - вес априорной вероятности 50% при сглаживании hit-rate;
+	// паттерны с малым числом наблюдений остаются близки к исходному весу
+	patternStatsPrior = 20.0
+
+	// patternEdgeScale переводит преимущество hit-rate над 50% в вес скоринга,
+	// patternMaxScore ограничивает вес паттерна с большим преимуществом
+	patternEdgeScale = 8.0
+	patternMaxScore  = 3.0
+)
+
+// patternDirections задает ожидаемое направление движения после паттерна
+var patternDirections = map[string]string{
+	"BULLISH_ENGULFING":       "BULLISH",
+	"HAMMER":                  "BULLISH",
+	"MORNING_STAR":            "BULLISH",
+	"THREE_WHITE_SOLDIERS":    "BULLISH",
+	"STRONG_BULLISH_MOMENTUM": "BULLISH",
+	"DOUBLE_BOTTOM":           "BULLISH",
+	"BEARISH_ENGULFING":       "BEARISH",
+	"SHOOTING_STAR":           "BEARISH",
+	"EVENING_STAR":            "BEARISH",
+	"THREE_BLACK_CROWS":       "BEARISH",
+	"STRONG_BEARISH_MOMENTUM": "BEARISH",
+	"DOUBLE_TOP":              "BEARISH",
+}
+
+// statsRegistry хранит загруженные таблицы статистики по ключу символ/таймфрейм
+var statsRegistry = struct {
+	mu     sync.RWMutex
+	tables map[string]*models.PatternStatsTable
+}{
+	tables: make(map[string]*models.PatternStatsTable),
+}
+
+// PatternDirection возвращает ожидаемое направление паттерна (BULLISH, BEARISH или NEUTRAL)
+func PatternDirection(pattern string) string {
+	if direction, ok := patternDirections[pattern]; ok {
+		return direction
+	}
+	return "NEUTRAL"
+}
+
+// BuildPatternStats сканирует историю свечей, находит паттерны и оценивает
+// доходность через horizon свечей после каждого появления
+func BuildPatternStats(candles []models.Candle, symbol, interval string, horizon int) *models.PatternStatsTable {
+	table := &models.PatternStatsTable{
+		Symbol:      symbol,
+		Interval:    interval,
+		Horizon:     horizon,
+		Candles:     len(candles),
+		GeneratedAt: time.Now(),
+		Stats:       make(map[string]models.PatternStats),
+	}
+
+	if len(candles) > 0 {
+		table.DataEnd = candles[len(candles)-1].Timestamp
+	}
+
+	if horizon <= 0 || len(candles) < patternWindowSize+horizon {
+		return table
+	}
+
+	type accumulator struct {
+		occurrences         int
+		hits                int
+		totalReturn         float64
+		wins, losses        int
+		totalWin, totalLoss float64
+	}
+	acc := make(map[string]*accumulator)
+
+	for i := patternWindowSize; i+horizon < len(candles); i++ {
+		window := candles[i-patternWindowSize : i+1]
+		entry := candles[i].Close
+		if entry == 0 {
+			continue
+		}
+		forwardReturn := (candles[i+horizon].Close - entry) / entry * 100
+
+		for _, pattern := range IdentifyPriceActionPatterns(window) {
+			a, ok := acc[pattern]
+			if !ok {
+				a = &accumulator{}
+				acc[pattern] = a
+			}
+			a.occurrences++
+			a.totalReturn += forwardReturn
+
+			// Доходность в направлении паттерна
+			directional := forwardReturn
+			switch PatternDirection(pattern) {
+			case "BEARISH":
+				directional = -forwardReturn
+			case "NEUTRAL":
+				directional = math.Abs(forwardReturn)
+			}
+
+			if directional > 0 {
+				a.hits++
+				a.wins++
+				a.totalWin += directional
+			} else {
+				a.losses++
+				a.totalLoss += -directional
+			}
+		}
+	}
+
+	for pattern, a := range acc {
+		stats := models.PatternStats{
+			Pattern:          pattern,
+			Direction:        PatternDirection(pattern),
+			Occurrences:      a.occurrences,
+			Hits:             a.hits,
+			HitRate:          float64(a.hits) / float64(a.occurrences),
+			AvgForwardReturn: a.totalReturn / float64(a.occurrences),
+		}
+		if a.wins > 0 {
+			stats.AvgWin = a.totalWin / float64(a.wins)
+		}
+		if a.losses > 0 {
+			stats.AvgLoss = a.totalLoss / float64(a.losses)
+		}
+		stats.Expectancy = stats.HitRate*stats.AvgWin - (1-stats.HitRate)*stats.AvgLoss
+		table.Stats[pattern] = stats
+	}
+
+	return table
+}
+
+// PatternStatsFileName возвращает имя файла статистики для символа и таймфрейма
+func PatternStatsFileName(symbol, interval string) string {
+	return fmt.Sprintf("%s_%s.json", strings.ReplaceAll(symbol, "/", ""), interval)
+}
+
+// SavePatternStats сохраняет таблицу статистики в каталог dir
+func SavePatternStats(dir string, table *models.PatternStatsTable) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating stats directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding pattern stats: %w", err)
+	}
+
+	path := filepath.Join(dir, PatternStatsFileName(table.Symbol, table.Interval))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing pattern stats: %w", err)
+	}
+
+	return nil
+}
+
+// LoadPatternStats читает таблицу статистики из файла
+func LoadPatternStats(path string) (*models.PatternStatsTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading pattern stats: %w", err)
+	}
+
+	var table models.PatternStatsTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("parsing pattern stats %s: %w", path, err)
+	}
+
+	return &table, nil
+}
+
+// LoadPatternStatsDir загружает все таблицы из каталога и делает их доступными для скоринга
+func LoadPatternStatsDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing pattern stats: %w", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		table, err := LoadPatternStats(file)
+		if err != nil {
+			return loaded, err
+		}
+		RegisterPatternStats(table)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// RegisterPatternStats регистрирует таблицу статистики для использования в скоринге
+func RegisterPatternStats(table *models.PatternStatsTable) {
+	statsRegistry.mu.Lock()
+	defer statsRegistry.mu.Unlock()
+	statsRegistry.tables[PatternStatsFileName(table.Symbol, table.Interval)] = table
+}
+
+// GetPatternStatsTable возвращает таблицу статистики символа и таймфрейма
+func GetPatternStatsTable(symbol, interval string) (*models.PatternStatsTable, bool) {
+	statsRegistry.mu.RLock()
+	defer statsRegistry.mu.RUnlock()
+	table, ok := statsRegistry.tables[PatternStatsFileName(symbol, interval)]
+	return table, ok
+}
+
+// GetPatternStats возвращает статистику паттерна для символа и таймфрейма
+func GetPatternStats(symbol, interval, pattern string) (models.PatternStats, bool) {
+	statsRegistry.mu.RLock()
+	defer statsRegistry.mu.RUnlock()
+
+	table, ok := statsRegistry.tables[PatternStatsFileName(symbol, interval)]
+	if !ok {
+		return models.PatternStats{}, false
+	}
+	stats, ok := table.Stats[pattern]
+	return stats, ok
+}
+
+// SmoothedHitRate возвращает hit-rate паттерна, сглаженный к 50% с весом patternStatsPrior
+func SmoothedHitRate(stats models.PatternStats) float64 {
+	return (float64(stats.Hits) + 0.5*patternStatsPrior) / (float64(stats.Occurrences) + patternStatsPrior)
+}
+
+// PatternScore возвращает вес паттерна для скоринга. При загруженной статистике вес
+// выводится из измеренного преимущества: сглаженный hit-rate выше 50% умножается на
+// patternEdgeScale (hit-rate 60% дает вес 0.8, 75% - 2.0, не больше patternMaxScore).
+// Паттерн без преимущества или с неположительным матожиданием получает вес 0.
+// fallback - исходная константа веса, используется только когда статистики нет
+func PatternScore(symbol, interval, pattern string, fallback float64) float64 {
+	stats, ok := GetPatternStats(symbol, interval, pattern)
+	if !ok || stats.Occurrences == 0 {
+		return fallback
+	}
+
+	edge := SmoothedHitRate(stats) - 0.5
+	if edge <= 0 || stats.Expectancy <= 0 {
+		return 0
+	}
+	return math.Min(patternMaxScore, edge*patternEdgeScale)
+}
@@ -0,0 +1,88 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// breakoutChannel - длина канала Дончиана для определения пробоя
+const breakoutChannel = 20
+
+// Breakout - стратегия пробоя канала Дончиана с подтверждением объемом и волатильностью
+type Breakout struct{}
+
+func (s *Breakout) Name() string { return "breakout" }
+
+func (s *Breakout) Description() string {
+	return "Trades closes outside the 20-bar channel confirmed by volume and volatility"
+}
+
+func (s *Breakout) Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	if err := validateInput(input, breakoutChannel+1); err != nil {
+		return nil, err
+	}
+
+	candles := input.Candles
+	current := candles[len(candles)-1]
+	channel := candles[len(candles)-1-breakoutChannel : len(candles)-1]
+
+	highest, lowest := channel[0].High, channel[0].Low
+	var totalVolume float64
+	for _, c := range channel {
+		highest = math.Max(highest, c.High)
+		lowest = math.Min(lowest, c.Low)
+		totalVolume += float64(c.Volume)
+	}
+
+	// Пробой оценивается как один фактор: подтверждения только усиливают его
+	b := explain.NewBuilder()
+	direction := 0.0
+	var breakout map[string]string
+	if current.Close > highest {
+		direction = 1
+		breakout = explain.Text(
+			fmt.Sprintf("Close above %d-bar high %.5f", breakoutChannel, highest),
+			fmt.Sprintf("Закрытие выше максимума %d свечей %.5f", breakoutChannel, highest))
+	} else if current.Close < lowest {
+		direction = -1
+		breakout = explain.Text(
+			fmt.Sprintf("Close below %d-bar low %.5f", breakoutChannel, lowest),
+			fmt.Sprintf("Закрытие ниже минимума %d свечей %.5f", breakoutChannel, lowest))
+	}
+
+	if direction != 0 {
+		b.Add("CHANNEL_BREAKOUT", current.Close, 2.5*direction, breakout)
+
+		// Подтверждение объемом
+		if avgVolume := totalVolume / breakoutChannel; avgVolume > 0 && float64(current.Volume) > avgVolume*1.5 {
+			b.Add("CHANNEL_BREAKOUT", float64(current.Volume), 0.5*direction, explain.Text(
+				"Breakout volume above average", "Объем на пробое выше среднего"))
+		}
+
+		// Расширение волатильности и сила движения
+		if input.Indicators.VolatilityRatio > 1.2 {
+			b.Add("CHANNEL_BREAKOUT", input.Indicators.VolatilityRatio, 0.5*direction, explain.Text(
+				"Volatility expansion", "Расширение волатильности"))
+		}
+		if input.Indicators.ADX > 20 {
+			b.Add("CHANNEL_BREAKOUT", input.Indicators.ADX, 0.5*direction, explain.Text(
+				fmt.Sprintf("ADX confirms the move (%.1f)", input.Indicators.ADX),
+				fmt.Sprintf("ADX подтверждает движение (%.1f)", input.Indicators.ADX)))
+		}
+
+		// Пробой после сжатия во флэте надежнее
+		if input.Regime.Type == "RANGING" || input.Regime.VolatilityLevel == "LOW" {
+			b.Add("CHANNEL_BREAKOUT", 1, 0.5*direction, explain.Text(
+				"Breakout from low-volatility range", "Пробой из диапазона низкой волатильности"))
+		}
+	}
+
+	dampAnomaly(b, input.Anomaly)
+	b.Clamp(scoreLimit)
+
+	return buildPrediction(s.Name(), input, b), nil
+}
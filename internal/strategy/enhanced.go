@@ -0,0 +1,40 @@
+package strategy
+
+import (
+	"context"
+
+	"github.com/Alias1177/Predictor/internal/analyze"
+	"github.com/Alias1177/Predictor/internal/calendar"
+	"github.com/Alias1177/Predictor/internal/calibration"
+	"github.com/Alias1177/Predictor/internal/horizon"
+	"github.com/Alias1177/Predictor/internal/volatility"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// Enhanced - исходная многофакторная стратегия analyze.EnhancedPrediction
+type Enhanced struct{}
+
+func (s *Enhanced) Name() string { return analyze.StrategyName }
+
+func (s *Enhanced) Description() string {
+	return "Multi-factor scoring: trend alignment, patterns, divergences, regime"
+}
+
+func (s *Enhanced) Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	if err := validateInput(input, 2); err != nil {
+		return nil, err
+	}
+
+	prediction, err := analyze.EnhancedPrediction(ctx, input.Candles, input.Indicators,
+		input.MTFData, input.Regime, input.Anomaly, input.MarketAnalysis, input.Config)
+	if err != nil {
+		return nil, err
+	}
+	prediction.Strategy = s.Name()
+	calibration.Apply(prediction, input.Config.Symbol, input.Config.Interval)
+	horizon.Apply(prediction, input.Candles, input.Config.Symbol, input.Config.Interval)
+	volatility.Apply(prediction, input.Candles, input.Config.Symbol, input.Config.Interval)
+	calendar.Apply(prediction, input.Candles, input.Config.Symbol, input.Config.Interval)
+
+	return prediction, nil
+}
@@ -0,0 +1,225 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Alias1177/Predictor/internal/anomaly"
+	"github.com/Alias1177/Predictor/internal/calculate"
+	"github.com/Alias1177/Predictor/internal/ensemble"
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/internal/ml"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/internal/patterns"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// EnsembleMembers - стратегии, голосующие в ансамбле, в порядке весов стекинга
+var EnsembleMembers = []string{"enhanced", "ml", "market_analysis", "pattern_stats"}
+
+// Ensemble - мета-стратегия: объединяет голоса участников логистическим стекингом
+// с весами, обученными на отложенной истории, или равновзвешенным голосованием без весов
+type Ensemble struct{}
+
+func (s *Ensemble) Name() string { return "ensemble" }
+
+func (s *Ensemble) Description() string {
+	return "Stacked vote of the rule-based score, market analysis, pattern statistics and the ML model"
+}
+
+func (s *Ensemble) Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	if err := validateInput(input, 20); err != nil {
+		return nil, err
+	}
+
+	p := params.Active()
+	votes, members, errs := memberVotes(ctx, input, p)
+
+	active := 0
+	for _, member := range members {
+		if member != nil {
+			active++
+		}
+	}
+	if active == 0 {
+		return nil, fmt.Errorf("all ensemble members abstained")
+	}
+
+	// Вклады участников описаны строками согласия ниже, поэтому в разложении у них нет текста
+	b := explain.NewBuilder()
+	weights, ok := ensemble.Get(input.Config.Symbol, input.Config.Interval)
+	if ok && strings.Join(weights.Members, ",") == strings.Join(EnsembleMembers, ",") {
+		probability := ensemble.Probability(weights, votes)
+		b.Note(explain.Text(
+			fmt.Sprintf("Stacked ensemble: P(up)=%.1f%% (validation accuracy %.1f%%)", probability*100, weights.ValidationAccuracy),
+			fmt.Sprintf("Стекинг ансамбля: P(рост)=%.1f%% (точность на проверке %.1f%%)", probability*100, weights.ValidationAccuracy)))
+		// Вклады в логит приведены к шкале счета так же, как у стратегии ml
+		for i, name := range EnsembleMembers {
+			b.Add("MEMBER_"+strings.ToUpper(name), votes[i], weights.Weights[i]*votes[i]*mlScoreScale/4, nil)
+		}
+		b.SettleTo("ENSEMBLE_PROBABILITY", (probability-0.5)*mlScoreScale, explain.Text(
+			"Log-odds converted to the probability score", "Логит переведен в счет по вероятности"))
+	} else {
+		b.Note(explain.Text(
+			fmt.Sprintf("Equal-weight vote of %d members (no fitted weights)", active),
+			fmt.Sprintf("Равновзвешенное голосование %d участников (веса не обучены)", active)))
+		for i, name := range EnsembleMembers {
+			b.Add("MEMBER_"+strings.ToUpper(name), votes[i], votes[i]*p.Thresholds.HighConfidence/float64(active), nil)
+		}
+	}
+	b.Clamp(scoreLimit)
+
+	// Согласие участников с итоговым направлением
+	direction, _ := params.Direction(p, b.Score())
+	agree, disagree := 0, 0
+	for i, name := range EnsembleMembers {
+		member := members[i]
+		if member == nil {
+			b.Note(explain.Text(
+				fmt.Sprintf("%s: abstained (%v)", name, errs[i]),
+				fmt.Sprintf("%s: воздержалась (%v)", name, errs[i])))
+			continue
+		}
+
+		verdict := explain.Text("neutral", "нейтральна")
+		switch {
+		case member.Direction == "NEUTRAL" || direction == "NEUTRAL":
+		case member.Direction == direction:
+			verdict = explain.Text("agrees", "согласна")
+			agree++
+		default:
+			verdict = explain.Text("disagrees", "против")
+			disagree++
+		}
+		b.Note(explain.Text(
+			fmt.Sprintf("%s: %s (score %.2f) %s", name, member.Direction, member.Score, verdict["en"]),
+			fmt.Sprintf("%s: %s (счет %.2f) %s", name, member.Direction, member.Score, verdict["ru"])))
+	}
+	b.Note(explain.Text(
+		fmt.Sprintf("Agreement: %d of %d members agree with %s", agree, active, direction),
+		fmt.Sprintf("Согласие: %d из %d участников за %s", agree, active, direction)))
+	if disagree > 0 {
+		b.Note(explain.Text(
+			fmt.Sprintf("Warning: %d member(s) point the other way", disagree),
+			fmt.Sprintf("Внимание: %d участник(ов) указывают в другую сторону", disagree)))
+	}
+
+	return buildPrediction(s.Name(), withoutMarketAnalysis(input), b), nil
+}
+
+// memberVotes запускает участников ансамбля и возвращает их голоса: счет, нормированный
+// на порог высокой уверенности, в диапазоне -1..1. Участник с ошибкой воздерживается (голос 0)
+func memberVotes(ctx context.Context, input *models.StrategyInput, p *models.StrategyParams) ([]float64, []*models.Prediction, []error) {
+	votes := make([]float64, len(EnsembleMembers))
+	members := make([]*models.Prediction, len(EnsembleMembers))
+	errs := make([]error, len(EnsembleMembers))
+
+	// Анализ рынка голосует только через участника market_analysis, а не внутри каждого участника
+	stripped := withoutMarketAnalysis(input)
+	for i, name := range EnsembleMembers {
+		member, err := Get(name)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		memberInput := stripped
+		if name == "market_analysis" {
+			memberInput = input
+		}
+		prediction, err := member.Predict(ctx, memberInput)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		members[i] = prediction
+		if p.Thresholds.HighConfidence > 0 {
+			votes[i] = math.Max(-1, math.Min(1, prediction.Score/p.Thresholds.HighConfidence))
+		}
+	}
+
+	return votes, members, errs
+}
+
+// MembersTrainingEnd возвращает конец самой поздней обучающей истории моделей участников
+// (ML-модели и статистики паттернов) для символа и таймфрейма. Для файлов без DataEnd
+// берется время обучения - история не может заканчиваться позже него
+func MembersTrainingEnd(symbol, interval string) time.Time {
+	var end time.Time
+	later := func(dataEnd, trainedAt time.Time) {
+		if dataEnd.IsZero() {
+			dataEnd = trainedAt
+		}
+		if dataEnd.After(end) {
+			end = dataEnd
+		}
+	}
+	if model, ok := ml.Get(symbol, interval); ok {
+		later(model.DataEnd, model.TrainedAt)
+	}
+	if table, ok := patterns.GetPatternStatsTable(symbol, interval); ok {
+		later(table.DataEnd, table.GeneratedAt)
+	}
+	return end
+}
+
+// CollectEnsembleVotes проходит историю окном window, как бэктест, и собирает голоса
+// участников и метки направления через horizon свечей для обучения весов ансамбля.
+// Голоса собираются только на свечах после from - конца обучающей истории участников
+// (MembersTrainingEnd), иначе голоса внутри выборки завышают вклад участников
+func CollectEnsembleVotes(ctx context.Context, candles []models.Candle, cfg *models.Config, window, horizon int, from time.Time) (*ensemble.Dataset, error) {
+	if window < 20 || horizon < 1 {
+		return nil, fmt.Errorf("invalid window %d or horizon %d", window, horizon)
+	}
+	if len(candles) < window+horizon {
+		return nil, fmt.Errorf("insufficient candles: need %d, got %d", window+horizon, len(candles))
+	}
+
+	p := params.Active()
+	regimeHistory := anomaly.ClassifyRegimeHistory(candles, window)
+	dataset := &ensemble.Dataset{Members: append([]string(nil), EnsembleMembers...)}
+
+	for i := window; i-1+horizon < len(candles); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if !candles[i-1].Timestamp.After(from) {
+			continue
+		}
+
+		testWindow := candles[i-window : i]
+		change := candles[i-1+horizon].Close - candles[i-1].Close
+		if change == 0 {
+			continue
+		}
+
+		regime := regimeHistory[i-1]
+		if regime == nil {
+			regime = &models.MarketRegime{Type: "UNKNOWN", Direction: "NEUTRAL"}
+		}
+
+		votes, _, _ := memberVotes(ctx, &models.StrategyInput{
+			Candles:    testWindow,
+			Indicators: calculate.CalculateAllIndicators(testWindow, cfg),
+			MTFData:    map[string][]models.Candle{cfg.Interval: testWindow},
+			Regime:     regime,
+			Anomaly:    anomaly.DetectMarketAnomalies(testWindow),
+			Config:     cfg,
+		}, p)
+
+		label := 0.0
+		if change > 0 {
+			label = 1
+		}
+		dataset.Votes = append(dataset.Votes, votes)
+		dataset.Y = append(dataset.Y, label)
+	}
+
+	if len(dataset.Y) == 0 {
+		return nil, fmt.Errorf("no candles after member training end %s", from.Format(time.RFC3339))
+	}
+	return dataset, nil
+}
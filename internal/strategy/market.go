@@ -0,0 +1,46 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Alias1177/Predictor/internal/analyze"
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// marketAnalysisScale переводит уверенность AnalyzeMarket (0-1) в счет стратегии
+const marketAnalysisScale = 6.0
+
+// MarketAnalysis - направление комплексного анализа рынка: настроения, ликвидность,
+// объемы, микроструктура и режим (без запроса новостей)
+type MarketAnalysis struct{}
+
+func (s *MarketAnalysis) Name() string { return "market_analysis" }
+
+func (s *MarketAnalysis) Description() string {
+	return "Direction of the market structure analysis: sentiment, liquidity, volume and microstructure"
+}
+
+func (s *MarketAnalysis) Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	if err := validateInput(input, 20); err != nil {
+		return nil, err
+	}
+
+	// Полный анализ с бенчмарком и новостями, если он подготовлен, иначе - только по свечам
+	analysis := input.MarketAnalysis
+	if analysis == nil {
+		analysis = analyze.AnalyzeMarketStructure(input.Candles, nil)
+	}
+
+	b := explain.NewBuilder()
+	if mood := analysis.MarketSentiment; mood != nil {
+		b.Note(explain.Text(
+			fmt.Sprintf("Sentiment: %s (fear/greed %.0f), regime %s", mood.MarketMood, mood.FearGreedIndex, analysis.MarketRegime),
+			fmt.Sprintf("Настроение: %s (страх/жадность %.0f), режим %s", mood.MarketMood, mood.FearGreedIndex, analysis.MarketRegime)))
+	}
+	analyze.AddMarketAnalysisFactor(b, analysis, marketAnalysisScale)
+	b.Clamp(scoreLimit)
+
+	return buildPrediction(s.Name(), withoutMarketAnalysis(input), b), nil
+}
@@ -0,0 +1,85 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// MeanReversion - стратегия возврата к среднему: торгует против отклонений
+// от полос Боллинджера при перекупленности/перепроданности осцилляторов
+type MeanReversion struct{}
+
+func (s *MeanReversion) Name() string { return "mean_reversion" }
+
+func (s *MeanReversion) Description() string {
+	return "Fades Bollinger Band extremes confirmed by RSI and Stochastic"
+}
+
+func (s *MeanReversion) Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	if err := validateInput(input, 20); err != nil {
+		return nil, err
+	}
+
+	ind := input.Indicators
+	price := input.Candles[len(input.Candles)-1].Close
+	b := explain.NewBuilder()
+
+	// Положение цены относительно полос Боллинджера
+	if price < ind.BBLower {
+		b.Add("BOLLINGER_POSITION", price, 2.0, explain.Text(
+			"Price below lower Bollinger Band", "Цена ниже нижней полосы Боллинджера"))
+	} else if price > ind.BBUpper {
+		b.Add("BOLLINGER_POSITION", price, -2.0, explain.Text(
+			"Price above upper Bollinger Band", "Цена выше верхней полосы Боллинджера"))
+	} else if halfWidth := ind.BBUpper - ind.BBMiddle; halfWidth > 0 {
+		position := (price - ind.BBMiddle) / halfWidth
+		b.Add("BOLLINGER_POSITION", position, -position, explain.Text(
+			fmt.Sprintf("Price inside Bollinger Bands (%+.2f of half-width)", position),
+			fmt.Sprintf("Цена внутри полос Боллинджера (%+.2f полуширины)", position)))
+	}
+
+	// Перекупленность/перепроданность
+	if ind.RSI < 30 {
+		b.Add("RSI", ind.RSI, 1.5, explain.Text(
+			fmt.Sprintf("RSI oversold (%.1f)", ind.RSI), fmt.Sprintf("RSI в перепроданности (%.1f)", ind.RSI)))
+	} else if ind.RSI > 70 {
+		b.Add("RSI", ind.RSI, -1.5, explain.Text(
+			fmt.Sprintf("RSI overbought (%.1f)", ind.RSI), fmt.Sprintf("RSI в перекупленности (%.1f)", ind.RSI)))
+	}
+
+	if ind.Stochastic < 20 {
+		b.Add("STOCHASTIC", ind.Stochastic, 1.0, explain.Text("Stochastic oversold", "Стохастик в перепроданности"))
+	} else if ind.Stochastic > 80 {
+		b.Add("STOCHASTIC", ind.Stochastic, -1.0, explain.Text("Stochastic overbought", "Стохастик в перекупленности"))
+	}
+
+	// Возврат к среднему работает во флэте и опасен в сильном тренде
+	switch {
+	case input.Regime.Type == "RANGING":
+		b.Scale("REGIME", 1.3, explain.Text(
+			"Ranging regime favours mean reversion", "Флэт благоприятен для возврата к среднему"))
+	case input.Regime.Type == "TRENDING" && input.Regime.Strength > 0.6:
+		b.Scale("REGIME", 0.5, explain.Text(
+			"Strong trend weakens mean reversion", "Сильный тренд ослабляет возврат к среднему"))
+	}
+
+	dampAnomaly(b, input.Anomaly)
+	b.Clamp(scoreLimit)
+
+	return buildPrediction(s.Name(), input, b), nil
+}
+
+// scoreLimit ограничивает счет стратегий диапазоном, сопоставимым с EnhancedPrediction
+const scoreLimit = 6.0
+
+// dampAnomaly ослабляет счет стратегии во время рыночной аномалии
+func dampAnomaly(b *explain.Builder, anomaly *models.AnomalyDetection) {
+	if anomaly.IsAnomaly {
+		b.Scale("ANOMALY", 1.0-anomaly.AnomalyScore*0.3, explain.Text(
+			fmt.Sprintf("Market anomaly (score %.2f) damps the signal", anomaly.AnomalyScore),
+			fmt.Sprintf("Аномалия рынка (оценка %.2f) ослабляет сигнал", anomaly.AnomalyScore)))
+	}
+}
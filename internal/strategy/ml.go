@@ -0,0 +1,114 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Alias1177/Predictor/internal/calculate"
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/internal/horizon"
+	"github.com/Alias1177/Predictor/internal/ml"
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	// mlScoreScale переводит вероятность роста в счет: P=57.5% дает порог направления 1.5
+	mlScoreScale = 20.0
+
+	// mlTopFeatures - сколько признаков с наибольшим вкладом показывать в факторах
+	mlTopFeatures = 3
+)
+
+// MachineLearning - стратегия на обученной офлайн модели (логистическая регрессия
+// или бустинг деревьев), выбирается модель с лучшей проверкой по времени
+type MachineLearning struct{}
+
+func (s *MachineLearning) Name() string { return "ml" }
+
+func (s *MachineLearning) Description() string {
+	return "Offline-trained logistic regression or gradient-boosted trees on indicator features"
+}
+
+func (s *MachineLearning) Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	if err := validateInput(input, 20); err != nil {
+		return nil, err
+	}
+
+	model, ok := ml.Get(input.Config.Symbol, input.Config.Interval)
+	if !ok {
+		return nil, fmt.Errorf("no trained ML model for %s %s", input.Config.Symbol, input.Config.Interval)
+	}
+
+	// Признаки считаются на окне той же длины, что и при обучении
+	candles, indicators := input.Candles, input.Indicators
+	if model.Window > 0 && len(candles) > model.Window {
+		candles = candles[len(candles)-model.Window:]
+		indicators = calculate.CalculateAllIndicators(candles, input.Config)
+	}
+	features, err := ml.Features(candles, indicators)
+	if err != nil {
+		return nil, err
+	}
+
+	probability := ml.Probability(model, features)
+	score := (probability - 0.5) * mlScoreScale
+
+	// Вклады признаков логистической регрессии в логит, приведенные к шкале счета;
+	// в описании прогноза остаются только признаки с наибольшим вкладом
+	contributions := ml.Contributions(model, features)
+	ranked := make([]string, 0, len(contributions))
+	for name := range contributions {
+		ranked = append(ranked, name)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return math.Abs(contributions[ranked[i]]) > math.Abs(contributions[ranked[j]])
+	})
+	top := make(map[string]bool, mlTopFeatures)
+	for _, name := range ranked[:min(mlTopFeatures, len(ranked))] {
+		top[name] = true
+	}
+
+	b := explain.NewBuilder()
+	summary := explain.Text(
+		fmt.Sprintf("%s model: P(up)=%.1f%% over %d bars (CV accuracy %.1f%%)",
+			model.Type, probability*100, model.Horizon, model.CV.Accuracy),
+		fmt.Sprintf("Модель %s: P(рост)=%.1f%% за %d свечей (точность на CV %.1f%%)",
+			model.Type, probability*100, model.Horizon, model.CV.Accuracy))
+	if len(contributions) == 0 {
+		b.Add("ML_"+model.Type, probability, score, summary)
+	} else {
+		b.Note(summary)
+	}
+	for i, name := range model.Features {
+		contribution, ok := contributions[name]
+		if !ok {
+			continue
+		}
+		var text map[string]string
+		if top[name] {
+			text = explain.Text(
+				fmt.Sprintf("%s contributes %+.2f to log-odds", name, contribution),
+				fmt.Sprintf("%s дает %+.2f к логиту", name, contribution))
+		}
+		b.Add("ML_"+name, features[i], contribution*mlScoreScale/4, text)
+	}
+	b.SettleTo("ML_PROBABILITY", score, explain.Text(
+		"Log-odds converted to the probability score", "Логит переведен в счет по вероятности"))
+
+	prediction := buildPrediction(s.Name(), input, b)
+
+	// Без калибратора используется вероятность самой модели
+	if prediction.Probability == 0 && model.Horizon == 1 {
+		prediction.Probability = math.Max(probability, 1-probability)
+		prediction.CalibrationMethod = "MODEL"
+		for i := range prediction.Horizons {
+			if prediction.Horizons[i].Horizon == horizon.NextBar {
+				prediction.Horizons[i].Probability = prediction.Probability
+			}
+		}
+	}
+
+	return prediction, nil
+}
@@ -0,0 +1,57 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/internal/patterns"
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	// patternStatsMinOccurrences - паттерны с меньшим числом наблюдений в истории не учитываются
+	patternStatsMinOccurrences = 10
+
+	// patternStatsScale переводит преимущество hit-rate над 50% в счет: 75% дает 3
+	patternStatsScale = 12.0
+)
+
+// PatternStatsStrategy - голосует по паттернам свечей с учетом их исторического hit-rate
+// на символе и таймфрейме; без загруженной статистики воздерживается
+type PatternStatsStrategy struct{}
+
+func (s *PatternStatsStrategy) Name() string { return "pattern_stats" }
+
+func (s *PatternStatsStrategy) Description() string {
+	return "Candlestick patterns weighted by their historical hit rate on the symbol"
+}
+
+func (s *PatternStatsStrategy) Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	if err := validateInput(input, 5); err != nil {
+		return nil, err
+	}
+
+	b := explain.NewBuilder()
+	for _, pattern := range patterns.IdentifyPriceActionPatterns(input.Candles) {
+		sign := 0.0
+		switch patterns.PatternDirection(pattern) {
+		case "BULLISH":
+			sign = 1
+		case "BEARISH":
+			sign = -1
+		}
+		stats, ok := patterns.GetPatternStats(input.Config.Symbol, input.Config.Interval, pattern)
+		if sign == 0 || !ok || stats.Occurrences < patternStatsMinOccurrences {
+			continue
+		}
+
+		hitRate := patterns.SmoothedHitRate(stats)
+		b.Add("PATTERN_"+pattern, hitRate, sign*(hitRate-0.5)*patternStatsScale, explain.Text(
+			fmt.Sprintf("%s: hit rate %.0f%% over %d occurrences", pattern, stats.HitRate*100, stats.Occurrences),
+			fmt.Sprintf("%s: точность %.0f%% на %d случаях", pattern, stats.HitRate*100, stats.Occurrences)))
+	}
+	b.Clamp(scoreLimit)
+
+	return buildPrediction(s.Name(), input, b), nil
+}
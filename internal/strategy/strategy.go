@@ -0,0 +1,213 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Alias1177/Predictor/internal/analyze"
+	"github.com/Alias1177/Predictor/internal/calculate"
+	"github.com/Alias1177/Predictor/internal/calendar"
+	"github.com/Alias1177/Predictor/internal/calibration"
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/internal/horizon"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/internal/utils"
+	"github.com/Alias1177/Predictor/internal/volatility"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// DefaultStrategy - стратегия, используемая, если другая не выбрана
+const DefaultStrategy = analyze.StrategyName
+
+// Strategy - торговая стратегия, формирующая прогноз по рыночным данным
+type Strategy interface {
+	// Name возвращает уникальное имя стратегии для реестра и настроек
+	Name() string
+	// Description возвращает короткое описание для пользователя
+	Description() string
+	// Predict формирует прогноз по подготовленным рыночным данным
+	Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error)
+}
+
+// registry хранит зарегистрированные стратегии и переопределения по символам
+var registry = struct {
+	mu         sync.RWMutex
+	strategies map[string]Strategy
+	overrides  map[string]string
+}{
+	strategies: make(map[string]Strategy),
+	overrides:  make(map[string]string),
+}
+
+func init() {
+	Register(&Enhanced{})
+	Register(&MeanReversion{})
+	Register(&Breakout{})
+	Register(&TrendFollowing{})
+	Register(&MachineLearning{})
+	Register(&MarketAnalysis{})
+	Register(&PatternStatsStrategy{})
+	Register(&Ensemble{})
+}
+
+// Register добавляет стратегию в реестр; стратегия с тем же именем заменяется
+func Register(s Strategy) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.strategies[s.Name()] = s
+}
+
+// Get возвращает стратегию по имени
+func Get(name string) (Strategy, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	s, ok := registry.strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q", name)
+	}
+	return s, nil
+}
+
+// Names возвращает имена зарегистрированных стратегий в алфавитном порядке
+func Names() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.strategies))
+	for name := range registry.strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetSymbolOverrides задает стратегию для отдельных символов
+func SetSymbolOverrides(overrides map[string]string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.overrides = make(map[string]string, len(overrides))
+	for symbol, name := range overrides {
+		registry.overrides[strings.ToUpper(symbol)] = name
+	}
+}
+
+// ParseSymbolOverrides разбирает строку вида "XAU/USD=breakout,BTC/USD=trend"
+func ParseSymbolOverrides(value string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid strategy override %q", item)
+		}
+		name := strings.TrimSpace(parts[1])
+		if _, err := Get(name); err != nil {
+			return nil, err
+		}
+		overrides[strings.TrimSpace(parts[0])] = name
+	}
+	return overrides, nil
+}
+
+// Select выбирает стратегию: явно заданная пользователем, затем переопределение
+// для символа, затем cfg.Strategy и стратегия по умолчанию
+func Select(cfg *models.Config, preferred string) (Strategy, error) {
+	if preferred != "" {
+		return Get(preferred)
+	}
+
+	registry.mu.RLock()
+	override, ok := registry.overrides[strings.ToUpper(cfg.Symbol)]
+	registry.mu.RUnlock()
+	if ok {
+		return Get(override)
+	}
+
+	if cfg.Strategy != "" {
+		return Get(cfg.Strategy)
+	}
+	return Get(DefaultStrategy)
+}
+
+// buildPrediction переводит итоговый счет стратегии в направление, уверенность
+// и торговую рекомендацию по активным порогам параметров, общим с EnhancedPrediction.
+// Разложение счета и вклады факторов для обучения их весов берутся из b.
+// Анализ рынка с бенчмарком, если он есть во входных данных, добавляется к счету стратегии
+func buildPrediction(name string, input *models.StrategyInput, b *explain.Builder) *models.Prediction {
+	p := params.Active()
+	if input.MarketAnalysis != nil {
+		analyze.AddMarketAnalysisFactor(b, input.MarketAnalysis,
+			p.Weights.MarketAnalysis*utils.GetFactorWeight(name, input.Config.Symbol, input.Config.Interval, "MARKET_ANALYSIS"))
+		b.Clamp(scoreLimit)
+	}
+	netScore := b.Score()
+	direction, confidence := params.Direction(p, netScore)
+	factors := b.Explain(direction, input.Config.Language)
+
+	candles := input.Candles
+	currentPrice := candles[len(candles)-1].Close
+	stopLoss := calculate.DetermineStopLoss(candles, input.Indicators, direction)
+	sizing := calculate.CalculatePositionSize(currentPrice, stopLoss, p.Risk.AccountSize, p.Risk.RiskPerTrade)
+
+	suggestion := &models.TradingSuggestion{
+		Action:          "NO_TRADE",
+		Direction:       direction,
+		Confidence:      confidence,
+		Score:           netScore,
+		EntryPrice:      currentPrice,
+		StopLoss:        sizing.StopLoss,
+		TakeProfit:      sizing.TakeProfit,
+		PositionSize:    sizing.PositionSize,
+		RiskRewardRatio: sizing.RiskRewardRatio,
+		AccountRisk:     sizing.AccountRisk * 100,
+		Factors:         factors,
+	}
+	if direction != "NEUTRAL" && confidence != "LOW" {
+		suggestion.Action = direction
+	}
+
+	prediction := &models.Prediction{
+		Direction:         direction,
+		Confidence:        confidence,
+		Score:             netScore,
+		Factors:           factors,
+		TradingSuggestion: suggestion,
+		Strategy:          name,
+		ParamsVersion:     p.Version,
+		FactorScores:      b.FactorScores(),
+		Breakdown:         b.Breakdown(),
+	}
+	calibration.Apply(prediction, input.Config.Symbol, input.Config.Interval)
+	horizon.Apply(prediction, input.Candles, input.Config.Symbol, input.Config.Interval)
+	volatility.Apply(prediction, input.Candles, input.Config.Symbol, input.Config.Interval)
+	calendar.Apply(prediction, input.Candles, input.Config.Symbol, input.Config.Interval)
+
+	return prediction
+}
+
+// withoutMarketAnalysis возвращает копию входных данных без анализа рынка - для стратегий,
+// которые уже учли его сами, чтобы buildPrediction не добавил его второй раз
+func withoutMarketAnalysis(input *models.StrategyInput) *models.StrategyInput {
+	stripped := *input
+	stripped.MarketAnalysis = nil
+	return &stripped
+}
+
+// validateInput проверяет наличие минимально необходимых данных
+func validateInput(input *models.StrategyInput, minCandles int) error {
+	if input == nil || input.Indicators == nil || input.Config == nil || input.Regime == nil || input.Anomaly == nil {
+		return fmt.Errorf("strategy input is incomplete")
+	}
+	if len(input.Candles) < minCandles {
+		return fmt.Errorf("insufficient candles: need %d, got %d", minCandles, len(input.Candles))
+	}
+	return nil
+}
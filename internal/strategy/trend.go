@@ -0,0 +1,73 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Alias1177/Predictor/internal/explain"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// TrendFollowing - стратегия следования за трендом по EMA, MACD и направленным индикаторам
+type TrendFollowing struct{}
+
+func (s *TrendFollowing) Name() string { return "trend_following" }
+
+func (s *TrendFollowing) Description() string {
+	return "Follows EMA, MACD and DI direction when ADX confirms a trend"
+}
+
+func (s *TrendFollowing) Predict(ctx context.Context, input *models.StrategyInput) (*models.Prediction, error) {
+	if err := validateInput(input, 20); err != nil {
+		return nil, err
+	}
+
+	ind := input.Indicators
+	price := input.Candles[len(input.Candles)-1].Close
+	b := explain.NewBuilder()
+
+	if price > ind.EMA {
+		b.Add("EMA_POSITION", ind.EMA, 1.0, explain.Text("Price above EMA", "Цена выше EMA"))
+	} else if price < ind.EMA {
+		b.Add("EMA_POSITION", ind.EMA, -1.0, explain.Text("Price below EMA", "Цена ниже EMA"))
+	}
+
+	if ind.MACDHist > 0 {
+		b.Add("MACD", ind.MACDHist, 1.0, explain.Text("Positive MACD histogram", "Положительная гистограмма MACD"))
+	} else if ind.MACDHist < 0 {
+		b.Add("MACD", ind.MACDHist, -1.0, explain.Text("Negative MACD histogram", "Отрицательная гистограмма MACD"))
+	}
+
+	if ind.PlusDI > ind.MinusDI {
+		b.Add("DI_DIRECTION", ind.PlusDI-ind.MinusDI, 1.0, explain.Text("+DI above -DI", "+DI выше -DI"))
+	} else if ind.MinusDI > ind.PlusDI {
+		b.Add("DI_DIRECTION", ind.PlusDI-ind.MinusDI, -1.0, explain.Text("-DI above +DI", "-DI выше +DI"))
+	}
+
+	// Совпадение с направлением режима рынка
+	if input.Regime.Type == "TRENDING" {
+		if input.Regime.Direction == "BULLISH" && b.Score() > 0 {
+			b.Add("REGIME_TREND", input.Regime.Strength, input.Regime.Strength, explain.Text(
+				"Trending regime confirms uptrend", "Трендовый режим подтверждает рост"))
+		} else if input.Regime.Direction == "BEARISH" && b.Score() < 0 {
+			b.Add("REGIME_TREND", input.Regime.Strength, -input.Regime.Strength, explain.Text(
+				"Trending regime confirms downtrend", "Трендовый режим подтверждает падение"))
+		}
+	}
+
+	// ADX определяет, есть ли тренд, которому стоит следовать
+	if ind.ADX >= 25 {
+		b.Scale("ADX", math.Min(1+(ind.ADX-25)/25, 2), explain.Text(
+			fmt.Sprintf("ADX confirms trend (%.1f)", ind.ADX), fmt.Sprintf("ADX подтверждает тренд (%.1f)", ind.ADX)))
+	} else if ind.ADX < 20 {
+		b.Scale("ADX", 0.5, explain.Text(
+			fmt.Sprintf("Weak trend halves the signal (ADX %.1f)", ind.ADX),
+			fmt.Sprintf("Слабый тренд вдвое ослабляет сигнал (ADX %.1f)", ind.ADX)))
+	}
+
+	dampAnomaly(b, input.Anomaly)
+	b.Clamp(scoreLimit)
+
+	return buildPrediction(s.Name(), input, b), nil
+}
@@ -0,0 +1,330 @@
+package strength
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/config"
+	"github.com/Alias1177/Predictor/models"
+)
+
+// Currencies - валюты, сила которых рассчитывается
+var Currencies = []string{"USD", "EUR", "GBP", "JPY", "AUD", "CAD", "CHF", "NZD"}
+
+// DefaultPairs - пары, по которым считается сила, если другой список не задан
+var DefaultPairs = []string{
+	"EUR/USD", "GBP/USD", "USD/JPY", "AUD/USD", "USD/CAD", "USD/CHF", "NZD/USD",
+	"EUR/GBP", "EUR/JPY", "GBP/JPY", "AUD/CAD", "EUR/CAD",
+}
+
+// DefaultIntervals - таймфреймы рейтинга: внутридневной, сессионный и дневной
+var DefaultIntervals = []string{"1h", "4h", "1day"}
+
+// DefaultLookback - окно изменения в свечах каждого таймфрейма
+const DefaultLookback = 24
+
+// Neutral - разница оценок валют пары, ниже которой пара считается уравновешенной
+const Neutral = 0.25
+
+// Pairs отбирает из symbols валютные пары, обе валюты которых есть в Currencies
+func Pairs(symbols []string) []string {
+	var pairs []string
+	for _, symbol := range symbols {
+		base, quote, ok := strings.Cut(strings.ToUpper(symbol), "/")
+		if ok && known(base) && known(quote) {
+			pairs = append(pairs, symbol)
+		}
+	}
+	return pairs
+}
+
+func known(currency string) bool {
+	for _, c := range Currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// Decompose раскладывает изменения пар (в процентах) на силу валют: ищет значения s,
+// при которых s[base] - s[quote] ближе всего к изменению каждой пары, при сумме s равной 0.
+// Валюты без пар в результат не попадают
+func Decompose(returns map[string]float64) map[string]float64 {
+	var currencies []string
+	index := make(map[string]int)
+	type edge struct {
+		base, quote int
+		change      float64
+	}
+	var edges []edge
+	for symbol, change := range returns {
+		base, quote, ok := strings.Cut(strings.ToUpper(symbol), "/")
+		if !ok || base == quote {
+			continue
+		}
+		for _, currency := range []string{base, quote} {
+			if _, seen := index[currency]; !seen {
+				index[currency] = len(currencies)
+				currencies = append(currencies, currency)
+			}
+		}
+		edges = append(edges, edge{index[base], index[quote], change})
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+
+	// Нормальные уравнения L·s = d (L - лапласиан графа пар) с добавленной матрицей единиц,
+	// которая фиксирует сумму s = 0 и делает систему невырожденной для связного графа
+	n := len(currencies)
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n+1)
+		for j := 0; j < n; j++ {
+			matrix[i][j] = 1
+		}
+	}
+	for _, e := range edges {
+		matrix[e.base][e.base]++
+		matrix[e.quote][e.quote]++
+		matrix[e.base][e.quote]--
+		matrix[e.quote][e.base]--
+		matrix[e.base][n] += e.change
+		matrix[e.quote][n] -= e.change
+	}
+
+	values, ok := solve(matrix)
+	if !ok {
+		// Граф пар несвязный: сила валюты - среднее изменение пар с ее участием
+		values = make([]float64, n)
+		counts := make([]float64, n)
+		for _, e := range edges {
+			values[e.base] += e.change
+			values[e.quote] -= e.change
+			counts[e.base]++
+			counts[e.quote]++
+		}
+		for i := range values {
+			values[i] /= counts[i]
+		}
+	}
+
+	strength := make(map[string]float64, n)
+	for i, currency := range currencies {
+		strength[currency] = values[i]
+	}
+	return strength
+}
+
+// solve решает систему с расширенной матрицей методом Гаусса с выбором ведущего элемента
+func solve(matrix [][]float64) ([]float64, bool) {
+	n := len(matrix)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(matrix[row][col]) > math.Abs(matrix[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(matrix[pivot][col]) < 1e-9 {
+			return nil, false
+		}
+		matrix[col], matrix[pivot] = matrix[pivot], matrix[col]
+
+		for row := 0; row < n; row++ {
+			if row == col {
+				continue
+			}
+			factor := matrix[row][col] / matrix[col][col]
+			for k := col; k <= n; k++ {
+				matrix[row][k] -= factor * matrix[col][k]
+			}
+		}
+	}
+
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = matrix[i][n] / matrix[i][i]
+	}
+	return result, true
+}
+
+// pairChange возвращает логарифмическое изменение цены за последние lookback свечей в процентах
+func pairChange(candles []models.Candle, lookback int) (float64, bool) {
+	if len(candles) < 2 {
+		return 0, false
+	}
+	start := max(0, len(candles)-1-lookback)
+	first, last := candles[start].Close, candles[len(candles)-1].Close
+	if first <= 0 || last <= 0 {
+		return 0, false
+	}
+	return math.Log(last/first) * 100, true
+}
+
+// Compute строит рейтинг валют по свечам series (таймфрейм -> пара -> свечи).
+// Сила на каждом таймфрейме делится на разброс валют, чтобы дневные изменения
+// не перевешивали часовые, и сводная оценка - среднее этих значений
+func Compute(series map[string]map[string][]models.Candle, intervals []string, lookback int) *models.CurrencyStrengthSnapshot {
+	snapshot := &models.CurrencyStrengthSnapshot{Lookback: lookback, UpdatedAt: time.Now()}
+	byCurrency := make(map[string]*models.CurrencyStrength)
+	scored := make(map[string]int)
+
+	pairs := make(map[string]bool)
+	for _, interval := range intervals {
+		returns := make(map[string]float64)
+		for symbol, candles := range series[interval] {
+			if value, ok := pairChange(candles, lookback); ok {
+				returns[symbol] = value
+				pairs[symbol] = true
+			}
+		}
+		strength := Decompose(returns)
+		if len(strength) < 2 {
+			continue
+		}
+		snapshot.Intervals = append(snapshot.Intervals, interval)
+
+		var spread float64
+		for _, value := range strength {
+			spread += value * value
+		}
+		spread = math.Sqrt(spread / float64(len(strength)))
+
+		for currency, value := range strength {
+			entry, ok := byCurrency[currency]
+			if !ok {
+				entry = &models.CurrencyStrength{Currency: currency, Strength: make(map[string]float64)}
+				byCurrency[currency] = entry
+			}
+			entry.Strength[interval] = value
+			if spread > 0 {
+				entry.Score += value / spread
+			}
+			scored[currency]++
+		}
+	}
+	snapshot.Pairs = len(pairs)
+
+	for currency, entry := range byCurrency {
+		entry.Score /= float64(scored[currency])
+		snapshot.Currencies = append(snapshot.Currencies, *entry)
+	}
+	sort.Slice(snapshot.Currencies, func(i, j int) bool {
+		return snapshot.Currencies[i].Score > snapshot.Currencies[j].Score
+	})
+	return snapshot
+}
+
+// Differential возвращает силу базовой и котируемой валют пары symbol и разницу их оценок.
+// ok = false, если одной из валют нет в рейтинге (металлы, нефть, криптовалюты)
+func Differential(snapshot *models.CurrencyStrengthSnapshot, symbol string) (base, quote models.CurrencyStrength, diff float64, ok bool) {
+	if snapshot == nil {
+		return base, quote, 0, false
+	}
+	baseCode, quoteCode, found := strings.Cut(strings.ToUpper(symbol), "/")
+	if !found {
+		return base, quote, 0, false
+	}
+
+	var hasBase, hasQuote bool
+	for _, entry := range snapshot.Currencies {
+		switch entry.Currency {
+		case baseCode:
+			base, hasBase = entry, true
+		case quoteCode:
+			quote, hasQuote = entry, true
+		}
+	}
+	if !hasBase || !hasQuote {
+		return base, quote, 0, false
+	}
+	return base, quote, base.Score - quote.Score, true
+}
+
+// state - последний рассчитанный рейтинг
+var state = struct {
+	mu       sync.RWMutex
+	snapshot *models.CurrencyStrengthSnapshot
+}{}
+
+// Set делает рейтинг активным
+func Set(snapshot *models.CurrencyStrengthSnapshot) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.snapshot = snapshot
+}
+
+// Active возвращает активный рейтинг или nil, если он еще не рассчитан
+func Active() *models.CurrencyStrengthSnapshot {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.snapshot
+}
+
+// Update загружает свечи пар из symbols на таймфреймах intervals (ключ API и таймаут берутся
+// из cfg), рассчитывает рейтинг и делает его активным. Пары, свечи которых не загрузились, пропускаются
+func Update(ctx context.Context, cfg *models.Config, symbols, intervals []string, lookback int) (*models.CurrencyStrengthSnapshot, error) {
+	pairs := Pairs(symbols)
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no currency pairs among %d symbols", len(symbols))
+	}
+
+	series := make(map[string]map[string][]models.Candle, len(intervals))
+	var lastErr error
+	for _, interval := range intervals {
+		series[interval] = make(map[string][]models.Candle, len(pairs))
+		for _, symbol := range pairs {
+			pairCfg := *cfg
+			pairCfg.Symbol = symbol
+			pairCfg.Interval = interval
+			pairCfg.CandleCount = lookback + 1
+			candles, err := config.NewClient(&pairCfg).GetCandles(ctx)
+			if err != nil {
+				lastErr = fmt.Errorf("fetching %s %s: %w", symbol, interval, err)
+				continue
+			}
+			series[interval][symbol] = candles
+		}
+	}
+
+	snapshot := Compute(series, intervals, lookback)
+	if len(snapshot.Intervals) == 0 {
+		if lastErr == nil {
+			return nil, fmt.Errorf("not enough pairs for currency strength")
+		}
+		return nil, fmt.Errorf("not enough pairs for currency strength: %w", lastErr)
+	}
+	Set(snapshot)
+	return snapshot, nil
+}
+
+// Refresh периодически пересчитывает рейтинг. При ошибке остается прежний рейтинг;
+// onUpdate вызывается после каждой попытки
+func Refresh(ctx context.Context, cfg *models.Config, symbols, intervals []string, lookback int,
+	every time.Duration, onUpdate func(*models.CurrencyStrengthSnapshot, error)) {
+	update := func() {
+		snapshot, err := Update(ctx, cfg, symbols, intervals, lookback)
+		if onUpdate != nil {
+			onUpdate(snapshot, err)
+		}
+	}
+
+	update()
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			update()
+		}
+	}
+}
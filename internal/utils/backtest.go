@@ -2,14 +2,228 @@ package utils
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"sort"
 
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/internal/patterns"
 	"github.com/Alias1177/Predictor/models"
 )
 
-// RunBacktest выполняет бэктестинг стратегии
-func RunBacktest(ctx context.Context, candles []models.Candle, cfg *models.Config) (*models.BacktestResults, error) {
-	// TODO: Реализовать бэктестинг
-	return &models.BacktestResults{
+const (
+	// inlineBacktestHorizon - через сколько свечей проверяется прогноз, как в baktest
+	inlineBacktestHorizon = 1
+
+	// Периоды стохастика и окна потока ордеров, как в расчете индикаторов
+	inlineStochasticPeriod = 14
+	inlineStochasticSmooth = 3
+	inlineOrderFlowWindow  = 5
+)
+
+// RunBacktest выполняет облегченный проход по свечам для самокалибровки весов факторов.
+// Индикаторы считаются один раз по всей истории, затем на каждой свече формируются факторы
+// RSI, MACD, STOCHASTIC и ORDER_FLOW, как в EnhancedPrediction, и TREND_PROXY - замена
+// многотаймфреймового TREND_ALIGNMENT по одному таймфрейму со своим выученным весом.
+// Прогноз сравнивается с движением цены через inlineBacktestHorizon свечей.
+// Факторы взвешиваются выученными весами стратегии strategy на инструменте и таймфрейме
+func RunBacktest(ctx context.Context, candles []models.Candle, cfg *models.Config, strategy string) (*models.BacktestResults, error) {
+	fastPeriod := positiveOr(cfg.MACDFastPeriod, 12)
+	slowPeriod := positiveOr(cfg.MACDSlowPeriod, 26)
+	signalPeriod := positiveOr(cfg.MACDSignalPeriod, 9)
+	rsiPeriod := positiveOr(cfg.RSIPeriod, 14)
+	emaPeriod := positiveOr(cfg.EMAPeriod, 20)
+	atrPeriod := positiveOr(cfg.ATRPeriod, 14)
+
+	warmup := MaxInt(slowPeriod+signalPeriod, MaxInt(rsiPeriod+1, inlineStochasticPeriod+inlineStochasticSmooth))
+	warmup = MaxInt(warmup, MaxInt(emaPeriod+1, atrPeriod+1))
+	if len(candles) < warmup+inlineBacktestHorizon+1 {
+		return nil, fmt.Errorf("insufficient data for inline backtest: need %d candles, got %d",
+			warmup+inlineBacktestHorizon+1, len(candles))
+	}
+
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+
+	// Индикаторы по всей истории, выровненные по индексам свечей
+	rsi := patterns.RSISeries(candles, rsiPeriod)
+	fast := patterns.EMASeries(closes, fastPeriod)
+	slow := patterns.EMASeries(closes, slowPeriod)
+	macd := make([]float64, len(candles))
+	for i := range macd {
+		macd[i] = fast[i] - slow[i]
+	}
+	signal := patterns.EMASeries(macd[slowPeriod-1:], signalPeriod)
+	ema := patterns.EMASeries(closes, emaPeriod)
+	stochK := patterns.StochasticSeries(candles, inlineStochasticPeriod)
+	atr := atrSeries(candles, atrPeriod)
+
+	p := params.Active()
+	w, t := p.Weights, p.Thresholds
+	symbol, interval := cfg.Symbol, cfg.Interval
+	if symbol == "" {
+		symbol = candles[len(candles)-1].Symbol
+	}
+	if interval == "" {
+		interval = candles[len(candles)-1].TimeFrame
+	}
+
+	results := &models.BacktestResults{
+		Strategy:        "inline",
+		ParamsVersion:   p.Version,
 		DetailedResults: []models.PredictionResult{},
-	}, nil
+	}
+
+	for i := warmup; i+inlineBacktestHorizon < len(candles); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		factorScores := make(map[string]float64)
+		addFactor := func(factorID string, score float64) {
+			factorScores[factorID] += score * GetFactorWeight(strategy, symbol, interval, factorID)
+		}
+
+		if r := rsi[i]; r < t.RSIOversold {
+			addFactor("RSI", w.RSI)
+		} else if r > t.RSIOverbought {
+			addFactor("RSI", -w.RSI)
+		}
+
+		hist := macd[i] - signal[i-slowPeriod+1]
+		if hist > 0 && hist > macd[i]*0.1 {
+			addFactor("MACD", w.MACD)
+		} else if hist < 0 && hist < macd[i]*0.1 {
+			addFactor("MACD", -w.MACD)
+		}
+
+		stochD := CalculateAverage(stochK[i-inlineStochasticSmooth+1 : i+1])
+		if stochK[i] < t.StochasticOversold && stochK[i] > stochD {
+			addFactor("STOCHASTIC", w.Stochastic)
+		} else if stochK[i] > t.StochasticOverbought && stochK[i] < stochD {
+			addFactor("STOCHASTIC", -w.Stochastic)
+		}
+
+		// На одном таймфрейме согласованность тренда заменяется положением цены и наклоном EMA.
+		// Это другой сигнал, поэтому он учится отдельно от TREND_ALIGNMENT живых прогнозов
+		if atr[i] > 0 {
+			strength := math.Min(1, math.Abs(closes[i]-ema[i])/atr[i])
+			if closes[i] > ema[i] && ema[i] > ema[i-1] {
+				addFactor("TREND_PROXY", w.TrendAlignment*strength)
+			} else if closes[i] < ema[i] && ema[i] < ema[i-1] {
+				addFactor("TREND_PROXY", -w.TrendAlignment*strength)
+			}
+		}
+
+		if flow := orderFlowDirection(candles[i-inlineOrderFlowWindow+1 : i+1]); flow == "BULLISH" {
+			addFactor("ORDER_FLOW", w.OrderFlow)
+		} else if flow == "BEARISH" {
+			addFactor("ORDER_FLOW", -w.OrderFlow)
+		}
+
+		netScore := 0.0
+		for _, id := range []string{"RSI", "MACD", "STOCHASTIC", "TREND_PROXY", "ORDER_FLOW"} {
+			netScore += factorScores[id]
+		}
+		direction, confidence := params.Direction(p, netScore)
+
+		actualOutcome := "NEUTRAL"
+		if change := closes[i+inlineBacktestHorizon] - closes[i]; change > 0 {
+			actualOutcome = "BUY"
+		} else if change < 0 {
+			actualOutcome = "SELL"
+		}
+
+		result := models.PredictionResult{
+			Direction:        direction,
+			Confidence:       confidence,
+			Score:            netScore,
+			Timestamp:        candles[i].Timestamp,
+			PredictionID:     inlinePredictionID(symbol, interval, candles, i),
+			PredictionTarget: candles[i+inlineBacktestHorizon].Timestamp,
+			ActualOutcome:    actualOutcome,
+			WasCorrect:       direction != "NEUTRAL" && direction == actualOutcome,
+			ParamsVersion:    p.Version,
+			FactorScores:     factorScores,
+		}
+		for factorID := range factorScores {
+			result.Factors = append(result.Factors, factorID)
+		}
+		sort.Strings(result.Factors)
+		results.DetailedResults = append(results.DetailedResults, result)
+
+		if direction == "NEUTRAL" {
+			continue
+		}
+		results.TotalTrades++
+		if result.WasCorrect {
+			results.WinningTrades++
+		} else {
+			results.LosingTrades++
+		}
+	}
+
+	if results.TotalTrades > 0 {
+		results.WinPercentage = float64(results.WinningTrades) / float64(results.TotalTrades) * 100
+	}
+
+	return results, nil
+}
+
+// inlinePredictionID строит идентификатор по времени свечи, чтобы пересекающиеся
+// прогоны на одних и тех же свечах не учитывались при обучении весов повторно
+func inlinePredictionID(symbol, interval string, candles []models.Candle, i int) string {
+	if candles[i].Timestamp.IsZero() {
+		return fmt.Sprintf("IB-%s-%s-#%d", symbol, interval, i)
+	}
+	return fmt.Sprintf("IB-%s-%s-%d", symbol, interval, candles[i].Timestamp.Unix())
+}
+
+func positiveOr(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// atrSeries возвращает ATR Уайлдера по индексам свечей
+func atrSeries(candles []models.Candle, period int) []float64 {
+	atr := make([]float64, len(candles))
+	sum := 0.0
+	for i := 1; i < len(candles); i++ {
+		high, low, prevClose := candles[i].High, candles[i].Low, candles[i-1].Close
+		tr := math.Max(high-low, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+		if i <= period {
+			sum += tr
+			atr[i] = sum / float64(i)
+			continue
+		}
+		atr[i] = (atr[i-1]*float64(period-1) + tr) / float64(period)
+	}
+	return atr
+}
+
+// orderFlowDirection определяет направление потока объема по доле объема растущих свечей
+func orderFlowDirection(window []models.Candle) string {
+	var upVolume, downVolume int64
+	for _, c := range window {
+		if c.Volume == 0 {
+			return "NO_VOLUME_DATA"
+		}
+		if c.Close > c.Open {
+			upVolume += c.Volume
+		} else {
+			downVolume += c.Volume
+		}
+	}
+
+	volumeRatio := float64(upVolume) / float64(upVolume+downVolume)
+	if volumeRatio > 0.65 {
+		return "BULLISH"
+	} else if volumeRatio < 0.35 {
+		return "BEARISH"
+	}
+	return "NEUTRAL"
 }
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"sync"
@@ -8,7 +9,11 @@ import (
 	"github.com/Alias1177/Predictor/models"
 )
 
-// CalculateMarketFeatures вычисляет основные рыночные признаки
+// ErrNoVolumeData - у инструмента нет объемов (FX у Twelve Data); ценовые признаки при этом рассчитаны
+var ErrNoVolumeData = errors.New("no volume data")
+
+// CalculateMarketFeatures вычисляет основные рыночные признаки: ATR к цене, наклон EMA, RSI
+// и изменение объема. Если объемов нет, признаки возвращаются вместе с ErrNoVolumeData
 func CalculateMarketFeatures(candles []models.Candle) ([]float64, error) {
 	if len(candles) < 20 {
 		return nil, fmt.Errorf("insufficient data: need at least 20 candles, got %d", len(candles))
@@ -60,7 +65,7 @@ func CalculateMarketFeatures(candles []models.Candle) ([]float64, error) {
 		defer wg.Done()
 		volumeChange := calculateVolumeChange(candles, 20)
 		if volumeChange == 0 {
-			errChan <- fmt.Errorf("failed to calculate volume change")
+			errChan <- fmt.Errorf("failed to calculate volume change: %w", ErrNoVolumeData)
 			return
 		}
 		features[3] = volumeChange
@@ -69,11 +74,15 @@ func CalculateMarketFeatures(candles []models.Candle) ([]float64, error) {
 	wg.Wait()
 	close(errChan)
 
-	if len(errChan) > 0 {
-		return nil, <-errChan
+	var volumeErr error
+	for err := range errChan {
+		if !errors.Is(err, ErrNoVolumeData) {
+			return nil, err
+		}
+		volumeErr = err
 	}
 
-	return features, nil
+	return features, volumeErr
 }
 
 // calculateATR вычисляет Average True Range
@@ -1,54 +1,207 @@
 package utils
 
 import (
+	"fmt"
 	"math"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/Alias1177/Predictor/models"
 )
 
-// FactorWeight представляет вес и корреляцию фактора
-type FactorWeight struct {
-	Weight      float64
-	Correlation float64
-	LastUpdate  time.Time
+const (
+	// factorWeightPrior - вес априорной точности 50% при сглаживании;
+	// пока наблюдений мало, вес фактора остается близким к 1
+	factorWeightPrior = 20.0
+
+	// factorWeightDecay - затухание счетчиков на каждое новое наблюдение фактора
+	factorWeightDecay = 0.995
+
+	// factorContributionAlpha - коэффициент скользящего среднего вклада фактора
+	factorContributionAlpha = 0.05
+
+	// Границы выученного веса
+	minFactorWeight = 0.25
+	maxFactorWeight = 2.0
+
+	// learnedPredictionsTTL - сколько хранить идентификаторы уже учтенных прогнозов
+	learnedPredictionsTTL = 30 * 24 * time.Hour
+)
+
+// FactorStore сохраняет выученные веса и исходы факторов (реализуется database.DB)
+type FactorStore interface {
+	SaveFactorWeights(weights []models.FactorWeight) error
+	RecordFactorOutcomes(outcomes []models.FactorOutcome) error
 }
 
-var factorWeights = map[string]FactorWeight{
-	"TREND":   {1.5, 0.0, time.Now()},
-	"RSI":     {1.0, 0.0, time.Now()},
-	"MACD":    {1.2, 0.0, time.Now()},
-	"BB":      {1.0, 0.0, time.Now()},
-	"VOLUME":  {1.3, 0.0, time.Now()},
-	"PATTERN": {1.8, 0.0, time.Now()},
+// factorWeights хранит выученные веса по ключу стратегия/символ/таймфрейм/фактор и уже учтенные прогнозы.
+// Бэктест перезапускается на пересекающихся окнах, поэтому один прогноз учитывается один раз
+var factorWeights = struct {
+	mu      sync.RWMutex
+	weights map[string]models.FactorWeight
+	learned map[string]time.Time
+	store   FactorStore
+}{
+	weights: make(map[string]models.FactorWeight),
+	learned: make(map[string]time.Time),
 }
 
-// UpdateFactorWeights обновляет веса на основе исторических данных
-func UpdateFactorWeights(results []models.PredictionResult) {
+// factorKey возвращает ключ веса: одинаковые идентификаторы факторов разных стратегий
+// (например, RSI) и разных инструментов учатся независимо
+func factorKey(strategy, symbol, interval, factorID string) string {
+	return strategy + "|" + symbol + "|" + interval + "|" + factorID
+}
+
+// SetFactorStore задает хранилище, в которое сохраняются веса после каждого обновления
+func SetFactorStore(store FactorStore) {
+	factorWeights.mu.Lock()
+	defer factorWeights.mu.Unlock()
+	factorWeights.store = store
+}
+
+// LoadFactorWeights заменяет текущие веса сохраненными
+func LoadFactorWeights(weights []models.FactorWeight) {
+	factorWeights.mu.Lock()
+	defer factorWeights.mu.Unlock()
+
+	factorWeights.weights = make(map[string]models.FactorWeight, len(weights))
+	for _, weight := range weights {
+		factorWeights.weights[factorKey(weight.Strategy, weight.Symbol, weight.Interval, weight.FactorID)] = weight
+	}
+}
+
+// FactorOutcomes извлекает исходы факторов из проверенных прогнозов. Фактор считается
+// верным, если знак его вклада совпал с фактическим движением цены; прогнозы
+// без движения цены пропускаются
+func FactorOutcomes(strategy, symbol, interval string, results []models.PredictionResult) []models.FactorOutcome {
+	var outcomes []models.FactorOutcome
 	for _, result := range results {
-		for _, factor := range result.Factors {
-			if weight, exists := factorWeights[factor]; exists {
-				// Обновляем корреляцию на основе успешности предсказания
-				if result.WasCorrect {
-					weight.Correlation += 0.1
-				} else {
-					weight.Correlation -= 0.1
-				}
-				weight.Correlation = math.Max(-1.0, math.Min(1.0, weight.Correlation))
-
-				// Обновляем вес с учетом корреляции
-				weight.Weight *= (1.0 + weight.Correlation*0.1)
-				weight.LastUpdate = time.Now()
-				factorWeights[factor] = weight
+		if result.ActualOutcome != "BUY" && result.ActualOutcome != "SELL" {
+			continue
+		}
+		for factorID, contribution := range result.FactorScores {
+			if contribution == 0 {
+				continue
 			}
+			outcomes = append(outcomes, models.FactorOutcome{
+				Strategy:     strategy,
+				FactorID:     factorID,
+				PredictionID: result.PredictionID,
+				Symbol:       symbol,
+				Interval:     interval,
+				Timestamp:    result.Timestamp,
+				Contribution: contribution,
+				Correct:      (contribution > 0) == (result.ActualOutcome == "BUY"),
+			})
 		}
 	}
+	return outcomes
 }
 
-// GetFactorWeight возвращает вес фактора
-func GetFactorWeight(factor string) float64 {
-	if weight, exists := factorWeights[factor]; exists {
+// UpdateFactorWeights обновляет веса факторов стратегии по проверенным прогнозам и сохраняет их
+// в хранилище, если оно задано. Уже учтенные прогнозы (по PredictionID) пропускаются
+func UpdateFactorWeights(strategy, symbol, interval string, results []models.PredictionResult) error {
+	factorWeights.mu.Lock()
+
+	now := time.Now()
+	for id, learnedAt := range factorWeights.learned {
+		if now.Sub(learnedAt) > learnedPredictionsTTL {
+			delete(factorWeights.learned, id)
+		}
+	}
+
+	var fresh []models.PredictionResult
+	for _, result := range results {
+		if result.PredictionID == "" {
+			continue
+		}
+		learnedID := strategy + "|" + result.PredictionID
+		if _, seen := factorWeights.learned[learnedID]; seen {
+			continue
+		}
+		factorWeights.learned[learnedID] = now
+		fresh = append(fresh, result)
+	}
+
+	outcomes := FactorOutcomes(strategy, symbol, interval, fresh)
+	updated := make(map[string]bool)
+	for _, outcome := range outcomes {
+		key := factorKey(strategy, symbol, interval, outcome.FactorID)
+		weight, ok := factorWeights.weights[key]
+		if !ok {
+			weight = models.FactorWeight{
+				Strategy: strategy,
+				Symbol:   symbol,
+				Interval: interval,
+				FactorID: outcome.FactorID,
+				Weight:   1.0,
+			}
+		}
+
+		weight.Occurrences = weight.Occurrences*factorWeightDecay + 1
+		weight.Hits *= factorWeightDecay
+		if outcome.Correct {
+			weight.Hits++
+		}
+		weight.Contribution += factorContributionAlpha * (math.Abs(outcome.Contribution) - weight.Contribution)
+		weight.Weight = learnedWeight(weight.Hits, weight.Occurrences)
+		weight.LastUpdate = now
+
+		factorWeights.weights[key] = weight
+		updated[key] = true
+	}
+
+	store := factorWeights.store
+	changed := make([]models.FactorWeight, 0, len(updated))
+	for key := range updated {
+		changed = append(changed, factorWeights.weights[key])
+	}
+	factorWeights.mu.Unlock()
+
+	if store == nil || len(outcomes) == 0 {
+		return nil
+	}
+	if err := store.RecordFactorOutcomes(outcomes); err != nil {
+		return fmt.Errorf("recording factor outcomes: %w", err)
+	}
+	if err := store.SaveFactorWeights(changed); err != nil {
+		return fmt.Errorf("saving factor weights: %w", err)
+	}
+	return nil
+}
+
+// learnedWeight переводит сглаженную точность фактора в множитель вклада:
+// точность 50% оставляет вес 1, 75% - удваивает, 25% и ниже - снижает до минимума
+func learnedWeight(hits, occurrences float64) float64 {
+	accuracy := (hits + 0.5*factorWeightPrior) / (occurrences + factorWeightPrior)
+	return math.Max(minFactorWeight, math.Min(maxFactorWeight, 1+(accuracy-0.5)*4))
+}
+
+// GetFactorWeight возвращает выученный вес фактора стратегии на инструменте и таймфрейме
+func GetFactorWeight(strategy, symbol, interval, factorID string) float64 {
+	factorWeights.mu.RLock()
+	defer factorWeights.mu.RUnlock()
+
+	if weight, exists := factorWeights.weights[factorKey(strategy, symbol, interval, factorID)]; exists {
 		return weight.Weight
 	}
 	return 1.0 // значение по умолчанию
 }
+
+// FactorWeights возвращает копию выученных весов, отсортированную по стратегии, символу,
+// таймфрейму и идентификатору фактора
+func FactorWeights() []models.FactorWeight {
+	factorWeights.mu.RLock()
+	defer factorWeights.mu.RUnlock()
+
+	weights := make([]models.FactorWeight, 0, len(factorWeights.weights))
+	for _, weight := range factorWeights.weights {
+		weights = append(weights, weight)
+	}
+	sort.Slice(weights, func(i, j int) bool {
+		return factorKey(weights[i].Strategy, weights[i].Symbol, weights[i].Interval, weights[i].FactorID) <
+			factorKey(weights[j].Strategy, weights[j].Symbol, weights[j].Interval, weights[j].FactorID)
+	})
+	return weights
+}
@@ -0,0 +1,366 @@
+package volatility
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alias1177/Predictor/internal/calculate"
+	"github.com/Alias1177/Predictor/internal/params"
+	"github.com/Alias1177/Predictor/models"
+)
+
+const (
+	MethodGARCH = "GARCH"
+	MethodEWMA  = "EWMA"
+
+	// minGARCHSamples - на меньшем числе доходностей оценки GARCH неустойчивы
+	minGARCHSamples = 250
+
+	// minEWMASamples - минимум доходностей для подбора коэффициента EWMA
+	minEWMASamples = 20
+
+	// maxPersistence - при Alpha+Beta выше модель почти интегрирована и вырождается в EWMA
+	maxPersistence = 0.999
+
+	// Квантили нормального распределения для диапазонов 68% и 95%
+	z68 = 1.0
+	z95 = 1.96
+)
+
+// registry хранит обученные модели волатильности по символу и таймфрейму
+var registry = struct {
+	mu     sync.RWMutex
+	models map[string]*models.VolatilityModel
+}{
+	models: make(map[string]*models.VolatilityModel),
+}
+
+// LogReturns возвращает лог-доходности закрытий соседних свечей
+func LogReturns(candles []models.Candle) []float64 {
+	returns := make([]float64, 0, len(candles))
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].Close <= 0 || candles[i].Close <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(candles[i].Close/candles[i-1].Close))
+	}
+	return returns
+}
+
+// Fit обучает GARCH(1,1), если доходностей достаточно и процесс стационарен, иначе EWMA
+func Fit(returns []float64) (*models.VolatilityModel, error) {
+	if len(returns) >= minGARCHSamples {
+		if model, err := FitGARCH(returns); err == nil {
+			return model, nil
+		}
+	}
+	return FitEWMA(returns)
+}
+
+// FitGARCH оценивает GARCH(1,1) методом максимального правдоподобия с таргетированием
+// дисперсии: Omega = V*(1-Alpha-Beta), где V - выборочная дисперсия. Alpha и Beta
+// ищутся по сетке и уточняются покоординатным поиском
+func FitGARCH(returns []float64) (*models.VolatilityModel, error) {
+	if len(returns) < minGARCHSamples {
+		return nil, fmt.Errorf("insufficient returns for GARCH: need %d, got %d", minGARCHSamples, len(returns))
+	}
+
+	centered, variance := center(returns)
+	if variance <= 0 {
+		return nil, fmt.Errorf("returns have zero variance")
+	}
+
+	likelihood := func(alpha, beta float64) float64 {
+		if alpha <= 0 || beta <= 0 || alpha+beta >= maxPersistence {
+			return math.Inf(-1)
+		}
+		return garchLogLikelihood(centered, variance*(1-alpha-beta), alpha, beta, variance)
+	}
+
+	bestAlpha, bestBeta, best := 0.0, 0.0, math.Inf(-1)
+	for alpha := 0.01; alpha <= 0.30; alpha += 0.01 {
+		for beta := 0.50; beta < 0.99; beta += 0.01 {
+			if ll := likelihood(alpha, beta); ll > best {
+				bestAlpha, bestBeta, best = alpha, beta, ll
+			}
+		}
+	}
+	if math.IsInf(best, -1) {
+		return nil, fmt.Errorf("GARCH likelihood is undefined on the parameter grid")
+	}
+
+	for step := 0.005; step > 1e-5; step /= 2 {
+		for improved := true; improved; {
+			improved = false
+			for _, d := range [][2]float64{{step, 0}, {-step, 0}, {0, step}, {0, -step}} {
+				if ll := likelihood(bestAlpha+d[0], bestBeta+d[1]); ll > best {
+					bestAlpha, bestBeta, best = bestAlpha+d[0], bestBeta+d[1], ll
+					improved = true
+				}
+			}
+		}
+	}
+
+	if bestAlpha+bestBeta >= maxPersistence-1e-4 {
+		return nil, fmt.Errorf("GARCH is non-stationary (alpha+beta=%.4f)", bestAlpha+bestBeta)
+	}
+
+	return &models.VolatilityModel{
+		Method:        MethodGARCH,
+		Omega:         variance * (1 - bestAlpha - bestBeta),
+		Alpha:         bestAlpha,
+		Beta:          bestBeta,
+		LongRunVar:    variance,
+		LogLikelihood: best,
+		Samples:       len(returns),
+		TrainedAt:     time.Now(),
+	}, nil
+}
+
+// FitEWMA подбирает коэффициент затухания EWMA по правдоподобию
+func FitEWMA(returns []float64) (*models.VolatilityModel, error) {
+	if len(returns) < minEWMASamples {
+		return nil, fmt.Errorf("insufficient returns for EWMA: need %d, got %d", minEWMASamples, len(returns))
+	}
+
+	centered, variance := center(returns)
+	if variance <= 0 {
+		return nil, fmt.Errorf("returns have zero variance")
+	}
+
+	bestLambda, best := 0.94, math.Inf(-1)
+	for lambda := 0.80; lambda < 0.995; lambda += 0.005 {
+		if ll := garchLogLikelihood(centered, 0, 1-lambda, lambda, variance); ll > best {
+			bestLambda, best = lambda, ll
+		}
+	}
+
+	return &models.VolatilityModel{
+		Method:        MethodEWMA,
+		Lambda:        bestLambda,
+		LongRunVar:    variance,
+		LogLikelihood: best,
+		Samples:       len(returns),
+		TrainedAt:     time.Now(),
+	}, nil
+}
+
+// center вычитает среднее и возвращает выборочную дисперсию
+func center(returns []float64) ([]float64, float64) {
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	centered := make([]float64, len(returns))
+	variance := 0.0
+	for i, r := range returns {
+		centered[i] = r - mean
+		variance += centered[i] * centered[i]
+	}
+	return centered, variance / float64(len(returns))
+}
+
+// garchLogLikelihood - гауссово лог-правдоподобие рекурсии
+// sigma2[t+1] = omega + alpha*r[t]^2 + beta*sigma2[t]; EWMA - частный случай omega=0
+func garchLogLikelihood(returns []float64, omega, alpha, beta, initial float64) float64 {
+	sigma2 := initial
+	ll := 0.0
+	for _, r := range returns {
+		if sigma2 <= 0 {
+			return math.Inf(-1)
+		}
+		ll += -0.5 * (math.Log(2*math.Pi*sigma2) + r*r/sigma2)
+		sigma2 = omega + alpha*r*r + beta*sigma2
+	}
+	return ll
+}
+
+// NextVariance прогоняет рекурсию модели по доходностям и возвращает дисперсию следующей свечи
+func NextVariance(model *models.VolatilityModel, returns []float64) float64 {
+	sigma2 := model.LongRunVar
+	for _, r := range returns {
+		if model.Method == MethodGARCH {
+			sigma2 = model.Omega + model.Alpha*r*r + model.Beta*sigma2
+		} else {
+			sigma2 = model.Lambda*sigma2 + (1-model.Lambda)*r*r
+		}
+	}
+	return sigma2
+}
+
+// HorizonVariance суммирует прогнозы дисперсии на bars свечей: для GARCH дисперсия
+// возвращается к безусловной со скоростью Alpha+Beta, для EWMA остается постоянной
+func HorizonVariance(model *models.VolatilityModel, nextVariance float64, bars int) float64 {
+	if model.Method != MethodGARCH {
+		return nextVariance * float64(bars)
+	}
+
+	persistence := model.Alpha + model.Beta
+	total := 0.0
+	decay := 1.0
+	for k := 0; k < bars; k++ {
+		total += model.LongRunVar + decay*(nextVariance-model.LongRunVar)
+		decay *= persistence
+	}
+	return total
+}
+
+// Forecast прогнозирует волатильность и диапазоны цены на bars свечей вперед.
+// Используется обученная модель символа, а если ее нет - модель, подобранная по свечам
+func Forecast(candles []models.Candle, symbol, interval string, bars int) (*models.VolatilityForecast, error) {
+	if bars < 1 {
+		return nil, fmt.Errorf("volatility horizon must be positive, got %d", bars)
+	}
+
+	returns := LogReturns(candles)
+	model, ok := Get(symbol, interval)
+	if !ok {
+		var err error
+		if model, err = Fit(returns); err != nil {
+			return nil, err
+		}
+	}
+
+	nextVariance := NextVariance(model, returns)
+	horizonSigma := math.Sqrt(HorizonVariance(model, nextVariance, bars))
+	price := candles[len(candles)-1].Close
+
+	return &models.VolatilityForecast{
+		Method:       model.Method,
+		Bars:         bars,
+		BarSigma:     math.Sqrt(nextVariance),
+		HorizonSigma: horizonSigma,
+		Range68: models.PriceRange{
+			Low:  price * math.Exp(-z68*horizonSigma),
+			High: price * math.Exp(z68*horizonSigma),
+		},
+		Range95: models.PriceRange{
+			Low:  price * math.Exp(-z95*horizonSigma),
+			High: price * math.Exp(z95*horizonSigma),
+		},
+	}, nil
+}
+
+// Apply добавляет к торговой рекомендации ожидаемый диапазон и переносит стоп-лосс
+// за границу диапазона 68% (обычный шум не выбивает позицию), а тейк-профит -
+// на границу диапазона 95% в сторону сделки
+func Apply(prediction *models.Prediction, candles []models.Candle, symbol, interval string) {
+	suggestion := prediction.TradingSuggestion
+	if suggestion == nil || len(candles) == 0 {
+		return
+	}
+
+	p := params.Active()
+	forecast, err := Forecast(candles, symbol, interval, p.Risk.VolatilityBars)
+	if err != nil {
+		return
+	}
+	suggestion.ExpectedRange = forecast
+
+	var stopLoss, takeProfit float64
+	switch suggestion.Direction {
+	case "BUY":
+		stopLoss, takeProfit = forecast.Range68.Low, forecast.Range95.High
+	case "SELL":
+		stopLoss, takeProfit = forecast.Range68.High, forecast.Range95.Low
+	default:
+		return
+	}
+
+	entry := suggestion.EntryPrice
+	sizing := calculate.CalculatePositionSize(entry, stopLoss, p.Risk.AccountSize, p.Risk.RiskPerTrade)
+	suggestion.StopLoss = stopLoss
+	suggestion.TakeProfit = takeProfit
+	suggestion.PositionSize = sizing.PositionSize
+	if risk := math.Abs(entry - stopLoss); risk > 0 {
+		suggestion.RiskRewardRatio = math.Abs(takeProfit-entry) / risk
+	}
+}
+
+// FileName возвращает имя файла модели волатильности для символа и таймфрейма
+func FileName(symbol, interval string) string {
+	return fmt.Sprintf("volatility_%s_%s.json", strings.ReplaceAll(symbol, "/", ""), interval)
+}
+
+// Save сохраняет модель в каталог dir
+func Save(dir string, model *models.VolatilityModel) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating volatility model directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding volatility model: %w", err)
+	}
+
+	path := filepath.Join(dir, FileName(model.Symbol, model.Interval))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing volatility model: %w", err)
+	}
+
+	return nil
+}
+
+// Load читает модель волатильности из файла
+func Load(path string) (*models.VolatilityModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading volatility model: %w", err)
+	}
+
+	var model models.VolatilityModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("parsing volatility model %s: %w", path, err)
+	}
+	if model.LongRunVar <= 0 {
+		return nil, fmt.Errorf("volatility model %s: long-run variance must be positive", path)
+	}
+	if model.Method == MethodGARCH && model.Alpha+model.Beta >= 1 {
+		return nil, fmt.Errorf("volatility model %s: GARCH is non-stationary", path)
+	}
+
+	return &model, nil
+}
+
+// LoadDir загружает все модели волатильности из каталога
+func LoadDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "volatility_*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("listing volatility models: %w", err)
+	}
+
+	loaded := 0
+	for _, file := range files {
+		model, err := Load(file)
+		if err != nil {
+			return loaded, err
+		}
+		Register(model)
+		loaded++
+	}
+
+	return loaded, nil
+}
+
+// Register регистрирует модель для использования в прогнозах
+func Register(model *models.VolatilityModel) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.models[FileName(model.Symbol, model.Interval)] = model
+}
+
+// Get возвращает обученную модель для символа и таймфрейма
+func Get(symbol, interval string) (*models.VolatilityModel, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	model, ok := registry.models[FileName(symbol, interval)]
+	return model, ok
+}
@@ -24,6 +24,12 @@ type Config struct {
 	AdaptiveIndicator bool    `env:"ADAPTIVE_INDICATOR" envDefault:"true"`
 	EnableBacktest    bool    `env:"ENABLE_BACKTEST" envDefault:"true"`
 	BacktestDays      int     `env:"BACKTEST_DAYS" envDefault:"5"`
+	Strategy          string  `env:"STRATEGY" envDefault:"enhanced"`
+	Language          string  `env:"FACTOR_LANGUAGE" envDefault:"en"` // Язык описаний факторов: en или ru
+	// Комплексный анализ рынка с бенчмарком (индекс доллара) как дополнительный фактор прогноза
+	EnableMarketAnalysis bool `env:"ENABLE_MARKET_ANALYSIS" envDefault:"false"`
+	// Рейтинг силы валют по основным парам как фактор CURRENCY_STRENGTH
+	EnableCurrencyStrength bool `env:"ENABLE_CURRENCY_STRENGTH" envDefault:"false"`
 }
 
 // Candle represents a single price candle
@@ -91,32 +97,115 @@ type MarketRegime struct {
 	MomentumStrength float64 `json:"momentum_strength"` // 0-1 score
 	LiquidityRating  string  `json:"liquidity_rating"`  // LOW, NORMAL, HIGH
 	PriceStructure   string  `json:"price_structure"`   // TRENDING_UP, TRENDING_DOWN, RANGE_BOUND, BREAKOUT, BREAKDOWN
+
+	// Состояние скрытой марковской модели (заполняется при наличии обученной HMM)
+	HMMState                string             `json:"hmm_state,omitempty"`
+	StateProbabilities      map[string]float64 `json:"state_probabilities,omitempty"`      // Апостериорные вероятности режимов
+	TransitionProbabilities map[string]float64 `json:"transition_probabilities,omitempty"` // Вероятности перехода из текущего состояния на следующей свече
+	ExpectedDuration        float64            `json:"expected_duration,omitempty"`        // Ожидаемая длительность текущего состояния в свечах
+
+	ChangePoint *ChangePointAnalysis `json:"change_point,omitempty"` // Структурные сдвиги и возраст режима
+}
+
+// ChangePoint описывает обнаруженный структурный сдвиг рынка
+type ChangePoint struct {
+	Index       int       `json:"index"`     // Индекс свечи, с которой начался новый режим
+	Timestamp   time.Time `json:"timestamp"` // Время свечи сдвига
+	Method      string    `json:"method"`    // CUSUM, BOCPD
+	Series      string    `json:"series"`    // RETURNS, VOLATILITY
+	Probability float64   `json:"probability"`
+}
+
+// ChangePointAnalysis содержит результат поиска сдвигов режима
+type ChangePointAnalysis struct {
+	LastBreak        *ChangePoint  `json:"last_break,omitempty"`
+	BreakProbability float64       `json:"break_probability"`
+	RegimeAge        int           `json:"regime_age"`   // Количество свечей с начала текущего режима
+	RecentBreak      bool          `json:"recent_break"` // Сдвиг произошел недавно и прогноз следует ослабить
+	Damping          float64       `json:"damping"`      // Множитель силы прогноза (1 - без ослабления)
+	Breaks           []ChangePoint `json:"breaks,omitempty"`
+}
+
+// RegimeSegment - непрерывный участок истории с одним режимом рынка
+type RegimeSegment struct {
+	Type       string    `json:"type"`
+	Direction  string    `json:"direction"` // Преобладающее направление внутри сегмента
+	Strength   float64   `json:"strength"`  // Средняя сила режима
+	StartIndex int       `json:"start_index"`
+	EndIndex   int       `json:"end_index"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Bars       int       `json:"bars"`
+	Return     float64   `json:"return"`     // Суммарная доходность сегмента, %
+	Volatility float64   `json:"volatility"` // Стандартное отклонение доходности свечи, %
+}
+
+// RegimeStats - сводная статистика по одному типу режима
+type RegimeStats struct {
+	Type        string  `json:"type"`
+	Segments    int     `json:"segments"`
+	TotalBars   int     `json:"total_bars"`
+	AvgDuration float64 `json:"avg_duration"` // Средняя длительность сегмента в свечах
+	MaxDuration int     `json:"max_duration"`
+	Share       float64 `json:"share"`      // Доля истории в этом режиме, %
+	AvgReturn   float64 `json:"avg_return"` // Средняя доходность свечи, %
+	Volatility  float64 `json:"volatility"` // Стандартное отклонение доходности свечи, %
+}
+
+// RegimeTimeline - история смены режимов рынка
+type RegimeTimeline struct {
+	Symbol   string                 `json:"symbol"`
+	Interval string                 `json:"interval"`
+	Segments []RegimeSegment        `json:"segments"`
+	Stats    map[string]RegimeStats `json:"stats"`
 }
 
 // AnomalyDetection contains information about market anomalies
 type AnomalyDetection struct {
 	IsAnomaly        bool     `json:"is_anomaly"`
-	AnomalyType      string   `json:"anomaly_type,omitempty"` // PRICE_SPIKE, VOLUME_SPIKE, GAP, PATTERN_BREAK
+	AnomalyType      string   `json:"anomaly_type,omitempty"` // PRICE_SPIKE, VOLUME_SPIKE, GAP, PATTERN_BREAK, MULTIVARIATE_OUTLIER, WEEKEND_GAP, SESSION_OPEN_SPIKE, ROLLOVER_SPREAD
 	AnomalyScore     float64  `json:"anomaly_score"`          // 0-1 score
 	Details          string   `json:"details,omitempty"`
 	RecommendedFlags []string `json:"recommended_flags,omitempty"`
+
+	ModelScore           float64            `json:"model_score,omitempty"`           // Калиброванная оценка изолирующего леса (перцентиль 0-1)
+	FeatureContributions map[string]float64 `json:"feature_contributions,omitempty"` // Вклад признаков в изоляцию свечи, %
+
+	Session string   `json:"session,omitempty"` // Торговая сессия сессионной аномалии (LONDON, NEW_YORK, ROLLOVER, ...)
+	Gap     *GapInfo `json:"gap,omitempty"`
+}
+
+// GapInfo описывает незакрытый гэп выходного дня
+type GapInfo struct {
+	Direction       string    `json:"direction"` // UP, DOWN
+	Size            float64   `json:"size"`      // Размер гэпа в единицах цены
+	SizeATR         float64   `json:"size_atr"`  // Размер гэпа в долях ATR
+	OpenTime        time.Time `json:"open_time"`
+	FillProbability float64   `json:"fill_probability"` // Историческая вероятность заполнения; 0 без статистики гэпов
+	Occurrences     int       `json:"occurrences"`      // Количество гэпов в истории для оценки вероятности
+	FillTargets     []float64 `json:"fill_targets"`     // Уровни 50% и 100% заполнения
 }
 
 // PredictionResult stores the outcome of a prediction
 type PredictionResult struct {
-	Direction        string    `json:"direction"`
-	Confidence       string    `json:"confidence"`
-	Score            float64   `json:"score"`
-	Factors          []string  `json:"factors"`
-	Timestamp        time.Time `json:"timestamp"`
-	PredictionID     string    `json:"prediction_id"`
-	PredictionTarget time.Time `json:"prediction_target"` // When this prediction should be validated
-	ActualOutcome    string    `json:"actual_outcome,omitempty"`
-	WasCorrect       bool      `json:"was_correct,omitempty"`
+	Direction        string             `json:"direction"`
+	Confidence       string             `json:"confidence"`
+	Score            float64            `json:"score"`
+	Factors          []string           `json:"factors"`
+	Timestamp        time.Time          `json:"timestamp"`
+	PredictionID     string             `json:"prediction_id"`
+	PredictionTarget time.Time          `json:"prediction_target"` // When this prediction should be validated
+	ActualOutcome    string             `json:"actual_outcome,omitempty"`
+	WasCorrect       bool               `json:"was_correct,omitempty"`
+	ParamsVersion    string             `json:"params_version,omitempty"` // Версия параметров скоринга
+	FactorScores     map[string]float64 `json:"factor_scores,omitempty"`  // Вклад факторов по идентификаторам
+	Probability      float64            `json:"probability,omitempty"`    // Откалиброванная вероятность верного направления
 }
 
 // BacktestResults stores backtesting results
 type BacktestResults struct {
+	Strategy       string  `json:"strategy"`
+	ParamsVersion  string  `json:"params_version,omitempty"` // Версия параметров скоринга
 	TotalTrades    int     `json:"total_trades"`
 	WinningTrades  int     `json:"winning_trades"`
 	LosingTrades   int     `json:"losing_trades"`
@@ -127,19 +216,28 @@ type BacktestResults struct {
 		Wins  int `json:"wins"`
 		Loses int `json:"loses"`
 	} `json:"max_consecutive"`
-	MarketRegimePerformance map[string]float64 `json:"market_regime_performance"`
-	TimeframePerformance    map[string]float64 `json:"timeframe_performance"`
-	DetailedResults         []PredictionResult `json:"detailed_results"`
-	ProfitFactor            float64            `json:"profit_factor"`
-	MaxDrawdown             float64            `json:"max_drawdown"`
-	SharpeRatio             float64            `json:"sharpe_ratio"`
-	EquityCurve             []float64          `json:"equity_curve,omitempty"`
-	EquityGrowthPercent     float64            `json:"equity_growth_percent"` // Рост капитала в %
-	MonthlySharpe           float64            `json:"monthly_sharpe"`        // Месячный коэф. Шарпа
-	MonthlyReturns          map[string]float64 `json:"monthly_returns"`
-	AverageGainPercent      float64            `json:"average_gain_percent"`
-	AverageLossPercent      float64            `json:"average_loss_percent"`
-	TotalReturnPercent      float64            `json:"total_return_percent"`
+	MarketRegimePerformance  map[string]float64            `json:"market_regime_performance"`
+	RegimeMonthlyPerformance map[string]map[string]float64 `json:"regime_monthly_performance,omitempty"` // Месяц -> режим -> % верных прогнозов
+	RegimeTimeline           *RegimeTimeline               `json:"regime_timeline,omitempty"`
+	Calibration              *CalibrationReport            `json:"calibration,omitempty"`
+	HorizonPerformance       map[string]float64            `json:"horizon_performance,omitempty"` // Горизонт -> % верных направленных прогнозов
+	HorizonCalibration       map[string]*CalibrationReport `json:"horizon_calibration,omitempty"` // Калибровка прогнозов на горизонтах дальше одной свечи
+	TimeframePerformance     map[string]float64            `json:"timeframe_performance"`
+	DetailedResults          []PredictionResult            `json:"detailed_results"`
+	ProfitFactor             float64                       `json:"profit_factor"`
+	MaxDrawdown              float64                       `json:"max_drawdown"`
+	SharpeRatio              float64                       `json:"sharpe_ratio"`
+	EquityCurve              []float64                     `json:"equity_curve,omitempty"`
+	EquityGrowthPercent      float64                       `json:"equity_growth_percent"` // Рост капитала в %
+	MonthlySharpe            float64                       `json:"monthly_sharpe"`        // Месячный коэф. Шарпа
+	MonthlyReturns           map[string]float64            `json:"monthly_returns"`
+	AverageGainPercent       float64                       `json:"average_gain_percent"`
+	AverageLossPercent       float64                       `json:"average_loss_percent"`
+	TotalReturnPercent       float64                       `json:"total_return_percent"`
+	From                     time.Time                     `json:"from"`                  // Время последней свечи первого проверенного окна
+	To                       time.Time                     `json:"to"`                    // Время последней свечи последнего проверенного окна
+	Trades                   []SimulatedTrade              `json:"trades,omitempty"`      // Сделки симулятора по рекомендациям стратегии
+	TradeStats               *TradeStats                   `json:"trade_stats,omitempty"` // Итоги симуляции; денежные метрики выше считаются по ним
 
 	DivergenceStats struct {
 		BullishCorrect   int `json:"bullish_correct"`
@@ -149,6 +247,85 @@ type BacktestResults struct {
 	} `json:"divergence_stats"`
 }
 
+// SimulatedTrade - сделка, исполненная симулятором бэктеста по рекомендации стратегии
+type SimulatedTrade struct {
+	PredictionID string     `json:"prediction_id"`
+	Direction    string     `json:"direction"` // BUY или SELL
+	EntryTime    time.Time  `json:"entry_time"`
+	EntryPrice   float64    `json:"entry_price"` // Средняя цена; цена исполнения отличается на спред и проскальзывание
+	StopLoss     float64    `json:"stop_loss"`
+	TakeProfit   float64    `json:"take_profit"`
+	Units        float64    `json:"units"` // Размер позиции в единицах базовой валюты
+	ExitTime     time.Time  `json:"exit_time"`
+	ExitPrice    float64    `json:"exit_price"`
+	ExitReason   string     `json:"exit_reason"` // STOP_LOSS, TAKE_PROFIT, TIME_STOP, OPPOSITE_SIGNAL, END_OF_DATA
+	Bars         int        `json:"bars"`        // Число свечей в позиции, включая свечу входа
+	PnLPips      float64    `json:"pnl_pips"`    // За вычетом спреда, проскальзывания и свопа
+	PnL          float64    `json:"pnl"`         // За вычетом всех издержек, в валюте TradeStats.Currency
+	GrossPnLPips float64    `json:"gross_pnl_pips"`
+	GrossPnL     float64    `json:"gross_pnl"` // По средним ценам входа и выхода без издержек
+	Costs        TradeCosts `json:"costs"`
+	Balance      float64    `json:"balance"` // Баланс после закрытия сделки
+}
+
+// TradeCosts - издержки сделки в валюте результата; положительное значение уменьшает прибыль
+type TradeCosts struct {
+	Spread     float64 `json:"spread"`
+	Slippage   float64 `json:"slippage"`
+	Commission float64 `json:"commission"`
+	Swap       float64 `json:"swap"` // Отрицательный, если своп начислен в пользу позиции
+	Total      float64 `json:"total"`
+}
+
+// TradeStats - итоги симуляции сделок
+type TradeStats struct {
+	Currency        string         `json:"currency"` // Валюта счета или котируемая валюта, если курса пересчета нет
+	InitialBalance  float64        `json:"initial_balance"`
+	FinalBalance    float64        `json:"final_balance"`
+	NetPnL          float64        `json:"net_pnl"`
+	Trades          int            `json:"trades"`
+	Wins            int            `json:"wins"`
+	Losses          int            `json:"losses"`
+	SkippedSignals  int            `json:"skipped_signals"`  // Уровни не окружают цену входа, например после гэпа
+	Ruined          bool           `json:"ruined,omitempty"` // Баланс исчерпан, дальнейшие сигналы не исполнялись
+	WinRate         float64        `json:"win_rate"`         // В процентах
+	TotalPips       float64        `json:"total_pips"`
+	AverageWinPips  float64        `json:"average_win_pips"`
+	AverageLossPips float64        `json:"average_loss_pips"`
+	ProfitFactor    float64        `json:"profit_factor"`
+	MaxDrawdown     float64        `json:"max_drawdown"` // В процентах от максимума баланса
+	AverageBars     float64        `json:"average_bars"`
+	ExitReasons     map[string]int `json:"exit_reasons"`
+	Costs           TradeCosts     `json:"costs"`           // Сумма издержек по сделкам
+	Gross           *TradeStats    `json:"gross,omitempty"` // Те же сделки без издержек, объем от валового баланса
+}
+
+// CostProfile - торговые издержки инструмента. Спред, проскальзывание и своп задаются
+// в пунктах инструмента (своп криптовалют - в процентах стоимости позиции), комиссия -
+// в валюте счета за стандартный лот
+type CostProfile struct {
+	Symbol           string         `json:"symbol"`
+	SpreadPips       float64        `json:"spread_pips"`               // Спред вне окон SessionSpreads
+	SessionSpreads   []SpreadWindow `json:"session_spreads,omitempty"` // Спред по времени суток
+	CommissionPerLot float64        `json:"commission_per_lot"`        // За сторону сделки
+	LotSize          float64        `json:"lot_size"`                  // Единиц базового актива в лоте
+	SlippagePips     float64        `json:"slippage_pips"`             // На каждое рыночное исполнение
+	SlippageRange    float64        `json:"slippage_range"`            // Дополнительно доля диапазона свечи исполнения
+	SwapLong         float64        `json:"swap_long"`                 // Пунктов за ночь; положительный начисляется в пользу позиции
+	SwapShort        float64        `json:"swap_short"`
+	SwapLongPercent  float64        `json:"swap_long_percent,omitempty"` // Процентов стоимости позиции за ночь (криптовалюты)
+	SwapShortPercent float64        `json:"swap_short_percent,omitempty"`
+	TripleSwapDay    string         `json:"triple_swap_day,omitempty"` // День тройного свопа за выходные, по умолчанию Wednesday
+	DailySwap        bool           `json:"daily_swap,omitempty"`      // Своп каждую ночь без тройного дня (криптовалюты)
+}
+
+// SpreadWindow - спред в часы [FromHour, ToHour) UTC; FromHour > ToHour - окно через полночь
+type SpreadWindow struct {
+	FromHour   int     `json:"from_hour"`
+	ToHour     int     `json:"to_hour"`
+	SpreadPips float64 `json:"spread_pips"`
+}
+
 // Client is a wrapper for HTTP client with rate limiting
 
 // Структура для управления риском
@@ -269,6 +446,203 @@ type Divergence struct {
 	SignalStrength  float64           `json:"signal_strength"` // Сила сигнала от 0 до 1
 }
 
+// DivergenceConfluence представляет совпадение дивергенций нескольких индикаторов на одном свинге
+type DivergenceConfluence struct {
+	Direction       string   `json:"direction"`        // BULLISH или BEARISH
+	SwingIndex      int      `json:"swing_index"`      // Индекс свечи, на которой завершилась дивергенция
+	Indicators      []string `json:"indicators"`       // Индикаторы, подтвердившие дивергенцию
+	Count           int      `json:"count"`            // Общее количество дивергенций в группе
+	AverageStrength float64  `json:"average_strength"` // Средняя сила сигнала
+}
+
+// FactorWeight - выученный вес фактора скоринга для стратегии, инструмента и таймфрейма.
+// Счетчики затухают, чтобы вес отражал недавнюю точность фактора
+type FactorWeight struct {
+	Strategy     string    `json:"strategy"`
+	Symbol       string    `json:"symbol"`
+	Interval     string    `json:"interval"`
+	FactorID     string    `json:"factor_id"`
+	Weight       float64   `json:"weight"`       // Множитель вклада фактора в счет
+	Occurrences  float64   `json:"occurrences"`  // Затухающее число проверенных сигналов
+	Hits         float64   `json:"hits"`         // Затухающее число сигналов, совпавших с движением цены
+	Contribution float64   `json:"contribution"` // Средний модуль вклада в счет
+	LastUpdate   time.Time `json:"last_update"`
+}
+
+// FactorOutcome - исход одного фактора в проверенном прогнозе
+type FactorOutcome struct {
+	Strategy     string    `json:"strategy"`
+	FactorID     string    `json:"factor_id"`
+	PredictionID string    `json:"prediction_id"`
+	Symbol       string    `json:"symbol"`
+	Interval     string    `json:"interval"`
+	Timestamp    time.Time `json:"timestamp"`
+	Contribution float64   `json:"contribution"` // Вклад фактора, положительный - бычий
+	Correct      bool      `json:"correct"`      // Направление фактора совпало с движением цены
+}
+
+// FactorReportRow - точность и вклад фактора за период
+type FactorReportRow struct {
+	Strategy        string  `json:"strategy"`
+	FactorID        string  `json:"factor_id"`
+	Period          string  `json:"period"` // Начало периода (YYYY-MM-DD) или ALL
+	Occurrences     int     `json:"occurrences"`
+	Hits            int     `json:"hits"`
+	Accuracy        float64 `json:"accuracy"`         // Доля верных сигналов, %
+	AvgContribution float64 `json:"avg_contribution"` // Средний модуль вклада в счет
+	Weight          float64 `json:"weight"`           // Текущий выученный вес
+}
+
+// MLModel - обученная модель вероятности роста цены через Horizon свечей:
+// логистическая регрессия (Weights, Bias) или градиентный бустинг деревьев (Trees, Bias - начальный логит).
+// Признаки стандартизуются по средним и отклонениям обучающей выборки
+type MLModel struct {
+	Symbol       string         `json:"symbol"`
+	Interval     string         `json:"interval"`
+	Type         string         `json:"type"`    // LOGISTIC или GBT
+	Horizon      int            `json:"horizon"` // Через сколько свечей проверяется направление
+	Window       int            `json:"window"`  // Число свечей окна расчета признаков
+	Features     []string       `json:"features"`
+	Mean         []float64      `json:"mean"`
+	Std          []float64      `json:"std"`
+	Weights      []float64      `json:"weights,omitempty"`
+	Bias         float64        `json:"bias"`
+	LearningRate float64        `json:"learning_rate,omitempty"`
+	Trees        [][]MLTreeNode `json:"trees,omitempty"`
+	CV           MLCVReport     `json:"cv"`
+	Samples      int            `json:"samples"`
+	TrainedAt    time.Time      `json:"trained_at"`
+	DataEnd      time.Time      `json:"data_end"` // Время последней свечи обучающей истории
+}
+
+// MLTreeNode - узел дерева регрессии; лист, если Feature < 0
+type MLTreeNode struct {
+	Feature   int     `json:"f"`
+	Threshold float64 `json:"t,omitempty"` // Стандартизованный признак <= Threshold - влево
+	Left      int     `json:"l,omitempty"`
+	Right     int     `json:"r,omitempty"`
+	Value     float64 `json:"v,omitempty"` // Вклад листа в логит
+}
+
+// MLCVReport - качество модели на скользящей проверке по времени
+type MLCVReport struct {
+	Folds       int     `json:"folds"`
+	TestSamples int     `json:"test_samples"`
+	LogLoss     float64 `json:"log_loss"`
+	BaseLogLoss float64 `json:"base_log_loss"` // Лог-лосс постоянной доли роста из обучающей части
+	Accuracy    float64 `json:"accuracy"`
+}
+
+// EnsembleWeights - веса стекинга ансамбля: P(рост) = sigmoid(Bias + Σ Weights[i]*голос Members[i]),
+// голос участника - его счет, нормированный на порог высокой уверенности, в диапазоне -1..1
+type EnsembleWeights struct {
+	Symbol             string             `json:"symbol"`
+	Interval           string             `json:"interval"`
+	Method             string             `json:"method"` // STACKING
+	Members            []string           `json:"members"`
+	Weights            []float64          `json:"weights"`
+	Bias               float64            `json:"bias"`
+	ValidationAccuracy float64            `json:"validation_accuracy"` // Точность ансамбля на отложенной части, %
+	MemberAccuracy     map[string]float64 `json:"member_accuracy"`     // Точность участников на отложенной части, %
+	Samples            int                `json:"samples"`
+	TrainedAt          time.Time          `json:"trained_at"`
+}
+
+// VolatilityModel - параметры модели условной дисперсии лог-доходностей:
+// GARCH(1,1) sigma2[t+1] = Omega + Alpha*r[t]^2 + Beta*sigma2[t] или EWMA с Lambda
+type VolatilityModel struct {
+	Symbol        string    `json:"symbol"`
+	Interval      string    `json:"interval"`
+	Method        string    `json:"method"` // GARCH или EWMA
+	Omega         float64   `json:"omega,omitempty"`
+	Alpha         float64   `json:"alpha,omitempty"`
+	Beta          float64   `json:"beta,omitempty"`
+	Lambda        float64   `json:"lambda,omitempty"`
+	LongRunVar    float64   `json:"long_run_var"` // Безусловная дисперсия за свечу
+	LogLikelihood float64   `json:"log_likelihood"`
+	Samples       int       `json:"samples"`
+	TrainedAt     time.Time `json:"trained_at"`
+}
+
+// PriceRange - диапазон цены с заданной вероятностью
+type PriceRange struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// VolatilityForecast - прогноз волатильности и ожидаемого диапазона цены на Bars свечей вперед
+type VolatilityForecast struct {
+	Method       string     `json:"method"` // GARCH или EWMA
+	Bars         int        `json:"bars"`
+	BarSigma     float64    `json:"bar_sigma"`     // Прогноз стандартного отклонения доходности следующей свечи
+	HorizonSigma float64    `json:"horizon_sigma"` // Стандартное отклонение суммарной доходности за Bars свечей
+	Range68      PriceRange `json:"range_68"`
+	Range95      PriceRange `json:"range_95"`
+}
+
+// Calibrator переводит счет прогноза в вероятность верного направления.
+// Обучается на исторических исходах отдельно для символа, таймфрейма и стратегии
+type Calibrator struct {
+	Symbol     string    `json:"symbol"`
+	Interval   string    `json:"interval"`
+	Strategy   string    `json:"strategy"`
+	Horizon    string    `json:"horizon,omitempty"` // Пусто - следующая свеча
+	Method     string    `json:"method"`            // PLATT или ISOTONIC
+	PlattA     float64   `json:"platt_a,omitempty"` // p = 1 / (1 + exp(-(A*|score| + B)))
+	PlattB     float64   `json:"platt_b,omitempty"`
+	Knots      []float64 `json:"knots,omitempty"`  // |score| узлов изотонической регрессии
+	Values     []float64 `json:"values,omitempty"` // Вероятности в узлах
+	Samples    int       `json:"samples"`
+	BaseRate   float64   `json:"base_rate"`   // Доля верных прогнозов в обучающей выборке
+	BrierScore float64   `json:"brier_score"` // На отложенной выборке
+	TrainedAt  time.Time `json:"trained_at"`
+}
+
+// ReliabilityBin - корзина диаграммы надежности: предсказанная и наблюдаемая частота
+type ReliabilityBin struct {
+	Lower         float64 `json:"lower"`
+	Upper         float64 `json:"upper"`
+	MeanPredicted float64 `json:"mean_predicted"`
+	ObservedRate  float64 `json:"observed_rate"`
+	Count         int     `json:"count"`
+}
+
+// CalibrationReport - качество калибровки на отложенной части истории
+type CalibrationReport struct {
+	Method        string           `json:"method"`
+	TrainSamples  int              `json:"train_samples"`
+	TestSamples   int              `json:"test_samples"`
+	BrierScore    float64          `json:"brier_score"`
+	BaselineBrier float64          `json:"baseline_brier"` // Брайер постоянного прогноза базовой частотой
+	ECE           float64          `json:"ece"`            // Ожидаемая ошибка калибровки
+	Bins          []ReliabilityBin `json:"bins"`
+	Calibrator    *Calibrator      `json:"calibrator,omitempty"` // Обучен на всей истории
+}
+
+// PatternStats содержит статистику исходов для одного свечного паттерна
+type PatternStats struct {
+	Pattern          string  `json:"pattern"`
+	Direction        string  `json:"direction"`          // BULLISH, BEARISH или NEUTRAL
+	Occurrences      int     `json:"occurrences"`        // Сколько раз паттерн был найден
+	Hits             int     `json:"hits"`               // Сколько раз цена ушла в ожидаемую сторону
+	HitRate          float64 `json:"hit_rate"`           // Доля успешных исходов (0-1)
+	AvgForwardReturn float64 `json:"avg_forward_return"` // Средняя доходность через Horizon свечей, %
+	AvgWin           float64 `json:"avg_win"`            // Средний выигрыш в направлении паттерна, %
+	AvgLoss          float64 `json:"avg_loss"`           // Средний проигрыш в направлении паттерна, %
+	Expectancy       float64 `json:"expectancy"`         // Математическое ожидание в направлении паттерна, %
+}
+
+// PatternStatsTable содержит статистику паттернов для символа и таймфрейма
+type PatternStatsTable struct {
+	Symbol      string                  `json:"symbol"`
+	Interval    string                  `json:"interval"`
+	Horizon     int                     `json:"horizon"` // Горизонт оценки исхода в свечах
+	Candles     int                     `json:"candles"` // Количество просканированных свечей
+	GeneratedAt time.Time               `json:"generated_at"`
+	DataEnd     time.Time               `json:"data_end"` // Время последней просканированной свечи
+	Stats       map[string]PatternStats `json:"stats"`
+}
+
 // TradingSuggestion содержит конкретные рекомендации по торговле
 type TradingSuggestion struct {
 	Action          string   `json:"action"`            // BUY, SELL, NO_TRADE
@@ -282,6 +656,8 @@ type TradingSuggestion struct {
 	RiskRewardRatio float64  `json:"risk_reward_ratio"` // Соотношение риск/доходность
 	AccountRisk     float64  `json:"account_risk"`      // Процент риска от размера счета
 	Factors         []string `json:"factors"`           // Факторы, повлиявшие на решение
+	// ExpectedRange - прогноз волатильности, по которому выставлены стоп-лосс и тейк-профит
+	ExpectedRange *VolatilityForecast `json:"expected_range,omitempty"`
 }
 
 // Prediction представляет результат анализа и предсказания
@@ -291,6 +667,136 @@ type Prediction struct {
 	Score             float64
 	Factors           []string
 	TradingSuggestion *TradingSuggestion
+	Strategy          string // Имя стратегии, сформировавшей прогноз
+	ParamsVersion     string // Версия параметров скоринга, с которыми получен прогноз
+	// FactorScores - вклад факторов в счет по структурным идентификаторам
+	// (RSI, MACD, PATTERN_HAMMER...); положительный вклад - бычий
+	FactorScores map[string]float64
+	// Probability - откалиброванная вероятность того, что направление окажется верным
+	// через горизонт прогноза; 0, если калибратор для символа не обучен
+	Probability       float64
+	CalibrationMethod string // PLATT или ISOTONIC
+	// PredictionTarget - время закрытия следующей свечи, по которой проверяется прогноз
+	PredictionTarget time.Time
+	// Horizons - прогнозы на несколько свечей вперед и до конца торговой сессии
+	Horizons []HorizonForecast
+	// Breakdown - разложение Score на вклады факторов и корректировок
+	Breakdown *ScoreBreakdown
+	// EventRisk - важные экономические релизы рядом со временем прогноза; nil, если календарь пуст
+	EventRisk *EventRisk
+}
+
+// EconomicEvent - запланированный релиз экономического календаря
+type EconomicEvent struct {
+	Time     time.Time `json:"time"`               // Время релиза, UTC
+	Currency string    `json:"currency"`           // Валюта, на которую влияет релиз
+	Impact   string    `json:"impact"`             // LOW, MEDIUM или HIGH
+	Title    string    `json:"title"`              // Название релиза
+	Forecast string    `json:"forecast,omitempty"` // Прогноз аналитиков
+	Previous string    `json:"previous,omitempty"` // Предыдущее значение
+}
+
+// EventRisk - близость прогноза к важным релизам по валютам инструмента
+type EventRisk struct {
+	Blackout   bool            `json:"blackout"`       // Интервал прогноза попадает в окно вокруг важного релиза
+	Suppressed bool            `json:"suppressed"`     // Торговая рекомендация снята из-за окна
+	Events     []EconomicEvent `json:"events"`         // Релизы, окна которых пересекают интервал прогноза
+	Next       *EconomicEvent  `json:"next,omitempty"` // Ближайший следующий важный релиз
+}
+
+// Factor - фактор прогноза с вкладом в итоговый счет
+type Factor struct {
+	ID           string            `json:"id"`           // Структурный идентификатор: RSI, PATTERN_HAMMER...
+	Category     string            `json:"category"`     // TREND, MOMENTUM, PATTERN, LEVELS, VOLUME, DIVERGENCE, REGIME, MODEL, ADJUSTMENT
+	Direction    string            `json:"direction"`    // BULLISH, BEARISH или NEUTRAL по знаку вклада
+	Value        float64           `json:"value"`        // Исходное значение: RSI, сила режима, множитель корректировки
+	Contribution float64           `json:"contribution"` // Вклад в итоговый счет
+	Text         map[string]string `json:"text"`         // Описание по языкам (en, ru)
+}
+
+// ScoreBreakdown - разложение итогового счета: RawScore плюс вклады корректировок равен Score
+type ScoreBreakdown struct {
+	Factors     []Factor `json:"factors"`               // Вклады сигналов в порядке расчета
+	RawScore    float64  `json:"raw_score"`             // Сумма вкладов сигналов
+	Adjustments []Factor `json:"adjustments,omitempty"` // Множители и ограничения, выраженные как вклады
+	Score       float64  `json:"score"`
+}
+
+// HorizonForecast - прогноз на отдельный горизонт со своим направлением и целевым временем
+type HorizonForecast struct {
+	Horizon          string    `json:"horizon"` // NEXT_BAR, 3_BARS, 12_BARS, SESSION_END
+	Bars             int       `json:"bars"`
+	Direction        string    `json:"direction"`
+	Confidence       string    `json:"confidence"`
+	Score            float64   `json:"score"`
+	Probability      float64   `json:"probability,omitempty"` // 0, если калибратор горизонта не обучен
+	ExpectedMove     float64   `json:"expected_move"`         // Типичное абсолютное изменение цены за горизонт
+	ExpectedMovePct  float64   `json:"expected_move_pct"`
+	PredictionTarget time.Time `json:"prediction_target"` // Время закрытия последней свечи горизонта
+}
+
+// StrategyInput содержит рыночные данные, передаваемые стратегии
+type StrategyInput struct {
+	Candles    []Candle
+	Indicators *TechnicalIndicators
+	MTFData    map[string][]Candle
+	Regime     *MarketRegime
+	Anomaly    *AnomalyDetection
+	Config     *Config
+	// MarketAnalysis - результат AnalyzeMarket с бенчмарком; nil, если этап отключен
+	MarketAnalysis *MarketAnalysis
+}
+
+// StrategyParams - версионированный набор весов и порогов скоринга EnhancedPrediction
+type StrategyParams struct {
+	Version     string            `json:"version"`
+	Description string            `json:"description,omitempty"`
+	Weights     ScoringWeights    `json:"weights"`
+	Thresholds  ScoringThresholds `json:"thresholds"`
+	Risk        RiskParams        `json:"risk"`
+}
+
+// ScoringWeights - веса факторов, добавляемые к бычьему или медвежьему счету
+type ScoringWeights struct {
+	RegimeTrend          float64            `json:"regime_trend"`          // Трендовый режим, умножается на силу режима
+	RegimeRange          float64            `json:"regime_range"`          // Возврат к средней в боковике
+	TrendAlignment       float64            `json:"trend_alignment"`       // Согласованность трендов на таймфреймах
+	RSI                  float64            `json:"rsi"`                   // Перепроданность/перекупленность RSI
+	MACD                 float64            `json:"macd"`                  // Гистограмма MACD
+	Stochastic           float64            `json:"stochastic"`            // Разворот стохастика
+	Patterns             map[string]float64 `json:"patterns"`              // Базовые веса паттернов price action
+	SupportPenalty       float64            `json:"support_penalty"`       // Снижение встречного счета у уровня
+	SupportBonus         float64            `json:"support_bonus"`         // Добавка к счету отскока от уровня
+	OrderFlow            float64            `json:"order_flow"`            // Направление потока ордеров
+	StrongTradeSignal    float64            `json:"strong_trade_signal"`   // STRONG_BUY / STRONG_SELL
+	TradeSignal          float64            `json:"trade_signal"`          // BUY / SELL
+	RegularDivergence    float64            `json:"regular_divergence"`    // Регулярная дивергенция, умножается на силу
+	HiddenDivergence     float64            `json:"hidden_divergence"`     // Скрытая дивергенция, умножается на силу
+	DivergenceConfluence float64            `json:"divergence_confluence"` // Бонус за каждый дополнительный индикатор конфлюенции
+	AnomalyPenalty       float64            `json:"anomaly_penalty"`       // Доля счета, снимаемая при аномалии со score 1
+	HighVolatility       float64            `json:"high_volatility"`       // Множитель уверенности при высокой волатильности
+	LowVolatility        float64            `json:"low_volatility"`        // Множитель уверенности при низкой волатильности
+	MarketAnalysis       float64            `json:"market_analysis"`       // Направление AnalyzeMarket, умножается на его уверенность
+	CurrencyStrength     float64            `json:"currency_strength"`     // Разница силы базовой и котируемой валют
+}
+
+// ScoringThresholds - пороги индикаторов и итогового счета
+type ScoringThresholds struct {
+	Direction            float64 `json:"direction"`       // |счет| выше порога дает BUY/SELL
+	HighConfidence       float64 `json:"high_confidence"` // |счет| выше порога - HIGH
+	LowConfidence        float64 `json:"low_confidence"`  // |счет| ниже порога - LOW
+	RSIOversold          float64 `json:"rsi_oversold"`
+	RSIOverbought        float64 `json:"rsi_overbought"`
+	StochasticOversold   float64 `json:"stochastic_oversold"`
+	StochasticOverbought float64 `json:"stochastic_overbought"`
+}
+
+// RiskParams - параметры расчета размера позиции
+type RiskParams struct {
+	AccountSize  float64 `json:"account_size"`
+	RiskPerTrade float64 `json:"risk_per_trade"` // Доля счета, 0.01 = 1%
+	// VolatilityBars - горизонт прогноза волатильности в свечах для стоп-лосса и тейк-профита
+	VolatilityBars int `json:"volatility_bars"`
 }
 
 // MarketAnalysis представляет полный анализ рынка
@@ -326,10 +832,62 @@ type MarketSentiment struct {
 type CorrelationAnalysis struct {
 	AssetCorrelations     map[string]float64 `json:"asset_correlations"`     // Корреляции с другими активами
 	MarketCorrelation     float64            `json:"market_correlation"`     // Корреляция с рынком
+	Benchmark             string             `json:"benchmark,omitempty"`    // Символ бенчмарка рынка
+	MarketTrend           float64            `json:"market_trend"`           // Изменение бенчмарка за окно, %
 	VolatilityCorrelation float64            `json:"volatility_correlation"` // Корреляция с волатильностью
 	VolumeCorrelation     float64            `json:"volume_correlation"`     // Корреляция с объемом
 }
 
+// CorrelationMatrix - корреляции логарифмических доходностей инструментов за окно из Window свечей
+type CorrelationMatrix struct {
+	Window  int         `json:"window"`
+	Symbols []string    `json:"symbols"`
+	Values  [][]float64 `json:"values"`  // Values[i][j] - корреляция Symbols[i] и Symbols[j]; 0, если данных мало
+	Samples [][]int     `json:"samples"` // Число общих доходностей пары
+}
+
+// CorrelationBreakdown - пара, корреляция которой на коротком окне разошлась с длинным
+type CorrelationBreakdown struct {
+	SymbolA     string  `json:"symbol_a"`
+	SymbolB     string  `json:"symbol_b"`
+	ShortWindow int     `json:"short_window"`
+	LongWindow  int     `json:"long_window"`
+	Short       float64 `json:"short"`  // Корреляция на коротком окне
+	Long        float64 `json:"long"`   // Корреляция на длинном окне
+	Change      float64 `json:"change"` // Short - Long
+}
+
+// CorrelationSnapshot - матрицы корреляций на нескольких окнах для одного таймфрейма
+type CorrelationSnapshot struct {
+	Interval   string                 `json:"interval"`
+	Matrices   []CorrelationMatrix    `json:"matrices"` // По возрастанию окна
+	Breakdowns []CorrelationBreakdown `json:"breakdowns"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// PairCorrelation - корреляция инструмента с другим на каждом окне снимка
+type PairCorrelation struct {
+	Symbol       string    `json:"symbol"`
+	Correlations []float64 `json:"correlations"` // В порядке CorrelationSnapshot.Matrices
+	Breakdown    bool      `json:"breakdown"`
+}
+
+// CurrencyStrength - сила валюты, выделенная из доходностей всех пар с ее участием
+type CurrencyStrength struct {
+	Currency string             `json:"currency"`
+	Strength map[string]float64 `json:"strength"` // Изменение силы за окно в процентах по таймфреймам
+	Score    float64            `json:"score"`    // Среднее по таймфреймам значение в единицах разброса валют
+}
+
+// CurrencyStrengthSnapshot - рейтинг валют на нескольких таймфреймах
+type CurrencyStrengthSnapshot struct {
+	Intervals  []string           `json:"intervals"`
+	Lookback   int                `json:"lookback"`   // Окно изменения в свечах каждого таймфрейма
+	Currencies []CurrencyStrength `json:"currencies"` // По убыванию Score
+	Pairs      int                `json:"pairs"`      // Число пар, участвовавших в расчете
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
 // LiquidityAnalysis представляет результаты анализа ликвидности
 type LiquidityAnalysis struct {
 	BidAskSpread   float64 `json:"bid_ask_spread"`   // Спред между bid и ask
@@ -414,6 +972,10 @@ type NewsItem struct {
 	Impact      float64   `json:"impact"`       // Влияние (0-1)
 	PublishedAt time.Time `json:"published_at"` // Время публикации
 	Symbol      string    `json:"symbol"`       // Символ инструмента
+	Source      string    `json:"source,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Currencies  []string  `json:"currencies,omitempty"` // Упомянутые валюты и активы
+	Relevance   float64   `json:"relevance,omitempty"`  // Релевантность для инструмента (0-1)
 }
 
 // FundamentalAnalysis представляет фундаментальный анализ
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 func CalculateCandlesForBacktest(interval string, days int) int {
 	candlesPerDay := 0
 
@@ -43,3 +45,35 @@ func CalculateCandlesForBacktest(interval string, days int) int {
 	// Calculate the number of candles for the specified days and add a buffer
 	return int(float64(candlesPerDay) * float64(days) * 1.1)
 }
+
+// IntervalDuration возвращает длительность свечи для интервала Twelve Data; 0, если интервал неизвестен.
+// Месяц считается за 30 дней
+func IntervalDuration(interval string) time.Duration {
+	switch interval {
+	case "1min":
+		return time.Minute
+	case "5min":
+		return 5 * time.Minute
+	case "15min":
+		return 15 * time.Minute
+	case "30min":
+		return 30 * time.Minute
+	case "45min":
+		return 45 * time.Minute
+	case "1h":
+		return time.Hour
+	case "2h":
+		return 2 * time.Hour
+	case "4h":
+		return 4 * time.Hour
+	case "8h":
+		return 8 * time.Hour
+	case "1day":
+		return 24 * time.Hour
+	case "1week":
+		return 7 * 24 * time.Hour
+	case "1month":
+		return 30 * 24 * time.Hour
+	}
+	return 0
+}